@@ -1,30 +1,128 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"os"
+	"os/signal"
 	"soundporter/internal/actions"
+	"soundporter/internal/adapters"
 	"strings"
+	"syscall"
 
-	"github.com/charmbracelet/huh"
 	_ "github.com/joho/godotenv/autoload"
 	"github.com/urfave/cli/v2"
 )
 
+// platformFlag selects a registered platform. The usage lists every platform in the registry.
+func platformFlag(name string, aliases []string, usage string) cli.Flag {
+	return &cli.StringFlag{
+		Name:     name,
+		Aliases:  aliases,
+		Usage:    fmt.Sprintf("%s (%s)", usage, strings.Join(adapters.PlatformNames(), ", ")),
+		Required: false,
+	}
+}
+
+// platformsHelp describes every registered platform and the environment variables it reads credentials from
+func platformsHelp() string {
+	var b strings.Builder
+	b.WriteString("Platforms:")
+	for _, r := range adapters.Registrations() {
+		fmt.Fprintf(&b, "\n   %-10s %s", r.Name, r.DisplayName)
+		if r.Plugin != "" {
+			fmt.Fprintf(&b, "\n              %s", r.Plugin)
+			continue
+		}
+		var privacy []string
+		for _, p := range r.Capabilities.PrivacyLevels {
+			privacy = append(privacy, string(p))
+		}
+		fmt.Fprintf(&b, "\n              Playlist privacy: %s", strings.Join(privacy, ", "))
+		for _, spec := range r.Credentials {
+			optional := ""
+			if spec.Optional {
+				optional = " (optional)"
+			}
+			fmt.Fprintf(&b, "\n              %s: %s%s", spec.EnvVar, spec.Description, optional)
+		}
+	}
+	return b.String()
+}
+
+// privacyFlag selects the visibility of playlists created by import and transfer
+func privacyFlag() cli.Flag {
+	return &cli.StringFlag{
+		Name:     "privacy",
+		Usage:    "Visibility of the created playlist (private, unlisted, public), if the platform supports it (default: platform default)",
+		Required: false,
+	}
+}
+
+// matchingFlags are shared by the commands that match tracks on a target platform
+func matchingFlags() []cli.Flag {
+	return []cli.Flag{
+		&cli.StringFlag{
+			Name:     "cache",
+			Usage:    "Match cache file used to remember resolved tracks (default: user config directory)",
+			Required: false,
+		},
+		&cli.StringFlag{
+			Name:     "overrides",
+			Usage:    "CSV file pinning source tracks to target IDs or marking them as never import (default: user config directory)",
+			Required: false,
+		},
+		&cli.StringFlag{
+			Name:     "report",
+			Usage:    "CSV file listing tracks that could not be matched or added (default: unmatched.csv)",
+			Required: false,
+		},
+		&cli.Float64Flag{
+			Name:     "min-confidence",
+			Usage:    "Lowest match confidence (0-1) accepted for a search result",
+			Required: false,
+		},
+		&cli.BoolFlag{
+			Name:     "musicbrainz",
+			Usage:    "Identify tracks on MusicBrainz before searching, for more precise matches (one lookup per second)",
+			Required: false,
+		},
+	}
+}
+
 func main() {
+	// Ctrl-C cancels the context handed to every command, stopping paging and mutations
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	var cancelTimeout context.CancelFunc = func() {}
 	app := &cli.App{
-		Name:  "soundporter",
-		Usage: "Soundporter is a CLI tool to export and import playlists from and to music platforms.",
+		Name:        "soundporter",
+		Usage:       "Soundporter is a CLI tool to export and import playlists from and to music platforms.",
+		Description: platformsHelp(),
+		Flags: []cli.Flag{
+			&cli.DurationFlag{
+				Name:     "timeout",
+				Usage:    "Give up after this long, e.g. 30m (0 means no timeout)",
+				Required: false,
+			},
+		},
+		Before: func(c *cli.Context) error {
+			if timeout := c.Duration("timeout"); timeout > 0 {
+				c.Context, cancelTimeout = context.WithTimeout(c.Context, timeout)
+			}
+			return nil
+		},
+		After: func(c *cli.Context) error {
+			cancelTimeout()
+			return nil
+		},
 		Commands: []*cli.Command{
 			{
 				Name:  "export",
 				Usage: "Export playlists from a platform",
 				Flags: []cli.Flag{
-					&cli.StringFlag{
-						Name:     "from",
-						Usage:    "Platform to export from (spotify, youtube)",
-						Required: false,
-					},
+					platformFlag("from", nil, "Platform to export from"),
 					&cli.StringFlag{
 						Name:     "file",
 						Usage:    "File path to save the exported playlists (default: playlists.csv)",
@@ -36,43 +134,54 @@ func main() {
 			{
 				Name:  "import",
 				Usage: "Import playlists to a platform",
-				Flags: []cli.Flag{
-					&cli.StringFlag{
-						Name:     "to",
-						Aliases:  []string{"t"},
-						Usage:    "Platform to import to (spotify, youtube)",
-						Required: false,
-					},
+				Flags: append([]cli.Flag{
+					platformFlag("to", []string{"t"}, "Platform to import to"),
 					&cli.StringFlag{
 						Name:     "file",
 						Aliases:  []string{"f"},
 						Usage:    "CSV file to import",
 						Required: true,
 					},
-				},
-				Action: func(c *cli.Context) error {
-					platform := strings.ToLower(c.String("to"))
-					sourceFile := c.String("file")
-					if platform == "" {
-						huh.NewSelect[string]().
-							Title("Choose the platform to import to").
-							Options(
-								huh.NewOption("Spotify", "spotify"),
-								huh.NewOption("YouTube Music", "youtube"),
-							).
-							Value(&platform).
-							Run()
-					}
-					fmt.Println("Not implemented yet. Will importing from file:", sourceFile)
-					return nil
-				},
+					privacyFlag(),
+				}, matchingFlags()...),
+				Action: actions.ImportPlaylist,
+			},
+			{
+				Name:  "transfer",
+				Usage: "Transfer a playlist from one platform to another",
+				Flags: append([]cli.Flag{
+					platformFlag("from", nil, "Platform to transfer from"),
+					platformFlag("to", []string{"t"}, "Platform to transfer to"),
+					privacyFlag(),
+				}, matchingFlags()...),
+				Action: actions.TransferPlaylist,
+			},
+			{
+				Name:  "retry-unmatched",
+				Usage: "Retry the tracks listed in an unmatched tracks report against the playlists they were meant for",
+				Flags: append([]cli.Flag{
+					platformFlag("to", []string{"t"}, "Platform to retry on, by default the one recorded in the report for each track"),
+					&cli.StringFlag{
+						Name:     "playlist",
+						Aliases:  []string{"p"},
+						Usage:    "ID of the playlist to add tracks to (default: the playlist recorded in the report for each track)",
+						Required: false,
+					},
+					&cli.BoolFlag{
+						Name:     "relaxed",
+						Usage:    "Accept lower confidence matches than a normal import or transfer",
+						Required: false,
+					},
+				}, matchingFlags()...),
+				Action: actions.RetryUnmatched,
 			},
 		},
 	}
 
-	err := app.Run(os.Args)
+	err := app.RunContext(ctx, os.Args)
 	if err != nil {
 		fmt.Println(err)
+		stop()
 		os.Exit(1)
 	}
 }
@@ -0,0 +1,55 @@
+package actions
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"soundporter/internal/porter"
+	"strings"
+
+	"github.com/charmbracelet/huh"
+	"github.com/charmbracelet/huh/spinner"
+	"github.com/urfave/cli/v2"
+)
+
+func ImportPlaylist(c *cli.Context) error {
+	platform := strings.ToLower(c.String("to"))
+	sourceFile := c.String("file")
+
+	promptPlatform("Choose the platform to import to", &platform)
+
+	opts, err := loadTransferOptions(c)
+	if err != nil {
+		return err
+	}
+
+	p, err := porter.NewPorterWithCredentials(platform, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create porter for platform %s: %v", platform, err)
+	}
+	defer p.Close()
+	if err := checkPrivacy(p, opts.Privacy); err != nil {
+		return err
+	}
+
+	// handle auth
+	if err := p.Authenticate(c.Context); err != nil {
+		return err
+	}
+
+	playlistName := strings.TrimSuffix(filepath.Base(sourceFile), filepath.Ext(sourceFile))
+	huh.NewInput().
+		Title("Enter a name for the new playlist").
+		Value(&playlistName).
+		Run()
+
+	importFile := func(ctx context.Context) error {
+		return p.ImportPlaylistFromCSV(ctx, sourceFile, playlistName, opts)
+	}
+
+	err = spinner.New().Title("Importing...").Context(c.Context).ActionWithErr(importFile).Run()
+	if err != nil {
+		return err
+	}
+	return saveReport(c, opts.Report)
+}
@@ -0,0 +1,50 @@
+package actions
+
+import (
+	"context"
+	"soundporter/internal/adapters"
+	"soundporter/internal/playlist"
+	"soundporter/internal/porter"
+
+	"github.com/charmbracelet/huh"
+)
+
+// promptPlatform asks the user to pick a platform when none was given on the command line
+func promptPlatform(title string, platform *string) error {
+	if *platform != "" {
+		return nil
+	}
+	var options []huh.Option[string]
+	for _, r := range adapters.Registrations() {
+		options = append(options, huh.NewOption(r.DisplayName, string(r.Name)))
+	}
+	return huh.NewSelect[string]().
+		Title(title).
+		Options(options...).
+		Value(platform).
+		Run()
+}
+
+// promptPlaylist asks the user to pick one of the porter's playlists
+func promptPlaylist(ctx context.Context, title string, p *porter.Porter, playlistId *string) error {
+	return huh.NewSelect[string]().
+		Height(10).
+		Title(title).
+		OptionsFunc(func() []huh.Option[string] {
+			playlists, err := p.GetPlaylists(ctx)
+			if err != nil {
+				return nil
+			}
+			return getPlaylistOptions(playlists)
+		}, &p).
+		Value(playlistId).
+		Run()
+}
+
+func getPlaylistOptions(p []playlist.Playlist) []huh.Option[string] {
+	playlistOptions := make([]huh.Option[string], len(p))
+	for i, pl := range p {
+		playlistOptions[i] = huh.NewOption(pl.Name, pl.ID)
+	}
+	return playlistOptions
+}
@@ -0,0 +1,114 @@
+package actions
+
+import (
+	"context"
+	"fmt"
+	"soundporter/internal/porter"
+	"soundporter/internal/report"
+	"strings"
+
+	"github.com/charmbracelet/huh/spinner"
+	"github.com/urfave/cli/v2"
+)
+
+// relaxedMinConfidence is used by retry-unmatched when --relaxed is set
+const relaxedMinConfidence = 0.3
+
+func RetryUnmatched(c *cli.Context) error {
+	reportFile := c.String("report")
+	if reportFile == "" {
+		reportFile = defaultReportFile
+	}
+
+	previous, err := report.Load(reportFile)
+	if err != nil {
+		return fmt.Errorf("failed to read unmatched tracks report %s: %v", reportFile, err)
+	}
+	if len(previous.Entries) == 0 {
+		fmt.Println("No unmatched tracks to retry in", reportFile)
+		return nil
+	}
+
+	opts, err := loadTransferOptions(c)
+	if err != nil {
+		return err
+	}
+	if c.Bool("relaxed") && opts.MinConfidence == 0 {
+		opts.MinConfidence = relaxedMinConfidence
+	}
+
+	// Retry each track on the platform and playlist recorded for it, unless flags override them
+	porters := make(map[string]*porter.Porter)
+	for _, target := range retryTargets(previous.Entries, strings.ToLower(c.String("to")), c.String("playlist")) {
+		platform := target.platform
+		promptPlatform("Choose the platform to retry on", &platform)
+
+		p, ok := porters[platform]
+		if !ok {
+			p, err = porter.NewPorterWithCredentials(platform, nil)
+			if err != nil {
+				return fmt.Errorf("failed to create porter for platform %s: %v", platform, err)
+			}
+			defer p.Close()
+
+			// handle auth
+			if err := p.Authenticate(c.Context); err != nil {
+				return err
+			}
+			porters[platform] = p
+		}
+
+		playlistId := target.playlistID
+		if playlistId == "" {
+			promptPlaylist(c.Context, "Choose the playlist to add tracks to", p, &playlistId)
+		}
+
+		retry := func(ctx context.Context) error {
+			return p.RetryUnmatched(ctx, target.entries, playlistId, opts)
+		}
+		err = spinner.New().Title("Retrying...").Context(c.Context).ActionWithErr(retry).Run()
+		if err != nil {
+			return err
+		}
+	}
+
+	// Rewrite the report so that it only lists the tracks that are still unmatched
+	if opts.Report.Len() == 0 {
+		fmt.Println("All previously unmatched tracks were added")
+	}
+	if err := opts.Report.Save(reportFile); err != nil {
+		return fmt.Errorf("failed to write unmatched tracks report: %v", err)
+	}
+	return nil
+}
+
+// retryTarget is a platform and playlist that tracks of a report were meant for
+type retryTarget struct {
+	platform   string
+	playlistID string
+	entries    []report.Entry
+}
+
+// retryTargets groups report entries by the platform and playlist recorded for them, in the
+// order they first appear. A non-empty platform or playlistID replaces the recorded one.
+func retryTargets(entries []report.Entry, platform, playlistID string) []retryTarget {
+	var targets []retryTarget
+	index := make(map[[2]string]int)
+	for _, e := range entries {
+		key := [2]string{e.TargetPlatform, e.TargetPlaylistID}
+		if platform != "" {
+			key[0] = platform
+		}
+		if playlistID != "" {
+			key[1] = playlistID
+		}
+		i, ok := index[key]
+		if !ok {
+			i = len(targets)
+			index[key] = i
+			targets = append(targets, retryTarget{platform: key[0], playlistID: key[1]})
+		}
+		targets[i].entries = append(targets[i].entries, e)
+	}
+	return targets
+}
@@ -0,0 +1,50 @@
+package actions
+
+import (
+	"testing"
+
+	"soundporter/internal/report"
+)
+
+func TestRetryTargetsGroupsByPlatformAndPlaylist(t *testing.T) {
+	entries := []report.Entry{
+		{SourceID: "a", TargetPlatform: "youtube", TargetPlaylistID: "PL1"},
+		{SourceID: "b", TargetPlatform: "deezer", TargetPlaylistID: "42"},
+		{SourceID: "c", TargetPlatform: "youtube", TargetPlaylistID: "PL2"},
+		{SourceID: "d", TargetPlatform: "youtube", TargetPlaylistID: "PL1"},
+	}
+
+	tests := []struct {
+		name               string
+		platform, playlist string
+		want               []string
+	}{
+		{"recorded targets", "", "", []string{"youtube/PL1:a,d", "deezer/42:b", "youtube/PL2:c"}},
+		{"platform flag", "tidal", "", []string{"tidal/PL1:a,d", "tidal/42:b", "tidal/PL2:c"}},
+		{"playlist flag", "", "X", []string{"youtube/X:a,c,d", "deezer/X:b"}},
+		{"both flags", "tidal", "X", []string{"tidal/X:a,b,c,d"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, target := range retryTargets(entries, tt.platform, tt.playlist) {
+				s := target.platform + "/" + target.playlistID + ":"
+				for i, e := range target.entries {
+					if i > 0 {
+						s += ","
+					}
+					s += e.SourceID
+				}
+				got = append(got, s)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("group %d: got %s, want %s", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,139 @@
+package actions
+
+import (
+	"context"
+	"fmt"
+	"soundporter/internal/adapters"
+	"soundporter/internal/matcher"
+	"soundporter/internal/musicbrainz"
+	"soundporter/internal/porter"
+	"soundporter/internal/report"
+	"strings"
+
+	"github.com/charmbracelet/huh"
+	"github.com/charmbracelet/huh/spinner"
+	"github.com/urfave/cli/v2"
+)
+
+// defaultReportFile is where tracks that could not be matched or added are written
+const defaultReportFile = "unmatched.csv"
+
+func TransferPlaylist(c *cli.Context) error {
+	from := strings.ToLower(c.String("from"))
+	to := strings.ToLower(c.String("to"))
+
+	promptPlatform("Choose the platform to transfer from", &from)
+	promptPlatform("Choose the platform to transfer to", &to)
+
+	opts, err := loadTransferOptions(c)
+	if err != nil {
+		return err
+	}
+
+	source, err := porter.NewPorterWithCredentials(from, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create porter for platform %s: %v", from, err)
+	}
+	defer source.Close()
+	target, err := porter.NewPorterWithCredentials(to, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create porter for platform %s: %v", to, err)
+	}
+	defer target.Close()
+	if err := checkPrivacy(target, opts.Privacy); err != nil {
+		return err
+	}
+
+	// handle auth
+	if err := source.Authenticate(c.Context); err != nil {
+		return err
+	}
+	if err := target.Authenticate(c.Context); err != nil {
+		return err
+	}
+
+	var playlistId, playlistName string
+	promptPlaylist(c.Context, "Choose a playlist to transfer", source, &playlistId)
+	huh.NewInput().
+		Title("Enter a name for the new playlist").
+		Value(&playlistName).
+		Run()
+
+	transfer := func(ctx context.Context) error {
+		return target.TransferPlaylist(ctx, source, playlistId, playlistName, opts)
+	}
+
+	err = spinner.New().Title("Transferring...").Context(c.Context).ActionWithErr(transfer).Run()
+	if err != nil {
+		return err
+	}
+	return saveReport(c, opts.Report)
+}
+
+// loadTransferOptions builds the matching options shared by import, transfer and retry-unmatched from their flags
+func loadTransferOptions(c *cli.Context) (porter.TransferOptions, error) {
+	cachePath := c.String("cache")
+	if cachePath == "" {
+		cachePath = matcher.DefaultCachePath()
+	}
+	overridesPath := c.String("overrides")
+	if overridesPath == "" {
+		overridesPath = matcher.DefaultOverridesPath()
+	}
+
+	cache, err := matcher.LoadCache(cachePath)
+	if err != nil {
+		return porter.TransferOptions{}, err
+	}
+	overrides, err := matcher.LoadOverrides(overridesPath)
+	if err != nil {
+		return porter.TransferOptions{}, err
+	}
+
+	var resolver *musicbrainz.Resolver
+	if c.Bool("musicbrainz") {
+		mbCache, err := musicbrainz.LoadCache(musicbrainz.DefaultCachePath())
+		if err != nil {
+			return porter.TransferOptions{}, err
+		}
+		resolver = musicbrainz.NewResolver(musicbrainz.NewClient(), mbCache)
+	}
+
+	return porter.TransferOptions{
+		Cache:         cache,
+		Overrides:     overrides,
+		Resolver:      resolver,
+		Report:        &report.Report{},
+		MinConfidence: c.Float64("min-confidence"),
+		Privacy:       adapters.Privacy(strings.ToLower(c.String("privacy"))),
+	}, nil
+}
+
+// checkPrivacy fails early when the target platform can't create playlists with the requested visibility
+func checkPrivacy(p *porter.Porter, privacy adapters.Privacy) error {
+	caps := p.Capabilities()
+	if privacy == "" || caps.SupportsPrivacy(privacy) {
+		return nil
+	}
+	levels := make([]string, len(caps.PrivacyLevels))
+	for i, l := range caps.PrivacyLevels {
+		levels[i] = string(l)
+	}
+	return fmt.Errorf("%s does not support %s playlists, choose one of: %s", p.Platform(), privacy, strings.Join(levels, ", "))
+}
+
+// saveReport writes the unmatched tracks to the report file, if there are any
+func saveReport(c *cli.Context, r *report.Report) error {
+	if r.Len() == 0 {
+		return nil
+	}
+	path := c.String("report")
+	if path == "" {
+		path = defaultReportFile
+	}
+	if err := r.Save(path); err != nil {
+		return fmt.Errorf("failed to write unmatched tracks report: %v", err)
+	}
+	fmt.Printf("Wrote %d unmatched tracks to %s. Run `soundporter retry-unmatched --report %s` to try again.\n", r.Len(), path, path)
+	return nil
+}
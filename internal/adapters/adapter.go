@@ -1,45 +1,180 @@
 package adapters
 
 import (
-	"fmt"
+	"context"
+	"errors"
+	"iter"
+	"slices"
 	"soundporter/internal/playlist"
+	"strings"
 )
 
+// ErrRegionUnavailable is returned when a track exists but cannot be used in the user's region
+var ErrRegionUnavailable = errors.New("track is not available in this region")
+
+// ErrRateLimited is returned when a platform rejects a request because too many were made recently
+var ErrRateLimited = errors.New("rate limited, try again later")
+
 // ApiAdapter defines the interface for adapting different music platform APIs
-// to a common interface that can be used by the application
+// to a common interface that can be used by the application.
+// Every call that talks to the platform takes a context so it can be cancelled.
 type ApiAdapter interface {
 	// Authentication methods
-	Authenticate() error
+	Authenticate(ctx context.Context) error
 	IsAuthenticated() bool
 
 	// Platform-specific methods
-	GetUserPlaylists() ([]playlist.Playlist, error)
-	GetPlaylistItems(playlistID string) ([]playlist.Track, error)
-	CreateNewPlaylist(name string, description string) (playlist.Playlist, error)
-	AddItemsToPlaylist(playlistID string, trackIDs []string) error
+	GetUserPlaylists(ctx context.Context) ([]playlist.Playlist, error)
+	GetPlaylistItems(ctx context.Context, playlistID string) ([]playlist.Track, error)
+	// UserPlaylists and PlaylistItems stream results page by page instead of collecting them first.
+	// Iteration stops after the first error is yielded.
+	UserPlaylists(ctx context.Context) iter.Seq2[playlist.Playlist, error]
+	PlaylistItems(ctx context.Context, playlistID string) iter.Seq2[playlist.Track, error]
+	CreateNewPlaylist(ctx context.Context, name string, description string, privacy Privacy) (playlist.Playlist, error)
+	AddItemsToPlaylist(ctx context.Context, playlistID string, trackIDs []string) error
 
 	// Search functionality
-	SearchTracks(query string, limit int) ([]playlist.Track, error)
+	SearchTracks(ctx context.Context, query string, limit int) ([]playlist.Track, error)
+	SearchTracksBy(ctx context.Context, query SearchQuery, limit int) ([]playlist.Track, error)
+
+	// Capabilities describes the platform's API limits and features
+	Capabilities() Capabilities
 }
 
-// PlatformType represents the supported music platforms
-type PlatformType string
+// Privacy is the visibility of a playlist
+type Privacy string
+
+const (
+	PrivacyPublic   Privacy = "public"
+	PrivacyUnlisted Privacy = "unlisted"
+	PrivacyPrivate  Privacy = "private"
+)
+
+// Capabilities describes the limits and features of a platform's API
+type Capabilities struct {
+	// MaxBatchSize is the most tracks a single AddItemsToPlaylist call should be given
+	MaxBatchSize int
+	// MaxSearchLimit is the most results a single search can return
+	MaxSearchLimit int
+	// SearchFields are the structured fields SearchTracksBy can narrow a search by
+	SearchFields []SearchField
+	// MaxDescriptionLength is the longest playlist description accepted, or 0 if unlimited
+	MaxDescriptionLength int
+	// PrivacyLevels are the playlist visibilities the platform supports; the first is the default
+	PrivacyLevels []Privacy
+	// CanReorderItems and CanRemoveItems report whether playlist items can be moved or deleted
+	CanReorderItems bool
+	CanRemoveItems  bool
+}
+
+// SupportsISRC reports whether tracks can be looked up by ISRC
+func (c Capabilities) SupportsISRC() bool {
+	return slices.Contains(c.SearchFields, SearchFieldISRC)
+}
+
+// SupportsPrivacy reports whether playlists can be created with the given visibility
+func (c Capabilities) SupportsPrivacy(p Privacy) bool {
+	return slices.Contains(c.PrivacyLevels, p)
+}
+
+// SearchField is a structured field that a track search can be narrowed by
+type SearchField string
 
 const (
-	SpotifyPlatform    PlatformType = "spotify"
-	YoutubePlatform    PlatformType = "youtube"
-	AppleMusicPlatform PlatformType = "apple"
+	SearchFieldISRC   SearchField = "isrc"
+	SearchFieldArtist SearchField = "artist"
+	SearchFieldTrack  SearchField = "track"
+	SearchFieldAlbum  SearchField = "album"
 )
 
-// NewApiAdapter is a factory function that creates a new adapter for the specified platform
-func NewApiAdapter(platform string, clientID, clientSecret string) (ApiAdapter, error) {
-	p := PlatformType(platform)
-	switch p {
-	case SpotifyPlatform:
-		return NewSpotifyAdapter(clientID, clientSecret)
-	case YoutubePlatform:
-		return NewYouTubeAdapter(clientID, clientSecret)
-	default:
-		return nil, fmt.Errorf("unsupported platform: %s", platform)
+// SearchQuery describes a track by structured fields. Empty fields are ignored.
+type SearchQuery struct {
+	ISRC   string
+	Artist string
+	Track  string
+	Album  string
+}
+
+// FreeText joins the descriptive fields of the query for platforms without structured search
+func (q SearchQuery) FreeText() string {
+	var parts []string
+	for _, p := range []string{q.Artist, q.Track, q.Album} {
+		if p != "" {
+			parts = append(parts, p)
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+// ParseSearchQuery splits Spotify-style field filters like isrc:X and artist:"Y Z" out of a
+// free-text query. Any remaining free text is added to the Track field.
+func ParseSearchQuery(query string) SearchQuery {
+	var q SearchQuery
+	var free []string
+	rest := query
+	for {
+		rest = strings.TrimLeft(rest, " ")
+		if rest == "" {
+			break
+		}
+
+		var word string
+		space := strings.IndexByte(rest, ' ')
+		if i := strings.Index(rest, `:"`); i > 0 && (space < 0 || i < space) {
+			end := strings.IndexByte(rest[i+2:], '"')
+			if end < 0 {
+				end = len(rest) - i - 2
+			}
+			word = rest[:i+1] + rest[i+2:i+2+end]
+			rest = rest[min(i+3+end, len(rest)):]
+		} else if space < 0 {
+			word, rest = rest, ""
+		} else {
+			word, rest = rest[:space], rest[space:]
+		}
+
+		key, value, _ := strings.Cut(word, ":")
+		switch key {
+		case "isrc":
+			q.ISRC = value
+		case "artist":
+			q.Artist = value
+		case "track":
+			q.Track = value
+		case "album":
+			q.Album = value
+		default:
+			free = append(free, word)
+		}
 	}
+	if len(free) > 0 {
+		q.Track = strings.TrimSpace(q.Track + " " + strings.Join(free, " "))
+	}
+	return q
 }
+
+// SupportsSearchField reports whether the adapter can narrow searches by the given field
+func SupportsSearchField(a ApiAdapter, field SearchField) bool {
+	return slices.Contains(a.Capabilities().SearchFields, field)
+}
+
+// PlatformType represents the supported music platforms
+type PlatformType string
+
+const (
+	SpotifyPlatform      PlatformType = "spotify"
+	YoutubePlatform      PlatformType = "youtube"
+	YTMusicPlatform      PlatformType = "ytmusic"
+	AppleMusicPlatform   PlatformType = "apple"
+	DeezerPlatform       PlatformType = "deezer"
+	TidalPlatform        PlatformType = "tidal"
+	SoundCloudPlatform   PlatformType = "soundcloud"
+	SubsonicPlatform     PlatformType = "subsonic"
+	JellyfinPlatform     PlatformType = "jellyfin"
+	EmbyPlatform         PlatformType = "emby"
+	PlexPlatform         PlatformType = "plex"
+	LocalPlatform        PlatformType = "local"
+	LastfmPlatform       PlatformType = "lastfm"
+	ListenBrainzPlatform PlatformType = "listenbrainz"
+	FakePlatform         PlatformType = "fake"
+)
@@ -0,0 +1,561 @@
+package adapters
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"html/template"
+	"io"
+	"iter"
+	"net/http"
+	"net/url"
+	"os"
+	"soundporter/internal/playlist"
+	"soundporter/internal/utils"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+// appleDeveloperTokenTTL is how long a signed developer token is valid. Apple allows up to six months.
+const appleDeveloperTokenTTL = 12 * time.Hour
+
+var appleMusicCapabilities = Capabilities{
+	MaxBatchSize:   100,
+	MaxSearchLimit: 25,
+	SearchFields:   []SearchField{SearchFieldISRC},
+	// Library playlists are only visible to their owner
+	PrivacyLevels: []Privacy{PrivacyPrivate},
+}
+
+func init() {
+	Register(Registration{
+		Name:        AppleMusicPlatform,
+		DisplayName: "Apple Music",
+		Credentials: []CredentialSpec{
+			{Key: "team_id", EnvVar: "APPLE_MUSIC_TEAM_ID", Description: "Apple developer team ID"},
+			{Key: "key_id", EnvVar: "APPLE_MUSIC_KEY_ID", Description: "ID of the MusicKit private key"},
+			{Key: "private_key", EnvVar: "APPLE_MUSIC_PRIVATE_KEY", Description: "Path to the MusicKit private key (.p8 file)"},
+			{Key: "user_token", EnvVar: "APPLE_MUSIC_USER_TOKEN", Description: "Music user token; if unset you sign in in the browser", Optional: true},
+		},
+		Capabilities: appleMusicCapabilities,
+		New: func(creds Credentials, opts ...Option) (ApiAdapter, error) {
+			key, err := LoadAppleMusicKey(creds["private_key"])
+			if err != nil {
+				return nil, err
+			}
+			if creds["user_token"] != "" {
+				opts = append(opts, WithToken(&oauth2.Token{AccessToken: creds["user_token"]}))
+			}
+			return NewAppleMusicAdapter(creds["team_id"], creds["key_id"], key, opts...)
+		},
+	})
+}
+
+// AppleMusicAdapter adapts the Apple Music API to our common adapter interface.
+// Requests are signed with a developer token and act on the library of the user whose music user token is set.
+type AppleMusicAdapter struct {
+	BaseAdapter
+	teamID     string
+	keyID      string
+	key        *ecdsa.PrivateKey
+	opts       options
+	api        *httpAPI
+	userToken  string
+	storefront string
+
+	tokenMu        sync.Mutex
+	developerToken string
+	tokenExpiry    time.Time
+}
+
+// NewAppleMusicAdapter creates a new AppleMusicAdapter. A token given with WithToken is used as the
+// music user token; without one Authenticate signs the user in with MusicKit JS in the browser.
+func NewAppleMusicAdapter(teamID, keyID string, key *ecdsa.PrivateKey, opts ...Option) (*AppleMusicAdapter, error) {
+	if teamID == "" || keyID == "" || key == nil {
+		return nil, fmt.Errorf("apple music team ID, key ID and private key must be provided")
+	}
+
+	a := &AppleMusicAdapter{
+		BaseAdapter: NewBaseAdapter("Apple Music"),
+		teamID:      teamID,
+		keyID:       keyID,
+		key:         key,
+		opts: newOptions(options{
+			baseURL: "https://api.music.apple.com/v1/",
+		}, opts),
+	}
+	if a.opts.token != nil {
+		a.userToken = a.opts.token.AccessToken
+	}
+	a.api = newHTTPAPI(a.opts)
+	a.api.authorize = a.authorize
+	a.api.decodeError = appleMusicError
+	return a, nil
+}
+
+// LoadAppleMusicKey reads a MusicKit private key from a .p8 file
+func LoadAppleMusicKey(path string) (*ecdsa.PrivateKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading apple music private key: %v", err)
+	}
+	return ParseAppleMusicKey(data)
+}
+
+// ParseAppleMusicKey parses a PEM encoded PKCS #8 EC private key, the format of .p8 files
+func ParseAppleMusicKey(data []byte) (*ecdsa.PrivateKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("apple music private key is not PEM encoded")
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing apple music private key: %v", err)
+	}
+	key, ok := parsed.(*ecdsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("apple music private key is not an EC key")
+	}
+	return key, nil
+}
+
+// Authenticate signs the user in unless a music user token was given, then looks up their storefront
+func (a *AppleMusicAdapter) Authenticate(ctx context.Context) error {
+	if a.userToken == "" {
+		token, err := a.browserLogin(ctx)
+		if err != nil {
+			return err
+		}
+		a.userToken = token
+	}
+
+	var storefront struct {
+		Data []struct {
+			ID string `json:"id"`
+		} `json:"data"`
+	}
+	if err := a.api.get(ctx, "me/storefront", nil, &storefront); err != nil {
+		return fmt.Errorf("error getting storefront: %v", err)
+	}
+	if len(storefront.Data) == 0 {
+		return errors.New("apple music account has no storefront")
+	}
+	a.storefront = storefront.Data[0].ID
+
+	fmt.Println("You are logged in to Apple Music, storefront:", a.storefront)
+	a.SetAuthenticated(true)
+	return nil
+}
+
+// appleLoginPage loads MusicKit JS, asks the user to authorize and posts the music user token back
+var appleLoginPage = template.Must(template.New("login").Parse(`<!DOCTYPE html>
+<html><head><title>Soundporter</title>
+<script src="https://js-cdn.music.apple.com/musickit/v3/musickit.js" data-web-components async></script>
+</head><body><p>Signing in to Apple Music...</p>
+<script>
+document.addEventListener('musickitloaded', async () => {
+	await MusicKit.configure({developerToken: {{.}}, app: {name: 'Soundporter', build: '1.0'}});
+	const token = await MusicKit.getInstance().authorize();
+	await fetch('/callback', {method: 'POST', body: token});
+	document.body.textContent = 'Signed in to Apple Music, you can close this page.';
+});
+</script></body></html>`))
+
+// browserLogin serves a page that signs the user in with MusicKit JS and waits for their music user token
+func (a *AppleMusicAdapter) browserLogin(ctx context.Context) (string, error) {
+	developerToken, err := a.DeveloperToken()
+	if err != nil {
+		return "", err
+	}
+
+	ch := make(chan string)
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /", func(w http.ResponseWriter, r *http.Request) {
+		appleLoginPage.Execute(w, developerToken)
+	})
+	mux.HandleFunc("POST /callback", func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		if err != nil || len(body) == 0 {
+			http.Error(w, "Missing music user token", http.StatusBadRequest)
+			return
+		}
+		ch <- string(body)
+	})
+	server := startCallbackServer(mux)
+
+	loginURL := "http://localhost" + callbackAddr + "/"
+	fmt.Println("Please log in to Apple Music by visiting the following page in your browser:", loginURL)
+	utils.OpenBrowser(loginURL)
+
+	return awaitCallback(ctx, ch, server)
+}
+
+// DeveloperToken returns an ES256 signed developer token, signing a new one when the last one is close to expiring
+func (a *AppleMusicAdapter) DeveloperToken() (string, error) {
+	a.tokenMu.Lock()
+	defer a.tokenMu.Unlock()
+	if a.developerToken != "" && time.Until(a.tokenExpiry) > time.Minute {
+		return a.developerToken, nil
+	}
+
+	now := time.Now()
+	expiry := now.Add(appleDeveloperTokenTTL)
+	header, _ := json.Marshal(map[string]string{"alg": "ES256", "kid": a.keyID})
+	claims, _ := json.Marshal(map[string]any{"iss": a.teamID, "iat": now.Unix(), "exp": expiry.Unix()})
+	signingInput := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(claims)
+
+	digest := sha256.Sum256([]byte(signingInput))
+	r, s, err := ecdsa.Sign(rand.Reader, a.key, digest[:])
+	if err != nil {
+		return "", fmt.Errorf("error signing apple music developer token: %v", err)
+	}
+	// JWS encodes ES256 signatures as the fixed size big-endian R and S values
+	signature := make([]byte, 64)
+	r.FillBytes(signature[:32])
+	s.FillBytes(signature[32:])
+
+	a.developerToken = signingInput + "." + base64.RawURLEncoding.EncodeToString(signature)
+	a.tokenExpiry = expiry
+	return a.developerToken, nil
+}
+
+// authorize adds the developer token and music user token to a request
+func (a *AppleMusicAdapter) authorize(req *http.Request) error {
+	token, err := a.DeveloperToken()
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	if a.userToken != "" {
+		req.Header.Set("Music-User-Token", a.userToken)
+	}
+	return nil
+}
+
+type appleResource struct {
+	ID         string          `json:"id"`
+	Type       string          `json:"type"`
+	Attributes json.RawMessage `json:"attributes"`
+	// Relationships holds the catalog song a library song refers to when requested with include=catalog
+	Relationships struct {
+		Catalog struct {
+			Data []appleResource `json:"data"`
+		} `json:"catalog"`
+	} `json:"relationships"`
+}
+
+type appleResponse struct {
+	Data []appleResource `json:"data"`
+	Next string          `json:"next"`
+}
+
+type applePlaylistAttributes struct {
+	Name        string `json:"name"`
+	DateAdded   string `json:"dateAdded"`
+	Description struct {
+		Standard string `json:"standard"`
+	} `json:"description"`
+}
+
+type appleSongAttributes struct {
+	Name             string `json:"name"`
+	ArtistName       string `json:"artistName"`
+	AlbumName        string `json:"albumName"`
+	ISRC             string `json:"isrc"`
+	DurationInMillis int    `json:"durationInMillis"`
+	DiscNumber       int    `json:"discNumber"`
+	TrackNumber      int    `json:"trackNumber"`
+	ReleaseDate      string `json:"releaseDate"`
+	ContentRating    string `json:"contentRating"`
+	URL              string `json:"url"`
+	// PlayParams is missing for songs that can't be played in the storefront
+	PlayParams *struct {
+		ID        string `json:"id"`
+		Kind      string `json:"kind"`
+		CatalogID string `json:"catalogId"`
+	} `json:"playParams"`
+}
+
+// GetUserPlaylists retrieves all library playlists
+func (a *AppleMusicAdapter) GetUserPlaylists(ctx context.Context) ([]playlist.Playlist, error) {
+	return collect(a.UserPlaylists(ctx))
+}
+
+// UserPlaylists streams the user's library playlists page by page
+func (a *AppleMusicAdapter) UserPlaylists(ctx context.Context) iter.Seq2[playlist.Playlist, error] {
+	return func(yield func(playlist.Playlist, error) bool) {
+		if err := a.CheckAuth(); err != nil {
+			yield(playlist.Playlist{}, err)
+			return
+		}
+
+		for page, err := range a.pages(ctx, "me/library/playlists", url.Values{"limit": {"100"}}) {
+			if err != nil {
+				yield(playlist.Playlist{}, fmt.Errorf("error getting playlists: %v", err))
+				return
+			}
+			for _, res := range page {
+				var attrs applePlaylistAttributes
+				json.Unmarshal(res.Attributes, &attrs)
+				added, _ := time.Parse(time.RFC3339, attrs.DateAdded)
+				if !yield(playlist.Playlist{
+					ID:          res.ID,
+					Name:        attrs.Name,
+					Description: attrs.Description.Standard,
+					CreatedAt:   added,
+				}, nil) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// GetPlaylistItems retrieves all tracks in a library playlist
+func (a *AppleMusicAdapter) GetPlaylistItems(ctx context.Context, playlistID string) ([]playlist.Track, error) {
+	return collect(a.PlaylistItems(ctx, playlistID))
+}
+
+// PlaylistItems streams the tracks of a library playlist page by page. Tracks are identified by
+// their catalog ID where there is one, so they can be added to other playlists and matched by ISRC.
+func (a *AppleMusicAdapter) PlaylistItems(ctx context.Context, playlistID string) iter.Seq2[playlist.Track, error] {
+	return func(yield func(playlist.Track, error) bool) {
+		if err := a.CheckAuth(); err != nil {
+			yield(playlist.Track{}, err)
+			return
+		}
+
+		path := "me/library/playlists/" + url.PathEscape(playlistID) + "/tracks"
+		for page, err := range a.pages(ctx, path, url.Values{"limit": {"100"}, "include": {"catalog"}}) {
+			if err != nil {
+				yield(playlist.Track{}, fmt.Errorf("error getting playlist items: %v", err))
+				return
+			}
+			for _, res := range page {
+				track := appleMusicTrack(res)
+				if catalog := res.Relationships.Catalog.Data; len(catalog) > 0 {
+					// The catalog song carries the ISRC and URL the library song lacks
+					track = appleMusicTrack(catalog[0])
+				}
+				if !yield(track, nil) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// pages follows the next links of a paginated Apple Music response
+func (a *AppleMusicAdapter) pages(ctx context.Context, path string, query url.Values) iter.Seq2[[]appleResource, error] {
+	return func(yield func([]appleResource, error) bool) {
+		for path != "" {
+			var resp appleResponse
+			if err := a.api.get(ctx, path, query, &resp); err != nil {
+				yield(nil, err)
+				return
+			}
+			if !yield(resp.Data, nil) {
+				return
+			}
+			// Next links are relative to the API host and already carry the query
+			path, query = a.nextPath(resp.Next), nil
+		}
+	}
+}
+
+// nextPath resolves a next link like /v1/me/library/playlists?offset=100 against the base URL
+func (a *AppleMusicAdapter) nextPath(next string) string {
+	if next == "" {
+		return ""
+	}
+	base, err := url.Parse(a.opts.baseURL)
+	if err != nil {
+		return ""
+	}
+	ref, err := url.Parse(next)
+	if err != nil {
+		return ""
+	}
+	return base.ResolveReference(ref).String()
+}
+
+// CreateNewPlaylist creates a library playlist. Library playlists are always private.
+func (a *AppleMusicAdapter) CreateNewPlaylist(ctx context.Context, name string, description string, privacy Privacy) (playlist.Playlist, error) {
+	if err := a.CheckAuth(); err != nil {
+		return playlist.Playlist{}, err
+	}
+
+	body := map[string]any{
+		"attributes": map[string]string{"name": name, "description": description},
+	}
+	var resp appleResponse
+	if err := a.api.post(ctx, "me/library/playlists", body, &resp); err != nil {
+		return playlist.Playlist{}, fmt.Errorf("error creating playlist: %v", err)
+	}
+	if len(resp.Data) == 0 {
+		return playlist.Playlist{}, errors.New("error creating playlist: empty response")
+	}
+
+	return playlist.Playlist{
+		ID:          resp.Data[0].ID,
+		Name:        name,
+		Description: description,
+		CreatedAt:   time.Now(),
+	}, nil
+}
+
+// AddItemsToPlaylist adds catalog or library songs to a library playlist
+func (a *AppleMusicAdapter) AddItemsToPlaylist(ctx context.Context, playlistID string, trackIDs []string) error {
+	if err := a.CheckAuth(); err != nil {
+		return err
+	}
+
+	type songRef struct {
+		ID   string `json:"id"`
+		Type string `json:"type"`
+	}
+	data := make([]songRef, len(trackIDs))
+	for i, id := range trackIDs {
+		// Songs uploaded to the library have no catalog ID, only a library ID like i.AbC123
+		kind := "songs"
+		if strings.HasPrefix(id, "i.") {
+			kind = "library-songs"
+		}
+		data[i] = songRef{ID: id, Type: kind}
+	}
+
+	path := "me/library/playlists/" + url.PathEscape(playlistID) + "/tracks"
+	if err := a.api.post(ctx, path, map[string]any{"data": data}, nil); err != nil {
+		return fmt.Errorf("error adding tracks to playlist: %v", err)
+	}
+	return nil
+}
+
+// SearchTracks searches the catalog of the user's storefront
+func (a *AppleMusicAdapter) SearchTracks(ctx context.Context, query string, limit int) ([]playlist.Track, error) {
+	if err := a.CheckAuth(); err != nil {
+		return nil, err
+	}
+
+	if limit <= 0 || limit > appleMusicCapabilities.MaxSearchLimit {
+		limit = appleMusicCapabilities.MaxSearchLimit
+	}
+
+	var resp struct {
+		Results struct {
+			Songs appleResponse `json:"songs"`
+		} `json:"results"`
+	}
+	params := url.Values{"term": {query}, "types": {"songs"}, "limit": {strconv.Itoa(limit)}}
+	if err := a.api.get(ctx, "catalog/"+a.storefront+"/search", params, &resp); err != nil {
+		return nil, fmt.Errorf("error searching tracks: %v", err)
+	}
+	return appleMusicPlayable(resp.Results.Songs.Data)
+}
+
+// SearchTracksBy looks songs up by ISRC, falling back to a free-text search for the other fields
+func (a *AppleMusicAdapter) SearchTracksBy(ctx context.Context, query SearchQuery, limit int) ([]playlist.Track, error) {
+	if query.ISRC == "" {
+		text := query.FreeText()
+		if text == "" {
+			return nil, nil
+		}
+		return a.SearchTracks(ctx, text, limit)
+	}
+
+	if err := a.CheckAuth(); err != nil {
+		return nil, err
+	}
+
+	var resp appleResponse
+	params := url.Values{"filter[isrc]": {query.ISRC}}
+	if err := a.api.get(ctx, "catalog/"+a.storefront+"/songs", params, &resp); err != nil {
+		return nil, fmt.Errorf("error searching tracks by ISRC: %v", err)
+	}
+	if limit > 0 && len(resp.Data) > limit {
+		resp.Data = resp.Data[:limit]
+	}
+	return appleMusicPlayable(resp.Data)
+}
+
+// Capabilities describes the Apple Music API limits
+func (a *AppleMusicAdapter) Capabilities() Capabilities {
+	return appleMusicCapabilities
+}
+
+// appleMusicPlayable converts catalog songs, dropping the ones that can't be played in the storefront
+func appleMusicPlayable(songs []appleResource) ([]playlist.Track, error) {
+	var tracks []playlist.Track
+	var unplayable int
+	for _, res := range songs {
+		var attrs appleSongAttributes
+		json.Unmarshal(res.Attributes, &attrs)
+		if attrs.PlayParams == nil {
+			unplayable++
+			continue
+		}
+		tracks = append(tracks, appleMusicTrack(res))
+	}
+	if len(tracks) == 0 && unplayable > 0 {
+		return nil, ErrRegionUnavailable
+	}
+	return tracks, nil
+}
+
+// appleMusicTrack converts a catalog or library song into our track model
+func appleMusicTrack(res appleResource) playlist.Track {
+	var attrs appleSongAttributes
+	json.Unmarshal(res.Attributes, &attrs)
+
+	id := res.ID
+	if attrs.PlayParams != nil && attrs.PlayParams.CatalogID != "" {
+		id = attrs.PlayParams.CatalogID
+	}
+
+	track := playlist.Track{
+		Name:        attrs.Name,
+		Album:       attrs.AlbumName,
+		ID:          id,
+		URL:         attrs.URL,
+		ISRC:        attrs.ISRC,
+		DurationMs:  attrs.DurationInMillis,
+		Explicit:    attrs.ContentRating == "explicit",
+		DiscNumber:  attrs.DiscNumber,
+		TrackNumber: attrs.TrackNumber,
+		ReleaseDate: attrs.ReleaseDate,
+	}
+	// artistName credits every artist in one string like "JAY-Z & Kanye West"
+	if attrs.ArtistName != "" {
+		track.Artists = []string{attrs.ArtistName}
+	}
+	return track
+}
+
+// appleMusicError decodes the Apple Music API's error format
+func appleMusicError(status int, body []byte) error {
+	var resp struct {
+		Errors []struct {
+			Title  string `json:"title"`
+			Detail string `json:"detail"`
+		} `json:"errors"`
+	}
+	if json.Unmarshal(body, &resp) != nil || len(resp.Errors) == 0 {
+		return nil
+	}
+	e := resp.Errors[0]
+	if e.Detail != "" {
+		return fmt.Errorf("apple music: %s: %s (HTTP %d)", e.Title, e.Detail, status)
+	}
+	return fmt.Errorf("apple music: %s (HTTP %d)", e.Title, status)
+}
@@ -0,0 +1,96 @@
+package adapters_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"slices"
+	"testing"
+
+	"soundporter/internal/adapters"
+	"soundporter/internal/playlist"
+	"soundporter/internal/standin"
+
+	"golang.org/x/oauth2"
+)
+
+// startAppleMusic starts an Apple Music stand-in for the conformance suite
+func startAppleMusic(t *testing.T, catalog []playlist.Track, playlists []playlist.Playlist) (*standin.AppleMusic, standinRun) {
+	t.Helper()
+	s := standin.NewAppleMusic(catalog, playlists)
+	t.Cleanup(s.Close)
+	withKey := func(key *ecdsa.PrivateKey, userToken string) adapters.ApiAdapter {
+		opts := append(s.Options(), adapters.WithToken(&oauth2.Token{AccessToken: userToken}))
+		a, err := adapters.NewAppleMusicAdapter(standin.AppleTeamID, standin.AppleKeyID, key, opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return a
+	}
+
+	// The developer token is signed with the key loaded from a .p8 file
+	key, err := adapters.ParseAppleMusicKey(s.PrivateKeyPEM())
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s, standinRun{
+		adapter: withKey(key, standin.AppleUserToken),
+		refused: map[string]adapters.ApiAdapter{
+			"a developer token signed with another key": withKey(other, standin.AppleUserToken),
+			"an unknown music user token":               withKey(key, "someone-else"),
+		},
+		stored:      s,
+		unavailable: s.Unavailable,
+	}
+}
+
+func TestAppleMusicStandinTracks(t *testing.T) {
+	_, run := startAppleMusic(t, nil, nil)
+	a := authenticate(t, run.adapter)
+	catalog := adapters.FakeCatalog()
+
+	tracks, err := a.GetPlaylistItems(context.Background(), "fake-playlist-3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Library songs are identified by their catalog song, which carries the ISRC
+	want := catalog[9]
+	if got := tracks[9]; got.ISRC != want.ISRC || got.Name != want.Name || got.URL == "" || got.DurationMs != want.DurationMs {
+		t.Fatalf("track converted to %+v, want %+v", got, want)
+	}
+	// The artist credit is one artist, even when it joins several
+	if got := tracks[12]; !slices.Equal(got.Artists, []string{"Data Race & Fencepost"}) {
+		t.Fatalf("artist credit split into %q", got.Artists)
+	}
+
+	found, err := a.SearchTracksBy(context.Background(), adapters.SearchQuery{ISRC: catalog[5].ISRC}, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(found) != 1 || found[0].ReleaseDate != "2025-01-01" {
+		t.Fatalf("song converted to %+v", found)
+	}
+}
+
+func TestAppleMusicStandinLibrarySongs(t *testing.T) {
+	ctx := context.Background()
+	s, run := startAppleMusic(t, nil, nil)
+	a := authenticate(t, run.adapter)
+
+	p, err := a.CreateNewPlaylist(ctx, "Copy", "", adapters.PrivacyPrivate)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Library songs are added by their library ID
+	if err := a.AddItemsToPlaylist(ctx, p.ID, []string{"fake-02", "i.fake-01"}); err != nil {
+		t.Fatal(err)
+	}
+	if created, _ := s.Playlist(p.ID); !slices.Equal(trackIDs(created.Tracks), []string{"fake-02", "fake-01"}) {
+		t.Fatalf("unexpected tracks after the library song: %v", trackIDs(created.Tracks))
+	}
+}
@@ -1,9 +1,79 @@
 package adapters
 
 import (
+	"context"
 	"fmt"
+	"iter"
+	"log"
+	"net/http"
+	"time"
+
+	"golang.org/x/oauth2"
 )
 
+const callbackAddr = ":8080"
+
+// Option configures where and how an adapter talks to its platform.
+// The defaults are the platform's public endpoints; tests point them at local stand-in servers.
+type Option func(*options)
+
+type options struct {
+	httpClient *http.Client
+	baseURL    string
+	authURL    string
+	tokenURL   string
+	token      *oauth2.Token
+	// rateLimitWait is how long a request over the platform's rate limit waits to be retried
+	rateLimitWait time.Duration
+}
+
+// WithHTTPClient sets the HTTP client used for API and OAuth requests
+func WithHTTPClient(client *http.Client) Option {
+	return func(o *options) { o.httpClient = client }
+}
+
+// WithBaseURL sets the base URL of the platform's API
+func WithBaseURL(url string) Option {
+	return func(o *options) { o.baseURL = url }
+}
+
+// WithOAuthEndpoints sets the OAuth authorization and token URLs
+func WithOAuthEndpoints(authURL, tokenURL string) Option {
+	return func(o *options) {
+		o.authURL = authURL
+		o.tokenURL = tokenURL
+	}
+}
+
+// WithToken authenticates with an existing OAuth token instead of the browser login flow.
+// Expired tokens are refreshed through the token URL.
+func WithToken(token *oauth2.Token) Option {
+	return func(o *options) { o.token = token }
+}
+
+// WithRateLimitWait sets how long requests over the platform's rate limit wait before they are
+// retried, for adapters that retry them
+func WithRateLimitWait(wait time.Duration) Option {
+	return func(o *options) { o.rateLimitWait = wait }
+}
+
+// newOptions applies opts over the given defaults
+func newOptions(defaults options, opts []Option) options {
+	o := defaults
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// oauthContext makes the oauth2 package use the configured HTTP client for token requests
+func (o options) oauthContext(ctx context.Context) context.Context {
+	if o.httpClient == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, oauth2.HTTPClient, o.httpClient)
+}
+
 // BaseAdapter provides common functionality for platform adapters
 type BaseAdapter struct {
 	authenticated bool
@@ -40,3 +110,44 @@ func (b *BaseAdapter) CheckAuth() error {
 func (b *BaseAdapter) PlatformName() string {
 	return b.platformName
 }
+
+// startCallbackServer serves the OAuth callback on its own mux so that several
+// adapters can authenticate one after another in the same process
+func startCallbackServer(mux *http.ServeMux) *http.Server {
+	server := &http.Server{Addr: callbackAddr, Handler: mux}
+	go func() {
+		err := server.ListenAndServe()
+		if err != nil && err != http.ErrServerClosed {
+			log.Fatal(err)
+		}
+	}()
+	return server
+}
+
+// awaitCallback waits for the OAuth callback to deliver a client on ch and then stops the server.
+// If ctx is cancelled first, the server is closed and the context's error is returned.
+func awaitCallback[T any](ctx context.Context, ch <-chan T, server *http.Server) (T, error) {
+	select {
+	case client := <-ch:
+		if err := server.Shutdown(ctx); err != nil {
+			log.Println("Error shutting down callback server:", err)
+		}
+		return client, nil
+	case <-ctx.Done():
+		server.Close()
+		var zero T
+		return zero, ctx.Err()
+	}
+}
+
+// collect drains a paged iterator into a slice, stopping at the first error
+func collect[T any](seq iter.Seq2[T, error]) ([]T, error) {
+	var items []T
+	for item, err := range seq {
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
@@ -0,0 +1,541 @@
+package adapters
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"iter"
+	"log"
+	"net/http"
+	"net/url"
+	"soundporter/internal/playlist"
+	"soundporter/internal/utils"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	deezerRedirectURI = "http://localhost:8080/callback"
+	deezerPermissions = "basic_access,manage_library,offline_access"
+	// deezerPageSize is the number of items requested per page; Deezer caps pages at 100
+	deezerPageSize = 100
+	// deezerQuotaWindow is the period Deezer counts its quota of 50 requests over
+	deezerQuotaWindow = 5 * time.Second
+	// deezerMaxRetries is how often a request over the quota is retried
+	deezerMaxRetries = 3
+)
+
+// Deezer reports errors in the body of successful responses, identified by these codes
+const (
+	deezerErrQuota    = 4
+	deezerErrNotFound = 800
+)
+
+var deezerCapabilities = Capabilities{
+	MaxBatchSize:    100,
+	MaxSearchLimit:  100,
+	SearchFields:    []SearchField{SearchFieldISRC, SearchFieldArtist, SearchFieldTrack, SearchFieldAlbum},
+	PrivacyLevels:   []Privacy{PrivacyPrivate, PrivacyPublic},
+	CanReorderItems: true,
+	CanRemoveItems:  true,
+}
+
+func init() {
+	Register(Registration{
+		Name:        DeezerPlatform,
+		DisplayName: "Deezer",
+		Credentials: []CredentialSpec{
+			{Key: "app_id", EnvVar: "DEEZER_APP_ID", Description: "Deezer application ID"},
+			{Key: "secret", EnvVar: "DEEZER_SECRET", Description: "Deezer application secret key"},
+		},
+		Capabilities: deezerCapabilities,
+		New: func(creds Credentials, opts ...Option) (ApiAdapter, error) {
+			return NewDeezerAdapter(creds["app_id"], creds["secret"], opts...)
+		},
+	})
+}
+
+// DeezerAdapter adapts the Deezer API to our common adapter interface
+type DeezerAdapter struct {
+	BaseAdapter
+	appID       string
+	secret      string
+	opts        options
+	api         *httpAPI
+	accessToken string
+	state       string
+	ch          chan string
+}
+
+// NewDeezerAdapter creates a new DeezerAdapter
+func NewDeezerAdapter(appID, secret string, opts ...Option) (*DeezerAdapter, error) {
+	if appID == "" || secret == "" {
+		return nil, fmt.Errorf("deezer app ID and secret must be provided")
+	}
+
+	a := &DeezerAdapter{
+		BaseAdapter: NewBaseAdapter("Deezer"),
+		appID:       appID,
+		secret:      secret,
+		opts: newOptions(options{
+			baseURL:  "https://api.deezer.com/",
+			authURL:  "https://connect.deezer.com/oauth/auth.php",
+			tokenURL: "https://connect.deezer.com/oauth/access_token.php",
+
+			rateLimitWait: deezerQuotaWindow,
+		}, opts),
+		state: utils.GenerateState(),
+		ch:    make(chan string),
+	}
+	if a.opts.token != nil {
+		a.accessToken = a.opts.token.AccessToken
+	}
+	a.api = newHTTPAPI(a.opts)
+	a.api.authorize = a.authorize
+	a.api.checkBody = deezerError
+	return a, nil
+}
+
+// Authenticate handles user authentication with Deezer. Tokens are requested with the
+// offline_access permission, so they don't expire.
+func (a *DeezerAdapter) Authenticate(ctx context.Context) error {
+	if a.accessToken == "" {
+		mux := http.NewServeMux()
+		mux.HandleFunc("/callback", a.completeAuth)
+		server := startCallbackServer(mux)
+
+		authURL := a.opts.authURL + "?" + url.Values{
+			"app_id":       {a.appID},
+			"redirect_uri": {deezerRedirectURI},
+			"perms":        {deezerPermissions},
+			"state":        {a.state},
+		}.Encode()
+		fmt.Println("Please log in to Deezer by visiting the following page in your browser:", authURL)
+		utils.OpenBrowser(authURL)
+
+		token, err := awaitCallback(ctx, a.ch, server)
+		if err != nil {
+			return err
+		}
+		a.accessToken = token
+	}
+
+	var user struct {
+		ID   int64  `json:"id"`
+		Name string `json:"name"`
+	}
+	if err := a.get(ctx, "user/me", nil, &user); err != nil {
+		return fmt.Errorf("authentication failed: %v", err)
+	}
+
+	fmt.Println("You are logged in as:", user.Name)
+	a.SetAuthenticated(true)
+	return nil
+}
+
+// completeAuth is the callback handler for the Deezer auth flow
+func (a *DeezerAdapter) completeAuth(w http.ResponseWriter, r *http.Request) {
+	if st := r.FormValue("state"); st != a.state {
+		http.NotFound(w, r)
+		log.Fatalf("State mismatch: %s != %s\n", st, a.state)
+	}
+
+	code := r.FormValue("code")
+	if code == "" {
+		http.Error(w, "Deezer login was cancelled: "+r.FormValue("error_reason"), http.StatusForbidden)
+		return
+	}
+
+	token, err := a.exchangeCode(r.Context(), code)
+	if err != nil {
+		http.Error(w, "Couldn't get token", http.StatusForbidden)
+		fmt.Println("Error getting Deezer token:", err)
+		return
+	}
+
+	fmt.Fprintf(w, "Login Completed!")
+	a.ch <- token
+}
+
+// exchangeCode trades an authorization code for an access token. Deezer's token endpoint takes
+// its parameters in the query string rather than following the OAuth 2.0 token request format.
+func (a *DeezerAdapter) exchangeCode(ctx context.Context, code string) (string, error) {
+	var resp struct {
+		AccessToken string `json:"access_token"`
+	}
+	params := url.Values{
+		"app_id": {a.appID},
+		"secret": {a.secret},
+		"code":   {code},
+		"output": {"json"},
+	}
+	token := &httpAPI{client: a.api.client, checkBody: deezerError}
+	if err := token.get(ctx, a.opts.tokenURL, params, &resp); err != nil {
+		return "", err
+	}
+	if resp.AccessToken == "" {
+		return "", errors.New("deezer returned no access token")
+	}
+	return resp.AccessToken, nil
+}
+
+// authorize adds the access token to a request's query
+func (a *DeezerAdapter) authorize(req *http.Request) error {
+	q := req.URL.Query()
+	q.Set("access_token", a.accessToken)
+	req.URL.RawQuery = q.Encode()
+	return nil
+}
+
+type deezerPlaylist struct {
+	ID           jsonID `json:"id"`
+	Title        string `json:"title"`
+	Description  string `json:"description"`
+	NbTracks     int    `json:"nb_tracks"`
+	CreationDate string `json:"creation_date"`
+}
+
+type deezerTrack struct {
+	ID           jsonID `json:"id"`
+	Readable     *bool  `json:"readable"`
+	Title        string `json:"title"`
+	TitleShort   string `json:"title_short"`
+	TitleVersion string `json:"title_version"`
+	ISRC         string `json:"isrc"`
+	Link         string `json:"link"`
+	// Duration is in seconds
+	Duration       int    `json:"duration"`
+	TrackPosition  int    `json:"track_position"`
+	DiskNumber     int    `json:"disk_number"`
+	Rank           int    `json:"rank"`
+	ReleaseDate    string `json:"release_date"`
+	ExplicitLyrics bool   `json:"explicit_lyrics"`
+	Artist         struct {
+		ID   jsonID `json:"id"`
+		Name string `json:"name"`
+	} `json:"artist"`
+	Contributors []struct {
+		ID   jsonID `json:"id"`
+		Name string `json:"name"`
+	} `json:"contributors"`
+	Album struct {
+		ID          jsonID `json:"id"`
+		Title       string `json:"title"`
+		ReleaseDate string `json:"release_date"`
+	} `json:"album"`
+}
+
+// deezerPage is a page of a paginated list. Next is the absolute URL of the next page.
+type deezerPage[T any] struct {
+	Data  []T    `json:"data"`
+	Total int    `json:"total"`
+	Next  string `json:"next"`
+}
+
+// GetUserPlaylists retrieves all playlists for the authenticated user
+func (a *DeezerAdapter) GetUserPlaylists(ctx context.Context) ([]playlist.Playlist, error) {
+	return collect(a.UserPlaylists(ctx))
+}
+
+// UserPlaylists streams the authenticated user's playlists page by page
+func (a *DeezerAdapter) UserPlaylists(ctx context.Context) iter.Seq2[playlist.Playlist, error] {
+	return func(yield func(playlist.Playlist, error) bool) {
+		if err := a.CheckAuth(); err != nil {
+			yield(playlist.Playlist{}, err)
+			return
+		}
+
+		for page, err := range deezerPages[deezerPlaylist](ctx, a, "user/me/playlists") {
+			if err != nil {
+				yield(playlist.Playlist{}, fmt.Errorf("error getting playlists: %v", err))
+				return
+			}
+			for _, p := range page {
+				created, _ := time.Parse(time.DateTime, p.CreationDate)
+				if !yield(playlist.Playlist{
+					ID:          string(p.ID),
+					Name:        p.Title,
+					Description: p.Description,
+					TrackCount:  p.NbTracks,
+					CreatedAt:   created,
+				}, nil) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// GetPlaylistItems retrieves all tracks in a playlist
+func (a *DeezerAdapter) GetPlaylistItems(ctx context.Context, playlistID string) ([]playlist.Track, error) {
+	return collect(a.PlaylistItems(ctx, playlistID))
+}
+
+// PlaylistItems streams the tracks of a playlist page by page. Playlist listings leave out the
+// ISRC, so each track's details are looked up as well.
+func (a *DeezerAdapter) PlaylistItems(ctx context.Context, playlistID string) iter.Seq2[playlist.Track, error] {
+	return func(yield func(playlist.Track, error) bool) {
+		if err := a.CheckAuth(); err != nil {
+			yield(playlist.Track{}, err)
+			return
+		}
+
+		path := "playlist/" + url.PathEscape(playlistID) + "/tracks"
+		for page, err := range deezerPages[deezerTrack](ctx, a, path) {
+			if err != nil {
+				yield(playlist.Track{}, fmt.Errorf("error getting playlist items: %v", err))
+				return
+			}
+			for _, t := range page {
+				if t.ISRC == "" {
+					var full deezerTrack
+					if err := a.get(ctx, "track/"+string(t.ID), nil, &full); err != nil {
+						yield(playlist.Track{}, fmt.Errorf("error getting track %s: %v", t.ID, err))
+						return
+					}
+					t = full
+				}
+				if !yield(deezerTrackToTrack(t), nil) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// get requests path from the API. The track lookups of a playlist soon use up Deezer's quota, so
+// a request over it waits for the quota window to pass and is retried.
+func (a *DeezerAdapter) get(ctx context.Context, path string, query url.Values, out any) error {
+	for attempt := 0; ; attempt++ {
+		err := a.api.get(ctx, path, query, out)
+		if !errors.Is(err, ErrRateLimited) || attempt == deezerMaxRetries {
+			return err
+		}
+		select {
+		case <-time.After(a.opts.rateLimitWait):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// deezerPages follows the next links of a paginated Deezer list
+func deezerPages[T any](ctx context.Context, a *DeezerAdapter, path string) iter.Seq2[[]T, error] {
+	return func(yield func([]T, error) bool) {
+		query := url.Values{"limit": {strconv.Itoa(deezerPageSize)}}
+		for path != "" {
+			var page deezerPage[T]
+			if err := a.get(ctx, path, query, &page); err != nil {
+				yield(nil, err)
+				return
+			}
+			if !yield(page.Data, nil) {
+				return
+			}
+			// Next links already carry the query, except for the access token
+			path, query = page.Next, nil
+		}
+	}
+}
+
+// CreateNewPlaylist creates a new Deezer playlist. Deezer only takes a title on creation, so the
+// description and visibility are set with a follow-up update.
+func (a *DeezerAdapter) CreateNewPlaylist(ctx context.Context, name string, description string, privacy Privacy) (playlist.Playlist, error) {
+	if err := a.CheckAuth(); err != nil {
+		return playlist.Playlist{}, err
+	}
+
+	var created struct {
+		ID jsonID `json:"id"`
+	}
+	if err := a.api.post(ctx, "user/me/playlists", url.Values{"title": {name}}, &created); err != nil {
+		return playlist.Playlist{}, fmt.Errorf("error creating playlist: %v", err)
+	}
+
+	update := url.Values{
+		"description": {description},
+		"public":      {strconv.FormatBool(privacy == PrivacyPublic)},
+	}
+	if err := a.api.post(ctx, "playlist/"+string(created.ID), update, nil); err != nil {
+		return playlist.Playlist{}, fmt.Errorf("error updating playlist %s: %v", created.ID, err)
+	}
+
+	return playlist.Playlist{
+		ID:          string(created.ID),
+		Name:        name,
+		Description: description,
+		CreatedAt:   time.Now(),
+	}, nil
+}
+
+// AddItemsToPlaylist adds tracks to a Deezer playlist in one request
+func (a *DeezerAdapter) AddItemsToPlaylist(ctx context.Context, playlistID string, trackIDs []string) error {
+	if err := a.CheckAuth(); err != nil {
+		return err
+	}
+
+	params := url.Values{"songs": {strings.Join(trackIDs, ",")}}
+	if err := a.api.post(ctx, "playlist/"+url.PathEscape(playlistID)+"/tracks", params, nil); err != nil {
+		return fmt.Errorf("error adding tracks to playlist: %v", err)
+	}
+	return nil
+}
+
+// SearchTracks searches for tracks on Deezer. Queries may use Deezer's advanced search syntax,
+// e.g. artist:"Queen" track:"Bohemian Rhapsody".
+func (a *DeezerAdapter) SearchTracks(ctx context.Context, query string, limit int) ([]playlist.Track, error) {
+	if err := a.CheckAuth(); err != nil {
+		return nil, err
+	}
+
+	if limit <= 0 || limit > deezerCapabilities.MaxSearchLimit {
+		limit = deezerCapabilities.MaxSearchLimit
+	}
+
+	var page deezerPage[deezerTrack]
+	params := url.Values{"q": {query}, "limit": {strconv.Itoa(limit)}}
+	if err := a.get(ctx, "search/track", params, &page); err != nil {
+		return nil, fmt.Errorf("error searching tracks: %v", err)
+	}
+	return deezerReadable(page.Data)
+}
+
+// SearchTracksBy looks a track up by ISRC, or searches with Deezer's field filters
+func (a *DeezerAdapter) SearchTracksBy(ctx context.Context, query SearchQuery, limit int) ([]playlist.Track, error) {
+	if query.ISRC != "" {
+		return a.trackByISRC(ctx, query.ISRC)
+	}
+
+	q := deezerSearchQuery(query)
+	if q == "" {
+		return nil, nil
+	}
+	return a.SearchTracks(ctx, q, limit)
+}
+
+// deezerSearchQuery builds a search with Deezer's field filters. Deezer has no escape sequences
+// in quoted values, so quotes are dropped from the values rather than escaped.
+func deezerSearchQuery(query SearchQuery) string {
+	var filters []string
+	for _, f := range []struct{ field, value string }{
+		{"track", query.Track},
+		{"artist", query.Artist},
+		{"album", query.Album},
+	} {
+		if value := strings.TrimSpace(strings.ReplaceAll(f.value, `"`, "")); value != "" {
+			filters = append(filters, f.field+`:"`+value+`"`)
+		}
+	}
+	return strings.Join(filters, " ")
+}
+
+// trackByISRC uses the track/isrc:<ISRC> endpoint, which returns a single track
+func (a *DeezerAdapter) trackByISRC(ctx context.Context, isrc string) ([]playlist.Track, error) {
+	if err := a.CheckAuth(); err != nil {
+		return nil, err
+	}
+
+	var t deezerTrack
+	err := a.get(ctx, "track/isrc:"+url.PathEscape(isrc), nil, &t)
+	var apiErr *deezerAPIError
+	if errors.As(err, &apiErr) && apiErr.Code == deezerErrNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error looking up ISRC %s: %v", isrc, err)
+	}
+	return deezerReadable([]deezerTrack{t})
+}
+
+// Capabilities describes the Deezer API limits
+func (a *DeezerAdapter) Capabilities() Capabilities {
+	return deezerCapabilities
+}
+
+// deezerReadable converts tracks, dropping the ones that can't be streamed in the user's country
+func deezerReadable(results []deezerTrack) ([]playlist.Track, error) {
+	var tracks []playlist.Track
+	var unreadable int
+	for _, t := range results {
+		if t.Readable != nil && !*t.Readable {
+			unreadable++
+			continue
+		}
+		tracks = append(tracks, deezerTrackToTrack(t))
+	}
+	if len(tracks) == 0 && unreadable > 0 {
+		return nil, ErrRegionUnavailable
+	}
+	return tracks, nil
+}
+
+// deezerTrackToTrack converts a Deezer track into our track model
+func deezerTrackToTrack(t deezerTrack) playlist.Track {
+	track := playlist.Track{
+		Name:        t.Title,
+		Album:       t.Album.Title,
+		ID:          string(t.ID),
+		AlbumID:     string(t.Album.ID),
+		URL:         t.Link,
+		Version:     t.TitleVersion,
+		ISRC:        t.ISRC,
+		DurationMs:  t.Duration * 1000,
+		Explicit:    t.ExplicitLyrics,
+		DiscNumber:  t.DiskNumber,
+		TrackNumber: t.TrackPosition,
+		ReleaseDate: t.ReleaseDate,
+	}
+	// The version is part of the full title, e.g. "Song (Live)"; the short title leaves it out
+	if t.TitleShort != "" {
+		track.Name = t.TitleShort
+	}
+	if track.ReleaseDate == "" {
+		track.ReleaseDate = t.Album.ReleaseDate
+	}
+	// Rank is an unbounded play count score; scale it to the 0-100 popularity of other platforms
+	track.Popularity = min(t.Rank/10000, 100)
+
+	// Contributors are only listed in track details; listings just have the main artist
+	if len(t.Contributors) > 0 {
+		for _, c := range t.Contributors {
+			track.Artists = append(track.Artists, c.Name)
+			track.ArtistIDs = append(track.ArtistIDs, string(c.ID))
+		}
+	} else if t.Artist.Name != "" {
+		track.Artists = []string{t.Artist.Name}
+		track.ArtistIDs = []string{string(t.Artist.ID)}
+	}
+	return track
+}
+
+// deezerAPIError is an error reported in the body of a Deezer response
+type deezerAPIError struct {
+	Type    string `json:"type"`
+	Message string `json:"message"`
+	Code    int    `json:"code"`
+}
+
+func (e *deezerAPIError) Error() string {
+	return fmt.Sprintf("deezer: %s (%s, code %d)", e.Message, e.Type, e.Code)
+}
+
+func (e *deezerAPIError) Unwrap() error {
+	if e.Code == deezerErrQuota {
+		return ErrRateLimited
+	}
+	return nil
+}
+
+// deezerError finds the error object Deezer returns with a 200 status
+func deezerError(body []byte) error {
+	var resp struct {
+		Error *deezerAPIError `json:"error"`
+	}
+	// Some successful responses are bare values like true, which don't decode into an object
+	if json.Unmarshal(body, &resp) != nil || resp.Error == nil {
+		return nil
+	}
+	return resp.Error
+}
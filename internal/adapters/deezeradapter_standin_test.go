@@ -0,0 +1,99 @@
+package adapters_test
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+
+	"soundporter/internal/adapters"
+	"soundporter/internal/playlist"
+	"soundporter/internal/standin"
+
+	"golang.org/x/oauth2"
+)
+
+// startDeezer starts a Deezer stand-in for the conformance suite
+func startDeezer(t *testing.T, catalog []playlist.Track, playlists []playlist.Playlist) (*standin.Deezer, standinRun) {
+	t.Helper()
+	d := standin.NewDeezer(catalog, playlists)
+	t.Cleanup(d.Close)
+	withToken := func(token *oauth2.Token) adapters.ApiAdapter {
+		a, err := adapters.NewDeezerAdapter("app-id", "secret", append(d.Options(), adapters.WithToken(token))...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return a
+	}
+	return d, standinRun{
+		adapter: withToken(d.OAuth.Token()),
+		// Deezer rejects bad tokens in the body of a 200 response
+		refused:     map[string]adapters.ApiAdapter{"an unknown token": withToken(&oauth2.Token{AccessToken: "unknown"})},
+		stored:      d,
+		unavailable: d.Unavailable,
+	}
+}
+
+func TestDeezerStandinTracks(t *testing.T) {
+	ctx := context.Background()
+	d, run := startDeezer(t, nil, nil)
+	a := authenticate(t, run.adapter)
+	catalog := adapters.FakeCatalog()
+
+	tracks, err := a.GetPlaylistItems(ctx, "fake-playlist-3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Listings lack the ISRC and contributors, so each track's details are looked up
+	for _, i := range []int{3, 9, 12} {
+		want, got := catalog[i], tracks[i]
+		if got.Name != want.Name || got.Version != want.Version || got.ISRC != want.ISRC || got.DurationMs != want.DurationMs {
+			t.Errorf("track %d converted to %+v, want %+v", i, got, want)
+		}
+		if !slices.Equal(got.Artists, want.Artists) || !slices.Equal(got.ArtistIDs, want.ArtistIDs) {
+			t.Errorf("track %d has artists %v %v, want %v %v", i, got.Artists, got.ArtistIDs, want.Artists, want.ArtistIDs)
+		}
+	}
+
+	// The lookups soon use up the quota, and requests over it are retried
+	d.QuotaEvery = 7
+	again, err := a.GetPlaylistItems(ctx, "fake-playlist-3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(trackIDs(again), trackIDs(catalog)) {
+		t.Fatalf("expected all %d tracks over the quota, got %d", len(catalog), len(again))
+	}
+}
+
+func TestDeezerStandinSearchFilters(t *testing.T) {
+	ctx := context.Background()
+	d, run := startDeezer(t, nil, nil)
+	a := authenticate(t, run.adapter)
+
+	if tracks, err := a.SearchTracksBy(ctx, adapters.SearchQuery{ISRC: "XXFAK2599999"}, 5); err != nil || tracks != nil {
+		t.Fatalf("unknown ISRC returned %v, %v", tracks, err)
+	}
+
+	tracks, err := a.SearchTracksBy(ctx, adapters.SearchQuery{Track: "Green Build", Artist: "Continuous Integration"}, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(trackIDs(tracks), []string{"fake-08", "fake-10"}) {
+		t.Fatalf("structured search found %v", trackIDs(tracks))
+	}
+	if got := tracks[1]; got.Name != "Green Build" || got.Version != "Remix" {
+		t.Fatalf("version not split from the title: %+v", got)
+	}
+
+	tracks, err = a.SearchTracksBy(ctx, adapters.SearchQuery{Track: "Lorem Ipsum", Artist: "Mock Orchestra"}, 5)
+	if err != nil || len(tracks) != 0 {
+		t.Fatalf("expected no results, got %v, %v", trackIDs(tracks), err)
+	}
+
+	// Quota errors come in the body of a 200 response too, and are returned once retries run out
+	d.QuotaEvery = 1
+	if _, err := a.SearchTracks(ctx, "Placeholders", 5); err == nil || !strings.Contains(err.Error(), "Quota") {
+		t.Fatalf("expected a quota error, got %v", err)
+	}
+}
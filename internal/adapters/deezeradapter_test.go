@@ -0,0 +1,22 @@
+package adapters
+
+import "testing"
+
+func TestDeezerSearchQuery(t *testing.T) {
+	tests := []struct {
+		query SearchQuery
+		want  string
+	}{
+		{SearchQuery{}, ""},
+		{SearchQuery{ISRC: "GBUM71029604"}, ""},
+		{SearchQuery{Track: "Bohemian Rhapsody", Artist: "Queen"}, `track:"Bohemian Rhapsody" artist:"Queen"`},
+		{SearchQuery{Track: "Café Del Mar", Artist: "Energy 52"}, `track:"Café Del Mar" artist:"Energy 52"`},
+		{SearchQuery{Track: `The "Real" Slim Shady`, Album: "The Marshall Mathers LP"}, `track:"The Real Slim Shady" album:"The Marshall Mathers LP"`},
+		{SearchQuery{Track: `""`, Artist: "Björk"}, `artist:"Björk"`},
+	}
+	for _, tt := range tests {
+		if got := deezerSearchQuery(tt.query); got != tt.want {
+			t.Errorf("deezerSearchQuery(%+v) = %s, want %s", tt.query, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,453 @@
+package adapters
+
+import (
+	"context"
+	"fmt"
+	"iter"
+	"slices"
+	"soundporter/internal/playlist"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+)
+
+// FakeConfig controls the fake adapter's seeded data and injected failures
+type FakeConfig struct {
+	// Catalog is the set of searchable tracks; nil uses a built-in demo catalog
+	Catalog []playlist.Track
+	// Playlists are the user's playlists, with their tracks; nil uses built-in demo playlists
+	Playlists []playlist.Playlist
+	// PageSize is how many items each page of results holds (default 10)
+	PageSize int
+	// FailAuth makes Authenticate fail
+	FailAuth bool
+	// RateLimitEvery makes every Nth API call fail with ErrRateLimited; 0 disables it
+	RateLimitEvery int
+	// FailTrackIDs are rejected by AddItemsToPlaylist. Tracks before the first rejected one in a
+	// batch are still added, like a partially failed request.
+	FailTrackIDs []string
+	// UnavailableTrackIDs are found by searches but reported as unavailable in the user's region
+	UnavailableTrackIDs []string
+}
+
+// FakeAdapter is a fully in-memory ApiAdapter for tests and offline demos
+type FakeAdapter struct {
+	BaseAdapter
+	mu        sync.Mutex
+	config    FakeConfig
+	catalog   []playlist.Track
+	playlists []playlist.Playlist
+	calls     int
+	nextID    int
+}
+
+var fakeCapabilities = Capabilities{
+	MaxBatchSize:         100,
+	MaxSearchLimit:       50,
+	SearchFields:         []SearchField{SearchFieldISRC, SearchFieldArtist, SearchFieldTrack, SearchFieldAlbum},
+	MaxDescriptionLength: 300,
+	PrivacyLevels:        []Privacy{PrivacyPrivate, PrivacyUnlisted, PrivacyPublic},
+	CanReorderItems:      true,
+	CanRemoveItems:       true,
+}
+
+// NewFakeAdapter creates a FakeAdapter seeded from config
+func NewFakeAdapter(config FakeConfig) *FakeAdapter {
+	if config.Catalog == nil {
+		config.Catalog = FakeCatalog()
+	}
+	if config.Playlists == nil {
+		config.Playlists = FakePlaylists(config.Catalog)
+	}
+	if config.PageSize <= 0 {
+		config.PageSize = 10
+	}
+
+	// Copy the seed data so that mutations don't leak back into the caller's config
+	playlists := make([]playlist.Playlist, len(config.Playlists))
+	for i, p := range config.Playlists {
+		p.Tracks = slices.Clone(p.Tracks)
+		p.TrackCount = len(p.Tracks)
+		playlists[i] = p
+	}
+
+	return &FakeAdapter{
+		BaseAdapter: NewBaseAdapter("Fake"),
+		config:      config,
+		catalog:     slices.Clone(config.Catalog),
+		playlists:   playlists,
+	}
+}
+
+func init() {
+	Register(Registration{
+		Name:        FakePlatform,
+		DisplayName: "Fake (offline demo)",
+		Demo:        true,
+		Credentials: []CredentialSpec{
+			{Key: "failures", EnvVar: "FAKE_FAILURES", Description: "Failures to inject, e.g. fail_auth,rate_limit_every=5", Optional: true},
+		},
+		Capabilities: fakeCapabilities,
+		New: func(creds Credentials, opts ...Option) (ApiAdapter, error) {
+			return NewFakeAdapterFromSpec(creds["failures"])
+		},
+	})
+}
+
+// NewFakeAdapterFromSpec creates a FakeAdapter with the demo data and the failures described by
+// spec, e.g. "fail_auth,rate_limit_every=5,fail_tracks=fake-03;fake-07"
+func NewFakeAdapterFromSpec(spec string) (*FakeAdapter, error) {
+	var config FakeConfig
+	for _, option := range strings.Split(spec, ",") {
+		key, value, _ := strings.Cut(strings.TrimSpace(option), "=")
+		switch key {
+		case "":
+		case "fail_auth":
+			config.FailAuth = true
+		case "rate_limit_every":
+			n, err := strconv.Atoi(value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid FAKE_FAILURES rate_limit_every %q: %v", value, err)
+			}
+			config.RateLimitEvery = n
+		case "fail_tracks":
+			config.FailTrackIDs = strings.Split(value, ";")
+		case "unavailable_tracks":
+			config.UnavailableTrackIDs = strings.Split(value, ";")
+		case "page_size":
+			n, err := strconv.Atoi(value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid FAKE_FAILURES page_size %q: %v", value, err)
+			}
+			config.PageSize = n
+		default:
+			return nil, fmt.Errorf("unknown FAKE_FAILURES option %q", key)
+		}
+	}
+	return NewFakeAdapter(config), nil
+}
+
+// Authenticate marks the adapter as authenticated unless auth failures are injected
+func (a *FakeAdapter) Authenticate(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if a.config.FailAuth {
+		return fmt.Errorf("authentication failed: invalid credentials")
+	}
+	a.SetAuthenticated(true)
+	fmt.Println("You are logged in to the fake platform")
+	return nil
+}
+
+// GetUserPlaylists retrieves all playlists
+func (a *FakeAdapter) GetUserPlaylists(ctx context.Context) ([]playlist.Playlist, error) {
+	return collect(a.UserPlaylists(ctx))
+}
+
+// UserPlaylists streams the playlists page by page
+func (a *FakeAdapter) UserPlaylists(ctx context.Context) iter.Seq2[playlist.Playlist, error] {
+	return func(yield func(playlist.Playlist, error) bool) {
+		for offset := 0; ; offset += a.config.PageSize {
+			a.mu.Lock()
+			err := a.call(ctx)
+			var page []playlist.Playlist
+			if err == nil {
+				page = a.playlists[min(offset, len(a.playlists)):min(offset+a.config.PageSize, len(a.playlists))]
+				page = slices.Clone(page)
+			}
+			a.mu.Unlock()
+			if err != nil {
+				yield(playlist.Playlist{}, err)
+				return
+			}
+
+			for _, p := range page {
+				// Like the real platforms, playlist listings don't include tracks
+				p.Tracks = nil
+				if !yield(p, nil) {
+					return
+				}
+			}
+			if len(page) < a.config.PageSize {
+				return
+			}
+		}
+	}
+}
+
+// GetPlaylistItems retrieves all tracks in a playlist
+func (a *FakeAdapter) GetPlaylistItems(ctx context.Context, playlistID string) ([]playlist.Track, error) {
+	return collect(a.PlaylistItems(ctx, playlistID))
+}
+
+// PlaylistItems streams the tracks of a playlist page by page
+func (a *FakeAdapter) PlaylistItems(ctx context.Context, playlistID string) iter.Seq2[playlist.Track, error] {
+	return func(yield func(playlist.Track, error) bool) {
+		for offset := 0; ; offset += a.config.PageSize {
+			a.mu.Lock()
+			err := a.call(ctx)
+			var page []playlist.Track
+			if err == nil {
+				var p *playlist.Playlist
+				if p, err = a.findPlaylist(playlistID); err == nil {
+					page = slices.Clone(p.Tracks[min(offset, len(p.Tracks)):min(offset+a.config.PageSize, len(p.Tracks))])
+				}
+			}
+			a.mu.Unlock()
+			if err != nil {
+				yield(playlist.Track{}, err)
+				return
+			}
+
+			for _, t := range page {
+				if !yield(t, nil) {
+					return
+				}
+			}
+			if len(page) < a.config.PageSize {
+				return
+			}
+		}
+	}
+}
+
+// CreateNewPlaylist creates an empty playlist
+func (a *FakeAdapter) CreateNewPlaylist(ctx context.Context, name string, description string, privacy Privacy) (playlist.Playlist, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if err := a.call(ctx); err != nil {
+		return playlist.Playlist{}, err
+	}
+
+	a.nextID++
+	p := playlist.Playlist{
+		ID:          fmt.Sprintf("fake-playlist-new-%d", a.nextID),
+		Name:        name,
+		Description: description,
+		CreatedAt:   time.Now(),
+	}
+	a.playlists = append(a.playlists, p)
+	return p, nil
+}
+
+// AddItemsToPlaylist appends catalog tracks to a playlist
+func (a *FakeAdapter) AddItemsToPlaylist(ctx context.Context, playlistID string, trackIDs []string) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if err := a.call(ctx); err != nil {
+		return err
+	}
+
+	p, err := a.findPlaylist(playlistID)
+	if err != nil {
+		return err
+	}
+	for _, id := range trackIDs {
+		if slices.Contains(a.config.FailTrackIDs, id) {
+			return fmt.Errorf("error adding track %s to playlist: rejected by the platform", id)
+		}
+		i := slices.IndexFunc(a.catalog, func(t playlist.Track) bool { return t.ID == id })
+		if i < 0 {
+			return fmt.Errorf("error adding track %s to playlist: track not found", id)
+		}
+		p.Tracks = append(p.Tracks, a.catalog[i])
+		p.TrackCount = len(p.Tracks)
+	}
+	return nil
+}
+
+// SearchTracks searches the catalog for tracks whose name, artists or album contain every word of the query.
+// Spotify-style field filters such as isrc:, artist: and track: are understood.
+func (a *FakeAdapter) SearchTracks(ctx context.Context, query string, limit int) ([]playlist.Track, error) {
+	return a.SearchTracksBy(ctx, ParseSearchQuery(query), limit)
+}
+
+// SearchTracksBy searches the catalog by structured fields
+func (a *FakeAdapter) SearchTracksBy(ctx context.Context, query SearchQuery, limit int) ([]playlist.Track, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if err := a.call(ctx); err != nil {
+		return nil, err
+	}
+	if limit <= 0 || limit > fakeCapabilities.MaxSearchLimit {
+		limit = fakeCapabilities.MaxSearchLimit
+	}
+
+	var tracks []playlist.Track
+	var unavailable int
+	for _, t := range a.catalog {
+		if !MatchesSearchQuery(t, query) {
+			continue
+		}
+		if slices.Contains(a.config.UnavailableTrackIDs, t.ID) {
+			unavailable++
+			continue
+		}
+		tracks = append(tracks, t)
+		if len(tracks) == limit {
+			break
+		}
+	}
+
+	if len(tracks) == 0 && unavailable > 0 {
+		return nil, ErrRegionUnavailable
+	}
+	return tracks, nil
+}
+
+// Capabilities describes the fake platform
+func (a *FakeAdapter) Capabilities() Capabilities {
+	return fakeCapabilities
+}
+
+// Playlist returns a copy of a playlist including its tracks, for inspecting the adapter's state
+func (a *FakeAdapter) Playlist(playlistID string) (playlist.Playlist, bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	p, err := a.findPlaylist(playlistID)
+	if err != nil {
+		return playlist.Playlist{}, false
+	}
+	copied := *p
+	copied.Tracks = slices.Clone(p.Tracks)
+	return copied, true
+}
+
+// call applies the checks every API call goes through. a.mu must be held.
+func (a *FakeAdapter) call(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if err := a.CheckAuth(); err != nil {
+		return err
+	}
+	a.calls++
+	if a.config.RateLimitEvery > 0 && a.calls%a.config.RateLimitEvery == 0 {
+		return ErrRateLimited
+	}
+	return nil
+}
+
+// findPlaylist returns the playlist with the given ID. a.mu must be held.
+func (a *FakeAdapter) findPlaylist(playlistID string) (*playlist.Playlist, error) {
+	for i := range a.playlists {
+		if a.playlists[i].ID == playlistID {
+			return &a.playlists[i], nil
+		}
+	}
+	return nil, fmt.Errorf("playlist %s not found", playlistID)
+}
+
+// MatchesSearchQuery reports whether a track satisfies every field of the query.
+// The track field is matched against the whole track so free text can mention the artist too.
+func MatchesSearchQuery(t playlist.Track, q SearchQuery) bool {
+	if q.ISRC != "" && !strings.EqualFold(q.ISRC, t.ISRC) {
+		return false
+	}
+	all := fakeWords(t.Name + " " + strings.Join(t.Artists, " ") + " " + t.Album)
+	if q.Track != "" && !containsWords(all, fakeWords(q.Track)) {
+		return false
+	}
+	if q.Artist != "" && !containsWords(fakeWords(strings.Join(t.Artists, " ")), fakeWords(q.Artist)) {
+		return false
+	}
+	if q.Album != "" && !containsWords(fakeWords(t.Album), fakeWords(q.Album)) {
+		return false
+	}
+	return q != SearchQuery{}
+}
+
+func fakeWords(s string) []string {
+	return strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+}
+
+func containsWords(haystack, needles []string) bool {
+	for _, n := range needles {
+		if !slices.Contains(haystack, n) {
+			return false
+		}
+	}
+	return true
+}
+
+// FakeCatalog returns the built-in demo catalog
+func FakeCatalog() []playlist.Track {
+	seed := []struct {
+		name, artist, album, version string
+		durationMs                   int
+		explicit                     bool
+	}{
+		{"Lorem Ipsum", "The Placeholders", "Dolor Sit Amet", "", 215000, false},
+		{"Consectetur", "The Placeholders", "Dolor Sit Amet", "", 187000, false},
+		{"Adipiscing Elit", "The Placeholders", "Dolor Sit Amet", "", 242000, true},
+		{"Lorem Ipsum", "The Placeholders", "Live at the Test Suite", "Live", 261000, false},
+		{"Midnight Fixture", "Mock Orchestra", "Stubs and Spies", "", 305000, false},
+		{"Golden Master", "Mock Orchestra", "Stubs and Spies", "", 198000, false},
+		{"Flaky Test Blues", "Mock Orchestra", "Stubs and Spies", "", 233000, false},
+		{"Green Build", "Continuous Integration", "Pipeline", "", 176000, false},
+		{"Red Build", "Continuous Integration", "Pipeline", "", 181000, true},
+		{"Green Build", "Continuous Integration", "Pipeline (Remixes)", "Remix", 224000, false},
+		{"Race Condition", "Data Race", "Happens Before", "", 201000, false},
+		{"Deadlock", "Data Race", "Happens Before", "", 256000, false},
+		{"Off By One", "Data Race feat. Fencepost", "Happens Before", "", 199000, false},
+		{"Null Pointer", "Segfault Sisters", "Core Dump", "", 167000, true},
+		{"Stack Overflow", "Segfault Sisters", "Core Dump", "", 212000, false},
+		{"Heap Of Trouble", "Segfault Sisters", "Core Dump", "", 244000, false},
+		{"Hello World", "First Commit", "Initial Release", "", 154000, false},
+		{"Semantic Version", "First Commit", "Initial Release", "", 203000, false},
+		{"Breaking Change", "First Commit", "Initial Release", "", 189000, false},
+		{"Rollback", "First Commit", "Initial Release", "Acoustic", 221000, false},
+	}
+
+	catalog := make([]playlist.Track, len(seed))
+	for i, s := range seed {
+		id := fmt.Sprintf("fake-%02d", i+1)
+		artists := strings.Split(s.artist, " feat. ")
+		artistIDs := make([]string, len(artists))
+		for j, artist := range artists {
+			artistIDs[j] = "fake-artist-" + strings.ToLower(strings.ReplaceAll(artist, " ", "-"))
+		}
+		catalog[i] = playlist.Track{
+			Name:        s.name,
+			Artists:     artists,
+			Album:       s.album,
+			ID:          id,
+			ArtistIDs:   artistIDs,
+			AlbumID:     "fake-album-" + strings.ToLower(strings.ReplaceAll(s.album, " ", "-")),
+			URL:         "https://fake.invalid/track/" + id,
+			Version:     s.version,
+			ISRC:        fmt.Sprintf("XXFAK25%05d", i+1),
+			DurationMs:  s.durationMs,
+			Explicit:    s.explicit,
+			Popularity:  100 - i*3,
+			DiscNumber:  1,
+			TrackNumber: i%3 + 1,
+			ReleaseDate: "2025-01-01",
+		}
+	}
+	return catalog
+}
+
+// FakePlaylists returns the built-in demo playlists drawn from catalog
+func FakePlaylists(catalog []playlist.Track) []playlist.Playlist {
+	pick := func(indexes ...int) []playlist.Track {
+		var tracks []playlist.Track
+		for _, i := range indexes {
+			if i < len(catalog) {
+				tracks = append(tracks, catalog[i])
+			}
+		}
+		return tracks
+	}
+	created := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	return []playlist.Playlist{
+		{ID: "fake-playlist-1", Name: "Road Trip", Description: "Songs for the road", Tracks: pick(0, 4, 7, 10, 13, 16), CreatedAt: created},
+		{ID: "fake-playlist-2", Name: "Focus", Description: "Deep work", Tracks: pick(1, 5, 11, 14), CreatedAt: created},
+		{ID: "fake-playlist-3", Name: "Everything", Description: "The whole catalog", Tracks: slices.Clone(catalog), CreatedAt: created},
+	}
+}
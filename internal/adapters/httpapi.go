@@ -0,0 +1,150 @@
+package adapters
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// httpAPI is a small JSON-over-HTTP client shared by the adapters that talk to REST APIs directly
+type httpAPI struct {
+	client  *http.Client
+	baseURL string
+	// authorize adds credentials to each request
+	authorize func(*http.Request) error
+	// decodeError turns an error response body into an error; nil uses the body as the message
+	decodeError func(status int, body []byte) error
+	// checkBody reports errors that APIs return with a successful status, if set
+	checkBody func(body []byte) error
+}
+
+// newHTTPAPI creates an httpAPI using the configured HTTP client and base URL
+func newHTTPAPI(o options) *httpAPI {
+	client := o.httpClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return &httpAPI{client: client, baseURL: o.baseURL}
+}
+
+// get requests path relative to the base URL and decodes the JSON response into out
+func (h *httpAPI) get(ctx context.Context, path string, query url.Values, out any) error {
+	return h.do(ctx, http.MethodGet, path, query, nil, out)
+}
+
+// post sends body and decodes the JSON response into out, if any. url.Values bodies are form
+// encoded, anything else is sent as JSON.
+func (h *httpAPI) post(ctx context.Context, path string, body any, out any) error {
+	return h.do(ctx, http.MethodPost, path, nil, body, out)
+}
+
+// do sends a request and decodes the JSON response into out. A nil out discards the response.
+// Responses with status 429 wrap ErrRateLimited.
+func (h *httpAPI) do(ctx context.Context, method, path string, query url.Values, body any, out any) error {
+	_, err := h.send(ctx, method, path, query, body, nil, out)
+	return err
+}
+
+// send is do with extra request headers, returning the response headers
+func (h *httpAPI) send(ctx context.Context, method, path string, query url.Values, body any, header http.Header, out any) (http.Header, error) {
+	u := path
+	if !strings.HasPrefix(path, "http://") && !strings.HasPrefix(path, "https://") {
+		u = strings.TrimSuffix(h.baseURL, "/") + "/" + strings.TrimPrefix(path, "/")
+	}
+	if len(query) > 0 {
+		sep := "?"
+		if strings.Contains(u, "?") {
+			sep = "&"
+		}
+		u += sep + query.Encode()
+	}
+
+	var reader io.Reader
+	contentType := ""
+	switch b := body.(type) {
+	case nil:
+	case url.Values:
+		reader = strings.NewReader(b.Encode())
+		contentType = "application/x-www-form-urlencoded"
+	default:
+		data, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		reader = bytes.NewReader(data)
+		contentType = "application/json"
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, u, reader)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if h.authorize != nil {
+		if err := h.authorize(req); err != nil {
+			return nil, err
+		}
+	}
+
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		var apiErr error
+		if h.decodeError != nil {
+			apiErr = h.decodeError(resp.StatusCode, data)
+		}
+		if apiErr == nil {
+			apiErr = fmt.Errorf("HTTP %d: %s", resp.StatusCode, strings.TrimSpace(string(data)))
+		}
+		if resp.StatusCode == http.StatusTooManyRequests {
+			return nil, fmt.Errorf("%v: %w", apiErr, ErrRateLimited)
+		}
+		return nil, apiErr
+	}
+
+	if h.checkBody != nil {
+		if err := h.checkBody(data); err != nil {
+			return nil, err
+		}
+	}
+	if out == nil || len(bytes.TrimSpace(data)) == 0 {
+		return resp.Header, nil
+	}
+	return resp.Header, json.Unmarshal(data, out)
+}
+
+// jsonID is an ID that APIs send as a JSON number, which we carry around as a string. Strings are
+// accepted as well, so an API switching to string IDs doesn't break decoding.
+type jsonID string
+
+func (id *jsonID) UnmarshalJSON(data []byte) error {
+	if len(data) > 0 && data[0] == '"' {
+		return json.Unmarshal(data, (*string)(id))
+	}
+	var n json.Number
+	if err := json.Unmarshal(data, &n); err != nil {
+		return err
+	}
+	*id = jsonID(n.String())
+	return nil
+}
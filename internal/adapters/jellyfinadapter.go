@@ -0,0 +1,441 @@
+package adapters
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"iter"
+	"net/http"
+	"net/url"
+	"os"
+	"soundporter/internal/playlist"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	jellyfinClientName    = "Soundporter"
+	jellyfinClientVersion = "1.0.0"
+	// jellyfinPageSize is the number of items requested per page
+	jellyfinPageSize = 200
+	// jellyfinTicksPerMs converts run times, which are in ticks of 100 nanoseconds
+	jellyfinTicksPerMs = 10000
+	// jellyfinItemFields are the optional item fields we request
+	jellyfinItemFields = "DateCreated,ChildCount,Overview,MediaType"
+)
+
+var jellyfinCapabilities = Capabilities{
+	MaxBatchSize:   100,
+	MaxSearchLimit: 100,
+	SearchFields:   []SearchField{SearchFieldTrack, SearchFieldArtist, SearchFieldAlbum},
+	PrivacyLevels:  []Privacy{PrivacyPrivate},
+}
+
+func init() {
+	for _, server := range []struct {
+		platform PlatformType
+		name     string
+		prefix   string
+	}{
+		{JellyfinPlatform, "Jellyfin", "JELLYFIN"},
+		{EmbyPlatform, "Emby", "EMBY"},
+	} {
+		Register(Registration{
+			Name:        server.platform,
+			DisplayName: server.name,
+			Credentials: []CredentialSpec{
+				{Key: "url", EnvVar: server.prefix + "_URL", Description: server.name + " server URL, e.g. http://localhost:8096"},
+				{Key: "user", EnvVar: server.prefix + "_USER", Description: "Username"},
+				{Key: "password", EnvVar: server.prefix + "_PASSWORD", Description: "Password, if no API key is set", Optional: true},
+				{Key: "api_key", EnvVar: server.prefix + "_API_KEY", Description: "API key created in the server's dashboard", Optional: true},
+			},
+			Capabilities: jellyfinCapabilities,
+			New: func(creds Credentials, opts ...Option) (ApiAdapter, error) {
+				return newJellyfinAdapter(server.name, creds["url"], creds["user"], creds["password"], creds["api_key"], opts...)
+			},
+		})
+	}
+}
+
+// JellyfinAdapter adapts the API of Jellyfin and Emby servers, which share their lineage, to
+// our common adapter interface
+type JellyfinAdapter struct {
+	BaseAdapter
+	serverName string
+	user       string
+	password   string
+	// token is the API key, or the access token of the user's session once logged in
+	token    string
+	deviceID string
+	userID   string
+	api      *httpAPI
+}
+
+// NewJellyfinAdapter creates an adapter for a Jellyfin server. It logs in with an API key if
+// one is given, and with the user's password otherwise.
+func NewJellyfinAdapter(serverURL, user, password, apiKey string, opts ...Option) (*JellyfinAdapter, error) {
+	return newJellyfinAdapter("Jellyfin", serverURL, user, password, apiKey, opts...)
+}
+
+// NewEmbyAdapter creates an adapter for an Emby server, like NewJellyfinAdapter
+func NewEmbyAdapter(serverURL, user, password, apiKey string, opts ...Option) (*JellyfinAdapter, error) {
+	return newJellyfinAdapter("Emby", serverURL, user, password, apiKey, opts...)
+}
+
+func newJellyfinAdapter(serverName, serverURL, user, password, apiKey string, opts ...Option) (*JellyfinAdapter, error) {
+	if serverURL == "" || user == "" {
+		return nil, fmt.Errorf("%s server URL and user must be provided", strings.ToLower(serverName))
+	}
+	if password == "" && apiKey == "" {
+		return nil, fmt.Errorf("%s API key or password must be provided", strings.ToLower(serverName))
+	}
+	if _, err := url.ParseRequestURI(serverURL); err != nil {
+		return nil, fmt.Errorf("invalid %s server URL %q: %v", strings.ToLower(serverName), serverURL, err)
+	}
+
+	// Servers list logged in devices by ID, so keep it stable between runs on the same machine
+	host, _ := os.Hostname()
+	a := &JellyfinAdapter{
+		BaseAdapter: NewBaseAdapter(serverName),
+		serverName:  serverName,
+		user:        user,
+		password:    password,
+		token:       apiKey,
+		deviceID:    "soundporter-" + host,
+	}
+	a.api = newHTTPAPI(newOptions(options{
+		baseURL: strings.TrimSuffix(serverURL, "/") + "/",
+	}, opts))
+	a.api.authorize = a.authorize
+	a.api.decodeError = jellyfinError
+	return a, nil
+}
+
+// Authenticate logs in with the password, if no API key was given, and looks up the user.
+// API keys aren't tied to a user, so the user is found by name among the server's users.
+func (a *JellyfinAdapter) Authenticate(ctx context.Context) error {
+	if a.token == "" {
+		var session struct {
+			AccessToken string       `json:"AccessToken"`
+			User        jellyfinUser `json:"User"`
+		}
+		body := map[string]string{"Username": a.user, "Pw": a.password}
+		if err := a.api.post(ctx, "Users/AuthenticateByName", body, &session); err != nil {
+			return fmt.Errorf("authentication failed: %v", err)
+		}
+		a.token = session.AccessToken
+		a.userID = session.User.ID
+	} else {
+		var users []jellyfinUser
+		if err := a.api.get(ctx, "Users", nil, &users); err != nil {
+			return fmt.Errorf("authentication failed: %v", err)
+		}
+		for _, u := range users {
+			if strings.EqualFold(u.Name, a.user) {
+				a.userID = u.ID
+			}
+		}
+		if a.userID == "" {
+			return fmt.Errorf("authentication failed: no %s user named %q", a.serverName, a.user)
+		}
+	}
+
+	fmt.Println("You are logged in as:", a.user)
+	a.SetAuthenticated(true)
+	return nil
+}
+
+// authorize adds the client description and token. Jellyfin reads the standard Authorization
+// header and Emby its own, so both are sent.
+func (a *JellyfinAdapter) authorize(req *http.Request) error {
+	value := fmt.Sprintf(`MediaBrowser Client=%q, Device=%q, DeviceId=%q, Version=%q`,
+		jellyfinClientName, jellyfinClientName, a.deviceID, jellyfinClientVersion)
+	if a.token != "" {
+		value += fmt.Sprintf(", Token=%q", a.token)
+	}
+	req.Header.Set("Authorization", value)
+	req.Header.Set("X-Emby-Authorization", value)
+	return nil
+}
+
+type jellyfinUser struct {
+	ID   string `json:"Id"`
+	Name string `json:"Name"`
+}
+
+type jellyfinItem struct {
+	ID          string `json:"Id"`
+	Name        string `json:"Name"`
+	Overview    string `json:"Overview"`
+	DateCreated string `json:"DateCreated"`
+	ChildCount  int    `json:"ChildCount"`
+	MediaType   string `json:"MediaType"`
+	// The fields below describe audio items
+	Album          string         `json:"Album"`
+	AlbumID        string         `json:"AlbumId"`
+	Artists        []string       `json:"Artists"`
+	ArtistItems    []jellyfinUser `json:"ArtistItems"`
+	AlbumArtist    string         `json:"AlbumArtist"`
+	RunTimeTicks   int64          `json:"RunTimeTicks"`
+	IndexNumber    int            `json:"IndexNumber"`
+	ParentIndex    int            `json:"ParentIndexNumber"`
+	ProductionYear int            `json:"ProductionYear"`
+	PremiereDate   string         `json:"PremiereDate"`
+}
+
+// jellyfinPage is a page of a query result
+type jellyfinPage struct {
+	Items            []jellyfinItem `json:"Items"`
+	TotalRecordCount int            `json:"TotalRecordCount"`
+}
+
+// GetUserPlaylists retrieves all playlists for the authenticated user
+func (a *JellyfinAdapter) GetUserPlaylists(ctx context.Context) ([]playlist.Playlist, error) {
+	return collect(a.UserPlaylists(ctx))
+}
+
+// UserPlaylists streams the user's music playlists page by page. Video playlists are skipped.
+func (a *JellyfinAdapter) UserPlaylists(ctx context.Context) iter.Seq2[playlist.Playlist, error] {
+	return func(yield func(playlist.Playlist, error) bool) {
+		if err := a.CheckAuth(); err != nil {
+			yield(playlist.Playlist{}, err)
+			return
+		}
+
+		query := url.Values{
+			"IncludeItemTypes": {"Playlist"},
+			"Recursive":        {"true"},
+			"SortBy":           {"SortName"},
+		}
+		for page, err := range a.pages(ctx, a.userItemsPath(), query) {
+			if err != nil {
+				yield(playlist.Playlist{}, fmt.Errorf("error getting playlists: %v", err))
+				return
+			}
+			for _, item := range page {
+				if item.MediaType != "" && item.MediaType != "Audio" {
+					continue
+				}
+				created, _ := time.Parse(time.RFC3339Nano, item.DateCreated)
+				if !yield(playlist.Playlist{
+					ID:          item.ID,
+					Name:        item.Name,
+					Description: item.Overview,
+					TrackCount:  item.ChildCount,
+					CreatedAt:   created,
+				}, nil) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// GetPlaylistItems retrieves all tracks in a playlist
+func (a *JellyfinAdapter) GetPlaylistItems(ctx context.Context, playlistID string) ([]playlist.Track, error) {
+	return collect(a.PlaylistItems(ctx, playlistID))
+}
+
+// PlaylistItems streams the tracks of a playlist page by page
+func (a *JellyfinAdapter) PlaylistItems(ctx context.Context, playlistID string) iter.Seq2[playlist.Track, error] {
+	return func(yield func(playlist.Track, error) bool) {
+		if err := a.CheckAuth(); err != nil {
+			yield(playlist.Track{}, err)
+			return
+		}
+
+		path := "Playlists/" + url.PathEscape(playlistID) + "/Items"
+		for page, err := range a.pages(ctx, path, url.Values{"UserId": {a.userID}}) {
+			if err != nil {
+				yield(playlist.Track{}, fmt.Errorf("error getting playlist items: %v", err))
+				return
+			}
+			for _, item := range page {
+				if !yield(a.itemToTrack(item), nil) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// userItemsPath is the path of the user's library items. The newer /Items?userId= form
+// isn't supported by Emby.
+func (a *JellyfinAdapter) userItemsPath() string {
+	return "Users/" + url.PathEscape(a.userID) + "/Items"
+}
+
+// pages pages through a query result by start index until all items have been read
+func (a *JellyfinAdapter) pages(ctx context.Context, path string, query url.Values) iter.Seq2[[]jellyfinItem, error] {
+	return func(yield func([]jellyfinItem, error) bool) {
+		query.Set("Fields", jellyfinItemFields)
+		query.Set("Limit", strconv.Itoa(jellyfinPageSize))
+		for start := 0; ; {
+			query.Set("StartIndex", strconv.Itoa(start))
+			var page jellyfinPage
+			if err := a.api.get(ctx, path, query, &page); err != nil {
+				yield(nil, err)
+				return
+			}
+			if !yield(page.Items, nil) {
+				return
+			}
+			start += len(page.Items)
+			if len(page.Items) == 0 || start >= page.TotalRecordCount {
+				return
+			}
+		}
+	}
+}
+
+// CreateNewPlaylist creates a new audio playlist owned by the user. Playlists can't be given a
+// description through the API, so it's left out.
+func (a *JellyfinAdapter) CreateNewPlaylist(ctx context.Context, name string, description string, privacy Privacy) (playlist.Playlist, error) {
+	if err := a.CheckAuth(); err != nil {
+		return playlist.Playlist{}, err
+	}
+
+	// Emby only reads query parameters, which Jellyfin accepts too
+	params := url.Values{"Name": {name}, "UserId": {a.userID}, "MediaType": {"Audio"}}
+	var created struct {
+		ID string `json:"Id"`
+	}
+	if err := a.api.do(ctx, http.MethodPost, "Playlists", params, nil, &created); err != nil {
+		return playlist.Playlist{}, fmt.Errorf("error creating playlist: %v", err)
+	}
+
+	return playlist.Playlist{
+		ID:        created.ID,
+		Name:      name,
+		CreatedAt: time.Now(),
+	}, nil
+}
+
+// AddItemsToPlaylist adds tracks to a playlist in one request
+func (a *JellyfinAdapter) AddItemsToPlaylist(ctx context.Context, playlistID string, trackIDs []string) error {
+	if err := a.CheckAuth(); err != nil {
+		return err
+	}
+
+	params := url.Values{"Ids": {strings.Join(trackIDs, ",")}, "UserId": {a.userID}}
+	path := "Playlists/" + url.PathEscape(playlistID) + "/Items"
+	if err := a.api.do(ctx, http.MethodPost, path, params, nil, nil); err != nil {
+		return fmt.Errorf("error adding tracks to playlist: %v", err)
+	}
+	return nil
+}
+
+// SearchTracks searches the library for audio items. Queries may use field filters like
+// artist:"Queen", and any other text is matched against track names.
+func (a *JellyfinAdapter) SearchTracks(ctx context.Context, query string, limit int) ([]playlist.Track, error) {
+	return a.SearchTracksBy(ctx, ParseSearchQuery(query), limit)
+}
+
+// SearchTracksBy searches the library for audio items by name, artist and album
+func (a *JellyfinAdapter) SearchTracksBy(ctx context.Context, query SearchQuery, limit int) ([]playlist.Track, error) {
+	if err := a.CheckAuth(); err != nil {
+		return nil, err
+	}
+	if query.Track == "" && query.Artist == "" && query.Album == "" {
+		return nil, nil
+	}
+
+	if limit <= 0 || limit > jellyfinCapabilities.MaxSearchLimit {
+		limit = jellyfinCapabilities.MaxSearchLimit
+	}
+
+	params := url.Values{
+		"IncludeItemTypes": {"Audio"},
+		"Recursive":        {"true"},
+		"Fields":           {jellyfinItemFields},
+		"Limit":            {strconv.Itoa(limit)},
+	}
+	if query.Track != "" {
+		params.Set("SearchTerm", query.Track)
+	}
+	// Artists and Albums take exact names, separated by pipes
+	if query.Artist != "" {
+		params.Set("Artists", query.Artist)
+	}
+	if query.Album != "" {
+		params.Set("Albums", query.Album)
+	}
+
+	var page jellyfinPage
+	if err := a.api.get(ctx, a.userItemsPath(), params, &page); err != nil {
+		return nil, fmt.Errorf("error searching tracks: %v", err)
+	}
+
+	var tracks []playlist.Track
+	for _, item := range page.Items {
+		tracks = append(tracks, a.itemToTrack(item))
+	}
+	return tracks, nil
+}
+
+// Capabilities describes the Jellyfin API limits
+func (a *JellyfinAdapter) Capabilities() Capabilities {
+	return jellyfinCapabilities
+}
+
+// itemToTrack converts an audio item into our track model
+func (a *JellyfinAdapter) itemToTrack(item jellyfinItem) playlist.Track {
+	track := playlist.Track{
+		Name:        item.Name,
+		Artists:     item.Artists,
+		Album:       item.Album,
+		ID:          item.ID,
+		AlbumID:     item.AlbumID,
+		URL:         a.itemURL(item.ID),
+		DurationMs:  int(item.RunTimeTicks / jellyfinTicksPerMs),
+		DiscNumber:  item.ParentIndex,
+		TrackNumber: item.IndexNumber,
+	}
+	for _, artist := range item.ArtistItems {
+		track.ArtistIDs = append(track.ArtistIDs, artist.ID)
+	}
+	// The album artist stands in for items without artists of their own
+	if len(track.Artists) == 0 && item.AlbumArtist != "" {
+		track.Artists = []string{item.AlbumArtist}
+	}
+	if date, _, ok := strings.Cut(item.PremiereDate, "T"); ok {
+		track.ReleaseDate = date
+	} else if item.ProductionYear > 0 {
+		track.ReleaseDate = strconv.Itoa(item.ProductionYear)
+	}
+	return track
+}
+
+// itemURL links to an item in the server's web client, whose routes differ between the servers
+func (a *JellyfinAdapter) itemURL(id string) string {
+	route := "web/#/details?id="
+	if a.serverName == "Emby" {
+		route = "web/index.html#!/item?id="
+	}
+	return a.api.baseURL + route + url.QueryEscape(id)
+}
+
+// jellyfinError decodes the error body of a Jellyfin or Emby response. Both servers also
+// answer some errors with plain text, which is left to the default message.
+func jellyfinError(status int, body []byte) error {
+	var problem struct {
+		Title   string `json:"title"`
+		Detail  string `json:"detail"`
+		Message string `json:"Message"`
+	}
+	if json.Unmarshal(body, &problem) != nil {
+		return nil
+	}
+	message := problem.Detail
+	if message == "" {
+		message = problem.Message
+	}
+	if message == "" {
+		message = problem.Title
+	}
+	if message == "" {
+		return nil
+	}
+	return fmt.Errorf("%s (HTTP %d)", message, status)
+}
@@ -0,0 +1,121 @@
+package adapters_test
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"soundporter/internal/adapters"
+	"soundporter/internal/playlist"
+	"soundporter/internal/standin"
+)
+
+// startJellyfin starts a Jellyfin stand-in for the conformance suite, logging in with the user's
+// password
+func startJellyfin(t *testing.T, catalog []playlist.Track, playlists []playlist.Playlist) (*standin.Jellyfin, standinRun) {
+	t.Helper()
+	j := standin.NewJellyfin(catalog, playlists)
+	t.Cleanup(j.Close)
+	login := func(user, password, apiKey string) adapters.ApiAdapter {
+		a, err := adapters.NewJellyfinAdapter(j.URL, user, password, apiKey, j.Options()...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return a
+	}
+	return j, standinRun{
+		adapter: login(standin.JellyfinUser, standin.JellyfinPassword, ""),
+		// API keys aren't tied to a user, so the user is found among the server's users
+		accepted: map[string]adapters.ApiAdapter{"an API key": login(standin.JellyfinUser, "", standin.JellyfinAPIKey)},
+		refused: map[string]adapters.ApiAdapter{
+			"a wrong password":               login(standin.JellyfinUser, "wrong", ""),
+			"an API key for an unknown user": login("nobody", "", standin.JellyfinAPIKey),
+		},
+		stored: j,
+	}
+}
+
+func TestJellyfinStandinEmby(t *testing.T) {
+	ctx := context.Background()
+	j, _ := startJellyfin(t, nil, nil)
+
+	// Emby reads its own header, which is sent alongside the standard one
+	j.Emby = true
+	a, err := adapters.NewEmbyAdapter(j.URL, standin.JellyfinUser, standin.JellyfinPassword, "", j.Options()...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := a.Authenticate(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := a.GetUserPlaylists(ctx); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestJellyfinStandinTracks(t *testing.T) {
+	j, run := startJellyfin(t, nil, nil)
+	a := authenticate(t, run.adapter)
+
+	tracks, err := a.GetPlaylistItems(context.Background(), "fake-playlist-3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The server's artist list is used as it is, including the IDs of each artist
+	for i, want := range adapters.FakeCatalog() {
+		got := tracks[i]
+		if got.Name != want.Name || got.Album != want.Album || got.DurationMs != want.DurationMs || got.ReleaseDate != want.ReleaseDate {
+			t.Errorf("track %d converted to %+v, want %+v", i, got, want)
+		}
+		if !slices.Equal(got.Artists, want.Artists) || !slices.Equal(got.ArtistIDs, want.ArtistIDs) {
+			t.Errorf("track %d has artists %v %v, want %v %v", i, got.Artists, got.ArtistIDs, want.Artists, want.ArtistIDs)
+		}
+		if got.URL != j.URL+"/web/#/details?id="+want.ID {
+			t.Errorf("track %d has URL %s", i, got.URL)
+		}
+	}
+}
+
+func TestJellyfinStandinSearchFilters(t *testing.T) {
+	ctx := context.Background()
+	_, run := startJellyfin(t, nil, nil)
+	a := authenticate(t, run.adapter)
+
+	// Artists and albums are matched by exact name, so a featured artist finds their track
+	tracks, err := a.SearchTracksBy(ctx, adapters.SearchQuery{Artist: "Fencepost"}, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(trackIDs(tracks), []string{"fake-13"}) {
+		t.Fatalf("artist search found %v", trackIDs(tracks))
+	}
+	tracks, err = a.SearchTracks(ctx, `Green Build album:"Pipeline (Remixes)"`, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(trackIDs(tracks), []string{"fake-10"}) {
+		t.Fatalf("album search found %v", trackIDs(tracks))
+	}
+}
+
+func TestJellyfinStandinUnknownItems(t *testing.T) {
+	ctx := context.Background()
+	j, run := startJellyfin(t, nil, nil)
+	a := authenticate(t, run.adapter)
+
+	p, err := a.CreateNewPlaylist(ctx, "Copy", "", adapters.PrivacyPrivate)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Like the real servers, the stand-in skips IDs that aren't audio items
+	if err := a.AddItemsToPlaylist(ctx, p.ID, []string{"missing", "fake-01"}); err != nil {
+		t.Fatal(err)
+	}
+	if created, _ := j.Playlist(p.ID); !slices.Equal(trackIDs(created.Tracks), []string{"fake-01"}) {
+		t.Fatalf("unexpected tracks after an unknown ID: %v", trackIDs(created.Tracks))
+	}
+
+	if err := a.AddItemsToPlaylist(ctx, "missing", []string{"fake-01"}); err == nil {
+		t.Fatal("expected an unknown playlist to fail")
+	}
+}
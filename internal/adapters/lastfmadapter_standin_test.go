@@ -0,0 +1,209 @@
+package adapters_test
+
+import (
+	"context"
+	"net/url"
+	"slices"
+	"strings"
+	"testing"
+
+	"golang.org/x/oauth2"
+
+	"soundporter/internal/adapters"
+	"soundporter/internal/playlist"
+	"soundporter/internal/standin"
+)
+
+// startLastfm starts a Last.fm stand-in for the conformance suite. Last.fm has no playlists, so
+// a given catalog is loved in its place.
+func startLastfm(t *testing.T, catalog []playlist.Track, _ []playlist.Playlist) (*standin.Lastfm, standinRun) {
+	t.Helper()
+	l := standin.NewLastfm(catalog)
+	t.Cleanup(l.Close)
+	if catalog != nil {
+		l.Loved = slices.Clone(catalog)
+	}
+	login := func(apiKey, secret, user string, opts ...adapters.Option) adapters.ApiAdapter {
+		a, err := adapters.NewLastfmAdapter(apiKey, secret, user, append(l.Options(), opts...)...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return a
+	}
+	return l, standinRun{
+		adapter: login(standin.LastfmAPIKey, standin.LastfmSecret, "", adapters.WithToken(l.Token())),
+		// Sessions don't expire, but an unknown one is rejected, as are signatures made with the
+		// wrong secret and unknown API keys
+		refused: map[string]adapters.ApiAdapter{
+			"a revoked session": login(standin.LastfmAPIKey, standin.LastfmSecret, "", adapters.WithToken(&oauth2.Token{AccessToken: "revoked"})),
+			"a wrong secret":    login(standin.LastfmAPIKey, "wrong", "", adapters.WithToken(l.Token())),
+			"a wrong API key":   login("wrong", "", standin.LastfmUser),
+			"an unknown user":   login(standin.LastfmAPIKey, "", "nobody"),
+		},
+	}
+}
+
+// lastfmID returns the ID the adapter gives a track, made of the artist and title the stand-in
+// lists it by
+func lastfmID(t playlist.Track) string {
+	return url.QueryEscape(strings.Join(t.Artists, ", ")) + "/_/" + url.QueryEscape(t.Name)
+}
+
+// lovedIDs returns the IDs of the stand-in's loved tracks
+func lovedIDs(l *standin.Lastfm) []string {
+	var ids []string
+	for _, track := range l.LovedTracks() {
+		ids = append(ids, lastfmID(track))
+	}
+	return ids
+}
+
+func TestLastfmStandinPublicHistory(t *testing.T) {
+	ctx := context.Background()
+	l, _ := startLastfm(t, nil, nil)
+
+	// Without the secret the public history is read, but nothing can be loved
+	a, err := adapters.NewLastfmAdapter(standin.LastfmAPIKey, "", standin.LastfmUser, l.Options()...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := a.Authenticate(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if tracks, err := a.GetPlaylistItems(ctx, adapters.LastfmLovedID); err != nil || len(tracks) != 3 {
+		t.Fatalf("read %d loved tracks: %v", len(tracks), err)
+	}
+	if _, err := a.CreateNewPlaylist(ctx, "Copy", "", adapters.PrivacyPublic); err == nil {
+		t.Fatal("expected creating without a session to fail")
+	}
+	if err := a.AddItemsToPlaylist(ctx, adapters.LastfmLovedID, []string{"Mock+Orchestra/_/Golden+Master"}); err == nil {
+		t.Fatal("expected loving without a session to fail")
+	}
+}
+
+func TestLastfmStandinHistory(t *testing.T) {
+	ctx := context.Background()
+	catalog, _ := pagedLibrary(450, 0)
+	_, run := startLastfm(t, catalog, nil)
+	a := authenticate(t, run.adapter)
+
+	var unique []string
+	for _, track := range catalog {
+		if id := lastfmID(track); !slices.Contains(unique, id) {
+			unique = append(unique, id)
+		}
+	}
+
+	// Each track is played every third day, the first ones several times, and listed once.
+	// Recordings sharing an artist and title, like the two Green Builds, are one track.
+	recent, err := a.GetPlaylistItems(ctx, "recent:1500d")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(trackIDs(recent), unique) {
+		t.Fatalf("expected %d played tracks once each, got %d", len(unique), len(recent))
+	}
+
+	// Loved tracks are listed without their album, which recent tracks have
+	loved, err := a.GetPlaylistItems(ctx, adapters.LastfmLovedID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, i := range []int{5, 12, 300} {
+		want, got := catalog[i], loved[i]
+		if got.URL != "https://www.last.fm/music/"+got.ID || got.Album != "" {
+			t.Errorf("loved track %d converted to %+v, want %+v", i, got, want)
+		}
+		if played := recent[slices.Index(unique, got.ID)]; played.Name != want.Name || played.Album != want.Album {
+			t.Errorf("played track %d converted to %+v, want %+v", i, played, want)
+		}
+	}
+	// Last.fm has a single artist name per track, which is kept whole
+	if got := loved[12]; !slices.Equal(got.Artists, []string{"Data Race, Fencepost"}) {
+		t.Fatalf("track artist converted to %q", got.Artists)
+	}
+
+	// The tracks played in the last week are ranked by play count, and cut to the count
+	top, err := a.GetPlaylistItems(ctx, "top:7day:2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(trackIDs(top), trackIDs(loved[:2])) || top[0].DurationMs != catalog[0].DurationMs/1000*1000 {
+		t.Fatalf("unexpected top tracks %+v", top)
+	}
+	// Numbers of days are ranked from the track chart, which isn't paged
+	chart, err := a.GetPlaylistItems(ctx, "top:30d:5")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(trackIDs(chart), trackIDs(loved[:5])) {
+		t.Fatalf("unexpected chart %v", trackIDs(chart))
+	}
+}
+
+func TestLastfmStandinSearchByTitle(t *testing.T) {
+	ctx := context.Background()
+	_, run := startLastfm(t, nil, nil)
+	a := authenticate(t, run.adapter)
+
+	// Both Green Build recordings share an artist and title, and so an ID
+	tracks, err := a.SearchTracksBy(ctx, adapters.SearchQuery{Track: "Green Build", Artist: "Continuous Integration"}, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(trackIDs(tracks), []string{"Continuous+Integration/_/Green+Build", "Continuous+Integration/_/Green+Build"}) {
+		t.Fatalf("search found %v", trackIDs(tracks))
+	}
+
+	// Last.fm searches by title only
+	if tracks, err := a.SearchTracksBy(ctx, adapters.SearchQuery{Artist: "The Placeholders"}, 5); err != nil || tracks != nil {
+		t.Fatalf("artist-only query returned %v, %v", tracks, err)
+	}
+}
+
+func TestLastfmStandinLove(t *testing.T) {
+	ctx := context.Background()
+	catalog, _ := pagedLibrary(120, 0)
+	l, run := startLastfm(t, catalog, nil)
+	a := authenticate(t, run.adapter)
+	l.Loved = slices.Clone(catalog[:3])
+
+	// Last.fm has no playlists, so transferring one loves its tracks
+	created, err := a.CreateNewPlaylist(ctx, "Copy", "Created by a test", adapters.PrivacyPublic)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if created.ID != adapters.LastfmLovedID {
+		t.Fatalf("created %+v", created)
+	}
+
+	// Each love puts the track first, unless it's loved already, as a second recording with the
+	// same artist and title is
+	batch := a.Capabilities().MaxBatchSize
+	want := []string{lastfmID(catalog[0]), lastfmID(catalog[1]), lastfmID(catalog[2])}
+	ids := make([]string, len(catalog)-3)
+	for i, track := range catalog[3:] {
+		ids[i] = lastfmID(track)
+		if !slices.Contains(want, ids[i]) {
+			want = append([]string{ids[i]}, want...)
+		}
+	}
+	for start := 0; start < len(ids); start += batch {
+		if err := a.AddItemsToPlaylist(ctx, created.ID, ids[start:min(start+batch, len(ids))]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := lovedIDs(l); !slices.Equal(got, want) {
+		t.Fatalf("expected %d loved tracks in order, got %d", len(want), len(got))
+	}
+
+	if err := a.AddItemsToPlaylist(ctx, created.ID, []string{"no-separator", lastfmID(catalog[0])}); err == nil {
+		t.Fatal("expected an invalid track ID to fail")
+	}
+	if err := a.AddItemsToPlaylist(ctx, "top:7day", ids[:1]); err == nil {
+		t.Fatal("expected adding to a top tracks playlist to fail")
+	}
+	if got := lovedIDs(l); len(got) != len(want) {
+		t.Fatalf("failed batches loved tracks: %d", len(got))
+	}
+}
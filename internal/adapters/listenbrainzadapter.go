@@ -0,0 +1,437 @@
+package adapters
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"html"
+	"iter"
+	"net/http"
+	"net/url"
+	"path"
+	"regexp"
+	"soundporter/internal/playlist"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	listenbrainzBaseURL = "https://api.listenbrainz.org/1/"
+	// listenbrainzPageSize is the number of playlists requested per page, the most the API allows
+	listenbrainzPageSize = 100
+	// listenbrainzRecordingURL prefixes the MBIDs that identify tracks in JSPF
+	listenbrainzRecordingURL = "https://musicbrainz.org/recording/"
+)
+
+// listenbrainzBlockRe and listenbrainzTagRe match the HTML tags of playlist annotations, the
+// former those that separate text
+var (
+	listenbrainzBlockRe = regexp.MustCompile(`(?i)<(/?p|br|/?div|/?li)\b[^>]*>`)
+	listenbrainzTagRe   = regexp.MustCompile(`<[^>]*>`)
+)
+
+var listenbrainzCapabilities = Capabilities{
+	MaxBatchSize: 100,
+	// The metadata lookup returns the single best match
+	MaxSearchLimit: 1,
+	SearchFields:   []SearchField{SearchFieldTrack, SearchFieldArtist, SearchFieldAlbum},
+	PrivacyLevels:  []Privacy{PrivacyPrivate, PrivacyPublic},
+}
+
+func init() {
+	Register(Registration{
+		Name:        ListenBrainzPlatform,
+		DisplayName: "ListenBrainz",
+		Credentials: []CredentialSpec{
+			{Key: "token", EnvVar: "LISTENBRAINZ_TOKEN", Description: "User token from your ListenBrainz settings"},
+		},
+		Capabilities: listenbrainzCapabilities,
+		New: func(creds Credentials, opts ...Option) (ApiAdapter, error) {
+			return NewListenBrainzAdapter(creds["token"], opts...)
+		},
+	})
+}
+
+// ListenBrainzAdapter adapts the JSPF playlist API of ListenBrainz to our common adapter
+// interface. Playlists and tracks are identified by their MusicBrainz IDs, so tracks read from
+// ListenBrainz are precise sources for transfers. The playlists ListenBrainz generates for the
+// user, like Weekly Jams and Weekly Exploration, are listed after the user's own.
+type ListenBrainzAdapter struct {
+	BaseAdapter
+	token string
+	user  string
+	api   *httpAPI
+}
+
+// NewListenBrainzAdapter creates an adapter authenticating with a ListenBrainz user token
+func NewListenBrainzAdapter(token string, opts ...Option) (*ListenBrainzAdapter, error) {
+	if token == "" {
+		return nil, fmt.Errorf("listenbrainz user token must be provided")
+	}
+
+	a := &ListenBrainzAdapter{
+		BaseAdapter: NewBaseAdapter("ListenBrainz"),
+		token:       token,
+	}
+	a.api = newHTTPAPI(newOptions(options{baseURL: listenbrainzBaseURL}, opts))
+	a.api.authorize = a.authorize
+	a.api.decodeError = listenbrainzError
+	return a, nil
+}
+
+// Authenticate validates the token and looks up the user it belongs to
+func (a *ListenBrainzAdapter) Authenticate(ctx context.Context) error {
+	var resp struct {
+		Valid    bool   `json:"valid"`
+		UserName string `json:"user_name"`
+		Message  string `json:"message"`
+	}
+	if err := a.api.get(ctx, "validate-token", nil, &resp); err != nil {
+		return fmt.Errorf("authentication failed: %v", err)
+	}
+	if !resp.Valid {
+		return fmt.Errorf("authentication failed: the ListenBrainz token was rejected: %s", resp.Message)
+	}
+	a.user = resp.UserName
+
+	fmt.Println("You are logged in as:", a.user)
+	a.SetAuthenticated(true)
+	return nil
+}
+
+// authorize adds the user token
+func (a *ListenBrainzAdapter) authorize(req *http.Request) error {
+	req.Header.Set("Authorization", "Token "+a.token)
+	return nil
+}
+
+// jspfPlaylist is a playlist in JSPF, the JSON form of XSPF, with ListenBrainz's extension
+type jspfPlaylist struct {
+	Title      string `json:"title"`
+	Identifier string `json:"identifier,omitempty"`
+	Creator    string `json:"creator,omitempty"`
+	Annotation string `json:"annotation,omitempty"`
+	Date       string `json:"date,omitempty"`
+	Extension  struct {
+		Playlist *jspfPlaylistExtension `json:"https://musicbrainz.org/doc/jspf#playlist,omitempty"`
+	} `json:"extension"`
+	Track []jspfTrack `json:"track"`
+}
+
+type jspfPlaylistExtension struct {
+	Public bool `json:"public"`
+}
+
+type jspfTrack struct {
+	Identifier jspfIdentifiers `json:"identifier"`
+	Title      string          `json:"title,omitempty"`
+	Creator    string          `json:"creator,omitempty"`
+	Album      string          `json:"album,omitempty"`
+	// Duration is in milliseconds
+	Duration  int `json:"duration,omitempty"`
+	Extension struct {
+		Track *jspfTrackExtension `json:"https://musicbrainz.org/doc/jspf#track,omitempty"`
+	} `json:"extension"`
+}
+
+type jspfTrackExtension struct {
+	ArtistIdentifiers  []string `json:"artist_identifiers"`
+	ReleaseIdentifier  string   `json:"release_identifier"`
+	AdditionalMetadata struct {
+		// Artists are the credited artists, which Creator joins with their join phrases
+		Artists []struct {
+			ArtistCreditName string `json:"artist_credit_name"`
+		} `json:"artists"`
+	} `json:"additional_metadata"`
+}
+
+// jspfIdentifiers are the identifiers of a JSPF track. ListenBrainz used to send a single
+// identifier as a string, so both forms are accepted.
+type jspfIdentifiers []string
+
+func (ids *jspfIdentifiers) UnmarshalJSON(data []byte) error {
+	if len(data) > 0 && data[0] == '"' {
+		var id string
+		if err := json.Unmarshal(data, &id); err != nil {
+			return err
+		}
+		*ids = jspfIdentifiers{id}
+		return nil
+	}
+	return json.Unmarshal(data, (*[]string)(ids))
+}
+
+// GetUserPlaylists retrieves all playlists for the authenticated user
+func (a *ListenBrainzAdapter) GetUserPlaylists(ctx context.Context) ([]playlist.Playlist, error) {
+	return collect(a.UserPlaylists(ctx))
+}
+
+// UserPlaylists streams the user's playlists, private ones included, followed by the playlists
+// ListenBrainz generated for the user
+func (a *ListenBrainzAdapter) UserPlaylists(ctx context.Context) iter.Seq2[playlist.Playlist, error] {
+	return func(yield func(playlist.Playlist, error) bool) {
+		if err := a.CheckAuth(); err != nil {
+			yield(playlist.Playlist{}, err)
+			return
+		}
+
+		user := url.PathEscape(a.user)
+		for _, p := range []string{"user/" + user + "/playlists", "user/" + user + "/playlists/createdfor"} {
+			for offset := 0; ; {
+				var page struct {
+					Playlists []struct {
+						Playlist jspfPlaylist `json:"playlist"`
+					} `json:"playlists"`
+					PlaylistCount int `json:"playlist_count"`
+				}
+				params := url.Values{"count": {strconv.Itoa(listenbrainzPageSize)}, "offset": {strconv.Itoa(offset)}}
+				if err := a.api.get(ctx, p, params, &page); err != nil {
+					yield(playlist.Playlist{}, fmt.Errorf("error getting playlists: %v", err))
+					return
+				}
+				for _, item := range page.Playlists {
+					if !yield(jspfToPlaylist(item.Playlist), nil) {
+						return
+					}
+				}
+				offset += len(page.Playlists)
+				if len(page.Playlists) == 0 || offset >= page.PlaylistCount {
+					break
+				}
+			}
+		}
+	}
+}
+
+// GetPlaylistItems retrieves all tracks in a playlist
+func (a *ListenBrainzAdapter) GetPlaylistItems(ctx context.Context, playlistID string) ([]playlist.Track, error) {
+	return collect(a.PlaylistItems(ctx, playlistID))
+}
+
+// PlaylistItems streams the tracks of a playlist, which ListenBrainz returns in one response
+func (a *ListenBrainzAdapter) PlaylistItems(ctx context.Context, playlistID string) iter.Seq2[playlist.Track, error] {
+	return func(yield func(playlist.Track, error) bool) {
+		if err := a.CheckAuth(); err != nil {
+			yield(playlist.Track{}, err)
+			return
+		}
+
+		var resp struct {
+			Playlist jspfPlaylist `json:"playlist"`
+		}
+		if err := a.api.get(ctx, "playlist/"+url.PathEscape(playlistID), nil, &resp); err != nil {
+			yield(playlist.Track{}, fmt.Errorf("error getting playlist items: %v", err))
+			return
+		}
+		for _, t := range resp.Playlist.Track {
+			track, ok := jspfToTrack(t)
+			if !ok {
+				continue
+			}
+			if !yield(track, nil) {
+				return
+			}
+		}
+	}
+}
+
+// CreateNewPlaylist creates an empty playlist
+func (a *ListenBrainzAdapter) CreateNewPlaylist(ctx context.Context, name string, description string, privacy Privacy) (playlist.Playlist, error) {
+	if err := a.CheckAuth(); err != nil {
+		return playlist.Playlist{}, err
+	}
+
+	p := jspfPlaylist{Title: name, Track: []jspfTrack{}}
+	// Annotations are HTML
+	p.Annotation = html.EscapeString(description)
+	p.Extension.Playlist = &jspfPlaylistExtension{Public: privacy == PrivacyPublic}
+	var resp struct {
+		PlaylistMBID string `json:"playlist_mbid"`
+	}
+	if err := a.api.post(ctx, "playlist/create", map[string]any{"playlist": p}, &resp); err != nil {
+		return playlist.Playlist{}, fmt.Errorf("error creating playlist: %v", err)
+	}
+	return playlist.Playlist{
+		ID:          resp.PlaylistMBID,
+		Name:        name,
+		Description: description,
+		CreatedAt:   time.Now(),
+	}, nil
+}
+
+// AddItemsToPlaylist appends recordings to a playlist, identified by their MBIDs
+func (a *ListenBrainzAdapter) AddItemsToPlaylist(ctx context.Context, playlistID string, trackIDs []string) error {
+	if err := a.CheckAuth(); err != nil {
+		return err
+	}
+
+	tracks := make([]map[string]string, len(trackIDs))
+	for i, id := range trackIDs {
+		tracks[i] = map[string]string{"identifier": listenbrainzRecordingURL + id}
+	}
+	body := map[string]any{"playlist": map[string]any{"track": tracks}}
+	if err := a.api.post(ctx, "playlist/"+url.PathEscape(playlistID)+"/item/add", body, nil); err != nil {
+		return fmt.Errorf("error adding tracks to playlist: %v", err)
+	}
+	return nil
+}
+
+// SearchTracks looks up a recording. Queries need artist:"..." filters, since the lookup
+// matches an artist and a title, and any other text is taken as the title.
+func (a *ListenBrainzAdapter) SearchTracks(ctx context.Context, query string, limit int) ([]playlist.Track, error) {
+	return a.SearchTracksBy(ctx, ParseSearchQuery(query), limit)
+}
+
+// SearchTracksBy looks up the recording best matching the query's artist, title and album in
+// ListenBrainz's mapping of MusicBrainz data. Queries without an artist or title find nothing.
+func (a *ListenBrainzAdapter) SearchTracksBy(ctx context.Context, query SearchQuery, limit int) ([]playlist.Track, error) {
+	if err := a.CheckAuth(); err != nil {
+		return nil, err
+	}
+	if query.Track == "" || query.Artist == "" {
+		return nil, nil
+	}
+
+	params := url.Values{
+		"artist_name":    {query.Artist},
+		"recording_name": {query.Track},
+		"metadata":       {"true"},
+		"inc":            {"artist release"},
+	}
+	if query.Album != "" {
+		params.Set("release_name", query.Album)
+	}
+	var match struct {
+		ArtistCreditName string   `json:"artist_credit_name"`
+		ArtistMBIDs      []string `json:"artist_mbids"`
+		RecordingMBID    string   `json:"recording_mbid"`
+		RecordingName    string   `json:"recording_name"`
+		ReleaseMBID      string   `json:"release_mbid"`
+		ReleaseName      string   `json:"release_name"`
+		Metadata         struct {
+			Recording struct {
+				Length int `json:"length"`
+			} `json:"recording"`
+			Release struct {
+				Year int `json:"year"`
+			} `json:"release"`
+			Artist struct {
+				Artists []struct {
+					Name string `json:"name"`
+				} `json:"artists"`
+			} `json:"artist"`
+		} `json:"metadata"`
+	}
+	if err := a.api.get(ctx, "metadata/lookup/", params, &match); err != nil {
+		return nil, fmt.Errorf("error searching tracks: %v", err)
+	}
+	// No match is an empty object
+	if match.RecordingMBID == "" {
+		return nil, nil
+	}
+
+	track := playlist.Track{
+		Name:       match.RecordingName,
+		Album:      match.ReleaseName,
+		ID:         match.RecordingMBID,
+		ArtistIDs:  match.ArtistMBIDs,
+		AlbumID:    match.ReleaseMBID,
+		URL:        listenbrainzRecordingURL + match.RecordingMBID,
+		MBID:       match.RecordingMBID,
+		DurationMs: match.Metadata.Recording.Length,
+	}
+	// The credit is only used if the artists aren't listed
+	for _, artist := range match.Metadata.Artist.Artists {
+		track.Artists = append(track.Artists, artist.Name)
+	}
+	if track.Artists == nil && match.ArtistCreditName != "" {
+		track.Artists = []string{match.ArtistCreditName}
+	}
+	if match.Metadata.Release.Year > 0 {
+		track.ReleaseDate = strconv.Itoa(match.Metadata.Release.Year)
+	}
+	return []playlist.Track{track}, nil
+}
+
+// Capabilities describes the ListenBrainz API limits
+func (a *ListenBrainzAdapter) Capabilities() Capabilities {
+	return listenbrainzCapabilities
+}
+
+// jspfToPlaylist converts a JSPF playlist into our playlist model. Listed playlists come
+// without their tracks, so the track count is only known once the tracks are read.
+func jspfToPlaylist(p jspfPlaylist) playlist.Playlist {
+	created, _ := time.Parse(time.RFC3339, p.Date)
+	return playlist.Playlist{
+		ID:          mbidFromURL(p.Identifier),
+		Name:        p.Title,
+		Description: annotationText(p.Annotation),
+		TrackCount:  len(p.Track),
+		CreatedAt:   created,
+	}
+}
+
+// jspfToTrack converts a JSPF track into our track model. Tracks without a recording MBID
+// can't be referred to, and are skipped.
+func jspfToTrack(t jspfTrack) (playlist.Track, bool) {
+	var mbid string
+	for _, id := range t.Identifier {
+		if strings.HasPrefix(id, listenbrainzRecordingURL) {
+			mbid = mbidFromURL(id)
+			break
+		}
+	}
+	if mbid == "" {
+		return playlist.Track{}, false
+	}
+
+	track := playlist.Track{
+		Name:       t.Title,
+		Album:      t.Album,
+		ID:         mbid,
+		URL:        listenbrainzRecordingURL + mbid,
+		MBID:       mbid,
+		DurationMs: t.Duration,
+	}
+	if ext := t.Extension.Track; ext != nil {
+		for _, artist := range ext.AdditionalMetadata.Artists {
+			track.Artists = append(track.Artists, artist.ArtistCreditName)
+		}
+		for _, id := range ext.ArtistIdentifiers {
+			track.ArtistIDs = append(track.ArtistIDs, mbidFromURL(id))
+		}
+		track.AlbumID = mbidFromURL(ext.ReleaseIdentifier)
+	}
+	// Tracks without the artist list only have the creator
+	if track.Artists == nil && t.Creator != "" {
+		track.Artists = []string{t.Creator}
+	}
+	return track, true
+}
+
+// mbidFromURL returns the MBID at the end of a ListenBrainz or MusicBrainz URL
+func mbidFromURL(u string) string {
+	if u == "" {
+		return ""
+	}
+	return path.Base(strings.TrimSuffix(u, "/"))
+}
+
+// annotationText turns an HTML playlist annotation into plain text
+func annotationText(annotation string) string {
+	text := listenbrainzBlockRe.ReplaceAllString(annotation, " ")
+	text = listenbrainzTagRe.ReplaceAllString(text, "")
+	return strings.Join(strings.Fields(html.UnescapeString(text)), " ")
+}
+
+// listenbrainzError decodes the error body of a ListenBrainz response
+func listenbrainzError(status int, body []byte) error {
+	var resp struct {
+		Error string `json:"error"`
+	}
+	if json.Unmarshal(body, &resp) != nil || resp.Error == "" {
+		return nil
+	}
+	return fmt.Errorf("%s (HTTP %d)", resp.Error, status)
+}
@@ -0,0 +1,170 @@
+package adapters_test
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+
+	"soundporter/internal/adapters"
+	"soundporter/internal/playlist"
+	"soundporter/internal/standin"
+)
+
+// startListenBrainz starts a ListenBrainz stand-in for the conformance suite
+func startListenBrainz(t *testing.T, catalog []playlist.Track, playlists []playlist.Playlist) (*standin.ListenBrainz, standinRun) {
+	t.Helper()
+	l := standin.NewListenBrainz(catalog, playlists)
+	t.Cleanup(l.Close)
+	withToken := func(token string) adapters.ApiAdapter {
+		a, err := adapters.NewListenBrainzAdapter(token, l.Options()...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return a
+	}
+	return l, standinRun{
+		adapter: withToken(standin.ListenBrainzToken),
+		// User tokens don't expire, and ListenBrainz answers a revoked one with a successful status
+		refused: map[string]adapters.ApiAdapter{"a revoked token": withToken("revoked")},
+		stored:  l,
+	}
+}
+
+// musicbrainzID returns the MBID the stand-ins give a catalog track
+func musicbrainzID(t playlist.Track) string {
+	return standin.MusicBrainzID(t.ID)
+}
+
+func TestListenBrainzStandinMissingToken(t *testing.T) {
+	l, _ := startListenBrainz(t, nil, nil)
+	if _, err := adapters.NewListenBrainzAdapter("", l.Options()...); err == nil {
+		t.Fatal("expected a missing token to fail")
+	}
+}
+
+func TestListenBrainzStandinTracks(t *testing.T) {
+	ctx := context.Background()
+	_, run := startListenBrainz(t, nil, nil)
+	a := authenticate(t, run.adapter)
+	catalog := adapters.FakeCatalog()
+
+	got, err := a.GetUserPlaylists(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if name := got[len(got)-2].Name; !strings.HasPrefix(name, "Weekly Jams for "+standin.ListenBrainzUser) {
+		t.Fatalf("generated playlist named %q", name)
+	}
+
+	tracks, err := a.GetPlaylistItems(ctx, "fake-playlist-3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, i := range []int{3, 9} {
+		want, got := catalog[i], tracks[i]
+		if got.Name != want.Name || got.Album != want.Album || got.DurationMs != want.DurationMs ||
+			got.MBID != got.ID || got.AlbumID != standin.MusicBrainzID(want.AlbumID) {
+			t.Errorf("track %d converted to %+v, want %+v", i, got, want)
+		}
+		if !slices.Equal(got.Artists, want.Artists) || len(got.ArtistIDs) != 1 || got.ArtistIDs[0] != standin.MusicBrainzID(want.ArtistIDs[0]) {
+			t.Errorf("track %d has artists %v %v, want %v %v", i, got.Artists, got.ArtistIDs, want.Artists, want.ArtistIDs)
+		}
+	}
+	// The credited artists are listed, so the creator's join phrases aren't needed to tell them apart
+	if got := tracks[12]; !slices.Equal(got.Artists, []string{"Data Race", "Fencepost"}) || len(got.ArtistIDs) != 2 {
+		t.Fatalf("credited artists converted to %q %v", got.Artists, got.ArtistIDs)
+	}
+}
+
+func TestListenBrainzStandinPlainArtists(t *testing.T) {
+	ctx := context.Background()
+	catalog := adapters.FakeCatalog()
+	l, run := startListenBrainz(t, catalog, []playlist.Playlist{{ID: "credits", Name: "Credits", Tracks: catalog[11:13]}})
+	a := authenticate(t, run.adapter)
+	l.Plain = true
+
+	// Without the artist list the creator is read as one artist
+	tracks, err := a.GetPlaylistItems(ctx, "credits")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tracks) != 2 || !slices.Equal(tracks[0].Artists, catalog[11].Artists) ||
+		!slices.Equal(tracks[1].Artists, []string{"Data Race & Fencepost"}) || len(tracks[1].ArtistIDs) != 2 {
+		t.Fatalf("creators converted to %+v", tracks)
+	}
+
+	found, err := a.SearchTracksBy(ctx, adapters.SearchQuery{Track: "Off By One", Artist: "Fencepost"}, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(found) != 1 || !slices.Equal(found[0].Artists, []string{"Data Race & Fencepost"}) {
+		t.Fatalf("lookup credit converted to %+v", found)
+	}
+}
+
+func TestListenBrainzStandinLookup(t *testing.T) {
+	ctx := context.Background()
+	_, run := startListenBrainz(t, nil, nil)
+	a := authenticate(t, run.adapter)
+
+	// The lookup returns the single best match, whatever the limit
+	tracks, err := a.SearchTracksBy(ctx, adapters.SearchQuery{Track: "Green Build", Artist: "Continuous Integration"}, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(trackIDs(tracks), []string{standin.MusicBrainzID("fake-08")}) {
+		t.Fatalf("search found %v", trackIDs(tracks))
+	}
+
+	tracks, err = a.SearchTracks(ctx, `Green Build artist:"Continuous Integration" album:"Pipeline (Remixes)"`, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(trackIDs(tracks), []string{standin.MusicBrainzID("fake-10")}) {
+		t.Fatalf("album search found %v", trackIDs(tracks))
+	}
+
+	tracks, err = a.SearchTracksBy(ctx, adapters.SearchQuery{Track: "Off By One", Artist: "Fencepost"}, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tracks) != 1 || !slices.Equal(tracks[0].Artists, []string{"Data Race", "Fencepost"}) || tracks[0].ReleaseDate != "2025" {
+		t.Fatalf("lookup converted to %+v", tracks)
+	}
+
+	// The lookup needs an artist as well as a title
+	if tracks, err := a.SearchTracksBy(ctx, adapters.SearchQuery{Track: "Golden Master"}, 5); err != nil || tracks != nil {
+		t.Fatalf("title-only query returned %v, %v", tracks, err)
+	}
+}
+
+func TestListenBrainzStandinPrivatePlaylists(t *testing.T) {
+	ctx := context.Background()
+	l, run := startListenBrainz(t, nil, nil)
+	a := authenticate(t, run.adapter)
+
+	created, err := a.CreateNewPlaylist(ctx, "Copy", "", adapters.PrivacyPrivate)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The private playlist is listed to its owner
+	listed, err := a.GetUserPlaylists(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Contains(playlistIDs(listed), created.ID) {
+		t.Fatalf("created playlist not listed: %v", playlistIDs(listed))
+	}
+
+	ids := []string{standin.MusicBrainzID("fake-01")}
+	if err := a.AddItemsToPlaylist(ctx, created.ID, slices.Repeat(ids, a.Capabilities().MaxBatchSize+1)); err == nil {
+		t.Fatal("expected a batch over the limit to fail")
+	}
+	if err := a.AddItemsToPlaylist(ctx, "lb-generated-1", ids); err == nil {
+		t.Fatal("expected adding to a generated playlist to fail")
+	}
+	if got, _ := l.Playlist(created.ID); len(got.Tracks) != 0 {
+		t.Fatalf("failed batches added tracks: %d", len(got.Tracks))
+	}
+}
@@ -0,0 +1,555 @@
+package adapters
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"iter"
+	"net/url"
+	"os"
+	"path/filepath"
+	"soundporter/internal/audiotag"
+	"soundporter/internal/playlist"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// localIndexVersion is bumped when the index format or the way tags are read changes, so
+	// old indexes are rebuilt
+	localIndexVersion = 1
+	// localPlaylistExt is the extension of the playlists we write
+	localPlaylistExt = ".m3u8"
+)
+
+// localAudioExts are the extensions of the files we index
+var localAudioExts = map[string]bool{
+	".mp3":  true,
+	".flac": true,
+	".ogg":  true,
+	".oga":  true,
+	".opus": true,
+	".m4a":  true,
+	".mp4":  true,
+}
+
+var localCapabilities = Capabilities{
+	MaxBatchSize:   1000,
+	MaxSearchLimit: 50,
+	SearchFields:   []SearchField{SearchFieldISRC, SearchFieldArtist, SearchFieldTrack, SearchFieldAlbum},
+	PrivacyLevels:  []Privacy{PrivacyPrivate},
+}
+
+func init() {
+	Register(Registration{
+		Name:        LocalPlatform,
+		DisplayName: "Local music files",
+		Credentials: []CredentialSpec{
+			{Key: "music_dir", EnvVar: "LOCAL_MUSIC_DIR", Description: "Directory of your audio files"},
+			{Key: "playlist_dir", EnvVar: "LOCAL_PLAYLIST_DIR", Description: "Directory of .m3u8 playlists, by default Playlists in the music directory", Optional: true},
+		},
+		Capabilities: localCapabilities,
+		New: func(creds Credentials, opts ...Option) (ApiAdapter, error) {
+			return NewLocalAdapter(creds["music_dir"], creds["playlist_dir"])
+		},
+	})
+}
+
+// LocalAdapter treats a directory of audio files as a music platform. Its catalog is an index of
+// the files' tags, track IDs are paths relative to the music directory, and playlists are .m3u8
+// files whose IDs are their file names.
+type LocalAdapter struct {
+	BaseAdapter
+	musicDir    string
+	playlistDir string
+	indexPath   string
+
+	mu sync.Mutex
+	// tracks are the indexed files in path order, and byID finds them by track ID
+	tracks []playlist.Track
+	byID   map[string]playlist.Track
+}
+
+// localIndex is the index of a music directory as stored between runs
+type localIndex struct {
+	Version int                        `json:"version"`
+	Files   map[string]localIndexEntry `json:"files"`
+}
+
+// localIndexEntry is the track read from a file, valid while the file's size and modification
+// time are unchanged
+type localIndexEntry struct {
+	Size    int64          `json:"size"`
+	ModTime time.Time      `json:"mod_time"`
+	Track   playlist.Track `json:"track"`
+}
+
+// NewLocalAdapter creates an adapter for the audio files in musicDir. Playlists are kept in
+// playlistDir, or in the Playlists directory of musicDir if it's empty.
+func NewLocalAdapter(musicDir, playlistDir string) (*LocalAdapter, error) {
+	if musicDir == "" {
+		return nil, fmt.Errorf("local music directory must be provided")
+	}
+	musicDir, err := filepath.Abs(musicDir)
+	if err != nil {
+		return nil, fmt.Errorf("invalid local music directory: %v", err)
+	}
+	if playlistDir == "" {
+		playlistDir = filepath.Join(musicDir, "Playlists")
+	}
+	if playlistDir, err = filepath.Abs(playlistDir); err != nil {
+		return nil, fmt.Errorf("invalid local playlist directory: %v", err)
+	}
+
+	return &LocalAdapter{
+		BaseAdapter: NewBaseAdapter("Local"),
+		musicDir:    musicDir,
+		playlistDir: playlistDir,
+		indexPath:   localIndexPath(musicDir),
+	}, nil
+}
+
+// localIndexPath returns where the index of musicDir is stored in the user's cache directory
+func localIndexPath(musicDir string) string {
+	sum := sha256.Sum256([]byte(musicDir))
+	name := "local-index-" + hex.EncodeToString(sum[:8]) + ".json"
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return filepath.Join(os.TempDir(), "soundporter-"+name)
+	}
+	return filepath.Join(dir, "soundporter", name)
+}
+
+// Authenticate checks the directories and indexes the music directory. Files whose size and
+// modification time haven't changed since the last run aren't read again.
+func (a *LocalAdapter) Authenticate(ctx context.Context) error {
+	if info, err := os.Stat(a.musicDir); err != nil || !info.IsDir() {
+		return fmt.Errorf("music directory %s is not a directory", a.musicDir)
+	}
+	if err := os.MkdirAll(a.playlistDir, 0o755); err != nil {
+		return fmt.Errorf("error creating playlist directory: %v", err)
+	}
+
+	indexed, read, failed, err := a.index(ctx)
+	if err != nil {
+		return fmt.Errorf("error indexing %s: %v", a.musicDir, err)
+	}
+	fmt.Printf("Indexed %d audio files in %s (%d read, %d unreadable)\n", indexed, a.musicDir, read, failed)
+	a.SetAuthenticated(true)
+	return nil
+}
+
+// index walks the music directory, reading the tags of new and changed files, and saves the
+// index. It returns the number of indexed files, of files read and of unreadable files.
+func (a *LocalAdapter) index(ctx context.Context) (indexed, read, failed int, err error) {
+	old := localIndex{Files: map[string]localIndexEntry{}}
+	if data, err := os.ReadFile(a.indexPath); err == nil {
+		if json.Unmarshal(data, &old) != nil || old.Version != localIndexVersion {
+			old.Files = map[string]localIndexEntry{}
+		}
+	}
+
+	index := localIndex{Version: localIndexVersion, Files: map[string]localIndexEntry{}}
+	var tracks []playlist.Track
+	err = filepath.WalkDir(a.musicDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if path != a.musicDir && strings.HasPrefix(d.Name(), ".") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !localAudioExts[strings.ToLower(filepath.Ext(path))] {
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		id := a.trackID(path)
+		entry, ok := old.Files[id]
+		if !ok || entry.Size != info.Size() || !entry.ModTime.Equal(info.ModTime()) {
+			tags, err := audiotag.ReadFile(path)
+			if err != nil {
+				failed++
+				return nil
+			}
+			read++
+			entry = localIndexEntry{Size: info.Size(), ModTime: info.ModTime(), Track: a.tagsToTrack(id, path, tags)}
+		}
+		index.Files[id] = entry
+		tracks = append(tracks, entry.Track)
+		return nil
+	})
+	if err != nil {
+		return 0, 0, 0, err
+	}
+
+	byID := make(map[string]playlist.Track, len(tracks))
+	for _, t := range tracks {
+		byID[t.ID] = t
+	}
+	a.mu.Lock()
+	a.tracks, a.byID = tracks, byID
+	a.mu.Unlock()
+
+	data, err := json.Marshal(index)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	if err := os.MkdirAll(filepath.Dir(a.indexPath), 0o755); err != nil {
+		return 0, 0, 0, fmt.Errorf("error creating index directory: %v", err)
+	}
+	if err := os.WriteFile(a.indexPath, data, 0o644); err != nil {
+		return 0, 0, 0, fmt.Errorf("error writing index: %v", err)
+	}
+	return len(tracks), read, failed, nil
+}
+
+// trackID returns the ID of the file at path: its slash separated path relative to the music
+// directory, or the absolute path for files outside of it
+func (a *LocalAdapter) trackID(path string) string {
+	if rel, err := filepath.Rel(a.musicDir, path); err == nil && !strings.HasPrefix(rel, "..") {
+		return filepath.ToSlash(rel)
+	}
+	return filepath.ToSlash(path)
+}
+
+// trackPath returns the absolute path of a track ID
+func (a *LocalAdapter) trackPath(id string) string {
+	path := filepath.FromSlash(id)
+	if filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(a.musicDir, path)
+}
+
+// tagsToTrack converts the tags of the file at path into our track model. Untagged files are
+// named after the file, split like "Artist - Title".
+func (a *LocalAdapter) tagsToTrack(id, path string, tags audiotag.Tags) playlist.Track {
+	track := playlist.Track{
+		Name:        tags.Title,
+		Artists:     tags.Artists,
+		Album:       tags.Album,
+		ID:          id,
+		URL:         (&url.URL{Scheme: "file", Path: filepath.ToSlash(path)}).String(),
+		ISRC:        strings.ToUpper(strings.ReplaceAll(tags.ISRC, "-", "")),
+		DurationMs:  int(tags.Duration.Milliseconds()),
+		Explicit:    tags.Explicit,
+		DiscNumber:  tags.DiscNumber,
+		TrackNumber: tags.TrackNumber,
+		ReleaseDate: tags.Date,
+	}
+	if len(track.Artists) == 0 && tags.AlbumArtist != "" {
+		track.Artists = []string{tags.AlbumArtist}
+	}
+	if track.Name == "" {
+		parsed := ParseVideoTitle(strings.TrimSuffix(filepath.Base(path), filepath.Ext(path)), "")
+		track.Name, track.Version = parsed.Title, parsed.Version
+		if len(track.Artists) == 0 {
+			track.Artists = parsed.Artists
+		}
+	}
+	return track
+}
+
+// GetUserPlaylists retrieves all playlists in the playlist directory
+func (a *LocalAdapter) GetUserPlaylists(ctx context.Context) ([]playlist.Playlist, error) {
+	return collect(a.UserPlaylists(ctx))
+}
+
+// UserPlaylists streams the .m3u8 and .m3u files in the playlist directory, sorted by name
+func (a *LocalAdapter) UserPlaylists(ctx context.Context) iter.Seq2[playlist.Playlist, error] {
+	return func(yield func(playlist.Playlist, error) bool) {
+		if err := a.CheckAuth(); err != nil {
+			yield(playlist.Playlist{}, err)
+			return
+		}
+
+		entries, err := os.ReadDir(a.playlistDir)
+		if err != nil {
+			yield(playlist.Playlist{}, fmt.Errorf("error getting playlists: %v", err))
+			return
+		}
+		for _, e := range entries {
+			ext := strings.ToLower(filepath.Ext(e.Name()))
+			if e.IsDir() || (ext != localPlaylistExt && ext != ".m3u") {
+				continue
+			}
+			m, err := a.readPlaylist(e.Name())
+			if err != nil {
+				yield(playlist.Playlist{}, fmt.Errorf("error getting playlists: %v", err))
+				return
+			}
+			if !yield(m, nil) {
+				return
+			}
+		}
+	}
+}
+
+// GetPlaylistItems retrieves all tracks in a playlist
+func (a *LocalAdapter) GetPlaylistItems(ctx context.Context, playlistID string) ([]playlist.Track, error) {
+	return collect(a.PlaylistItems(ctx, playlistID))
+}
+
+// PlaylistItems streams the tracks of a playlist. Entries for files that aren't in the index,
+// such as deleted files, are described by their #EXTINF line.
+func (a *LocalAdapter) PlaylistItems(ctx context.Context, playlistID string) iter.Seq2[playlist.Track, error] {
+	return func(yield func(playlist.Track, error) bool) {
+		if err := a.CheckAuth(); err != nil {
+			yield(playlist.Track{}, err)
+			return
+		}
+
+		m, err := a.readPlaylist(playlistID)
+		if err != nil {
+			yield(playlist.Track{}, fmt.Errorf("error getting playlist items: %v", err))
+			return
+		}
+		for _, t := range m.Tracks {
+			if !yield(t, nil) {
+				return
+			}
+		}
+	}
+}
+
+// readPlaylist reads a playlist file. The name and description come from #PLAYLIST and
+// #DESCRIPTION lines, and the name falls back to the file name.
+func (a *LocalAdapter) readPlaylist(playlistID string) (playlist.Playlist, error) {
+	path, err := a.playlistPath(playlistID)
+	if err != nil {
+		return playlist.Playlist{}, err
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return playlist.Playlist{}, err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return playlist.Playlist{}, err
+	}
+
+	m := playlist.Playlist{
+		ID:        playlistID,
+		Name:      strings.TrimSuffix(playlistID, filepath.Ext(playlistID)),
+		CreatedAt: info.ModTime(),
+	}
+	var extinf string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(strings.TrimPrefix(scanner.Text(), "\ufeff"))
+		switch {
+		case line == "":
+		case strings.HasPrefix(line, "#PLAYLIST:"):
+			m.Name = strings.TrimSpace(strings.TrimPrefix(line, "#PLAYLIST:"))
+		case strings.HasPrefix(line, "#DESCRIPTION:"):
+			m.Description = strings.TrimSpace(strings.TrimPrefix(line, "#DESCRIPTION:"))
+		case strings.HasPrefix(line, "#EXTINF:"):
+			extinf = strings.TrimPrefix(line, "#EXTINF:")
+		case strings.HasPrefix(line, "#"):
+		default:
+			m.Tracks = append(m.Tracks, a.entryToTrack(line, extinf))
+			extinf = ""
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return playlist.Playlist{}, err
+	}
+	m.TrackCount = len(m.Tracks)
+	return m, nil
+}
+
+// entryToTrack resolves a playlist entry, a path relative to the playlist directory or a file
+// URL, to an indexed track
+func (a *LocalAdapter) entryToTrack(entry, extinf string) playlist.Track {
+	path := entry
+	if u, err := url.Parse(entry); err == nil && u.Scheme == "file" {
+		path = u.Path
+	}
+	path = filepath.FromSlash(path)
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(a.playlistDir, path)
+	}
+	id := a.trackID(filepath.Clean(path))
+
+	a.mu.Lock()
+	t, ok := a.byID[id]
+	a.mu.Unlock()
+	if ok {
+		return t
+	}
+
+	// #EXTINF:<seconds>,<Artist> - <Title>
+	t = playlist.Track{ID: id, URL: (&url.URL{Scheme: "file", Path: filepath.ToSlash(path)}).String()}
+	seconds, title, _ := strings.Cut(extinf, ",")
+	if s, err := strconv.Atoi(strings.TrimSpace(seconds)); err == nil && s > 0 {
+		t.DurationMs = s * 1000
+	}
+	if title = strings.TrimSpace(title); title == "" {
+		title = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	}
+	parsed := ParseVideoTitle(title, "")
+	t.Name, t.Artists, t.Version = parsed.Title, parsed.Artists, parsed.Version
+	return t
+}
+
+// playlistPath returns the path of a playlist file, refusing IDs that leave the playlist directory
+func (a *LocalAdapter) playlistPath(playlistID string) (string, error) {
+	if playlistID == "" || playlistID != filepath.Base(playlistID) {
+		return "", fmt.Errorf("invalid playlist ID %q", playlistID)
+	}
+	return filepath.Join(a.playlistDir, playlistID), nil
+}
+
+// CreateNewPlaylist writes an empty playlist file named after the playlist. A number is added
+// to the file name if it's taken.
+func (a *LocalAdapter) CreateNewPlaylist(ctx context.Context, name string, description string, privacy Privacy) (playlist.Playlist, error) {
+	if err := a.CheckAuth(); err != nil {
+		return playlist.Playlist{}, err
+	}
+
+	content := "#EXTM3U\n#PLAYLIST:" + oneLine(name) + "\n"
+	if description != "" {
+		content += "#DESCRIPTION:" + oneLine(description) + "\n"
+	}
+
+	base := localFileName(name)
+	for n := 1; ; n++ {
+		id := base + localPlaylistExt
+		if n > 1 {
+			id = fmt.Sprintf("%s (%d)%s", base, n, localPlaylistExt)
+		}
+		f, err := os.OpenFile(filepath.Join(a.playlistDir, id), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+		if errors.Is(err, fs.ErrExist) {
+			continue
+		}
+		if err != nil {
+			return playlist.Playlist{}, fmt.Errorf("error creating playlist: %v", err)
+		}
+		_, err = f.WriteString(content)
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return playlist.Playlist{}, fmt.Errorf("error creating playlist: %v", err)
+		}
+		return playlist.Playlist{
+			ID:          id,
+			Name:        name,
+			Description: description,
+			CreatedAt:   time.Now(),
+		}, nil
+	}
+}
+
+// AddItemsToPlaylist appends tracks to a playlist file, as paths relative to the playlist
+// directory so the library can be moved or shared as a whole
+func (a *LocalAdapter) AddItemsToPlaylist(ctx context.Context, playlistID string, trackIDs []string) error {
+	if err := a.CheckAuth(); err != nil {
+		return err
+	}
+	path, err := a.playlistPath(playlistID)
+	if err != nil {
+		return err
+	}
+
+	var b strings.Builder
+	a.mu.Lock()
+	for _, id := range trackIDs {
+		t, ok := a.byID[id]
+		if !ok {
+			a.mu.Unlock()
+			return fmt.Errorf("error adding tracks to playlist: track %s is not in the library", id)
+		}
+		entry := a.trackPath(id)
+		if rel, err := filepath.Rel(a.playlistDir, entry); err == nil {
+			entry = rel
+		}
+		fmt.Fprintf(&b, "#EXTINF:%d,%s - %s\n%s\n", t.DurationMs/1000,
+			oneLine(strings.Join(t.Artists, ", ")), oneLine(t.Name), filepath.ToSlash(entry))
+	}
+	a.mu.Unlock()
+
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		return fmt.Errorf("error adding tracks to playlist: %v", err)
+	}
+	_, err = f.WriteString(b.String())
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("error adding tracks to playlist: %v", err)
+	}
+	return nil
+}
+
+// SearchTracks searches the index for tracks whose name, artists or album contain every word
+// of the query. Field filters such as isrc: and artist: are understood.
+func (a *LocalAdapter) SearchTracks(ctx context.Context, query string, limit int) ([]playlist.Track, error) {
+	return a.SearchTracksBy(ctx, ParseSearchQuery(query), limit)
+}
+
+// SearchTracksBy searches the index by structured fields
+func (a *LocalAdapter) SearchTracksBy(ctx context.Context, query SearchQuery, limit int) ([]playlist.Track, error) {
+	if err := a.CheckAuth(); err != nil {
+		return nil, err
+	}
+	if limit <= 0 || limit > localCapabilities.MaxSearchLimit {
+		limit = localCapabilities.MaxSearchLimit
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	var tracks []playlist.Track
+	for _, t := range a.tracks {
+		if MatchesSearchQuery(t, query) {
+			tracks = append(tracks, t)
+			if len(tracks) == limit {
+				break
+			}
+		}
+	}
+	return tracks, nil
+}
+
+// Capabilities describes the local library
+func (a *LocalAdapter) Capabilities() Capabilities {
+	return localCapabilities
+}
+
+// localFileName turns a playlist name into a file name that's valid on every platform
+func localFileName(name string) string {
+	name = strings.Map(func(r rune) rune {
+		if r < 0x20 || strings.ContainsRune(`<>:"/\|?*`, r) {
+			return '_'
+		}
+		return r
+	}, name)
+	name = strings.Trim(name, " .")
+	if name == "" {
+		name = "Playlist"
+	}
+	return name
+}
+
+// oneLine replaces line breaks, which would end a playlist line early
+func oneLine(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
@@ -0,0 +1,202 @@
+package adapters_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"soundporter/internal/adapters"
+	"soundporter/internal/playlist"
+)
+
+// id3File returns an MP3 file holding only an ID3v2.4 tag with a title and an artist
+func id3File(title, artist string) []byte {
+	var frames []byte
+	for _, f := range [][2]string{{"TIT2", title}, {"TPE1", artist}} {
+		frames = append(frames, f[0]...)
+		frames = append(frames, 0, 0, 0, byte(len(f[1])+1), 0, 0, 3)
+		frames = append(frames, f[1]...)
+	}
+	return append([]byte{'I', 'D', '3', 4, 0, 0, 0, 0, 0, byte(len(frames))}, frames...)
+}
+
+// newLocalLibrary returns an authenticated adapter for a music directory holding two tagged
+// files, with its index kept in a temporary cache directory
+func newLocalLibrary(t *testing.T) (*adapters.LocalAdapter, string) {
+	t.Helper()
+	cache := t.TempDir()
+	t.Setenv("XDG_CACHE_HOME", cache)
+	t.Setenv("HOME", cache)
+
+	musicDir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(musicDir, "Artist"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for name, data := range map[string][]byte{
+		"Song.mp3":       id3File("Tagged Song", "Tagged Artist"),
+		"Other Song.mp3": id3File("Other Song", "Tagged Artist"),
+	} {
+		if err := os.WriteFile(filepath.Join(musicDir, "Artist", name), data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	a, err := adapters.NewLocalAdapter(musicDir, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := a.Authenticate(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	return a, musicDir
+}
+
+// writePlaylist writes a playlist file to the default playlist directory of musicDir
+func writePlaylist(t *testing.T, musicDir, name, content string) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(musicDir, "Playlists", name), []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLocalPlaylistEntries(t *testing.T) {
+	tests := []struct {
+		file, content     string
+		name, description string
+		want              []playlist.Track
+	}{
+		{
+			file: "extended.m3u8",
+			content: "\ufeff#EXTM3U\r\n#PLAYLIST:Road Trip\r\n#DESCRIPTION:Songs for the road\r\n" +
+				"#EXTINF:215,Wrong - Ignored\r\n../Artist/Song.mp3\r\n" +
+				"file://{music}/Artist/Other%20Song.mp3\r\n" +
+				"#EXTINF:261,Ghost - Gone (Live)\r\nGone.flac\r\n",
+			name:        "Road Trip",
+			description: "Songs for the road",
+			want: []playlist.Track{
+				{ID: "Artist/Song.mp3", Name: "Tagged Song", Artists: []string{"Tagged Artist"}},
+				{ID: "Artist/Other Song.mp3", Name: "Other Song", Artists: []string{"Tagged Artist"}},
+				{ID: "Playlists/Gone.flac", Name: "Gone", Artists: []string{"Ghost"}, Version: "Live", DurationMs: 261000},
+			},
+		},
+		{
+			file:    "plain.m3u",
+			content: "../Artist/Song.mp3\n",
+			name:    "plain",
+			want:    []playlist.Track{{ID: "Artist/Song.mp3", Name: "Tagged Song", Artists: []string{"Tagged Artist"}}},
+		},
+		{
+			file: "empty.m3u8",
+			name: "empty",
+		},
+		{
+			file: "malformed.m3u8",
+			content: "#EXTM3U\n#EXTINF:abc\nMystery.mp3\n" +
+				"#EXTINF:-5,Nobody - Nothing\nnothing.ogg\n" +
+				"#EXTINF:\n#EXTINF:12,Lone\n%zz.mp3\n" +
+				"#EXT-X-UNKNOWN:directive\n   \n" +
+				"../../outside.mp3\n",
+			name: "malformed",
+			want: []playlist.Track{
+				{ID: "Playlists/Mystery.mp3", Name: "Mystery"},
+				{ID: "Playlists/nothing.ogg", Name: "Nothing", Artists: []string{"Nobody"}},
+				{ID: "Playlists/%zz.mp3", Name: "Lone", DurationMs: 12000},
+				{ID: "{parent}/outside.mp3", Name: "outside"},
+			},
+		},
+	}
+
+	ctx := context.Background()
+	a, musicDir := newLocalLibrary(t)
+	expand := strings.NewReplacer("{music}", filepath.ToSlash(musicDir), "{parent}", filepath.ToSlash(filepath.Dir(musicDir)))
+	for _, tt := range tests {
+		writePlaylist(t, musicDir, tt.file, expand.Replace(tt.content))
+	}
+
+	playlists, err := a.GetUserPlaylists(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := playlistIDs(playlists); !slices.Equal(got, []string{"empty.m3u8", "extended.m3u8", "malformed.m3u8", "plain.m3u"}) {
+		t.Fatalf("unexpected playlists %v", got)
+	}
+
+	for _, tt := range tests {
+		i := slices.IndexFunc(playlists, func(p playlist.Playlist) bool { return p.ID == tt.file })
+		if p := playlists[i]; p.Name != tt.name || p.Description != tt.description || p.TrackCount != len(tt.want) {
+			t.Errorf("%s: read as %q %q with %d tracks", tt.file, p.Name, p.Description, p.TrackCount)
+		}
+
+		tracks, err := a.GetPlaylistItems(ctx, tt.file)
+		if err != nil {
+			t.Errorf("%s: %v", tt.file, err)
+			continue
+		}
+		if len(tracks) != len(tt.want) {
+			t.Errorf("%s: expected %d tracks, got %v", tt.file, len(tt.want), trackIDs(tracks))
+			continue
+		}
+		for j, want := range tt.want {
+			want.ID = expand.Replace(want.ID)
+			got := tracks[j]
+			if got.ID != want.ID || got.Name != want.Name || !slices.Equal(got.Artists, want.Artists) ||
+				got.Version != want.Version || got.DurationMs != want.DurationMs {
+				t.Errorf("%s: entry %d read as %+v, want %+v", tt.file, j, got, want)
+			}
+		}
+	}
+}
+
+func TestLocalPlaylistErrors(t *testing.T) {
+	ctx := context.Background()
+	a, musicDir := newLocalLibrary(t)
+
+	// A line longer than the scanner's buffer fails the read instead of cutting the entry short
+	writePlaylist(t, musicDir, "long.m3u8", "#EXTM3U\n"+strings.Repeat("a", 70000)+".mp3\n")
+	if _, err := a.GetPlaylistItems(ctx, "long.m3u8"); err == nil {
+		t.Fatal("expected an overlong line to fail")
+	}
+
+	for _, id := range []string{"", "missing.m3u8", "../escape.m3u8", "nested/list.m3u8"} {
+		if _, err := a.GetPlaylistItems(ctx, id); err == nil {
+			t.Errorf("expected playlist ID %q to fail", id)
+		}
+	}
+}
+
+func TestLocalPlaylistRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	a, _ := newLocalLibrary(t)
+
+	p, err := a.CreateNewPlaylist(ctx, "Road Trip: Part 2", "Line one\nline two", adapters.PrivacyPrivate)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.ID != "Road Trip_ Part 2.m3u8" {
+		t.Fatalf("playlist written to %s", p.ID)
+	}
+	if err := a.AddItemsToPlaylist(ctx, p.ID, []string{"Artist/Other Song.mp3", "Artist/Song.mp3"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.AddItemsToPlaylist(ctx, p.ID, []string{"Artist/Missing.mp3"}); err == nil {
+		t.Fatal("expected a track outside the library to fail")
+	}
+
+	playlists, err := a.GetUserPlaylists(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(playlists) != 1 || playlists[0].Name != "Road Trip: Part 2" || playlists[0].Description != "Line one line two" {
+		t.Fatalf("playlist read back as %+v", playlists)
+	}
+	tracks, err := a.GetPlaylistItems(ctx, p.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(trackIDs(tracks), []string{"Artist/Other Song.mp3", "Artist/Song.mp3"}) {
+		t.Fatalf("unexpected tracks %v", trackIDs(tracks))
+	}
+}
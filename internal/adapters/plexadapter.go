@@ -0,0 +1,424 @@
+package adapters
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"iter"
+	"net/http"
+	"net/url"
+	"os"
+	"soundporter/internal/playlist"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	plexProduct = "Soundporter"
+	// plexPageSize is the number of items requested per page
+	plexPageSize = 200
+	// plexTrackType is the metadata type of tracks in library queries
+	plexTrackType = "10"
+	// plexMusicSection is the type of music library sections, which are organized by artist
+	plexMusicSection = "artist"
+)
+
+var plexCapabilities = Capabilities{
+	// Items are added by listing their rating keys in a URI, so keep the URL short
+	MaxBatchSize:   100,
+	MaxSearchLimit: 100,
+	SearchFields:   []SearchField{SearchFieldTrack, SearchFieldArtist, SearchFieldAlbum},
+	PrivacyLevels:  []Privacy{PrivacyPrivate},
+}
+
+func init() {
+	Register(Registration{
+		Name:        PlexPlatform,
+		DisplayName: "Plex",
+		Credentials: []CredentialSpec{
+			{Key: "url", EnvVar: "PLEX_URL", Description: "Plex Media Server URL, e.g. http://localhost:32400"},
+			{Key: "token", EnvVar: "PLEX_TOKEN", Description: "X-Plex-Token of your account or server"},
+		},
+		Capabilities: plexCapabilities,
+		New: func(creds Credentials, opts ...Option) (ApiAdapter, error) {
+			return NewPlexAdapter(creds["url"], creds["token"], opts...)
+		},
+	})
+}
+
+// PlexAdapter adapts the API of a Plex Media Server to our common adapter interface. Tracks and
+// playlists are identified by their rating keys.
+type PlexAdapter struct {
+	BaseAdapter
+	token    string
+	clientID string
+	// machineID identifies the server in the URIs of items added to playlists
+	machineID string
+	// sections are the keys of the server's music libraries
+	sections []string
+	api      *httpAPI
+}
+
+// NewPlexAdapter creates an adapter for the Plex Media Server at serverURL
+func NewPlexAdapter(serverURL, token string, opts ...Option) (*PlexAdapter, error) {
+	if serverURL == "" || token == "" {
+		return nil, fmt.Errorf("plex server URL and token must be provided")
+	}
+	if _, err := url.ParseRequestURI(serverURL); err != nil {
+		return nil, fmt.Errorf("invalid plex server URL %q: %v", serverURL, err)
+	}
+
+	// Plex lists authorized devices by client identifier, so keep it stable between runs
+	host, _ := os.Hostname()
+	a := &PlexAdapter{
+		BaseAdapter: NewBaseAdapter("Plex"),
+		token:       token,
+		clientID:    "soundporter-" + host,
+	}
+	a.api = newHTTPAPI(newOptions(options{
+		baseURL: strings.TrimSuffix(serverURL, "/") + "/",
+	}, opts))
+	a.api.authorize = a.authorize
+	a.api.decodeError = plexError
+	return a, nil
+}
+
+// Authenticate checks the token against the server and looks up its music libraries
+func (a *PlexAdapter) Authenticate(ctx context.Context) error {
+	var server struct {
+		MediaContainer struct {
+			FriendlyName      string `json:"friendlyName"`
+			MachineIdentifier string `json:"machineIdentifier"`
+		} `json:"MediaContainer"`
+	}
+	if err := a.api.get(ctx, "", nil, &server); err != nil {
+		return fmt.Errorf("authentication failed: %v", err)
+	}
+	a.machineID = server.MediaContainer.MachineIdentifier
+
+	var sections struct {
+		MediaContainer struct {
+			Directory []struct {
+				Key  string `json:"key"`
+				Type string `json:"type"`
+			} `json:"Directory"`
+		} `json:"MediaContainer"`
+	}
+	if err := a.api.get(ctx, "library/sections", nil, &sections); err != nil {
+		return fmt.Errorf("error getting library sections: %v", err)
+	}
+	a.sections = nil
+	for _, dir := range sections.MediaContainer.Directory {
+		if dir.Type == plexMusicSection {
+			a.sections = append(a.sections, dir.Key)
+		}
+	}
+	if len(a.sections) == 0 {
+		fmt.Println("Warning: the Plex server has no music libraries, so no tracks can be found")
+	}
+
+	fmt.Println("You are logged in to:", server.MediaContainer.FriendlyName)
+	a.SetAuthenticated(true)
+	return nil
+}
+
+// authorize adds the token and describes the client
+func (a *PlexAdapter) authorize(req *http.Request) error {
+	req.Header.Set("X-Plex-Token", a.token)
+	req.Header.Set("X-Plex-Client-Identifier", a.clientID)
+	req.Header.Set("X-Plex-Product", plexProduct)
+	return nil
+}
+
+type plexMetadata struct {
+	RatingKey string `json:"ratingKey"`
+	Type      string `json:"type"`
+	Title     string `json:"title"`
+	Summary   string `json:"summary"`
+	Smart     bool   `json:"smart"`
+	LeafCount int    `json:"leafCount"`
+	AddedAt   int64  `json:"addedAt"`
+	// PlaylistType is set on playlists, and is "audio" for music playlists
+	PlaylistType string `json:"playlistType"`
+	// The fields below describe tracks. Parents are albums and grandparents artists.
+	ParentTitle          string `json:"parentTitle"`
+	ParentRatingKey      string `json:"parentRatingKey"`
+	GrandparentTitle     string `json:"grandparentTitle"`
+	GrandparentRatingKey string `json:"grandparentRatingKey"`
+	// OriginalTitle is the track artist, when it differs from the album artist
+	OriginalTitle         string `json:"originalTitle"`
+	Duration              int    `json:"duration"`
+	Index                 int    `json:"index"`
+	ParentIndex           int    `json:"parentIndex"`
+	ParentYear            int    `json:"parentYear"`
+	OriginallyAvailableAt string `json:"originallyAvailableAt"`
+}
+
+// plexContainer is a page of metadata items
+type plexContainer struct {
+	MediaContainer struct {
+		Size      int            `json:"size"`
+		TotalSize int            `json:"totalSize"`
+		Metadata  []plexMetadata `json:"Metadata"`
+	} `json:"MediaContainer"`
+}
+
+// GetUserPlaylists retrieves all playlists for the authenticated user
+func (a *PlexAdapter) GetUserPlaylists(ctx context.Context) ([]playlist.Playlist, error) {
+	return collect(a.UserPlaylists(ctx))
+}
+
+// UserPlaylists streams the user's audio playlists page by page, smart playlists included
+func (a *PlexAdapter) UserPlaylists(ctx context.Context) iter.Seq2[playlist.Playlist, error] {
+	return func(yield func(playlist.Playlist, error) bool) {
+		if err := a.CheckAuth(); err != nil {
+			yield(playlist.Playlist{}, err)
+			return
+		}
+
+		for page, err := range a.pages(ctx, "playlists", url.Values{"playlistType": {"audio"}}) {
+			if err != nil {
+				yield(playlist.Playlist{}, fmt.Errorf("error getting playlists: %v", err))
+				return
+			}
+			for _, item := range page {
+				var created time.Time
+				if item.AddedAt > 0 {
+					created = time.Unix(item.AddedAt, 0)
+				}
+				if !yield(playlist.Playlist{
+					ID:          item.RatingKey,
+					Name:        item.Title,
+					Description: item.Summary,
+					TrackCount:  item.LeafCount,
+					CreatedAt:   created,
+				}, nil) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// GetPlaylistItems retrieves all tracks in a playlist
+func (a *PlexAdapter) GetPlaylistItems(ctx context.Context, playlistID string) ([]playlist.Track, error) {
+	return collect(a.PlaylistItems(ctx, playlistID))
+}
+
+// PlaylistItems streams the tracks of a playlist page by page
+func (a *PlexAdapter) PlaylistItems(ctx context.Context, playlistID string) iter.Seq2[playlist.Track, error] {
+	return func(yield func(playlist.Track, error) bool) {
+		if err := a.CheckAuth(); err != nil {
+			yield(playlist.Track{}, err)
+			return
+		}
+
+		path := "playlists/" + url.PathEscape(playlistID) + "/items"
+		for page, err := range a.pages(ctx, path, url.Values{}) {
+			if err != nil {
+				yield(playlist.Track{}, fmt.Errorf("error getting playlist items: %v", err))
+				return
+			}
+			for _, item := range page {
+				if item.Type != "" && item.Type != "track" {
+					continue
+				}
+				if !yield(a.metadataToTrack(item), nil) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// pages pages through a container with the X-Plex-Container parameters until all items have
+// been read
+func (a *PlexAdapter) pages(ctx context.Context, path string, query url.Values) iter.Seq2[[]plexMetadata, error] {
+	return func(yield func([]plexMetadata, error) bool) {
+		query.Set("X-Plex-Container-Size", strconv.Itoa(plexPageSize))
+		for start := 0; ; {
+			query.Set("X-Plex-Container-Start", strconv.Itoa(start))
+			var page plexContainer
+			if err := a.api.get(ctx, path, query, &page); err != nil {
+				yield(nil, err)
+				return
+			}
+			items := page.MediaContainer.Metadata
+			if !yield(items, nil) {
+				return
+			}
+			start += len(items)
+			if len(items) == 0 || start >= page.MediaContainer.TotalSize {
+				return
+			}
+		}
+	}
+}
+
+// CreateNewPlaylist creates an empty audio playlist and sets its description, if any
+func (a *PlexAdapter) CreateNewPlaylist(ctx context.Context, name string, description string, privacy Privacy) (playlist.Playlist, error) {
+	if err := a.CheckAuth(); err != nil {
+		return playlist.Playlist{}, err
+	}
+
+	params := url.Values{
+		"type":  {"audio"},
+		"title": {name},
+		"smart": {"0"},
+		"uri":   {a.itemsURI(nil)},
+	}
+	var created plexContainer
+	if err := a.api.do(ctx, http.MethodPost, "playlists", params, nil, &created); err != nil {
+		return playlist.Playlist{}, fmt.Errorf("error creating playlist: %v", err)
+	}
+	if len(created.MediaContainer.Metadata) == 0 {
+		return playlist.Playlist{}, fmt.Errorf("error creating playlist: the server returned no playlist")
+	}
+	p := playlist.Playlist{
+		ID:        created.MediaContainer.Metadata[0].RatingKey,
+		Name:      name,
+		CreatedAt: time.Now(),
+	}
+
+	if description != "" {
+		path := "playlists/" + url.PathEscape(p.ID)
+		if err := a.api.do(ctx, http.MethodPut, path, url.Values{"summary": {description}}, nil, nil); err != nil {
+			return playlist.Playlist{}, fmt.Errorf("error setting playlist description: %v", err)
+		}
+		p.Description = description
+	}
+	return p, nil
+}
+
+// AddItemsToPlaylist adds tracks to a playlist in one request
+func (a *PlexAdapter) AddItemsToPlaylist(ctx context.Context, playlistID string, trackIDs []string) error {
+	if err := a.CheckAuth(); err != nil {
+		return err
+	}
+
+	path := "playlists/" + url.PathEscape(playlistID) + "/items"
+	if err := a.api.do(ctx, http.MethodPut, path, url.Values{"uri": {a.itemsURI(trackIDs)}}, nil, nil); err != nil {
+		return fmt.Errorf("error adding tracks to playlist: %v", err)
+	}
+	return nil
+}
+
+// itemsURI builds the URI Plex uses to refer to library items on this server
+func (a *PlexAdapter) itemsURI(ratingKeys []string) string {
+	uri := "server://" + a.machineID + "/com.plexapp.plugins.library"
+	if len(ratingKeys) > 0 {
+		uri += "/library/metadata/" + strings.Join(ratingKeys, ",")
+	}
+	return uri
+}
+
+// SearchTracks searches the music libraries for tracks. Queries may use field filters like
+// artist:"Queen", and any other text is matched against track titles.
+func (a *PlexAdapter) SearchTracks(ctx context.Context, query string, limit int) ([]playlist.Track, error) {
+	return a.SearchTracksBy(ctx, ParseSearchQuery(query), limit)
+}
+
+// SearchTracksBy searches each music library for tracks whose title, artist and album contain
+// the query's fields, until limit tracks are found
+func (a *PlexAdapter) SearchTracksBy(ctx context.Context, query SearchQuery, limit int) ([]playlist.Track, error) {
+	if err := a.CheckAuth(); err != nil {
+		return nil, err
+	}
+	if query.Track == "" && query.Artist == "" && query.Album == "" {
+		return nil, nil
+	}
+
+	if limit <= 0 || limit > plexCapabilities.MaxSearchLimit {
+		limit = plexCapabilities.MaxSearchLimit
+	}
+
+	params := url.Values{"type": {plexTrackType}}
+	if query.Track != "" {
+		params.Set("title", query.Track)
+	}
+	if query.Artist != "" {
+		params.Set("grandparentTitle", query.Artist)
+	}
+	if query.Album != "" {
+		params.Set("parentTitle", query.Album)
+	}
+
+	var tracks []playlist.Track
+	for _, section := range a.sections {
+		params.Set("X-Plex-Container-Start", "0")
+		params.Set("X-Plex-Container-Size", strconv.Itoa(limit-len(tracks)))
+		var page plexContainer
+		if err := a.api.get(ctx, "library/sections/"+url.PathEscape(section)+"/all", params, &page); err != nil {
+			return nil, fmt.Errorf("error searching tracks: %v", err)
+		}
+		for _, item := range page.MediaContainer.Metadata {
+			tracks = append(tracks, a.metadataToTrack(item))
+		}
+		if len(tracks) >= limit {
+			break
+		}
+	}
+	return tracks, nil
+}
+
+// Capabilities describes the Plex API limits
+func (a *PlexAdapter) Capabilities() Capabilities {
+	return plexCapabilities
+}
+
+// metadataToTrack converts track metadata into our track model. Plex has no list of a track's
+// artists, only the artist tag as written.
+func (a *PlexAdapter) metadataToTrack(item plexMetadata) playlist.Track {
+	artist := item.OriginalTitle
+	if artist == "" {
+		artist = item.GrandparentTitle
+	}
+	track := playlist.Track{
+		Name:        item.Title,
+		Album:       item.ParentTitle,
+		ID:          item.RatingKey,
+		AlbumID:     item.ParentRatingKey,
+		URL:         a.itemURL(item.RatingKey),
+		DurationMs:  item.Duration,
+		DiscNumber:  item.ParentIndex,
+		TrackNumber: item.Index,
+	}
+	if artist != "" {
+		track.Artists = []string{artist}
+	}
+	// The artist key belongs to the album artist, so it only fits a track by that artist
+	if item.GrandparentRatingKey != "" && item.OriginalTitle == "" {
+		track.ArtistIDs = []string{item.GrandparentRatingKey}
+	}
+	if item.OriginallyAvailableAt != "" {
+		track.ReleaseDate = item.OriginallyAvailableAt
+	} else if item.ParentYear > 0 {
+		track.ReleaseDate = strconv.Itoa(item.ParentYear)
+	}
+	return track
+}
+
+// itemURL links to an item in the server's web app
+func (a *PlexAdapter) itemURL(ratingKey string) string {
+	return a.api.baseURL + "web/index.html#!/server/" + a.machineID + "/details?key=" +
+		url.QueryEscape("/library/metadata/"+ratingKey)
+}
+
+// plexError decodes the error body of a Plex response. Older servers answer errors with HTML,
+// which is left to the default message.
+func plexError(status int, body []byte) error {
+	var resp struct {
+		Errors []struct {
+			Code    int    `json:"code"`
+			Message string `json:"message"`
+		} `json:"errors"`
+	}
+	if json.Unmarshal(body, &resp) != nil || len(resp.Errors) == 0 || resp.Errors[0].Message == "" {
+		if status == http.StatusUnauthorized {
+			return fmt.Errorf("the Plex token was rejected (HTTP %d)", status)
+		}
+		return nil
+	}
+	return fmt.Errorf("%s (HTTP %d)", resp.Errors[0].Message, status)
+}
@@ -0,0 +1,68 @@
+package adapters_test
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"soundporter/internal/adapters"
+	"soundporter/internal/playlist"
+	"soundporter/internal/standin"
+)
+
+// startPlex starts a Plex stand-in for the conformance suite
+func startPlex(t *testing.T, catalog []playlist.Track, playlists []playlist.Playlist) (*standin.Plex, standinRun) {
+	t.Helper()
+	p := standin.NewPlex(catalog, playlists)
+	t.Cleanup(p.Close)
+	withToken := func(token string) adapters.ApiAdapter {
+		a, err := adapters.NewPlexAdapter(p.URL, token, p.Options()...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return a
+	}
+	return p, standinRun{
+		adapter: withToken(standin.PlexToken),
+		// Plex rejects tokens with an HTML page, which still gives a readable error
+		refused: map[string]adapters.ApiAdapter{"a wrong token": withToken("wrong")},
+		stored:  p,
+	}
+}
+
+func TestPlexStandinTracks(t *testing.T) {
+	_, run := startPlex(t, nil, nil)
+	a := authenticate(t, run.adapter)
+	catalog := adapters.FakeCatalog()
+
+	tracks, err := a.GetPlaylistItems(context.Background(), "fake-playlist-3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, i := range []int{3, 9} {
+		want, got := catalog[i], tracks[i]
+		if got.Name != want.Name || got.Album != want.Album || got.DurationMs != want.DurationMs || got.ReleaseDate != want.ReleaseDate[:4] {
+			t.Errorf("track %d converted to %+v, want %+v", i, got, want)
+		}
+		if !slices.Equal(got.Artists, want.Artists) || !slices.Equal(got.ArtistIDs, want.ArtistIDs) {
+			t.Errorf("track %d has artists %v %v, want %v %v", i, got.Artists, got.ArtistIDs, want.Artists, want.ArtistIDs)
+		}
+	}
+	// The track artist tag is read as written, and the album artist's key doesn't belong to it
+	if got := tracks[12]; !slices.Equal(got.Artists, []string{"Data Race, Fencepost"}) || got.ArtistIDs != nil {
+		t.Fatalf("track artist converted to %q %v", got.Artists, got.ArtistIDs)
+	}
+}
+
+func TestPlexStandinSearchFilters(t *testing.T) {
+	_, run := startPlex(t, nil, nil)
+	a := authenticate(t, run.adapter)
+
+	tracks, err := a.SearchTracks(context.Background(), `Green Build album:"Pipeline (Remixes)"`, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(trackIDs(tracks), []string{"fake-10"}) {
+		t.Fatalf("album search found %v", trackIDs(tracks))
+	}
+}
@@ -0,0 +1,513 @@
+package adapters
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"soundporter/internal/playlist"
+	"strings"
+	"sync"
+	"time"
+)
+
+// PluginPrefix is the executable name prefix of external adapters. An executable named
+// soundporter-adapter-<name> on PATH provides the platform <name>.
+const PluginPrefix = "soundporter-adapter-"
+
+// PluginProtocolVersion is sent to plugins on initialize. See docs/adapter-protocol.md.
+const PluginProtocolVersion = 1
+
+// Error codes plugins use to report conditions the core handles specially
+const (
+	pluginErrRegionUnavailable = -32001
+	pluginErrRateLimited       = -32002
+)
+
+// PluginAdapter runs an external adapter executable and talks to it with JSON-RPC 2.0 over its
+// stdin and stdout, one message per line
+type PluginAdapter struct {
+	BaseAdapter
+	name         string
+	cmd          *exec.Cmd
+	stdin        io.WriteCloser
+	capabilities Capabilities
+
+	writeMu sync.Mutex
+	mu      sync.Mutex
+	nextID  int64
+	pending map[int64]chan pluginResponse
+	readErr error
+}
+
+type pluginRequest struct {
+	JSONRPC string `json:"jsonrpc"`
+	ID      int64  `json:"id"`
+	Method  string `json:"method"`
+	Params  any    `json:"params"`
+}
+
+type pluginResponse struct {
+	ID     int64           `json:"id"`
+	Result json.RawMessage `json:"result"`
+	Error  *pluginError    `json:"error"`
+}
+
+type pluginError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+type pluginCapabilities struct {
+	MaxBatchSize         int      `json:"max_batch_size"`
+	MaxSearchLimit       int      `json:"max_search_limit"`
+	SearchFields         []string `json:"search_fields"`
+	MaxDescriptionLength int      `json:"max_description_length"`
+	PrivacyLevels        []string `json:"privacy_levels"`
+	CanReorderItems      bool     `json:"can_reorder_items"`
+	CanRemoveItems       bool     `json:"can_remove_items"`
+}
+
+type pluginPlaylist struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	TrackCount  int    `json:"track_count"`
+}
+
+type pluginTrack struct {
+	ID          string   `json:"id"`
+	Name        string   `json:"name"`
+	Artists     []string `json:"artists"`
+	Album       string   `json:"album"`
+	ArtistIDs   []string `json:"artist_ids"`
+	AlbumID     string   `json:"album_id"`
+	URL         string   `json:"url"`
+	Version     string   `json:"version"`
+	ISRC        string   `json:"isrc"`
+	DurationMs  int      `json:"duration_ms"`
+	Explicit    bool     `json:"explicit"`
+	Popularity  int      `json:"popularity"`
+	DiscNumber  int      `json:"disc_number"`
+	TrackNumber int      `json:"track_number"`
+	ReleaseDate string   `json:"release_date"`
+	MBID        string   `json:"mbid"`
+}
+
+type pluginSearchQuery struct {
+	ISRC   string `json:"isrc,omitempty"`
+	Artist string `json:"artist,omitempty"`
+	Track  string `json:"track,omitempty"`
+	Album  string `json:"album,omitempty"`
+}
+
+// FindPlugin returns the path of the executable providing the platform, if there is one on PATH
+func FindPlugin(platform string) (string, bool) {
+	if platform == "" || strings.ContainsAny(platform, `/\`) {
+		return "", false
+	}
+	path, err := exec.LookPath(PluginPrefix + platform)
+	return path, err == nil
+}
+
+// PluginPlatforms returns the names of the platforms provided by executables on PATH
+func PluginPlatforms() []string {
+	seen := make(map[string]bool)
+	var names []string
+	for _, dir := range filepath.SplitList(os.Getenv("PATH")) {
+		matches, _ := filepath.Glob(filepath.Join(dir, PluginPrefix+"*"))
+		for _, m := range matches {
+			name := strings.TrimPrefix(filepath.Base(m), PluginPrefix)
+			name = strings.TrimSuffix(name, filepath.Ext(name))
+			if name == "" || seen[name] {
+				continue
+			}
+			if _, ok := FindPlugin(name); ok {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+	return names
+}
+
+// pluginRegistration describes a plugin before it has been started. Plugins read their own
+// credentials from the environment they inherit, so none are declared here.
+func pluginRegistration(name, path string) Registration {
+	return Registration{
+		Name:        PlatformType(name),
+		DisplayName: name + " (plugin)",
+		Plugin:      path,
+		New: func(creds Credentials, opts ...Option) (ApiAdapter, error) {
+			return NewPluginAdapter(name, path)
+		},
+	}
+}
+
+// NewPluginAdapter starts the adapter executable at path and performs the initialize handshake
+func NewPluginAdapter(name, path string) (*PluginAdapter, error) {
+	cmd := exec.Command(path)
+	cmd.Stderr = os.Stderr
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("error starting plugin %s: %v", path, err)
+	}
+
+	a := &PluginAdapter{
+		BaseAdapter: NewBaseAdapter(name),
+		name:        name,
+		cmd:         cmd,
+		stdin:       stdin,
+		pending:     make(map[int64]chan pluginResponse),
+	}
+	go a.readResponses(stdout)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	var init struct {
+		DisplayName  string             `json:"display_name"`
+		Capabilities pluginCapabilities `json:"capabilities"`
+	}
+	err = a.call(ctx, "initialize", map[string]any{
+		"protocol_version": PluginProtocolVersion,
+		"platform":         name,
+	}, &init)
+	if err != nil {
+		a.Close()
+		return nil, fmt.Errorf("error initializing plugin %s: %w", path, err)
+	}
+	if init.DisplayName != "" {
+		a.BaseAdapter = NewBaseAdapter(init.DisplayName)
+	}
+	a.capabilities = init.Capabilities.capabilities()
+	return a, nil
+}
+
+// Close asks the plugin to shut down and waits for it to exit
+func (a *PluginAdapter) Close() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	a.call(ctx, "shutdown", struct{}{}, nil)
+	a.stdin.Close()
+
+	done := make(chan error, 1)
+	go func() { done <- a.cmd.Wait() }()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		a.cmd.Process.Kill()
+		return <-done
+	}
+}
+
+// Authenticate asks the plugin to authenticate with its service
+func (a *PluginAdapter) Authenticate(ctx context.Context) error {
+	if err := a.call(ctx, "authenticate", struct{}{}, nil); err != nil {
+		return err
+	}
+	a.SetAuthenticated(true)
+	return nil
+}
+
+// GetUserPlaylists retrieves all playlists for the authenticated user
+func (a *PluginAdapter) GetUserPlaylists(ctx context.Context) ([]playlist.Playlist, error) {
+	return collect(a.UserPlaylists(ctx))
+}
+
+// UserPlaylists streams the user's playlists page by page, following the plugin's cursors
+func (a *PluginAdapter) UserPlaylists(ctx context.Context) iter.Seq2[playlist.Playlist, error] {
+	return func(yield func(playlist.Playlist, error) bool) {
+		if err := a.CheckAuth(); err != nil {
+			yield(playlist.Playlist{}, err)
+			return
+		}
+
+		cursor := ""
+		for {
+			var page struct {
+				Playlists  []pluginPlaylist `json:"playlists"`
+				NextCursor string           `json:"next_cursor"`
+			}
+			if err := a.call(ctx, "user_playlists", map[string]string{"cursor": cursor}, &page); err != nil {
+				yield(playlist.Playlist{}, fmt.Errorf("error getting playlists: %w", err))
+				return
+			}
+			for _, p := range page.Playlists {
+				if !yield(p.playlist(), nil) {
+					return
+				}
+			}
+			if page.NextCursor == "" {
+				return
+			}
+			cursor = page.NextCursor
+		}
+	}
+}
+
+// GetPlaylistItems retrieves all tracks in a playlist
+func (a *PluginAdapter) GetPlaylistItems(ctx context.Context, playlistID string) ([]playlist.Track, error) {
+	return collect(a.PlaylistItems(ctx, playlistID))
+}
+
+// PlaylistItems streams the tracks of a playlist page by page, following the plugin's cursors
+func (a *PluginAdapter) PlaylistItems(ctx context.Context, playlistID string) iter.Seq2[playlist.Track, error] {
+	return func(yield func(playlist.Track, error) bool) {
+		if err := a.CheckAuth(); err != nil {
+			yield(playlist.Track{}, err)
+			return
+		}
+
+		cursor := ""
+		for {
+			var page struct {
+				Tracks     []pluginTrack `json:"tracks"`
+				NextCursor string        `json:"next_cursor"`
+			}
+			params := map[string]string{"playlist_id": playlistID, "cursor": cursor}
+			if err := a.call(ctx, "playlist_items", params, &page); err != nil {
+				yield(playlist.Track{}, fmt.Errorf("error getting playlist items: %w", err))
+				return
+			}
+			for _, t := range page.Tracks {
+				if !yield(t.track(), nil) {
+					return
+				}
+			}
+			if page.NextCursor == "" {
+				return
+			}
+			cursor = page.NextCursor
+		}
+	}
+}
+
+// CreateNewPlaylist asks the plugin to create a playlist
+func (a *PluginAdapter) CreateNewPlaylist(ctx context.Context, name string, description string, privacy Privacy) (playlist.Playlist, error) {
+	if err := a.CheckAuth(); err != nil {
+		return playlist.Playlist{}, err
+	}
+
+	var p pluginPlaylist
+	params := map[string]string{"name": name, "description": description, "privacy": string(privacy)}
+	if err := a.call(ctx, "create_playlist", params, &p); err != nil {
+		return playlist.Playlist{}, fmt.Errorf("error creating playlist: %w", err)
+	}
+	return p.playlist(), nil
+}
+
+// AddItemsToPlaylist asks the plugin to add tracks to a playlist
+func (a *PluginAdapter) AddItemsToPlaylist(ctx context.Context, playlistID string, trackIDs []string) error {
+	if err := a.CheckAuth(); err != nil {
+		return err
+	}
+
+	params := map[string]any{"playlist_id": playlistID, "track_ids": trackIDs}
+	if err := a.call(ctx, "add_items", params, nil); err != nil {
+		return fmt.Errorf("error adding tracks to playlist: %w", err)
+	}
+	return nil
+}
+
+// SearchTracks runs a free-text search on the plugin
+func (a *PluginAdapter) SearchTracks(ctx context.Context, query string, limit int) ([]playlist.Track, error) {
+	return a.search(ctx, "search", map[string]any{"text": query, "limit": limit})
+}
+
+// SearchTracksBy runs a structured search on the plugin. Plugins only receive the fields they
+// listed in their capabilities.
+func (a *PluginAdapter) SearchTracksBy(ctx context.Context, query SearchQuery, limit int) ([]playlist.Track, error) {
+	q := pluginSearchQuery{Artist: query.Artist, Track: query.Track, Album: query.Album}
+	if a.capabilities.SupportsISRC() {
+		q.ISRC = query.ISRC
+	}
+	return a.search(ctx, "search_by", map[string]any{"query": q, "limit": limit})
+}
+
+// Capabilities returns the capabilities the plugin reported on initialize
+func (a *PluginAdapter) Capabilities() Capabilities {
+	return a.capabilities
+}
+
+func (a *PluginAdapter) search(ctx context.Context, method string, params map[string]any) ([]playlist.Track, error) {
+	if err := a.CheckAuth(); err != nil {
+		return nil, err
+	}
+
+	var result struct {
+		Tracks []pluginTrack `json:"tracks"`
+	}
+	if err := a.call(ctx, method, params, &result); err != nil {
+		return nil, fmt.Errorf("error searching tracks: %w", err)
+	}
+	tracks := make([]playlist.Track, len(result.Tracks))
+	for i, t := range result.Tracks {
+		tracks[i] = t.track()
+	}
+	return tracks, nil
+}
+
+// call sends a request and waits for its response. If ctx is done first the response is discarded.
+func (a *PluginAdapter) call(ctx context.Context, method string, params any, result any) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	a.mu.Lock()
+	if a.readErr != nil {
+		a.mu.Unlock()
+		return a.readErr
+	}
+	a.nextID++
+	id := a.nextID
+	ch := make(chan pluginResponse, 1)
+	a.pending[id] = ch
+	a.mu.Unlock()
+
+	line, err := json.Marshal(pluginRequest{JSONRPC: "2.0", ID: id, Method: method, Params: params})
+	if err != nil {
+		return err
+	}
+	a.writeMu.Lock()
+	_, err = a.stdin.Write(append(line, '\n'))
+	a.writeMu.Unlock()
+	if err != nil {
+		a.forget(id)
+		return fmt.Errorf("plugin %s: %v", a.name, err)
+	}
+
+	select {
+	case resp := <-ch:
+		if resp.Error != nil {
+			return resp.Error.err(a.name)
+		}
+		if result == nil || len(resp.Result) == 0 {
+			return nil
+		}
+		if err := json.Unmarshal(resp.Result, result); err != nil {
+			return fmt.Errorf("plugin %s: invalid %s result: %v", a.name, method, err)
+		}
+		return nil
+	case <-ctx.Done():
+		a.forget(id)
+		return ctx.Err()
+	}
+}
+
+func (a *PluginAdapter) forget(id int64) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	delete(a.pending, id)
+}
+
+// readResponses dispatches responses to their waiting calls until the plugin's stdout closes
+func (a *PluginAdapter) readResponses(stdout io.Reader) {
+	scanner := bufio.NewScanner(stdout)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		var resp pluginResponse
+		if err := json.Unmarshal(scanner.Bytes(), &resp); err != nil {
+			fmt.Fprintf(os.Stderr, "plugin %s: ignoring invalid message: %v\n", a.name, err)
+			continue
+		}
+		a.mu.Lock()
+		ch, ok := a.pending[resp.ID]
+		delete(a.pending, resp.ID)
+		a.mu.Unlock()
+		if ok {
+			ch <- resp
+		}
+	}
+
+	// Fail the calls that are still waiting and any made from now on
+	err := scanner.Err()
+	if err == nil {
+		err = io.ErrUnexpectedEOF
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.readErr = fmt.Errorf("plugin %s exited: %v", a.name, err)
+	for id, ch := range a.pending {
+		ch <- pluginResponse{ID: id, Error: &pluginError{Message: a.readErr.Error()}}
+		delete(a.pending, id)
+	}
+}
+
+// err converts a JSON-RPC error into the errors the rest of the program checks for
+func (e *pluginError) err(name string) error {
+	switch e.Code {
+	case pluginErrRegionUnavailable:
+		return ErrRegionUnavailable
+	case pluginErrRateLimited:
+		return fmt.Errorf("plugin %s: %s: %w", name, e.Message, ErrRateLimited)
+	}
+	return errors.New("plugin " + name + ": " + e.Message)
+}
+
+func (c pluginCapabilities) capabilities() Capabilities {
+	caps := Capabilities{
+		MaxBatchSize:         c.MaxBatchSize,
+		MaxSearchLimit:       c.MaxSearchLimit,
+		MaxDescriptionLength: c.MaxDescriptionLength,
+		CanReorderItems:      c.CanReorderItems,
+		CanRemoveItems:       c.CanRemoveItems,
+	}
+	for _, f := range c.SearchFields {
+		caps.SearchFields = append(caps.SearchFields, SearchField(f))
+	}
+	for _, p := range c.PrivacyLevels {
+		caps.PrivacyLevels = append(caps.PrivacyLevels, Privacy(p))
+	}
+	if caps.MaxBatchSize <= 0 {
+		caps.MaxBatchSize = 1
+	}
+	if len(caps.PrivacyLevels) == 0 {
+		caps.PrivacyLevels = []Privacy{PrivacyPrivate}
+	}
+	return caps
+}
+
+func (p pluginPlaylist) playlist() playlist.Playlist {
+	return playlist.Playlist{
+		ID:          p.ID,
+		Name:        p.Name,
+		Description: p.Description,
+		TrackCount:  p.TrackCount,
+		CreatedAt:   time.Now(),
+	}
+}
+
+func (t pluginTrack) track() playlist.Track {
+	return playlist.Track{
+		Name:        t.Name,
+		Artists:     t.Artists,
+		Album:       t.Album,
+		ID:          t.ID,
+		ArtistIDs:   t.ArtistIDs,
+		AlbumID:     t.AlbumID,
+		URL:         t.URL,
+		Version:     t.Version,
+		ISRC:        t.ISRC,
+		DurationMs:  t.DurationMs,
+		Explicit:    t.Explicit,
+		Popularity:  t.Popularity,
+		DiscNumber:  t.DiscNumber,
+		TrackNumber: t.TrackNumber,
+		ReleaseDate: t.ReleaseDate,
+		MBID:        t.MBID,
+	}
+}
@@ -0,0 +1,337 @@
+package adapters_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"soundporter/internal/adapters"
+)
+
+// testPluginEnv makes the test binary run as a plugin instead of running the tests. Its value
+// picks how the plugin behaves.
+const testPluginEnv = "SOUNDPORTER_TEST_PLUGIN"
+
+func TestMain(m *testing.M) {
+	if mode := os.Getenv(testPluginEnv); mode != "" {
+		serveTestPlugin(mode)
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+// serveTestPlugin answers requests on stdin the way a plugin does. Each request is answered on
+// its own goroutine, so slow requests are answered after the ones sent after them.
+func serveTestPlugin(mode string) {
+	var writeMu sync.Mutex
+	respond := func(id json.RawMessage, result any, code int, message string) {
+		resp := map[string]any{"jsonrpc": "2.0", "id": id, "result": result}
+		if message != "" {
+			resp = map[string]any{"jsonrpc": "2.0", "id": id, "error": map[string]any{"code": code, "message": message}}
+		}
+		line, _ := json.Marshal(resp)
+		writeMu.Lock()
+		defer writeMu.Unlock()
+		os.Stdout.Write(append(line, '\n'))
+	}
+
+	var wg sync.WaitGroup
+	scanner := bufio.NewScanner(os.Stdin)
+	for scanner.Scan() {
+		var req struct {
+			ID     json.RawMessage `json:"id"`
+			Method string          `json:"method"`
+			Params json.RawMessage `json:"params"`
+		}
+		if err := json.Unmarshal(scanner.Bytes(), &req); err != nil {
+			continue
+		}
+		if req.Method == "shutdown" {
+			wg.Wait()
+			respond(req.ID, nil, 0, "")
+			return
+		}
+		// Noisy plugins print a line that isn't JSON-RPC, which the core skips
+		if req.Method == "initialize" && mode == "noisy" {
+			writeMu.Lock()
+			fmt.Println("starting up")
+			writeMu.Unlock()
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			result, code, message := testPluginCall(mode, req.Method, req.Params)
+			respond(req.ID, result, code, message)
+		}()
+	}
+}
+
+// testPluginCall handles a request, returning its result or an error code and message
+func testPluginCall(mode, method string, raw json.RawMessage) (any, int, string) {
+	var params struct {
+		Cursor     string          `json:"cursor"`
+		PlaylistID string          `json:"playlist_id"`
+		TrackIDs   []string        `json:"track_ids"`
+		Name       string          `json:"name"`
+		Privacy    string          `json:"privacy"`
+		Text       string          `json:"text"`
+		Query      json.RawMessage `json:"query"`
+		Limit      int             `json:"limit"`
+	}
+	json.Unmarshal(raw, &params)
+
+	switch method {
+	case "initialize":
+		if mode == "defaults" {
+			return map[string]any{}, 0, ""
+		}
+		return map[string]any{
+			"display_name": "Test Plugin",
+			"capabilities": map[string]any{
+				"max_batch_size":   2,
+				"max_search_limit": 10,
+				"search_fields":    []string{"artist", "track"},
+				"privacy_levels":   []string{"unlisted", "public"},
+			},
+		}, 0, ""
+	case "authenticate":
+		if mode == "unauthorized" {
+			return nil, -32000, "invalid credentials"
+		}
+		if mode == "exit" {
+			os.Exit(3)
+		}
+		return nil, 0, ""
+	case "user_playlists":
+		// Five playlists in pages of two, the cursor being the index of the next one
+		start, _ := strconv.Atoi(params.Cursor)
+		var page []map[string]any
+		for i := start; i < min(start+2, 5); i++ {
+			page = append(page, map[string]any{"id": fmt.Sprintf("p%d", i+1), "name": fmt.Sprintf("Playlist %d", i+1), "track_count": 3})
+		}
+		next := ""
+		if start+2 < 5 {
+			next = strconv.Itoa(start + 2)
+		}
+		return map[string]any{"playlists": page, "next_cursor": next}, 0, ""
+	case "playlist_items":
+		switch params.PlaylistID {
+		case "region":
+			return nil, -32001, "not available in your country"
+		case "limited":
+			return nil, -32002, "slow down"
+		case "crash":
+			os.Exit(3)
+		case "p1":
+		default:
+			return nil, -32000, "playlist not found"
+		}
+		if params.Cursor == "" {
+			return map[string]any{"tracks": []map[string]any{
+				{"id": "t1", "name": "Golden Master", "artists": []string{"Mock Orchestra"}, "isrc": "XXFAK2500006", "duration_ms": 198000},
+				{"id": "t2", "name": "Green Build", "version": "Remix", "mbid": "mbid-2"},
+			}, "next_cursor": "more"}, 0, ""
+		}
+		return map[string]any{"tracks": []map[string]any{{"id": "t3", "name": "Off By One", "artists": []string{"Data Race", "Fencepost"}}}}, 0, ""
+	case "create_playlist":
+		return map[string]any{"id": "new-" + params.Privacy, "name": params.Name}, 0, ""
+	case "add_items":
+		if len(params.TrackIDs) > 2 {
+			return nil, -32602, "too many tracks"
+		}
+		return nil, 0, ""
+	case "search":
+		// Slow searches are answered after the ones sent after them
+		if params.Text == "slow" {
+			time.Sleep(100 * time.Millisecond)
+		}
+		return map[string]any{"tracks": []map[string]any{{"id": params.Text, "name": params.Text}}}, 0, ""
+	case "search_by":
+		// The query is sent back as the track's name, so tests see what the plugin received
+		return map[string]any{"tracks": []map[string]any{{"id": "q", "name": string(params.Query)}}}, 0, ""
+	}
+	return nil, -32601, "method not found: " + method
+}
+
+// newTestPlugin starts the test binary as a plugin behaving as mode
+func newTestPlugin(t *testing.T, mode string) *adapters.PluginAdapter {
+	t.Helper()
+	t.Setenv(testPluginEnv, mode)
+	a, err := adapters.NewPluginAdapter("test", os.Args[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { a.Close() })
+	return a
+}
+
+func TestPluginInitialize(t *testing.T) {
+	a := newTestPlugin(t, "noisy")
+	if a.PlatformName() != "Test Plugin" {
+		t.Fatalf("plugin named %q", a.PlatformName())
+	}
+	caps := a.Capabilities()
+	if caps.MaxBatchSize != 2 || caps.MaxSearchLimit != 10 || caps.SupportsISRC() ||
+		!slices.Equal(caps.PrivacyLevels, []adapters.Privacy{adapters.PrivacyUnlisted, adapters.PrivacyPublic}) {
+		t.Fatalf("capabilities read as %+v", caps)
+	}
+
+	// Plugins that leave out their capabilities add one track at a time, to private playlists
+	a = newTestPlugin(t, "defaults")
+	if a.PlatformName() != "test" {
+		t.Fatalf("plugin without a display name named %q", a.PlatformName())
+	}
+	caps = a.Capabilities()
+	if caps.MaxBatchSize != 1 || caps.MaxSearchLimit != 0 || len(caps.SearchFields) != 0 ||
+		!slices.Equal(caps.PrivacyLevels, []adapters.Privacy{adapters.PrivacyPrivate}) {
+		t.Fatalf("default capabilities are %+v", caps)
+	}
+
+	if _, err := adapters.NewPluginAdapter("missing", "/nonexistent/"+adapters.PluginPrefix+"missing"); err == nil {
+		t.Fatal("expected a missing executable to fail")
+	}
+}
+
+func TestPluginCalls(t *testing.T) {
+	ctx := context.Background()
+	a := newTestPlugin(t, "full")
+
+	if _, err := a.GetUserPlaylists(ctx); err == nil {
+		t.Fatal("expected an error before authenticating")
+	}
+	if err := a.Authenticate(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	// Pages are followed by their cursors
+	playlists, err := a.GetUserPlaylists(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := playlistIDs(playlists); !slices.Equal(got, []string{"p1", "p2", "p3", "p4", "p5"}) {
+		t.Fatalf("playlists listed as %v", got)
+	}
+	tracks, err := a.GetPlaylistItems(ctx, "p1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := trackIDs(tracks); !slices.Equal(got, []string{"t1", "t2", "t3"}) {
+		t.Fatalf("tracks listed as %v", got)
+	}
+	if got := tracks[0]; got.Name != "Golden Master" || got.ISRC != "XXFAK2500006" || got.DurationMs != 198000 ||
+		!slices.Equal(got.Artists, []string{"Mock Orchestra"}) {
+		t.Fatalf("track converted to %+v", got)
+	}
+	if got := tracks[1]; got.Version != "Remix" || got.MBID != "mbid-2" || got.Artists != nil {
+		t.Fatalf("track converted to %+v", got)
+	}
+
+	created, err := a.CreateNewPlaylist(ctx, "Copy", "", adapters.PrivacyUnlisted)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if created.ID != "new-unlisted" || created.Name != "Copy" {
+		t.Fatalf("created %+v", created)
+	}
+	if err := a.AddItemsToPlaylist(ctx, created.ID, []string{"t1", "t2"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.AddItemsToPlaylist(ctx, created.ID, []string{"t1", "t2", "t3"}); err == nil || !strings.Contains(err.Error(), "too many tracks") {
+		t.Fatalf("expected the plugin's error, got %v", err)
+	}
+
+	// The ISRC is only sent to plugins that search by it
+	found, err := a.SearchTracksBy(ctx, adapters.SearchQuery{ISRC: "XXFAK2500006", Track: "Golden Master", Artist: "Mock Orchestra"}, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(found) != 1 || found[0].Name != `{"artist":"Mock Orchestra","track":"Golden Master"}` {
+		t.Fatalf("plugin searched by %+v", found)
+	}
+}
+
+func TestPluginAnswersOutOfOrder(t *testing.T) {
+	ctx := context.Background()
+	a := newTestPlugin(t, "full")
+	if err := a.Authenticate(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	// Responses are matched to their requests by ID, whatever order they come in
+	var wg sync.WaitGroup
+	for _, text := range []string{"slow", "fast", "faster"} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			found, err := a.SearchTracks(ctx, text, 1)
+			if err != nil || len(found) != 1 || found[0].ID != text {
+				t.Errorf("search for %s found %v, %v", text, found, err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	// A call whose context ends first gives up without waiting
+	short, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	if _, err := a.SearchTracks(short, "slow", 1); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected the deadline to end the call, got %v", err)
+	}
+	if found, err := a.SearchTracks(ctx, "fast", 1); err != nil || found[0].ID != "fast" {
+		t.Fatalf("call after an abandoned one found %v, %v", found, err)
+	}
+}
+
+func TestPluginErrors(t *testing.T) {
+	ctx := context.Background()
+	a := newTestPlugin(t, "full")
+	if err := a.Authenticate(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	// Plugins report conditions the core handles specially with their own codes
+	if _, err := a.GetPlaylistItems(ctx, "region"); !errors.Is(err, adapters.ErrRegionUnavailable) {
+		t.Fatalf("expected ErrRegionUnavailable, got %v", err)
+	}
+	if _, err := a.GetPlaylistItems(ctx, "limited"); !errors.Is(err, adapters.ErrRateLimited) || !strings.Contains(err.Error(), "slow down") {
+		t.Fatalf("expected ErrRateLimited with the plugin's message, got %v", err)
+	}
+	if _, err := a.GetPlaylistItems(ctx, "missing"); err == nil || errors.Is(err, adapters.ErrRateLimited) {
+		t.Fatalf("expected a plain error, got %v", err)
+	}
+
+	a = newTestPlugin(t, "unauthorized")
+	if err := a.Authenticate(ctx); err == nil || !strings.Contains(err.Error(), "plugin test: invalid credentials") || a.IsAuthenticated() {
+		t.Fatalf("expected authentication to fail, got %v", err)
+	}
+}
+
+func TestPluginExits(t *testing.T) {
+	ctx := context.Background()
+
+	// A plugin that exits mid-call fails the call and every one after it
+	a := newTestPlugin(t, "full")
+	if err := a.Authenticate(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := a.GetPlaylistItems(ctx, "crash"); err == nil || !strings.Contains(err.Error(), "plugin test exited") {
+		t.Fatalf("expected the plugin to have exited, got %v", err)
+	}
+	if _, err := a.SearchTracks(ctx, "fast", 1); err == nil || !strings.Contains(err.Error(), "exited") {
+		t.Fatalf("expected calls after the exit to fail, got %v", err)
+	}
+
+	a = newTestPlugin(t, "exit")
+	if err := a.Authenticate(ctx); err == nil || a.IsAuthenticated() {
+		t.Fatalf("expected authentication to fail when the plugin exits, got %v", err)
+	}
+}
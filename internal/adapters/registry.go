@@ -0,0 +1,155 @@
+package adapters
+
+import (
+	"cmp"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// CredentialSpec describes a credential an adapter needs, such as an API client ID
+type CredentialSpec struct {
+	// Key is the name the adapter's constructor looks the value up by
+	Key string
+	// EnvVar is the environment variable the value is read from
+	EnvVar string
+	// Description is shown in help text
+	Description string
+	// Optional credentials may be left unset
+	Optional bool
+}
+
+// Credentials holds credential values by CredentialSpec.Key
+type Credentials map[string]string
+
+// Constructor creates an adapter from resolved credentials
+type Constructor func(creds Credentials, opts ...Option) (ApiAdapter, error)
+
+// Registration describes a platform adapter to the rest of the program
+type Registration struct {
+	// Name identifies the platform on the command line and in reports, e.g. "spotify"
+	Name PlatformType
+	// DisplayName is shown in prompts, e.g. "YouTube Music"
+	DisplayName string
+	// Demo platforms don't talk to a real service and are listed after the real ones
+	Demo bool
+	// Plugin is the path of the executable providing an external platform, empty for built-in ones.
+	// The capabilities of a plugin are only known once it has been started.
+	Plugin       string
+	Credentials  []CredentialSpec
+	Capabilities Capabilities
+	New          Constructor
+}
+
+var (
+	registryMu sync.RWMutex
+	registry   = make(map[PlatformType]Registration)
+)
+
+// Register makes a platform adapter available by name. It panics if the name is already registered.
+func Register(r Registration) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	if r.Name == "" || r.New == nil {
+		panic("adapters: Register called without a name or constructor")
+	}
+	if _, dup := registry[r.Name]; dup {
+		panic("adapters: Register called twice for platform " + string(r.Name))
+	}
+	registry[r.Name] = r
+}
+
+// Lookup returns the registration for a platform. Platforms that aren't built in are looked up
+// as plugin executables on PATH.
+func Lookup(platform string) (Registration, bool) {
+	registryMu.RLock()
+	r, ok := registry[PlatformType(platform)]
+	registryMu.RUnlock()
+	if ok {
+		return r, true
+	}
+	if path, ok := FindPlugin(platform); ok {
+		return pluginRegistration(platform, path), true
+	}
+	return Registration{}, false
+}
+
+// Registrations returns every built-in platform and plugin sorted by name, built-in platforms
+// first, then plugins, then demo platforms
+func Registrations() []Registration {
+	registryMu.RLock()
+	regs := make([]Registration, 0, len(registry))
+	for _, r := range registry {
+		regs = append(regs, r)
+	}
+	registryMu.RUnlock()
+	for _, name := range PluginPlatforms() {
+		if r, ok := Lookup(name); ok && r.Plugin != "" {
+			regs = append(regs, r)
+		}
+	}
+	slices.SortFunc(regs, func(a, b Registration) int {
+		if c := cmp.Compare(a.rank(), b.rank()); c != 0 {
+			return c
+		}
+		return strings.Compare(string(a.Name), string(b.Name))
+	})
+	return regs
+}
+
+// rank orders the groups of platforms in listings
+func (r Registration) rank() int {
+	switch {
+	case r.Demo:
+		return 2
+	case r.Plugin != "":
+		return 1
+	}
+	return 0
+}
+
+// PlatformNames returns the names of every registered platform, in the order of Registrations
+func PlatformNames() []string {
+	var names []string
+	for _, r := range Registrations() {
+		names = append(names, string(r.Name))
+	}
+	return names
+}
+
+// ResolveCredentials fills credentials missing from given with their environment variables and
+// reports the required ones that are still unset
+func (r Registration) ResolveCredentials(given Credentials) (Credentials, error) {
+	creds := make(Credentials, len(r.Credentials))
+	var missing []string
+	for _, spec := range r.Credentials {
+		value := given[spec.Key]
+		if value == "" {
+			value = os.Getenv(spec.EnvVar)
+		}
+		if value == "" && !spec.Optional {
+			missing = append(missing, spec.EnvVar)
+		}
+		creds[spec.Key] = value
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("%s credentials must be provided or set in environment variables: %s", r.DisplayName, strings.Join(missing, ", "))
+	}
+	return creds, nil
+}
+
+// NewApiAdapter creates an adapter for a registered platform. Credentials that aren't given are
+// read from the environment variables the platform registered.
+func NewApiAdapter(platform string, creds Credentials, opts ...Option) (ApiAdapter, error) {
+	r, ok := Lookup(platform)
+	if !ok {
+		return nil, fmt.Errorf("unsupported platform: %s (supported: %s)", platform, strings.Join(PlatformNames(), ", "))
+	}
+	resolved, err := r.ResolveCredentials(creds)
+	if err != nil {
+		return nil, err
+	}
+	return r.New(resolved, opts...)
+}
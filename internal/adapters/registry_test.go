@@ -0,0 +1,175 @@
+package adapters
+
+import (
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// withRegistry replaces the registry with regs for the duration of the test, and leaves no
+// plugins on PATH but those in the returned directory
+func withRegistry(t *testing.T, regs ...Registration) string {
+	t.Helper()
+	registryMu.Lock()
+	saved := maps.Clone(registry)
+	registry = make(map[PlatformType]Registration)
+	registryMu.Unlock()
+	t.Cleanup(func() {
+		registryMu.Lock()
+		registry = saved
+		registryMu.Unlock()
+	})
+	for _, r := range regs {
+		Register(r)
+	}
+
+	dir := t.TempDir()
+	t.Setenv("PATH", dir)
+	return dir
+}
+
+// writePlugin puts an executable for the plugin platform name in dir
+func writePlugin(t *testing.T, dir, name string) string {
+	t.Helper()
+	path := filepath.Join(dir, PluginPrefix+name)
+	if err := os.WriteFile(path, []byte("#!/bin/sh\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func newTestAdapter(creds Credentials, opts ...Option) (ApiAdapter, error) {
+	return NewFakeAdapter(FakeConfig{}), nil
+}
+
+func TestRegisterPanics(t *testing.T) {
+	withRegistry(t, Registration{Name: "alpha", New: newTestAdapter})
+
+	for name, r := range map[string]Registration{
+		"a duplicate name":      {Name: "alpha", New: newTestAdapter},
+		"a missing name":        {New: newTestAdapter},
+		"a missing constructor": {Name: "beta"},
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("expected registering %s to panic", name)
+				}
+			}()
+			Register(r)
+		}()
+	}
+	if r, _ := Lookup("alpha"); r.Name != "alpha" {
+		t.Fatalf("failed registrations replaced the first: %+v", r)
+	}
+}
+
+func TestLookup(t *testing.T) {
+	dir := withRegistry(t, Registration{Name: "alpha", DisplayName: "Alpha", New: newTestAdapter})
+	path := writePlugin(t, dir, "gamma")
+
+	if r, ok := Lookup("alpha"); !ok || r.DisplayName != "Alpha" || r.Plugin != "" {
+		t.Fatalf("built-in platform looked up as %+v, %v", r, ok)
+	}
+	// Platforms that aren't built in are plugins on PATH
+	if r, ok := Lookup("gamma"); !ok || r.Plugin != path || r.DisplayName != "gamma (plugin)" {
+		t.Fatalf("plugin looked up as %+v, %v", r, ok)
+	}
+	for _, name := range []string{"", "unknown", "../gamma", dir + "/" + PluginPrefix + "gamma"} {
+		if r, ok := Lookup(name); ok {
+			t.Errorf("%q looked up as %+v", name, r)
+		}
+	}
+
+	if _, err := NewApiAdapter("unknown", nil); err == nil || !strings.Contains(err.Error(), "supported: alpha, gamma") {
+		t.Fatalf("expected the supported platforms to be listed, got %v", err)
+	}
+}
+
+func TestRegistrationsOrder(t *testing.T) {
+	dir := withRegistry(t,
+		Registration{Name: "demo", Demo: true, New: newTestAdapter},
+		Registration{Name: "zeta", New: newTestAdapter},
+		Registration{Name: "alpha", New: newTestAdapter},
+	)
+	writePlugin(t, dir, "omega")
+	writePlugin(t, dir, "beta")
+	// Files that aren't executable aren't plugins
+	if err := os.WriteFile(filepath.Join(dir, PluginPrefix+"readme"), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Built-in platforms come first, then plugins, then demo platforms, each sorted by name
+	want := []string{"alpha", "zeta", "beta", "omega", "demo"}
+	if got := PlatformNames(); !slices.Equal(got, want) {
+		t.Fatalf("platforms listed as %v, want %v", got, want)
+	}
+}
+
+func TestResolveCredentials(t *testing.T) {
+	r := Registration{
+		Name:        "alpha",
+		DisplayName: "Alpha",
+		Credentials: []CredentialSpec{
+			{Key: "client_id", EnvVar: "ALPHA_CLIENT_ID"},
+			{Key: "secret", EnvVar: "ALPHA_SECRET"},
+			{Key: "region", EnvVar: "ALPHA_REGION", Optional: true},
+		},
+		New: newTestAdapter,
+	}
+	withRegistry(t, r)
+	t.Setenv("ALPHA_CLIENT_ID", "from-env")
+	t.Setenv("ALPHA_SECRET", "")
+	t.Setenv("ALPHA_REGION", "")
+
+	// Given values win over the environment, and optional ones may stay unset
+	creds, err := r.ResolveCredentials(Credentials{"secret": "given", "unrelated": "dropped"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Credentials{"client_id": "from-env", "secret": "given", "region": ""}
+	if !maps.Equal(creds, want) {
+		t.Fatalf("resolved %v, want %v", creds, want)
+	}
+	creds, err = r.ResolveCredentials(Credentials{"client_id": "given", "secret": "given"})
+	if err != nil || creds["client_id"] != "given" {
+		t.Fatalf("resolved %v, %v", creds, err)
+	}
+
+	// The missing required credentials are named by their environment variables, in order
+	t.Setenv("ALPHA_CLIENT_ID", "")
+	if _, err := r.ResolveCredentials(nil); err == nil || !strings.HasSuffix(err.Error(), "Alpha credentials must be provided or set in environment variables: ALPHA_CLIENT_ID, ALPHA_SECRET") {
+		t.Fatalf("expected the missing variables to be named, got %v", err)
+	}
+	if _, err := NewApiAdapter("alpha", Credentials{"client_id": "given"}); err == nil || !strings.Contains(err.Error(), "ALPHA_SECRET") {
+		t.Fatalf("expected the adapter not to be created, got %v", err)
+	}
+	if a, err := NewApiAdapter("alpha", Credentials{"client_id": "given", "secret": "given"}); err != nil || a == nil {
+		t.Fatalf("adapter not created: %v", err)
+	}
+}
+
+func TestBuiltinRegistrations(t *testing.T) {
+	// Every built-in platform registers itself, with an environment variable per credential
+	for _, r := range Registrations() {
+		if r.Plugin != "" {
+			continue
+		}
+		if r.DisplayName == "" {
+			t.Errorf("%s has no display name", r.Name)
+		}
+		for _, spec := range r.Credentials {
+			if spec.Key == "" || spec.EnvVar == "" || spec.Description == "" {
+				t.Errorf("%s has an incomplete credential %+v", r.Name, spec)
+			}
+		}
+	}
+	for _, name := range []PlatformType{SpotifyPlatform, DeezerPlatform} {
+		if _, ok := Lookup(string(name)); !ok {
+			t.Errorf("%s is not registered", name)
+		}
+	}
+}
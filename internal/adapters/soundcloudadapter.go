@@ -0,0 +1,469 @@
+package adapters
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"iter"
+	"log"
+	"net/http"
+	"net/url"
+	"soundporter/internal/playlist"
+	"soundporter/internal/utils"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+const (
+	soundCloudRedirectURI = "http://localhost:8080/callback"
+	// soundCloudPageSize is the number of items requested per page; SoundCloud caps pages at 200
+	soundCloudPageSize = 200
+	// soundCloudMaxPlaylistTracks is the most tracks a SoundCloud playlist can hold
+	soundCloudMaxPlaylistTracks = 500
+	// SoundCloudLikesID is the playlist ID under which the user's liked tracks are listed.
+	// Adding tracks to it likes them.
+	SoundCloudLikesID = "likes"
+)
+
+var soundCloudCapabilities = Capabilities{
+	MaxBatchSize:   100,
+	MaxSearchLimit: 200,
+	PrivacyLevels:  []Privacy{PrivacyPrivate, PrivacyPublic},
+}
+
+func init() {
+	Register(Registration{
+		Name:        SoundCloudPlatform,
+		DisplayName: "SoundCloud",
+		Credentials: []CredentialSpec{
+			{Key: "client_id", EnvVar: "SOUNDCLOUD_CLIENT_ID", Description: "SoundCloud app client ID"},
+			{Key: "client_secret", EnvVar: "SOUNDCLOUD_CLIENT_SECRET", Description: "SoundCloud app client secret"},
+		},
+		Capabilities: soundCloudCapabilities,
+		New: func(creds Credentials, opts ...Option) (ApiAdapter, error) {
+			return NewSoundCloudAdapter(creds["client_id"], creds["client_secret"], opts...)
+		},
+	})
+}
+
+// SoundCloudAdapter adapts the SoundCloud API to our common adapter interface
+type SoundCloudAdapter struct {
+	BaseAdapter
+	clientID     string
+	clientSecret string
+	opts         options
+	api          *httpAPI
+	ch           chan *oauth2.Token
+	state        string
+	verifier     string
+}
+
+// NewSoundCloudAdapter creates a new SoundCloudAdapter
+func NewSoundCloudAdapter(clientID, clientSecret string, opts ...Option) (*SoundCloudAdapter, error) {
+	if clientID == "" || clientSecret == "" {
+		return nil, fmt.Errorf("soundcloud client ID and secret must be provided")
+	}
+
+	return &SoundCloudAdapter{
+		BaseAdapter:  NewBaseAdapter("SoundCloud"),
+		clientID:     clientID,
+		clientSecret: clientSecret,
+		opts: newOptions(options{
+			baseURL:  "https://api.soundcloud.com/",
+			authURL:  "https://secure.soundcloud.com/authorize",
+			tokenURL: "https://secure.soundcloud.com/oauth/token",
+		}, opts),
+		ch:       make(chan *oauth2.Token),
+		state:    utils.GenerateState(),
+		verifier: oauth2.GenerateVerifier(),
+	}, nil
+}
+
+// Authenticate handles user authentication with SoundCloud. SoundCloud's OAuth 2.1 flow
+// requires a PKCE code challenge.
+func (a *SoundCloudAdapter) Authenticate(ctx context.Context) error {
+	config := &oauth2.Config{
+		ClientID:     a.clientID,
+		ClientSecret: a.clientSecret,
+		RedirectURL:  soundCloudRedirectURI,
+		Endpoint: oauth2.Endpoint{
+			AuthURL:  a.opts.authURL,
+			TokenURL: a.opts.tokenURL,
+		},
+	}
+
+	token := a.opts.token
+	if token == nil {
+		mux := http.NewServeMux()
+		mux.HandleFunc("/callback", func(w http.ResponseWriter, r *http.Request) {
+			a.completeAuth(w, r, config)
+		})
+		server := startCallbackServer(mux)
+
+		authURL := config.AuthCodeURL(a.state, oauth2.S256ChallengeOption(a.verifier))
+		fmt.Println("Please log in to SoundCloud by visiting the following page in your browser:", authURL)
+		utils.OpenBrowser(authURL)
+
+		var err error
+		token, err = awaitCallback(ctx, a.ch, server)
+		if err != nil {
+			return err
+		}
+	}
+
+	// The token source outlives any single request, so it must not use a request's context
+	a.api = newHTTPAPI(a.opts)
+	a.api.client = config.Client(a.opts.oauthContext(context.Background()), token)
+	a.api.decodeError = soundCloudError
+
+	var user struct {
+		Username string `json:"username"`
+	}
+	if err := a.api.get(ctx, "me", nil, &user); err != nil {
+		return fmt.Errorf("authentication failed: %v", err)
+	}
+
+	fmt.Println("You are logged in as:", user.Username)
+	a.SetAuthenticated(true)
+	return nil
+}
+
+// completeAuth is the callback handler for the SoundCloud auth flow
+func (a *SoundCloudAdapter) completeAuth(w http.ResponseWriter, r *http.Request, config *oauth2.Config) {
+	if st := r.FormValue("state"); st != a.state {
+		http.NotFound(w, r)
+		log.Fatalf("State mismatch: %s != %s\n", st, a.state)
+	}
+
+	tok, err := config.Exchange(a.opts.oauthContext(r.Context()), r.FormValue("code"), oauth2.VerifierOption(a.verifier))
+	if err != nil {
+		http.Error(w, "Couldn't get token", http.StatusForbidden)
+		log.Fatal(err)
+	}
+
+	fmt.Fprintf(w, "Login Completed! You can now close this window.")
+	a.ch <- tok
+}
+
+type soundCloudPlaylist struct {
+	ID          jsonID `json:"id"`
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	TrackCount  int    `json:"track_count"`
+	CreatedAt   string `json:"created_at"`
+}
+
+type soundCloudTrack struct {
+	ID           jsonID `json:"id"`
+	Title        string `json:"title"`
+	PermalinkURL string `json:"permalink_url"`
+	// Duration is in milliseconds
+	Duration      int    `json:"duration"`
+	PlaybackCount int    `json:"playback_count"`
+	Access        string `json:"access"`
+	ReleaseYear   int    `json:"release_year"`
+	ReleaseMonth  int    `json:"release_month"`
+	ReleaseDay    int    `json:"release_day"`
+	User          struct {
+		ID       jsonID `json:"id"`
+		Username string `json:"username"`
+	} `json:"user"`
+	// PublisherMetadata is filled in by labels and distributors; most uploads don't have it
+	PublisherMetadata *struct {
+		Artist     string `json:"artist"`
+		AlbumTitle string `json:"album_title"`
+		ISRC       string `json:"isrc"`
+		Explicit   bool   `json:"explicit"`
+	} `json:"publisher_metadata"`
+}
+
+// soundCloudPage is a page of a list requested with linked_partitioning. NextHref is the
+// absolute URL of the next page.
+type soundCloudPage[T any] struct {
+	Collection []T    `json:"collection"`
+	NextHref   string `json:"next_href"`
+}
+
+// soundCloudCreated is the layout of SoundCloud's timestamps, e.g. 2021/03/04 10:00:00 +0000
+const soundCloudCreated = "2006/01/02 15:04:05 -0700"
+
+// GetUserPlaylists retrieves all playlists for the authenticated user
+func (a *SoundCloudAdapter) GetUserPlaylists(ctx context.Context) ([]playlist.Playlist, error) {
+	return collect(a.UserPlaylists(ctx))
+}
+
+// UserPlaylists streams the authenticated user's playlists ("sets") page by page, after the
+// liked tracks, which are listed as a playlist of their own
+func (a *SoundCloudAdapter) UserPlaylists(ctx context.Context) iter.Seq2[playlist.Playlist, error] {
+	return func(yield func(playlist.Playlist, error) bool) {
+		if err := a.CheckAuth(); err != nil {
+			yield(playlist.Playlist{}, err)
+			return
+		}
+
+		if !yield(playlist.Playlist{ID: SoundCloudLikesID, Name: "Liked tracks"}, nil) {
+			return
+		}
+
+		query := url.Values{"show_tracks": {"false"}}
+		for page, err := range soundCloudPages[soundCloudPlaylist](ctx, a.api, "me/playlists", query) {
+			if err != nil {
+				yield(playlist.Playlist{}, fmt.Errorf("error getting playlists: %v", err))
+				return
+			}
+			for _, p := range page {
+				created, _ := time.Parse(soundCloudCreated, p.CreatedAt)
+				if !yield(playlist.Playlist{
+					ID:          string(p.ID),
+					Name:        p.Title,
+					Description: p.Description,
+					TrackCount:  p.TrackCount,
+					CreatedAt:   created,
+				}, nil) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// GetPlaylistItems retrieves all tracks in a playlist
+func (a *SoundCloudAdapter) GetPlaylistItems(ctx context.Context, playlistID string) ([]playlist.Track, error) {
+	return collect(a.PlaylistItems(ctx, playlistID))
+}
+
+// PlaylistItems streams the tracks of a playlist, or the liked tracks, page by page
+func (a *SoundCloudAdapter) PlaylistItems(ctx context.Context, playlistID string) iter.Seq2[playlist.Track, error] {
+	return func(yield func(playlist.Track, error) bool) {
+		if err := a.CheckAuth(); err != nil {
+			yield(playlist.Track{}, err)
+			return
+		}
+
+		path := "playlists/" + url.PathEscape(playlistID) + "/tracks"
+		if playlistID == SoundCloudLikesID {
+			path = "me/likes/tracks"
+		}
+		for page, err := range soundCloudPages[soundCloudTrack](ctx, a.api, path, nil) {
+			if err != nil {
+				yield(playlist.Track{}, fmt.Errorf("error getting playlist items: %v", err))
+				return
+			}
+			for _, t := range page {
+				if !yield(soundCloudTrackToTrack(t), nil) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// soundCloudPages follows the next links of a SoundCloud list
+func soundCloudPages[T any](ctx context.Context, api *httpAPI, path string, query url.Values) iter.Seq2[[]T, error] {
+	return func(yield func([]T, error) bool) {
+		if query == nil {
+			query = url.Values{}
+		}
+		query.Set("linked_partitioning", "true")
+		query.Set("limit", strconv.Itoa(soundCloudPageSize))
+
+		for path != "" {
+			var page soundCloudPage[T]
+			if err := api.get(ctx, path, query, &page); err != nil {
+				yield(nil, err)
+				return
+			}
+			if !yield(page.Collection, nil) {
+				return
+			}
+			// Next links already carry the query
+			path, query = page.NextHref, nil
+		}
+	}
+}
+
+// CreateNewPlaylist creates a new SoundCloud playlist ("set")
+func (a *SoundCloudAdapter) CreateNewPlaylist(ctx context.Context, name string, description string, privacy Privacy) (playlist.Playlist, error) {
+	if err := a.CheckAuth(); err != nil {
+		return playlist.Playlist{}, err
+	}
+
+	sharing := "private"
+	if privacy == PrivacyPublic {
+		sharing = "public"
+	}
+	body := map[string]any{
+		"playlist": map[string]any{
+			"title":       name,
+			"description": description,
+			"sharing":     sharing,
+			"tracks":      []any{},
+		},
+	}
+
+	var created soundCloudPlaylist
+	if err := a.api.post(ctx, "playlists", body, &created); err != nil {
+		return playlist.Playlist{}, fmt.Errorf("error creating playlist: %v", err)
+	}
+
+	return playlist.Playlist{
+		ID:          string(created.ID),
+		Name:        name,
+		Description: description,
+		CreatedAt:   time.Now(),
+	}, nil
+}
+
+// AddItemsToPlaylist appends tracks to a SoundCloud playlist. SoundCloud can only replace a
+// playlist's tracks as a whole, so the current tracks are read and sent back with the new ones.
+// Adding to the liked tracks likes each track instead.
+func (a *SoundCloudAdapter) AddItemsToPlaylist(ctx context.Context, playlistID string, trackIDs []string) error {
+	if err := a.CheckAuth(); err != nil {
+		return err
+	}
+
+	if playlistID == SoundCloudLikesID {
+		for _, id := range trackIDs {
+			if err := a.api.post(ctx, "likes/tracks/"+url.PathEscape(id), nil, nil); err != nil {
+				return fmt.Errorf("error liking track %s: %v", id, err)
+			}
+		}
+		return nil
+	}
+
+	var tracks []map[string]string
+	for t, err := range a.PlaylistItems(ctx, playlistID) {
+		if err != nil {
+			return err
+		}
+		tracks = append(tracks, map[string]string{"id": t.ID})
+	}
+	for _, id := range trackIDs {
+		tracks = append(tracks, map[string]string{"id": id})
+	}
+	if len(tracks) > soundCloudMaxPlaylistTracks {
+		return fmt.Errorf("soundcloud playlists hold at most %d tracks", soundCloudMaxPlaylistTracks)
+	}
+
+	body := map[string]any{"playlist": map[string]any{"tracks": tracks}}
+	path := "playlists/" + url.PathEscape(playlistID)
+	if err := a.api.do(ctx, http.MethodPut, path, nil, body, nil); err != nil {
+		return fmt.Errorf("error adding tracks to playlist: %v", err)
+	}
+	return nil
+}
+
+// SearchTracks searches for tracks on SoundCloud
+func (a *SoundCloudAdapter) SearchTracks(ctx context.Context, query string, limit int) ([]playlist.Track, error) {
+	if err := a.CheckAuth(); err != nil {
+		return nil, err
+	}
+
+	if limit <= 0 || limit > soundCloudCapabilities.MaxSearchLimit {
+		limit = soundCloudCapabilities.MaxSearchLimit
+	}
+
+	var page soundCloudPage[soundCloudTrack]
+	params := url.Values{"q": {query}, "limit": {strconv.Itoa(limit)}, "linked_partitioning": {"true"}}
+	if err := a.api.get(ctx, "tracks", params, &page); err != nil {
+		return nil, fmt.Errorf("error searching tracks: %v", err)
+	}
+	return soundCloudPlayable(page.Collection)
+}
+
+// SearchTracksBy searches for tracks on SoundCloud. SoundCloud has no structured search, so the
+// fields are combined into a plain text search.
+func (a *SoundCloudAdapter) SearchTracksBy(ctx context.Context, query SearchQuery, limit int) ([]playlist.Track, error) {
+	text := query.FreeText()
+	if text == "" {
+		return nil, nil
+	}
+	return a.SearchTracks(ctx, text, limit)
+}
+
+// Capabilities describes the SoundCloud API limits
+func (a *SoundCloudAdapter) Capabilities() Capabilities {
+	return soundCloudCapabilities
+}
+
+// soundCloudPlayable converts tracks, dropping the ones that are blocked in the user's country
+func soundCloudPlayable(results []soundCloudTrack) ([]playlist.Track, error) {
+	var tracks []playlist.Track
+	var blocked int
+	for _, t := range results {
+		if t.Access == "blocked" {
+			blocked++
+			continue
+		}
+		tracks = append(tracks, soundCloudTrackToTrack(t))
+	}
+	if len(tracks) == 0 && blocked > 0 {
+		return nil, ErrRegionUnavailable
+	}
+	return tracks, nil
+}
+
+// soundCloudTrackToTrack converts a SoundCloud track into our track model. Most uploads only
+// have a free-form title like "Artist - Title (X Remix)", which is parsed the same way as
+// YouTube video titles, with the uploader standing in for the artist.
+func soundCloudTrackToTrack(t soundCloudTrack) playlist.Track {
+	parsed := ParseVideoTitle(t.Title, t.User.Username)
+	track := playlist.Track{
+		Name:       parsed.Title,
+		Artists:    parsed.Artists,
+		ID:         string(t.ID),
+		URL:        t.PermalinkURL,
+		Version:    parsed.Version,
+		DurationMs: t.Duration,
+		// Play counts are unbounded; scale them to the 0-100 popularity of other platforms
+		Popularity: min(t.PlaybackCount/10000, 100),
+	}
+	if t.ReleaseYear > 0 {
+		track.ReleaseDate = strconv.Itoa(t.ReleaseYear)
+		if t.ReleaseMonth > 0 && t.ReleaseDay > 0 {
+			track.ReleaseDate = fmt.Sprintf("%04d-%02d-%02d", t.ReleaseYear, t.ReleaseMonth, t.ReleaseDay)
+		}
+	}
+	if m := t.PublisherMetadata; m != nil {
+		// The publisher's artist, when set, is more reliable than the one in the title
+		if m.Artist != "" {
+			track.Artists = []string{m.Artist}
+		}
+		track.Album = m.AlbumTitle
+		track.ISRC = m.ISRC
+		track.Explicit = m.Explicit
+	}
+	// The uploader's ID only belongs to the artist when the track is the uploader's own
+	if len(track.Artists) == 1 && t.User.ID != "" && strings.EqualFold(track.Artists[0], NormalizeChannelName(t.User.Username)) {
+		track.ArtistIDs = []string{string(t.User.ID)}
+	}
+	return track
+}
+
+// soundCloudError decodes the error body of a SoundCloud response
+func soundCloudError(status int, body []byte) error {
+	var resp struct {
+		Message string `json:"message"`
+		Error   string `json:"error"`
+		Errors  []struct {
+			ErrorMessage string `json:"error_message"`
+		} `json:"errors"`
+	}
+	if json.Unmarshal(body, &resp) != nil {
+		return nil
+	}
+	message := resp.Message
+	if message == "" && len(resp.Errors) > 0 {
+		message = resp.Errors[0].ErrorMessage
+	}
+	if message == "" {
+		message = resp.Error
+	}
+	if message == "" {
+		return nil
+	}
+	return fmt.Errorf("soundcloud: %s (HTTP %d)", message, status)
+}
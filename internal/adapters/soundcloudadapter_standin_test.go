@@ -0,0 +1,121 @@
+package adapters_test
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"golang.org/x/oauth2"
+
+	"soundporter/internal/adapters"
+	"soundporter/internal/playlist"
+	"soundporter/internal/standin"
+)
+
+// startSoundCloud starts a SoundCloud stand-in for the conformance suite
+func startSoundCloud(t *testing.T, catalog []playlist.Track, playlists []playlist.Playlist) (*standin.SoundCloud, standinRun) {
+	t.Helper()
+	s := standin.NewSoundCloud(catalog, playlists)
+	t.Cleanup(s.Close)
+	withToken := func(token *oauth2.Token) adapters.ApiAdapter {
+		a, err := adapters.NewSoundCloudAdapter("client-id", "client-secret", append(s.Options(), adapters.WithToken(token))...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return a
+	}
+	return s, standinRun{
+		adapter: withToken(s.OAuth.Token()),
+		// Expired tokens are refreshed through the token endpoint, and the /me lookup
+		// Authenticate makes rejects a revoked token
+		accepted:    map[string]adapters.ApiAdapter{"an expired token": withToken(s.OAuth.ExpiredToken())},
+		refused:     map[string]adapters.ApiAdapter{"a revoked token": withToken(&oauth2.Token{AccessToken: "revoked"})},
+		stored:      s,
+		unavailable: s.Unavailable,
+	}
+}
+
+func TestSoundCloudStandinTracks(t *testing.T) {
+	ctx := context.Background()
+	_, run := startSoundCloud(t, nil, nil)
+	a := authenticate(t, run.adapter)
+	catalog := adapters.FakeCatalog()
+
+	tracks, err := a.GetPlaylistItems(ctx, "fake-playlist-3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Titles are parsed for the version, and the publisher metadata fills in the rest
+	for _, i := range []int{3, 9} {
+		want, got := catalog[i], tracks[i]
+		if got.Name != want.Name || got.Version != want.Version || got.ISRC != want.ISRC || got.Album != want.Album ||
+			got.DurationMs != want.DurationMs || got.ReleaseDate != want.ReleaseDate {
+			t.Errorf("track %d converted to %+v, want %+v", i, got, want)
+		}
+		if !slices.Equal(got.Artists, want.Artists) || !slices.Equal(got.ArtistIDs, want.ArtistIDs[:1]) {
+			t.Errorf("track %d has artists %q %v, want %q %v", i, got.Artists, got.ArtistIDs, want.Artists, want.ArtistIDs[:1])
+		}
+	}
+	// The publisher's artist replaces the one parsed from the title, and credits more than the
+	// uploader, whose ID is left out
+	if got := tracks[12]; !slices.Equal(got.Artists, []string{"Data Race, Fencepost"}) || got.ArtistIDs != nil {
+		t.Fatalf("publisher artist converted to %q %v", got.Artists, got.ArtistIDs)
+	}
+
+	found, err := a.SearchTracksBy(ctx, adapters.SearchQuery{Track: "Golden Master", Artist: "Mock Orchestra"}, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(found) != 1 || found[0].ISRC != catalog[5].ISRC {
+		t.Fatalf("search result converted to %+v", found)
+	}
+}
+
+func TestSoundCloudStandinLikes(t *testing.T) {
+	ctx := context.Background()
+	s, run := startSoundCloud(t, nil, nil)
+	a := authenticate(t, run.adapter)
+
+	liked, err := a.GetPlaylistItems(ctx, adapters.SoundCloudLikesID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(trackIDs(liked), []string{"fake-01", "fake-02"}) {
+		t.Fatalf("unexpected liked tracks %v", trackIDs(liked))
+	}
+
+	// Adding to the liked tracks likes each track
+	if err := a.AddItemsToPlaylist(ctx, adapters.SoundCloudLikesID, []string{"fake-05", "fake-01"}); err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(s.Likes, []string{"fake-05", "fake-01", "fake-02"}) {
+		t.Fatalf("unexpected likes %v", s.Likes)
+	}
+}
+
+func TestSoundCloudStandinPlaylistLimit(t *testing.T) {
+	ctx := context.Background()
+	catalog, playlists := pagedLibrary(450, 3)
+	s, run := startSoundCloud(t, catalog, playlists)
+	a := authenticate(t, run.adapter)
+
+	p, err := a.CreateNewPlaylist(ctx, "Copy", "", adapters.PrivacyPublic)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Each batch rewrites the whole track list, which is read back across pages first
+	batch := a.Capabilities().MaxBatchSize
+	ids := trackIDs(catalog)
+	for start := 0; start < len(ids); start += batch {
+		if err := a.AddItemsToPlaylist(ctx, p.ID, ids[start:min(start+batch, len(ids))]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Playlists are capped at 500 tracks before anything is sent
+	if err := a.AddItemsToPlaylist(ctx, p.ID, ids[:51]); err == nil {
+		t.Fatal("expected a playlist over 500 tracks to be refused")
+	}
+	if created, _ := s.Playlist(p.ID); len(created.Tracks) != len(ids) {
+		t.Fatalf("refused batch added tracks: %d", len(created.Tracks))
+	}
+}
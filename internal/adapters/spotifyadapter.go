@@ -3,82 +3,122 @@ package adapters
 import (
 	"context"
 	"fmt"
+	"iter"
 	"log"
 	"net/http"
-	"os"
 	"soundporter/internal/playlist"
 	"soundporter/internal/utils"
+	"strings"
 	"time"
 
 	"github.com/zmb3/spotify/v2"
 	spotifyauth "github.com/zmb3/spotify/v2/auth"
+	"golang.org/x/oauth2"
 )
 
 const spotifyRedirectURI = "http://localhost:8080/callback"
 
+var spotifyCapabilities = Capabilities{
+	MaxBatchSize:         100,
+	MaxSearchLimit:       50,
+	SearchFields:         []SearchField{SearchFieldISRC, SearchFieldArtist, SearchFieldTrack, SearchFieldAlbum},
+	MaxDescriptionLength: 300,
+	PrivacyLevels:        []Privacy{PrivacyPrivate, PrivacyPublic},
+	CanReorderItems:      true,
+	CanRemoveItems:       true,
+}
+
+func init() {
+	Register(Registration{
+		Name:        SpotifyPlatform,
+		DisplayName: "Spotify",
+		Credentials: []CredentialSpec{
+			{Key: "client_id", EnvVar: "SPOTIFY_ID", Description: "Spotify app client ID"},
+			{Key: "client_secret", EnvVar: "SPOTIFY_SECRET", Description: "Spotify app client secret"},
+		},
+		Capabilities: spotifyCapabilities,
+		New: func(creds Credentials, opts ...Option) (ApiAdapter, error) {
+			return NewSpotifyAdapter(creds["client_id"], creds["client_secret"], opts...)
+		},
+	})
+}
+
 // SpotifyAdapter adapts the Spotify API to our common adapter interface
 type SpotifyAdapter struct {
 	BaseAdapter  // Embed the BaseAdapter
 	client       *spotify.Client
 	clientID     string
 	clientSecret string
+	opts         options
 	ch           chan *spotify.Client
 	state        string
 }
 
 // NewSpotifyAdapter creates a new SpotifyAdapter
-func NewSpotifyAdapter(clientID, clientSecret string) (*SpotifyAdapter, error) {
-	if clientID == "" {
-		clientID = os.Getenv("SPOTIFY_ID")
-	}
-	if clientSecret == "" {
-		clientSecret = os.Getenv("SPOTIFY_SECRET")
-	}
+func NewSpotifyAdapter(clientID, clientSecret string, opts ...Option) (*SpotifyAdapter, error) {
 	if clientID == "" || clientSecret == "" {
-		return nil, fmt.Errorf("spotify client ID and secret must be provided or set in environment variables")
+		return nil, fmt.Errorf("spotify client ID and secret must be provided")
 	}
 
 	return &SpotifyAdapter{
 		BaseAdapter:  NewBaseAdapter("Spotify"),
 		clientID:     clientID,
 		clientSecret: clientSecret,
-		ch:           make(chan *spotify.Client),
-		state:        utils.GenerateState(),
+		opts: newOptions(options{
+			baseURL:  "https://api.spotify.com/v1/",
+			authURL:  spotifyauth.AuthURL,
+			tokenURL: spotifyauth.TokenURL,
+		}, opts),
+		ch:    make(chan *spotify.Client),
+		state: utils.GenerateState(),
 	}, nil
 }
 
 // Authenticate handles user authentication with Spotify
-func (a *SpotifyAdapter) Authenticate() error {
-	auth := spotifyauth.New(
-		spotifyauth.WithRedirectURL(spotifyRedirectURI),
-		spotifyauth.WithScopes(spotifyauth.ScopeUserReadPrivate, spotifyauth.ScopePlaylistReadPrivate, spotifyauth.ScopePlaylistModifyPrivate),
-		spotifyauth.WithClientID(a.clientID),
-		spotifyauth.WithClientSecret(a.clientSecret),
-	)
+func (a *SpotifyAdapter) Authenticate(ctx context.Context) error {
+	config := &oauth2.Config{
+		ClientID:     a.clientID,
+		ClientSecret: a.clientSecret,
+		RedirectURL:  spotifyRedirectURI,
+		Scopes: []string{
+			spotifyauth.ScopeUserReadPrivate,
+			spotifyauth.ScopePlaylistReadPrivate,
+			spotifyauth.ScopePlaylistModifyPrivate,
+			spotifyauth.ScopePlaylistModifyPublic,
+		},
+		Endpoint: oauth2.Endpoint{
+			AuthURL:  a.opts.authURL,
+			TokenURL: a.opts.tokenURL,
+		},
+	}
 
-	http.HandleFunc("/callback", func(w http.ResponseWriter, r *http.Request) {
-		a.completeAuth(w, r, auth)
-	})
+	if a.opts.token != nil {
+		a.client = a.newClient(config, a.opts.token)
+	} else {
+		mux := http.NewServeMux()
+		mux.HandleFunc("/callback", func(w http.ResponseWriter, r *http.Request) {
+			a.completeAuth(w, r, config)
+		})
+		server := startCallbackServer(mux)
+
+		// Open browser for authentication
+		url := config.AuthCodeURL(a.state)
+		fmt.Println("Please log in to Spotify by visiting the following page in your browser:", url)
+		utils.OpenBrowser(url)
 
-	go func() {
-		err := http.ListenAndServe(":8080", nil)
+		// Wait for authentication to complete
+		client, err := awaitCallback(ctx, a.ch, server)
 		if err != nil {
-			log.Fatal(err)
+			return err
 		}
-	}()
-
-	// Open browser for authentication
-	url := auth.AuthURL(a.state)
-	fmt.Println("Please log in to Spotify by visiting the following page in your browser:", url)
-	utils.OpenBrowser(url)
-
-	// Wait for authentication to complete
-	a.client = <-a.ch
+		a.client = client
+	}
 	a.SetAuthenticated(true)
 
 	// Verify authentication by getting user info
-	user, err := a.client.CurrentUser(context.Background())
+	user, err := a.client.CurrentUser(ctx)
 	if err != nil {
+		a.SetAuthenticated(false)
 		return fmt.Errorf("authentication failed: %v", err)
 	}
 
@@ -86,101 +126,107 @@ func (a *SpotifyAdapter) Authenticate() error {
 	return nil
 }
 
-// GetUserPlaylists retrieves all playlists for the authenticated user
-func (a *SpotifyAdapter) GetUserPlaylists() ([]playlist.Playlist, error) {
-	if err := a.CheckAuth(); err != nil {
-		return nil, err
-	}
+// newClient creates a Spotify client that authorizes requests with the token, refreshing it when needed
+func (a *SpotifyAdapter) newClient(config *oauth2.Config, token *oauth2.Token) *spotify.Client {
+	// The token source outlives any single request, so it must not use a request's context
+	httpClient := config.Client(a.opts.oauthContext(context.Background()), token)
+	return spotify.New(httpClient, spotify.WithBaseURL(a.opts.baseURL))
+}
 
-	ctx := context.Background()
-	var allPlaylists []playlist.Playlist
-	limit := 50
-	offset := 0
+// GetUserPlaylists retrieves all playlists for the authenticated user
+func (a *SpotifyAdapter) GetUserPlaylists(ctx context.Context) ([]playlist.Playlist, error) {
+	return collect(a.UserPlaylists(ctx))
+}
 
-	for {
-		playlistPage, err := a.client.CurrentUsersPlaylists(ctx, spotify.Limit(limit), spotify.Offset(offset))
-		if err != nil {
-			return nil, fmt.Errorf("error getting playlists: %v", err)
+// UserPlaylists streams the authenticated user's playlists page by page
+func (a *SpotifyAdapter) UserPlaylists(ctx context.Context) iter.Seq2[playlist.Playlist, error] {
+	return func(yield func(playlist.Playlist, error) bool) {
+		if err := a.CheckAuth(); err != nil {
+			yield(playlist.Playlist{}, err)
+			return
 		}
 
-		for _, p := range playlistPage.Playlists {
-			allPlaylists = append(allPlaylists, playlist.Playlist{
-				ID:         string(p.ID),
-				Name:       p.Name,
-				TrackCount: int(p.Tracks.Total),
-				CreatedAt:  time.Now(), // Spotify doesn't provide creation date easily
-			})
-		}
+		limit := 50
+		offset := 0
+
+		for {
+			playlistPage, err := a.client.CurrentUsersPlaylists(ctx, spotify.Limit(limit), spotify.Offset(offset))
+			if err != nil {
+				yield(playlist.Playlist{}, fmt.Errorf("error getting playlists: %v", err))
+				return
+			}
 
-		if len(playlistPage.Playlists) < limit {
-			break
+			for _, p := range playlistPage.Playlists {
+				if !yield(playlist.Playlist{
+					ID:         string(p.ID),
+					Name:       p.Name,
+					TrackCount: int(p.Tracks.Total),
+					CreatedAt:  time.Now(), // Spotify doesn't provide creation date easily
+				}, nil) {
+					return
+				}
+			}
+
+			if len(playlistPage.Playlists) < limit {
+				return
+			}
+			offset += limit
 		}
-		offset += limit
 	}
-
-	return allPlaylists, nil
 }
 
 // GetPlaylistItems retrieves all tracks in a playlist
-func (a *SpotifyAdapter) GetPlaylistItems(playlistID string) ([]playlist.Track, error) {
-	if err := a.CheckAuth(); err != nil {
-		return nil, err
-	}
+func (a *SpotifyAdapter) GetPlaylistItems(ctx context.Context, playlistID string) ([]playlist.Track, error) {
+	return collect(a.PlaylistItems(ctx, playlistID))
+}
 
-	ctx := context.Background()
-	var tracks []playlist.Track
-	limit := 50
-	offset := 0
-
-	for {
-		playlistItems, err := a.client.GetPlaylistItems(
-			ctx,
-			spotify.ID(playlistID),
-			spotify.Limit(limit),
-			spotify.Offset(offset),
-		)
-		if err != nil {
-			return nil, fmt.Errorf("error getting playlist items: %v", err)
+// PlaylistItems streams the tracks of a playlist page by page
+func (a *SpotifyAdapter) PlaylistItems(ctx context.Context, playlistID string) iter.Seq2[playlist.Track, error] {
+	return func(yield func(playlist.Track, error) bool) {
+		if err := a.CheckAuth(); err != nil {
+			yield(playlist.Track{}, err)
+			return
 		}
 
-		for _, item := range playlistItems.Items {
-			track := item.Track.Track
-
-			// Convert artists
-			var artistNames []string
-			var artistIDs []string
-			for _, artist := range track.Artists {
-				artistNames = append(artistNames, artist.Name)
-				artistIDs = append(artistIDs, string(artist.ID))
+		limit := 50
+		offset := 0
+
+		for {
+			playlistItems, err := a.client.GetPlaylistItems(
+				ctx,
+				spotify.ID(playlistID),
+				spotify.Limit(limit),
+				spotify.Offset(offset),
+			)
+			if err != nil {
+				yield(playlist.Track{}, fmt.Errorf("error getting playlist items: %v", err))
+				return
 			}
 
-			tracks = append(tracks, playlist.Track{
-				Name:      track.Name,
-				Artists:   artistNames,
-				Album:     track.Album.Name,
-				ID:        string(track.ID),
-				ArtistIDs: artistIDs,
-				AlbumID:   string(track.Album.ID),
-				URL:       fmt.Sprintf("https://open.spotify.com/track/%s", track.ID),
-			})
-		}
+			for _, item := range playlistItems.Items {
+				// Episodes and tracks unavailable in the user's market come back without a track
+				if item.Track.Track == nil {
+					continue
+				}
+				if !yield(spotifyTrack(item.Track.Track), nil) {
+					return
+				}
+			}
 
-		if len(playlistItems.Items) < limit {
-			break
+			if len(playlistItems.Items) < limit {
+				return
+			}
+			offset += limit
 		}
-		offset += limit
 	}
-
-	return tracks, nil
 }
 
 // CreateNewPlaylist creates a new Spotify playlist
-func (a *SpotifyAdapter) CreateNewPlaylist(name string, description string) (playlist.Playlist, error) {
+func (a *SpotifyAdapter) CreateNewPlaylist(ctx context.Context, name string, description string, privacy Privacy) (playlist.Playlist, error) {
 	if err := a.CheckAuth(); err != nil {
 		return playlist.Playlist{}, err
 	}
 
-	ctx := context.Background()
 	user, err := a.client.CurrentUser(ctx)
 	if err != nil {
 		return playlist.Playlist{}, fmt.Errorf("error getting current user: %v", err)
@@ -191,7 +237,7 @@ func (a *SpotifyAdapter) CreateNewPlaylist(name string, description string) (pla
 		user.ID,
 		name,
 		description,
-		false, // Not public
+		privacy == PrivacyPublic,
 		false, // Not collaborative
 	)
 	if err != nil {
@@ -208,7 +254,7 @@ func (a *SpotifyAdapter) CreateNewPlaylist(name string, description string) (pla
 }
 
 // AddItemsToPlaylist adds tracks to a Spotify playlist
-func (a *SpotifyAdapter) AddItemsToPlaylist(playlistID string, trackIDs []string) error {
+func (a *SpotifyAdapter) AddItemsToPlaylist(ctx context.Context, playlistID string, trackIDs []string) error {
 	if err := a.CheckAuth(); err != nil {
 		return err
 	}
@@ -219,7 +265,6 @@ func (a *SpotifyAdapter) AddItemsToPlaylist(playlistID string, trackIDs []string
 		spotifyTrackIDs = append(spotifyTrackIDs, spotify.ID(id))
 	}
 
-	ctx := context.Background()
 	_, err := a.client.AddTracksToPlaylist(ctx, spotify.ID(playlistID), spotifyTrackIDs...)
 	if err != nil {
 		return fmt.Errorf("error adding tracks to playlist: %v", err)
@@ -229,64 +274,120 @@ func (a *SpotifyAdapter) AddItemsToPlaylist(playlistID string, trackIDs []string
 }
 
 // SearchTracks searches for tracks on Spotify
-func (a *SpotifyAdapter) SearchTracks(query string, limit int) ([]playlist.Track, error) {
+func (a *SpotifyAdapter) SearchTracks(ctx context.Context, query string, limit int) ([]playlist.Track, error) {
 	if err := a.CheckAuth(); err != nil {
 		return nil, err
 	}
 
-	if limit <= 0 || limit > 50 {
-		limit = 50 // Spotify API maximum is 50 per request
+	if limit <= 0 || limit > spotifyCapabilities.MaxSearchLimit {
+		limit = spotifyCapabilities.MaxSearchLimit
 	}
 
-	ctx := context.Background()
 	results, err := a.client.Search(
 		ctx,
 		query,
 		spotify.SearchTypeTrack,
 		spotify.Limit(limit),
+		spotify.Market(spotify.MarketFromToken),
 	)
 	if err != nil {
 		return nil, fmt.Errorf("error searching tracks: %v", err)
 	}
 
 	var tracks []playlist.Track
+	var unplayable int
 	for _, item := range results.Tracks.Tracks {
-		// Convert artists
-		var artistNames []string
-		var artistIDs []string
-		for _, artist := range item.Artists {
-			artistNames = append(artistNames, artist.Name)
-			artistIDs = append(artistIDs, string(artist.ID))
+		// Tracks that can't be played in the user's market can't be listened to once imported
+		if item.IsPlayable != nil && !*item.IsPlayable {
+			unplayable++
+			continue
 		}
 
-		tracks = append(tracks, playlist.Track{
-			Name:      item.Name,
-			Artists:   artistNames,
-			Album:     item.Album.Name,
-			ID:        string(item.ID),
-			ArtistIDs: artistIDs,
-			AlbumID:   string(item.Album.ID),
-			URL:       fmt.Sprintf("https://open.spotify.com/track/%s", item.ID),
-		})
+		tracks = append(tracks, spotifyTrack(&item))
+	}
+
+	if len(tracks) == 0 && unplayable > 0 {
+		return nil, ErrRegionUnavailable
 	}
 
 	return tracks, nil
 }
 
-// completeAuth is the callback handler for the Spotify auth flow
-func (a *SpotifyAdapter) completeAuth(w http.ResponseWriter, r *http.Request, auth *spotifyauth.Authenticator) {
-	tok, err := auth.Token(r.Context(), a.state, r)
-	if err != nil {
-		http.Error(w, "Couldn't get token", http.StatusForbidden)
-		log.Fatal(err)
+// SearchTracksBy searches for tracks on Spotify using field filters such as isrc: and artist:
+func (a *SpotifyAdapter) SearchTracksBy(ctx context.Context, query SearchQuery, limit int) ([]playlist.Track, error) {
+	q := spotifySearchQuery(query)
+	if q == "" {
+		return nil, nil
+	}
+	return a.SearchTracks(ctx, q, limit)
+}
+
+// spotifySearchQuery builds a search query from field filters. Spotify's query syntax has no
+// escapes, so values are wrapped in quotes with any quotes of their own removed.
+func spotifySearchQuery(query SearchQuery) string {
+	var filters []string
+	if query.ISRC != "" {
+		filters = append(filters, "isrc:"+query.ISRC)
 	}
+	for _, f := range []struct{ field, value string }{
+		{"track", query.Track},
+		{"artist", query.Artist},
+		{"album", query.Album},
+	} {
+		if value := strings.TrimSpace(strings.ReplaceAll(f.value, `"`, "")); value != "" {
+			filters = append(filters, f.field+`:"`+value+`"`)
+		}
+	}
+	return strings.Join(filters, " ")
+}
+
+// Capabilities describes the Spotify Web API limits
+func (a *SpotifyAdapter) Capabilities() Capabilities {
+	return spotifyCapabilities
+}
+
+// spotifyTrack converts a Spotify track into our track model
+func spotifyTrack(t *spotify.FullTrack) playlist.Track {
+	var artistNames []string
+	var artistIDs []string
+	for _, artist := range t.Artists {
+		artistNames = append(artistNames, artist.Name)
+		artistIDs = append(artistIDs, string(artist.ID))
+	}
+
+	return playlist.Track{
+		Name:        t.Name,
+		Artists:     artistNames,
+		Album:       t.Album.Name,
+		ID:          string(t.ID),
+		ArtistIDs:   artistIDs,
+		AlbumID:     string(t.Album.ID),
+		URL:         fmt.Sprintf("https://open.spotify.com/track/%s", t.ID),
+		ISRC:        t.ExternalIDs["isrc"],
+		DurationMs:  int(t.Duration),
+		Explicit:    t.Explicit,
+		Popularity:  int(t.Popularity),
+		DiscNumber:  int(t.DiscNumber),
+		TrackNumber: int(t.TrackNumber),
+		ReleaseDate: t.Album.ReleaseDate,
+	}
+}
+
+// completeAuth is the callback handler for the Spotify auth flow
+func (a *SpotifyAdapter) completeAuth(w http.ResponseWriter, r *http.Request, config *oauth2.Config) {
 	if st := r.FormValue("state"); st != a.state {
 		http.NotFound(w, r)
 		log.Fatalf("State mismatch: %s != %s\n", st, a.state)
 	}
 
+	tok, err := config.Exchange(a.opts.oauthContext(r.Context()), r.FormValue("code"))
+	if err != nil {
+		http.Error(w, "Couldn't get token", http.StatusForbidden)
+		log.Fatal(err)
+	}
+
 	// Use the token to get an authenticated client
-	client := spotify.New(auth.Client(r.Context(), tok))
+	client := a.newClient(config, tok)
 	fmt.Fprintf(w, "Login Completed! You can now close this window.")
 	a.ch <- client
 }
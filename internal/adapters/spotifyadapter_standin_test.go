@@ -0,0 +1,97 @@
+package adapters_test
+
+import (
+	"context"
+	"reflect"
+	"slices"
+	"testing"
+
+	"golang.org/x/oauth2"
+
+	"soundporter/internal/adapters"
+	"soundporter/internal/playlist"
+	"soundporter/internal/standin"
+)
+
+// startSpotify starts a Spotify stand-in for the conformance suite
+func startSpotify(t *testing.T, catalog []playlist.Track, playlists []playlist.Playlist) (*standin.Spotify, standinRun) {
+	t.Helper()
+	s := standin.NewSpotify(catalog, playlists)
+	t.Cleanup(s.Close)
+	withToken := func(token *oauth2.Token) adapters.ApiAdapter {
+		a, err := adapters.NewSpotifyAdapter("client-id", "client-secret", append(s.Options(), adapters.WithToken(token))...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return a
+	}
+	return s, standinRun{
+		adapter: withToken(s.OAuth.Token()),
+		// Expired tokens are refreshed through the token endpoint
+		accepted:    map[string]adapters.ApiAdapter{"an expired token": withToken(s.OAuth.ExpiredToken())},
+		refused:     map[string]adapters.ApiAdapter{"a revoked token": withToken(&oauth2.Token{AccessToken: "revoked"})},
+		stored:      s,
+		unavailable: s.Unavailable,
+	}
+}
+
+func TestSpotifyStandinTracks(t *testing.T) {
+	_, run := startSpotify(t, nil, nil)
+	a := authenticate(t, run.adapter)
+
+	tracks, err := a.GetPlaylistItems(context.Background(), "fake-playlist-3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := adapters.FakeCatalog()[12]
+	want.URL = "https://open.spotify.com/track/" + want.ID
+	if !reflect.DeepEqual(tracks[12], want) {
+		t.Fatalf("track not converted:\n got %+v\nwant %+v", tracks[12], want)
+	}
+}
+
+func TestSpotifyStandinSearchFilters(t *testing.T) {
+	ctx := context.Background()
+	_, run := startSpotify(t, nil, nil)
+	a := authenticate(t, run.adapter)
+
+	tracks, err := a.SearchTracksBy(ctx, adapters.SearchQuery{Track: "Green Build", Artist: "Continuous Integration"}, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(trackIDs(tracks), []string{"fake-08", "fake-10"}) {
+		t.Fatalf("structured search found %v", trackIDs(tracks))
+	}
+
+	// The artist filter has to match the artist, not just any field
+	tracks, err = a.SearchTracksBy(ctx, adapters.SearchQuery{Track: "Lorem Ipsum", Artist: "Mock Orchestra"}, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tracks) != 0 {
+		t.Fatalf("expected no results, got %v", trackIDs(tracks))
+	}
+
+	if tracks, err := a.SearchTracksBy(ctx, adapters.SearchQuery{}, 5); err != nil || tracks != nil {
+		t.Fatalf("empty query returned %v, %v", tracks, err)
+	}
+}
+
+func TestSpotifyStandinBatchLimit(t *testing.T) {
+	ctx := context.Background()
+	catalog, playlists := pagedLibrary(250, 3)
+	s, run := startSpotify(t, catalog, playlists)
+	a := authenticate(t, run.adapter)
+
+	p, err := a.CreateNewPlaylist(ctx, "Copy", "", adapters.PrivacyPrivate)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// A batch larger than the API accepts is rejected as a whole
+	if err := a.AddItemsToPlaylist(ctx, p.ID, trackIDs(catalog[:a.Capabilities().MaxBatchSize+1])); err == nil {
+		t.Fatal("expected an oversized batch to fail")
+	}
+	if created, _ := s.Playlist(p.ID); len(created.Tracks) != 0 {
+		t.Fatalf("oversized batch added tracks: %d", len(created.Tracks))
+	}
+}
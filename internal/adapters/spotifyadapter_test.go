@@ -0,0 +1,30 @@
+package adapters
+
+import "testing"
+
+func TestSpotifySearchQuery(t *testing.T) {
+	tests := []struct {
+		query SearchQuery
+		want  string
+	}{
+		{SearchQuery{}, ""},
+		{SearchQuery{ISRC: "GBUM71029604"}, "isrc:GBUM71029604"},
+		{SearchQuery{Track: "Bohemian Rhapsody", Artist: "Queen"}, `track:"Bohemian Rhapsody" artist:"Queen"`},
+		{SearchQuery{Track: "Café Del Mar", Artist: "Energy 52"}, `track:"Café Del Mar" artist:"Energy 52"`},
+		{SearchQuery{Track: `The "Real" Slim Shady`, Album: "The Marshall Mathers LP"}, `track:"The Real Slim Shady" album:"The Marshall Mathers LP"`},
+		{SearchQuery{Track: `""`, Artist: "Björk"}, `artist:"Björk"`},
+	}
+	for _, tt := range tests {
+		if got := spotifySearchQuery(tt.query); got != tt.want {
+			t.Errorf("spotifySearchQuery(%+v) = %s, want %s", tt.query, got, tt.want)
+		}
+		// The query must parse back into the same fields
+		if tt.want == "" {
+			continue
+		}
+		parsed := ParseSearchQuery(tt.want)
+		if parsed.Artist != tt.query.Artist || parsed.ISRC != tt.query.ISRC {
+			t.Errorf("query %s parsed as %+v", tt.want, parsed)
+		}
+	}
+}
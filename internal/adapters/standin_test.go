@@ -0,0 +1,321 @@
+package adapters_test
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"testing"
+
+	"soundporter/internal/adapters"
+	"soundporter/internal/playlist"
+)
+
+// pagedPlaylistID is the playlist pagedLibrary fills with the whole catalog
+const pagedPlaylistID = "paged-playlist"
+
+// pagedLibrary returns the fake catalog followed by generated tracks up to n tracks, the fake
+// playlists, and enough empty playlists to make playlists playlists in total. A further playlist
+// with ID pagedPlaylistID holds every track, so both listings span several pages.
+func pagedLibrary(n, playlists int) ([]playlist.Track, []playlist.Playlist) {
+	catalog := adapters.FakeCatalog()
+	for i := len(catalog); i < n; i++ {
+		id := fmt.Sprintf("filler-%03d", i+1)
+		catalog = append(catalog, playlist.Track{
+			Name:        fmt.Sprintf("Filler %d", i+1),
+			Artists:     []string{"Padding Band"},
+			Album:       "Bulk Data",
+			ID:          id,
+			ArtistIDs:   []string{"filler-artist-padding-band"},
+			AlbumID:     "filler-album-bulk-data",
+			ISRC:        fmt.Sprintf("XXFIL25%05d", i+1),
+			DurationMs:  (120 + i) * 1000,
+			DiscNumber:  1,
+			TrackNumber: i + 1,
+			ReleaseDate: "2025-06-01",
+		})
+	}
+
+	lists := adapters.FakePlaylists(catalog)
+	for i := len(lists); i < playlists-1; i++ {
+		lists = append(lists, playlist.Playlist{ID: fmt.Sprintf("empty-playlist-%d", i+1), Name: fmt.Sprintf("Empty %d", i+1)})
+	}
+	lists = append(lists, playlist.Playlist{ID: pagedPlaylistID, Name: "Paged", Tracks: slices.Clone(catalog)})
+	return catalog, lists
+}
+
+// trackIDs returns the IDs of tracks
+func trackIDs(tracks []playlist.Track) []string {
+	ids := make([]string, len(tracks))
+	for i, t := range tracks {
+		ids[i] = t.ID
+	}
+	return ids
+}
+
+// playlistIDs returns the IDs of playlists
+func playlistIDs(playlists []playlist.Playlist) []string {
+	ids := make([]string, len(playlists))
+	for i, p := range playlists {
+		ids[i] = p.ID
+	}
+	return ids
+}
+
+// standinRun is a started stand-in and the adapters the conformance suite runs against it
+type standinRun struct {
+	// adapter holds credentials the stand-in accepts, and isn't authenticated yet
+	adapter adapters.ApiAdapter
+	// accepted and refused hold other credentials the stand-in accepts and refuses, by what
+	// those credentials are
+	accepted, refused map[string]adapters.ApiAdapter
+	// stored looks up a playlist as the stand-in holds it
+	stored interface {
+		Playlist(id string) (playlist.Playlist, bool)
+	}
+	// unavailable, if set, is the stand-in's list of tracks not available in the user's region
+	unavailable map[string]bool
+}
+
+// standinCase plugs a stand-in into the conformance suite
+type standinCase struct {
+	name string
+	// start starts the stand-in seeded with catalog and playlists, where nil falls back to the
+	// fake adapter's seed data
+	start func(t *testing.T, catalog []playlist.Track, playlists []playlist.Playlist) standinRun
+	// tracks and playlists size the library read back, past a few of the platform's pages
+	tracks, playlists int
+	// before and after are the playlists the platform lists around the user's own
+	before, after []string
+	// pagedID is the playlist the paged playlist is read back from, if not pagedPlaylistID
+	pagedID string
+	// id returns the ID the adapter gives a catalog track, if not the catalog's
+	id func(playlist.Track) string
+	// isrc is set for platforms that search by ISRC
+	isrc bool
+	// atomic is set for platforms that refuse a whole batch naming an unknown track
+	atomic bool
+	// noPlaylists is set for platforms whose user has no playlists to list or create
+	noPlaylists bool
+	// noDescriptions is set for platforms whose API can't describe a playlist
+	noDescriptions bool
+}
+
+// standinCases are the stand-ins the conformance suite runs against
+var standinCases = []standinCase{
+	{name: "AppleMusic", start: plug(startAppleMusic), tracks: 250, playlists: 120, isrc: true, atomic: true},
+	{name: "Deezer", start: plug(startDeezer), tracks: 250, playlists: 120, isrc: true, atomic: true},
+	{name: "Jellyfin", start: plug(startJellyfin), tracks: 450, playlists: 250, noDescriptions: true},
+	{name: "Lastfm", start: plug(startLastfm), tracks: 450, pagedID: adapters.LastfmLovedID, id: lastfmID, noPlaylists: true},
+	{name: "ListenBrainz", start: plug(startListenBrainz), tracks: 450, playlists: 250,
+		after: []string{"lb-generated-1", "lb-generated-2"}, id: musicbrainzID},
+	{name: "Plex", start: plug(startPlex), tracks: 450, playlists: 250, atomic: true},
+	{name: "SoundCloud", start: plug(startSoundCloud), tracks: 450, playlists: 250, before: []string{adapters.SoundCloudLikesID}, atomic: true},
+	{name: "Spotify", start: plug(startSpotify), tracks: 130, playlists: 60, isrc: true, atomic: true},
+	{name: "Subsonic", start: plug(startSubsonic), tracks: 250, playlists: 120, atomic: true},
+	{name: "Tidal", start: plug(startTidal), tracks: 250, playlists: 120, isrc: true, atomic: true},
+	{name: "YouTube", start: plug(startYouTube), tracks: 130, playlists: 60},
+	{name: "YTMusic", start: plug(startYTMusic), tracks: 450, playlists: 25, before: []string{"LM"}, atomic: true},
+}
+
+// plug adapts a stand-in's start function to the suite, which has no use for the stand-in itself
+func plug[S any](start func(*testing.T, []playlist.Track, []playlist.Playlist) (S, standinRun)) func(*testing.T, []playlist.Track, []playlist.Playlist) standinRun {
+	return func(t *testing.T, catalog []playlist.Track, playlists []playlist.Playlist) standinRun {
+		t.Helper()
+		_, run := start(t, catalog, playlists)
+		return run
+	}
+}
+
+// authenticate authenticates a, failing the test if it can't
+func authenticate(t *testing.T, a adapters.ApiAdapter) adapters.ApiAdapter {
+	t.Helper()
+	if err := a.Authenticate(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	return a
+}
+
+// ids returns the IDs the case's adapter gives tracks
+func (c standinCase) ids(tracks []playlist.Track) []string {
+	if c.id == nil {
+		return trackIDs(tracks)
+	}
+	ids := make([]string, len(tracks))
+	for i, t := range tracks {
+		ids[i] = c.id(t)
+	}
+	return ids
+}
+
+// TestStandins runs every adapter against its stand-in through the same checks. What is
+// particular to a platform is tested next to its adapter.
+func TestStandins(t *testing.T) {
+	for _, c := range standinCases {
+		t.Run(c.name, func(t *testing.T) {
+			t.Run("Auth", c.testAuth)
+			t.Run("Pagination", c.testPagination)
+			t.Run("Search", c.testSearch)
+			if !c.noPlaylists {
+				t.Run("CreateAndAdd", c.testCreateAndAdd)
+			}
+		})
+	}
+}
+
+func (c standinCase) testAuth(t *testing.T) {
+	ctx := context.Background()
+	run := c.start(t, nil, nil)
+
+	if _, err := run.adapter.GetUserPlaylists(ctx); err == nil {
+		t.Fatal("expected an error before authenticating")
+	}
+	if err := run.adapter.Authenticate(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := run.adapter.GetUserPlaylists(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, a := range run.accepted {
+		if err := a.Authenticate(ctx); err != nil {
+			t.Errorf("%s failed authentication: %v", name, err)
+		} else if _, err := a.GetUserPlaylists(ctx); err != nil {
+			t.Errorf("%s authenticated, but failed: %v", name, err)
+		}
+	}
+	for name, a := range run.refused {
+		err := a.Authenticate(ctx)
+		if err == nil {
+			// Adapters that take a token on trust find out on the first call instead
+			_, err = a.GetUserPlaylists(ctx)
+		} else if a.IsAuthenticated() {
+			t.Errorf("%s failed authentication, but left the adapter authenticated", name)
+		}
+		if err == nil {
+			t.Errorf("expected %s to be refused", name)
+		}
+	}
+}
+
+func (c standinCase) testPagination(t *testing.T) {
+	ctx := context.Background()
+	catalog, playlists := pagedLibrary(c.tracks, c.playlists)
+	a := authenticate(t, c.start(t, catalog, playlists).adapter)
+
+	if !c.noPlaylists {
+		got, err := a.GetUserPlaylists(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := slices.Concat(c.before, playlistIDs(playlists), c.after)
+		if !slices.Equal(playlistIDs(got), want) {
+			t.Fatalf("expected %d playlists, got %d: %v", len(want), len(got), playlistIDs(got))
+		}
+		// Not every platform counts the tracks in its listing
+		if p := got[len(c.before)+len(playlists)-1]; p.TrackCount != 0 && p.TrackCount != len(catalog) {
+			t.Fatalf("expected %d tracks in %s, got %d", len(catalog), p.Name, p.TrackCount)
+		}
+	}
+
+	paged := cmp.Or(c.pagedID, pagedPlaylistID)
+	tracks, err := a.GetPlaylistItems(ctx, paged)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(trackIDs(tracks), c.ids(catalog)) {
+		t.Fatalf("expected all %d tracks in order, got %d", len(catalog), len(tracks))
+	}
+	for i, got := range tracks {
+		if got.Name != catalog[i].Name {
+			t.Fatalf("track %d named %q, want %q", i, got.Name, catalog[i].Name)
+		}
+	}
+}
+
+func (c standinCase) testSearch(t *testing.T) {
+	ctx := context.Background()
+	run := c.start(t, nil, nil)
+	a := authenticate(t, run.adapter)
+	catalog := adapters.FakeCatalog()
+
+	tracks, err := a.SearchTracksBy(ctx, adapters.SearchQuery{Track: "Golden Master", Artist: "Mock Orchestra"}, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(trackIDs(tracks), c.ids(catalog[5:6])) {
+		t.Fatalf("search found %v", trackIDs(tracks))
+	}
+	if got := tracks[0]; got.Name != "Golden Master" || !slices.Equal(got.Artists, []string{"Mock Orchestra"}) {
+		t.Fatalf("search result converted to %+v", got)
+	}
+
+	tracks, err = a.SearchTracksBy(ctx, adapters.SearchQuery{Track: "Green Build", Artist: "Continuous Integration"}, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tracks) != 1 {
+		t.Fatalf("expected the limit to cap results at 1, got %d", len(tracks))
+	}
+
+	tracks, err = a.SearchTracksBy(ctx, adapters.SearchQuery{Track: "Unwritten Song", Artist: "Nobody"}, 5)
+	if err != nil || len(tracks) != 0 {
+		t.Fatalf("expected no results, got %v, %v", trackIDs(tracks), err)
+	}
+
+	// Platforms without ISRCs have nothing to search for in a query of one
+	tracks, err = a.SearchTracksBy(ctx, adapters.SearchQuery{ISRC: catalog[5].ISRC}, 5)
+	if c.isrc {
+		if err != nil || !slices.Equal(trackIDs(tracks), c.ids(catalog[5:6])) {
+			t.Fatalf("ISRC search found %v, %v", trackIDs(tracks), err)
+		}
+	} else if err != nil || tracks != nil {
+		t.Fatalf("ISRC-only query returned %v, %v", tracks, err)
+	}
+
+	if run.unavailable != nil {
+		run.unavailable[catalog[13].ID] = true
+		_, err := a.SearchTracksBy(ctx, adapters.SearchQuery{Track: "Null Pointer", Artist: "Segfault Sisters"}, 5)
+		if !errors.Is(err, adapters.ErrRegionUnavailable) {
+			t.Fatalf("expected ErrRegionUnavailable, got %v", err)
+		}
+	}
+}
+
+func (c standinCase) testCreateAndAdd(t *testing.T) {
+	ctx := context.Background()
+	catalog, playlists := pagedLibrary(c.tracks, 3)
+	run := c.start(t, catalog, playlists)
+	a := authenticate(t, run.adapter)
+
+	p, err := a.CreateNewPlaylist(ctx, "Copy", "Created by a test", adapters.PrivacyPrivate)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	batch := a.Capabilities().MaxBatchSize
+	ids := c.ids(catalog)
+	for start := 0; start < len(ids); start += batch {
+		if err := a.AddItemsToPlaylist(ctx, p.ID, ids[start:min(start+batch, len(ids))]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	created, ok := run.stored.Playlist(p.ID)
+	if !ok || created.Name != "Copy" || !c.noDescriptions && created.Description != "Created by a test" {
+		t.Fatalf("playlist not created: %+v", created)
+	}
+	if !slices.Equal(trackIDs(created.Tracks), trackIDs(catalog)) {
+		t.Fatalf("expected %d tracks in order, got %d", len(ids), len(created.Tracks))
+	}
+
+	if !c.atomic {
+		return
+	}
+	if err := a.AddItemsToPlaylist(ctx, p.ID, []string{ids[0], "missing"}); err == nil {
+		t.Fatal("expected an unknown track to fail")
+	}
+	if created, _ := run.stored.Playlist(p.ID); len(created.Tracks) != len(ids) {
+		t.Fatalf("failed batch added tracks: %d", len(created.Tracks))
+	}
+}
@@ -0,0 +1,431 @@
+package adapters
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"iter"
+	"net/http"
+	"net/url"
+	"soundporter/internal/playlist"
+	"soundporter/internal/utils"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// subsonicAPIVersion is the REST API version we speak; 1.14 made createPlaylist return the playlist
+	subsonicAPIVersion = "1.16.1"
+	subsonicClientName = "soundporter"
+)
+
+// subsonicErrTokenAuthUnsupported is the error code of servers that can't check salted tokens
+const subsonicErrTokenAuthUnsupported = 41
+
+var subsonicCapabilities = Capabilities{
+	MaxBatchSize:   100,
+	MaxSearchLimit: 500,
+	PrivacyLevels:  []Privacy{PrivacyPrivate, PrivacyPublic},
+}
+
+func init() {
+	Register(Registration{
+		Name:        SubsonicPlatform,
+		DisplayName: "Subsonic (Navidrome, Airsonic, ...)",
+		Credentials: []CredentialSpec{
+			{Key: "url", EnvVar: "SUBSONIC_URL", Description: "Server URL, e.g. https://music.example.com"},
+			{Key: "user", EnvVar: "SUBSONIC_USER", Description: "Username"},
+			{Key: "password", EnvVar: "SUBSONIC_PASSWORD", Description: "Password"},
+		},
+		Capabilities: subsonicCapabilities,
+		New: func(creds Credentials, opts ...Option) (ApiAdapter, error) {
+			return NewSubsonicAdapter(creds["url"], creds["user"], creds["password"], opts...)
+		},
+	})
+}
+
+// SubsonicAdapter adapts the Subsonic REST API of self-hosted servers like Navidrome and
+// Airsonic to our common adapter interface
+type SubsonicAdapter struct {
+	BaseAdapter
+	user     string
+	password string
+	api      *httpAPI
+	// legacyAuth sends the hex encoded password instead of a salted token, for servers
+	// that can't check tokens, such as ones backed by LDAP
+	legacyAuth bool
+}
+
+// NewSubsonicAdapter creates a new SubsonicAdapter for the server at serverURL
+func NewSubsonicAdapter(serverURL, user, password string, opts ...Option) (*SubsonicAdapter, error) {
+	if serverURL == "" || user == "" || password == "" {
+		return nil, fmt.Errorf("subsonic server URL, user and password must be provided")
+	}
+	if _, err := url.ParseRequestURI(serverURL); err != nil {
+		return nil, fmt.Errorf("invalid subsonic server URL %q: %v", serverURL, err)
+	}
+
+	a := &SubsonicAdapter{
+		BaseAdapter: NewBaseAdapter("Subsonic"),
+		user:        user,
+		password:    password,
+	}
+	a.api = newHTTPAPI(newOptions(options{
+		baseURL: strings.TrimSuffix(serverURL, "/") + "/rest/",
+	}, opts))
+	a.api.authorize = a.authorize
+	a.api.checkBody = subsonicError
+	return a, nil
+}
+
+// Authenticate checks the credentials with a ping
+func (a *SubsonicAdapter) Authenticate(ctx context.Context) error {
+	err := a.call(ctx, "ping", nil, nil)
+	var apiErr *subsonicAPIError
+	if errors.As(err, &apiErr) && apiErr.Code == subsonicErrTokenAuthUnsupported {
+		a.legacyAuth = true
+		err = a.call(ctx, "ping", nil, nil)
+	}
+	if err != nil {
+		return fmt.Errorf("authentication failed: %v", err)
+	}
+
+	fmt.Println("You are logged in as:", a.user)
+	a.SetAuthenticated(true)
+	return nil
+}
+
+// authorize adds the credentials and client parameters to a request's query. Each request
+// gets a new salt, so the token can't be replayed.
+func (a *SubsonicAdapter) authorize(req *http.Request) error {
+	q := req.URL.Query()
+	q.Set("u", a.user)
+	if a.legacyAuth {
+		q.Set("p", "enc:"+hex.EncodeToString([]byte(a.password)))
+	} else {
+		salt := utils.GenerateState()
+		sum := md5.Sum([]byte(a.password + salt))
+		q.Set("t", hex.EncodeToString(sum[:]))
+		q.Set("s", salt)
+	}
+	q.Set("v", subsonicAPIVersion)
+	q.Set("c", subsonicClientName)
+	q.Set("f", "json")
+	req.URL.RawQuery = q.Encode()
+	return nil
+}
+
+// call invokes a Subsonic method and decodes the contents of the response envelope into out
+func (a *SubsonicAdapter) call(ctx context.Context, method string, params url.Values, out any) error {
+	var envelope struct {
+		Response json.RawMessage `json:"subsonic-response"`
+	}
+	if err := a.api.get(ctx, method, params, &envelope); err != nil {
+		return err
+	}
+	if out == nil {
+		return nil
+	}
+	return json.Unmarshal(envelope.Response, out)
+}
+
+type subsonicPlaylist struct {
+	ID        jsonID         `json:"id"`
+	Name      string         `json:"name"`
+	Comment   string         `json:"comment"`
+	SongCount int            `json:"songCount"`
+	Created   string         `json:"created"`
+	Entry     []subsonicSong `json:"entry"`
+}
+
+type subsonicSong struct {
+	ID     jsonID `json:"id"`
+	Title  string `json:"title"`
+	Album  string `json:"album"`
+	Artist string `json:"artist"`
+	// Duration is in seconds
+	Duration   int    `json:"duration"`
+	Track      int    `json:"track"`
+	DiscNumber int    `json:"discNumber"`
+	Year       int    `json:"year"`
+	AlbumID    jsonID `json:"albumId"`
+	ArtistID   jsonID `json:"artistId"`
+	IsDir      bool   `json:"isDir"`
+	// The fields below are OpenSubsonic extensions; plain Subsonic servers leave them out
+	Artists []struct {
+		ID   jsonID `json:"id"`
+		Name string `json:"name"`
+	} `json:"artists"`
+	ISRC           []string `json:"isrc"`
+	ExplicitStatus string   `json:"explicitStatus"`
+}
+
+// GetUserPlaylists retrieves all playlists for the authenticated user
+func (a *SubsonicAdapter) GetUserPlaylists(ctx context.Context) ([]playlist.Playlist, error) {
+	return collect(a.UserPlaylists(ctx))
+}
+
+// UserPlaylists streams the user's playlists. Subsonic returns them all at once.
+func (a *SubsonicAdapter) UserPlaylists(ctx context.Context) iter.Seq2[playlist.Playlist, error] {
+	return func(yield func(playlist.Playlist, error) bool) {
+		if err := a.CheckAuth(); err != nil {
+			yield(playlist.Playlist{}, err)
+			return
+		}
+
+		var resp struct {
+			Playlists struct {
+				Playlist []subsonicPlaylist `json:"playlist"`
+			} `json:"playlists"`
+		}
+		if err := a.call(ctx, "getPlaylists", nil, &resp); err != nil {
+			yield(playlist.Playlist{}, fmt.Errorf("error getting playlists: %v", err))
+			return
+		}
+		for _, p := range resp.Playlists.Playlist {
+			if !yield(subsonicPlaylistToPlaylist(p), nil) {
+				return
+			}
+		}
+	}
+}
+
+// GetPlaylistItems retrieves all tracks in a playlist
+func (a *SubsonicAdapter) GetPlaylistItems(ctx context.Context, playlistID string) ([]playlist.Track, error) {
+	return collect(a.PlaylistItems(ctx, playlistID))
+}
+
+// PlaylistItems streams the tracks of a playlist. Subsonic returns them all at once.
+func (a *SubsonicAdapter) PlaylistItems(ctx context.Context, playlistID string) iter.Seq2[playlist.Track, error] {
+	return func(yield func(playlist.Track, error) bool) {
+		if err := a.CheckAuth(); err != nil {
+			yield(playlist.Track{}, err)
+			return
+		}
+
+		p, err := a.playlist(ctx, playlistID)
+		if err != nil {
+			yield(playlist.Track{}, fmt.Errorf("error getting playlist items: %v", err))
+			return
+		}
+		for _, song := range p.Entry {
+			if !yield(subsonicSongToTrack(song), nil) {
+				return
+			}
+		}
+	}
+}
+
+// playlist fetches a playlist with its entries
+func (a *SubsonicAdapter) playlist(ctx context.Context, playlistID string) (subsonicPlaylist, error) {
+	var resp struct {
+		Playlist subsonicPlaylist `json:"playlist"`
+	}
+	err := a.call(ctx, "getPlaylist", url.Values{"id": {playlistID}}, &resp)
+	return resp.Playlist, err
+}
+
+// CreateNewPlaylist creates a new playlist. createPlaylist only takes a name, so the comment
+// and visibility are set with a follow-up update.
+func (a *SubsonicAdapter) CreateNewPlaylist(ctx context.Context, name string, description string, privacy Privacy) (playlist.Playlist, error) {
+	if err := a.CheckAuth(); err != nil {
+		return playlist.Playlist{}, err
+	}
+
+	var resp struct {
+		Playlist *subsonicPlaylist `json:"playlist"`
+	}
+	if err := a.call(ctx, "createPlaylist", url.Values{"name": {name}}, &resp); err != nil {
+		return playlist.Playlist{}, fmt.Errorf("error creating playlist: %v", err)
+	}
+
+	// Servers older than API version 1.14 don't return the new playlist, so look it up
+	created := resp.Playlist
+	if created == nil {
+		p, err := a.newestPlaylistNamed(ctx, name)
+		if err != nil {
+			return playlist.Playlist{}, fmt.Errorf("error finding created playlist: %v", err)
+		}
+		created = &p
+	}
+
+	update := url.Values{
+		"playlistId": {string(created.ID)},
+		"comment":    {description},
+		"public":     {strconv.FormatBool(privacy == PrivacyPublic)},
+	}
+	if err := a.call(ctx, "updatePlaylist", update, nil); err != nil {
+		return playlist.Playlist{}, fmt.Errorf("error updating playlist %s: %v", created.ID, err)
+	}
+
+	return playlist.Playlist{
+		ID:          string(created.ID),
+		Name:        name,
+		Description: description,
+		CreatedAt:   time.Now(),
+	}, nil
+}
+
+// newestPlaylistNamed finds the most recently created playlist with the given name
+func (a *SubsonicAdapter) newestPlaylistNamed(ctx context.Context, name string) (subsonicPlaylist, error) {
+	var resp struct {
+		Playlists struct {
+			Playlist []subsonicPlaylist `json:"playlist"`
+		} `json:"playlists"`
+	}
+	if err := a.call(ctx, "getPlaylists", nil, &resp); err != nil {
+		return subsonicPlaylist{}, err
+	}
+
+	var newest *subsonicPlaylist
+	for i, p := range resp.Playlists.Playlist {
+		if p.Name == name && (newest == nil || p.Created > newest.Created) {
+			newest = &resp.Playlists.Playlist[i]
+		}
+	}
+	if newest == nil {
+		return subsonicPlaylist{}, fmt.Errorf("no playlist named %q", name)
+	}
+	return *newest, nil
+}
+
+// AddItemsToPlaylist appends songs to a playlist in one request
+func (a *SubsonicAdapter) AddItemsToPlaylist(ctx context.Context, playlistID string, trackIDs []string) error {
+	if err := a.CheckAuth(); err != nil {
+		return err
+	}
+
+	params := url.Values{"playlistId": {playlistID}, "songIdToAdd": trackIDs}
+	if err := a.call(ctx, "updatePlaylist", params, nil); err != nil {
+		return fmt.Errorf("error adding tracks to playlist: %v", err)
+	}
+	return nil
+}
+
+// SearchTracks searches the server's library for songs
+func (a *SubsonicAdapter) SearchTracks(ctx context.Context, query string, limit int) ([]playlist.Track, error) {
+	if err := a.CheckAuth(); err != nil {
+		return nil, err
+	}
+
+	if limit <= 0 || limit > subsonicCapabilities.MaxSearchLimit {
+		limit = subsonicCapabilities.MaxSearchLimit
+	}
+
+	var resp struct {
+		SearchResult3 struct {
+			Song []subsonicSong `json:"song"`
+		} `json:"searchResult3"`
+	}
+	params := url.Values{
+		"query":       {query},
+		"songCount":   {strconv.Itoa(limit)},
+		"artistCount": {"0"},
+		"albumCount":  {"0"},
+	}
+	if err := a.call(ctx, "search3", params, &resp); err != nil {
+		return nil, fmt.Errorf("error searching tracks: %v", err)
+	}
+
+	var tracks []playlist.Track
+	for _, song := range resp.SearchResult3.Song {
+		if !song.IsDir {
+			tracks = append(tracks, subsonicSongToTrack(song))
+		}
+	}
+	return tracks, nil
+}
+
+// SearchTracksBy searches the library by the query's fields. search3 matches words against
+// the title, artist and album tags alike, so the fields are combined into one query.
+func (a *SubsonicAdapter) SearchTracksBy(ctx context.Context, query SearchQuery, limit int) ([]playlist.Track, error) {
+	text := query.FreeText()
+	if text == "" {
+		return nil, nil
+	}
+	return a.SearchTracks(ctx, text, limit)
+}
+
+// Capabilities describes the Subsonic API limits
+func (a *SubsonicAdapter) Capabilities() Capabilities {
+	return subsonicCapabilities
+}
+
+func subsonicPlaylistToPlaylist(p subsonicPlaylist) playlist.Playlist {
+	created, _ := time.Parse(time.RFC3339, p.Created)
+	return playlist.Playlist{
+		ID:          string(p.ID),
+		Name:        p.Name,
+		Description: p.Comment,
+		TrackCount:  p.SongCount,
+		CreatedAt:   created,
+	}
+}
+
+// subsonicSongToTrack converts a song into our track model from the library's tags. The
+// OpenSubsonic artists list is preferred when the server has it. Otherwise the artist string is
+// the artist tag as it was written.
+func subsonicSongToTrack(s subsonicSong) playlist.Track {
+	track := playlist.Track{
+		Name:        s.Title,
+		Album:       s.Album,
+		ID:          string(s.ID),
+		AlbumID:     string(s.AlbumID),
+		DurationMs:  s.Duration * 1000,
+		Explicit:    s.ExplicitStatus == "explicit",
+		DiscNumber:  s.DiscNumber,
+		TrackNumber: s.Track,
+	}
+	if s.Year > 0 {
+		track.ReleaseDate = strconv.Itoa(s.Year)
+	}
+	if len(s.ISRC) > 0 {
+		track.ISRC = s.ISRC[0]
+	}
+
+	if len(s.Artists) > 0 {
+		for _, artist := range s.Artists {
+			track.Artists = append(track.Artists, artist.Name)
+			track.ArtistIDs = append(track.ArtistIDs, string(artist.ID))
+		}
+	} else if s.Artist != "" {
+		track.Artists = []string{s.Artist}
+		track.ArtistIDs = []string{string(s.ArtistID)}
+	}
+	return track
+}
+
+// subsonicAPIError is the error of a failed Subsonic response
+type subsonicAPIError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *subsonicAPIError) Error() string {
+	return fmt.Sprintf("subsonic: %s (code %d)", e.Message, e.Code)
+}
+
+// subsonicError reports the error of a response whose status is failed. Subsonic servers
+// answer with a 200 status either way.
+func subsonicError(body []byte) error {
+	var envelope struct {
+		Response struct {
+			Status string            `json:"status"`
+			Error  *subsonicAPIError `json:"error"`
+		} `json:"subsonic-response"`
+	}
+	if err := json.Unmarshal(body, &envelope); err != nil {
+		return fmt.Errorf("subsonic: invalid response: %v", err)
+	}
+	if envelope.Response.Status == "ok" {
+		return nil
+	}
+	if envelope.Response.Error == nil {
+		return fmt.Errorf("subsonic: request failed")
+	}
+	return envelope.Response.Error
+}
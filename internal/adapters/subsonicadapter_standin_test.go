@@ -0,0 +1,91 @@
+package adapters_test
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"soundporter/internal/adapters"
+	"soundporter/internal/playlist"
+	"soundporter/internal/standin"
+)
+
+// startSubsonic starts a Subsonic stand-in for the conformance suite
+func startSubsonic(t *testing.T, catalog []playlist.Track, playlists []playlist.Playlist) (*standin.Subsonic, standinRun) {
+	t.Helper()
+	s := standin.NewSubsonic(catalog, playlists)
+	t.Cleanup(s.Close)
+	login := func(password string) adapters.ApiAdapter {
+		a, err := adapters.NewSubsonicAdapter(s.URL, standin.SubsonicUser, password, s.Options()...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return a
+	}
+	return s, standinRun{
+		adapter: login(standin.SubsonicPassword),
+		refused: map[string]adapters.ApiAdapter{"a wrong password": login("wrong")},
+		stored:  s,
+	}
+}
+
+func TestSubsonicStandinLDAP(t *testing.T) {
+	ctx := context.Background()
+	s, run := startSubsonic(t, nil, nil)
+
+	// Servers backed by LDAP refuse salted tokens, so the adapter falls back to the password
+	s.LDAP = true
+	if err := run.adapter.Authenticate(ctx); err != nil {
+		t.Fatalf("password fallback failed: %v", err)
+	}
+	if _, err := run.adapter.GetUserPlaylists(ctx); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSubsonicStandinTracks(t *testing.T) {
+	ctx := context.Background()
+	s, run := startSubsonic(t, nil, nil)
+	a := authenticate(t, run.adapter)
+	catalog := adapters.FakeCatalog()
+
+	tracks, err := a.GetPlaylistItems(ctx, "fake-playlist-3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, i := range []int{3, 12} {
+		want, got := catalog[i], tracks[i]
+		if got.Name != want.Name || got.ISRC != want.ISRC || got.Album != want.Album || got.DurationMs != want.DurationMs {
+			t.Errorf("track %d converted to %+v, want %+v", i, got, want)
+		}
+		if !slices.Equal(got.Artists, want.Artists) || !slices.Equal(got.ArtistIDs, want.ArtistIDs) {
+			t.Errorf("track %d has artists %v %v, want %v %v", i, got.Artists, got.ArtistIDs, want.Artists, want.ArtistIDs)
+		}
+	}
+
+	// Without the OpenSubsonic artists list, the artist tag is kept as it was written
+	s.Plain = true
+	tracks, err = a.GetPlaylistItems(ctx, "fake-playlist-3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := tracks[12]; !slices.Equal(got.Artists, []string{"Data Race & Fencepost"}) || got.ISRC != "" {
+		t.Fatalf("plain song converted to %+v", got)
+	}
+}
+
+func TestSubsonicStandinLegacyAPI(t *testing.T) {
+	ctx := context.Background()
+	s, run := startSubsonic(t, nil, nil)
+	a := authenticate(t, run.adapter)
+
+	// Servers older than 1.14 don't return the new playlist, so it is looked up by name
+	s.LegacyAPI = true
+	legacy, err := a.CreateNewPlaylist(ctx, "Legacy", "", adapters.PrivacyPrivate)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if created, ok := s.Playlist(legacy.ID); !ok || created.Name != "Legacy" {
+		t.Fatalf("legacy playlist not found: %+v", legacy)
+	}
+}
@@ -0,0 +1,468 @@
+package adapters
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"iter"
+	"net/http"
+	"net/url"
+	"soundporter/internal/playlist"
+	"soundporter/internal/utils"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+const (
+	tidalScope = "r_usr w_usr w_sub"
+	// tidalPageSize is the number of items requested per page
+	tidalPageSize = 100
+)
+
+var tidalCapabilities = Capabilities{
+	MaxBatchSize:   100,
+	MaxSearchLimit: 100,
+	SearchFields:   []SearchField{SearchFieldISRC},
+	PrivacyLevels:  []Privacy{PrivacyPrivate},
+}
+
+func init() {
+	Register(Registration{
+		Name:        TidalPlatform,
+		DisplayName: "Tidal",
+		Credentials: []CredentialSpec{
+			{Key: "client_id", EnvVar: "TIDAL_CLIENT_ID", Description: "Tidal client ID"},
+			{Key: "client_secret", EnvVar: "TIDAL_CLIENT_SECRET", Description: "Tidal client secret"},
+		},
+		Capabilities: tidalCapabilities,
+		New: func(creds Credentials, opts ...Option) (ApiAdapter, error) {
+			return NewTidalAdapter(creds["client_id"], creds["client_secret"], opts...)
+		},
+	})
+}
+
+// TidalAdapter adapts the Tidal API to our common adapter interface
+type TidalAdapter struct {
+	BaseAdapter
+	clientID     string
+	clientSecret string
+	opts         options
+	api          *httpAPI
+	userID       string
+	countryCode  string
+}
+
+// NewTidalAdapter creates a new TidalAdapter. The auth URL option is Tidal's device
+// authorization endpoint, as Tidal logs users in with the OAuth device flow.
+func NewTidalAdapter(clientID, clientSecret string, opts ...Option) (*TidalAdapter, error) {
+	if clientID == "" || clientSecret == "" {
+		return nil, fmt.Errorf("tidal client ID and secret must be provided")
+	}
+
+	return &TidalAdapter{
+		BaseAdapter:  NewBaseAdapter("Tidal"),
+		clientID:     clientID,
+		clientSecret: clientSecret,
+		opts: newOptions(options{
+			baseURL:  "https://api.tidal.com/v1/",
+			authURL:  "https://auth.tidal.com/v1/oauth2/device_authorization",
+			tokenURL: "https://auth.tidal.com/v1/oauth2/token",
+		}, opts),
+	}, nil
+}
+
+// Authenticate handles user authentication with Tidal. The user confirms a code on Tidal's
+// website while we poll for the token, so no local callback server is needed.
+func (a *TidalAdapter) Authenticate(ctx context.Context) error {
+	config := &oauth2.Config{
+		ClientID:     a.clientID,
+		ClientSecret: a.clientSecret,
+		Scopes:       []string{tidalScope},
+		Endpoint: oauth2.Endpoint{
+			DeviceAuthURL: a.opts.authURL,
+			TokenURL:      a.opts.tokenURL,
+			AuthStyle:     oauth2.AuthStyleInParams,
+		},
+	}
+
+	token := a.opts.token
+	if token == nil {
+		da, err := a.deviceAuth(ctx)
+		if err != nil {
+			return fmt.Errorf("error starting Tidal login: %v", err)
+		}
+
+		fmt.Printf("Please log in to Tidal by visiting %s and confirming the code %s\n", da.VerificationURIComplete, da.UserCode)
+		utils.OpenBrowser(da.VerificationURIComplete)
+
+		token, err = config.DeviceAccessToken(a.opts.oauthContext(ctx), da)
+		if err != nil {
+			return fmt.Errorf("error getting Tidal token: %v", err)
+		}
+	}
+
+	// The token source outlives any single request, so it must not use a request's context
+	api := newHTTPAPI(a.opts)
+	api.client = config.Client(a.opts.oauthContext(context.Background()), token)
+	api.authorize = a.authorize
+	api.decodeError = tidalError
+	a.api = api
+
+	var session struct {
+		UserID      jsonID `json:"userId"`
+		CountryCode string `json:"countryCode"`
+	}
+	if err := a.api.get(ctx, "sessions", nil, &session); err != nil {
+		return fmt.Errorf("authentication failed: %v", err)
+	}
+	a.userID = string(session.UserID)
+	a.countryCode = session.CountryCode
+
+	fmt.Println("Tidal authentication successful!")
+	a.SetAuthenticated(true)
+	return nil
+}
+
+// deviceAuth starts the device flow. Tidal's response uses camelCase names rather than the
+// standard ones, so it's decoded here instead of by the oauth2 package.
+func (a *TidalAdapter) deviceAuth(ctx context.Context) (*oauth2.DeviceAuthResponse, error) {
+	var resp struct {
+		DeviceCode              string `json:"deviceCode"`
+		UserCode                string `json:"userCode"`
+		VerificationURI         string `json:"verificationUri"`
+		VerificationURIComplete string `json:"verificationUriComplete"`
+		ExpiresIn               int64  `json:"expiresIn"`
+		Interval                int64  `json:"interval"`
+	}
+	params := url.Values{"client_id": {a.clientID}, "scope": {tidalScope}}
+	auth := &httpAPI{client: newHTTPAPI(a.opts).client, decodeError: tidalError}
+	if err := auth.post(ctx, a.opts.authURL, params, &resp); err != nil {
+		return nil, err
+	}
+
+	da := &oauth2.DeviceAuthResponse{
+		DeviceCode:              resp.DeviceCode,
+		UserCode:                resp.UserCode,
+		VerificationURI:         tidalLink(resp.VerificationURI),
+		VerificationURIComplete: tidalLink(resp.VerificationURIComplete),
+		Interval:                resp.Interval,
+	}
+	if resp.ExpiresIn > 0 {
+		da.Expiry = time.Now().Add(time.Duration(resp.ExpiresIn) * time.Second)
+	}
+	if da.VerificationURIComplete == "" {
+		da.VerificationURIComplete = da.VerificationURI
+	}
+	return da, nil
+}
+
+// tidalLink adds the scheme Tidal leaves off its verification links, e.g. link.tidal.com/ABCDE
+func tidalLink(link string) string {
+	if link == "" || strings.Contains(link, "://") {
+		return link
+	}
+	return "https://" + link
+}
+
+// authorize adds the country code every catalog request needs. The bearer token is added by
+// the oauth2 client.
+func (a *TidalAdapter) authorize(req *http.Request) error {
+	if a.countryCode == "" {
+		return nil
+	}
+	q := req.URL.Query()
+	q.Set("countryCode", a.countryCode)
+	req.URL.RawQuery = q.Encode()
+	return nil
+}
+
+type tidalPlaylist struct {
+	UUID           string `json:"uuid"`
+	Title          string `json:"title"`
+	Description    string `json:"description"`
+	NumberOfTracks int    `json:"numberOfTracks"`
+	Created        string `json:"created"`
+}
+
+type tidalTrack struct {
+	ID       jsonID `json:"id"`
+	Title    string `json:"title"`
+	Version  string `json:"version"`
+	ISRC     string `json:"isrc"`
+	URL      string `json:"url"`
+	Explicit bool   `json:"explicit"`
+	// Duration is in seconds
+	Duration       int   `json:"duration"`
+	Popularity     int   `json:"popularity"`
+	TrackNumber    int   `json:"trackNumber"`
+	VolumeNumber   int   `json:"volumeNumber"`
+	StreamReady    *bool `json:"streamReady"`
+	AllowStreaming *bool `json:"allowStreaming"`
+	Artists        []struct {
+		ID   jsonID `json:"id"`
+		Name string `json:"name"`
+	} `json:"artists"`
+	Album struct {
+		ID          jsonID `json:"id"`
+		Title       string `json:"title"`
+		ReleaseDate string `json:"releaseDate"`
+	} `json:"album"`
+}
+
+// tidalPage is a page of a paginated list
+type tidalPage[T any] struct {
+	Limit              int `json:"limit"`
+	Offset             int `json:"offset"`
+	TotalNumberOfItems int `json:"totalNumberOfItems"`
+	Items              []T `json:"items"`
+}
+
+// tidalCreated is the layout of Tidal's timestamps, e.g. 2021-03-04T10:00:00.000+0000
+const tidalCreated = "2006-01-02T15:04:05.000-0700"
+
+// GetUserPlaylists retrieves all playlists for the authenticated user
+func (a *TidalAdapter) GetUserPlaylists(ctx context.Context) ([]playlist.Playlist, error) {
+	return collect(a.UserPlaylists(ctx))
+}
+
+// UserPlaylists streams the authenticated user's playlists page by page
+func (a *TidalAdapter) UserPlaylists(ctx context.Context) iter.Seq2[playlist.Playlist, error] {
+	return func(yield func(playlist.Playlist, error) bool) {
+		if err := a.CheckAuth(); err != nil {
+			yield(playlist.Playlist{}, err)
+			return
+		}
+
+		path := "users/" + url.PathEscape(a.userID) + "/playlists"
+		for page, err := range tidalPages[tidalPlaylist](ctx, a.api, path) {
+			if err != nil {
+				yield(playlist.Playlist{}, fmt.Errorf("error getting playlists: %v", err))
+				return
+			}
+			for _, p := range page {
+				created, _ := time.Parse(tidalCreated, p.Created)
+				if !yield(playlist.Playlist{
+					ID:          p.UUID,
+					Name:        p.Title,
+					Description: p.Description,
+					TrackCount:  p.NumberOfTracks,
+					CreatedAt:   created,
+				}, nil) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// GetPlaylistItems retrieves all tracks in a playlist
+func (a *TidalAdapter) GetPlaylistItems(ctx context.Context, playlistID string) ([]playlist.Track, error) {
+	return collect(a.PlaylistItems(ctx, playlistID))
+}
+
+// PlaylistItems streams the tracks of a playlist page by page
+func (a *TidalAdapter) PlaylistItems(ctx context.Context, playlistID string) iter.Seq2[playlist.Track, error] {
+	return func(yield func(playlist.Track, error) bool) {
+		if err := a.CheckAuth(); err != nil {
+			yield(playlist.Track{}, err)
+			return
+		}
+
+		path := "playlists/" + url.PathEscape(playlistID) + "/tracks"
+		for page, err := range tidalPages[tidalTrack](ctx, a.api, path) {
+			if err != nil {
+				yield(playlist.Track{}, fmt.Errorf("error getting playlist items: %v", err))
+				return
+			}
+			for _, t := range page {
+				if !yield(tidalTrackToTrack(t), nil) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// tidalPages pages through a Tidal list by offset until all items have been read
+func tidalPages[T any](ctx context.Context, api *httpAPI, path string) iter.Seq2[[]T, error] {
+	return func(yield func([]T, error) bool) {
+		for offset := 0; ; {
+			var page tidalPage[T]
+			query := url.Values{"limit": {strconv.Itoa(tidalPageSize)}, "offset": {strconv.Itoa(offset)}}
+			if err := api.get(ctx, path, query, &page); err != nil {
+				yield(nil, err)
+				return
+			}
+			if !yield(page.Items, nil) {
+				return
+			}
+			offset += len(page.Items)
+			if len(page.Items) == 0 || offset >= page.TotalNumberOfItems {
+				return
+			}
+		}
+	}
+}
+
+// CreateNewPlaylist creates a new Tidal playlist. Tidal creates playlists as private.
+func (a *TidalAdapter) CreateNewPlaylist(ctx context.Context, name string, description string, privacy Privacy) (playlist.Playlist, error) {
+	if err := a.CheckAuth(); err != nil {
+		return playlist.Playlist{}, err
+	}
+
+	var created tidalPlaylist
+	params := url.Values{"title": {name}, "description": {description}}
+	if err := a.api.post(ctx, "users/"+url.PathEscape(a.userID)+"/playlists", params, &created); err != nil {
+		return playlist.Playlist{}, fmt.Errorf("error creating playlist: %v", err)
+	}
+
+	return playlist.Playlist{
+		ID:          created.UUID,
+		Name:        name,
+		Description: description,
+		CreatedAt:   time.Now(),
+	}, nil
+}
+
+// AddItemsToPlaylist adds tracks to a Tidal playlist in one request. Tidal only accepts changes
+// that name the playlist's current ETag, so it's fetched first.
+func (a *TidalAdapter) AddItemsToPlaylist(ctx context.Context, playlistID string, trackIDs []string) error {
+	if err := a.CheckAuth(); err != nil {
+		return err
+	}
+
+	path := "playlists/" + url.PathEscape(playlistID)
+	header, err := a.api.send(ctx, http.MethodGet, path, nil, nil, nil, nil)
+	if err != nil {
+		return fmt.Errorf("error getting playlist %s: %v", playlistID, err)
+	}
+
+	params := url.Values{
+		"trackIds":           {strings.Join(trackIDs, ",")},
+		"onArtifactNotFound": {"FAIL"},
+		"onDupes":            {"ADD"},
+	}
+	etag := http.Header{"If-None-Match": {header.Get("ETag")}}
+	if _, err := a.api.send(ctx, http.MethodPost, path+"/items", nil, params, etag, nil); err != nil {
+		return fmt.Errorf("error adding tracks to playlist: %v", err)
+	}
+	return nil
+}
+
+// SearchTracks searches for tracks on Tidal
+func (a *TidalAdapter) SearchTracks(ctx context.Context, query string, limit int) ([]playlist.Track, error) {
+	if err := a.CheckAuth(); err != nil {
+		return nil, err
+	}
+
+	if limit <= 0 || limit > tidalCapabilities.MaxSearchLimit {
+		limit = tidalCapabilities.MaxSearchLimit
+	}
+
+	var page tidalPage[tidalTrack]
+	params := url.Values{"query": {query}, "limit": {strconv.Itoa(limit)}}
+	if err := a.api.get(ctx, "search/tracks", params, &page); err != nil {
+		return nil, fmt.Errorf("error searching tracks: %v", err)
+	}
+	return tidalStreamable(page.Items)
+}
+
+// SearchTracksBy looks tracks up by ISRC. Tidal's search has no field filters, so other fields
+// are combined into a plain text search.
+func (a *TidalAdapter) SearchTracksBy(ctx context.Context, query SearchQuery, limit int) ([]playlist.Track, error) {
+	if query.ISRC == "" {
+		text := query.FreeText()
+		if text == "" {
+			return nil, nil
+		}
+		return a.SearchTracks(ctx, text, limit)
+	}
+
+	if err := a.CheckAuth(); err != nil {
+		return nil, err
+	}
+
+	var page tidalPage[tidalTrack]
+	if err := a.api.get(ctx, "tracks", url.Values{"isrc": {query.ISRC}}, &page); err != nil {
+		return nil, fmt.Errorf("error searching tracks by ISRC: %v", err)
+	}
+	if limit > 0 && len(page.Items) > limit {
+		page.Items = page.Items[:limit]
+	}
+	return tidalStreamable(page.Items)
+}
+
+// Capabilities describes the Tidal API limits
+func (a *TidalAdapter) Capabilities() Capabilities {
+	return tidalCapabilities
+}
+
+// tidalStreamable converts tracks, dropping the ones that can't be streamed in the user's country
+func tidalStreamable(results []tidalTrack) ([]playlist.Track, error) {
+	var tracks []playlist.Track
+	var unavailable int
+	for _, t := range results {
+		if (t.StreamReady != nil && !*t.StreamReady) || (t.AllowStreaming != nil && !*t.AllowStreaming) {
+			unavailable++
+			continue
+		}
+		tracks = append(tracks, tidalTrackToTrack(t))
+	}
+	if len(tracks) == 0 && unavailable > 0 {
+		return nil, ErrRegionUnavailable
+	}
+	return tracks, nil
+}
+
+// tidalTrackToTrack converts a Tidal track into our track model. Tidal keeps the version,
+// e.g. "Remastered", out of the title.
+func tidalTrackToTrack(t tidalTrack) playlist.Track {
+	track := playlist.Track{
+		Name:        t.Title,
+		Album:       t.Album.Title,
+		ID:          string(t.ID),
+		AlbumID:     string(t.Album.ID),
+		URL:         t.URL,
+		Version:     t.Version,
+		ISRC:        t.ISRC,
+		DurationMs:  t.Duration * 1000,
+		Explicit:    t.Explicit,
+		Popularity:  t.Popularity,
+		DiscNumber:  t.VolumeNumber,
+		TrackNumber: t.TrackNumber,
+		ReleaseDate: t.Album.ReleaseDate,
+	}
+	for _, artist := range t.Artists {
+		track.Artists = append(track.Artists, artist.Name)
+		track.ArtistIDs = append(track.ArtistIDs, string(artist.ID))
+	}
+	return track
+}
+
+// tidalAPIError is the error body of a Tidal response
+type tidalAPIError struct {
+	Status      int    `json:"status"`
+	SubStatus   int    `json:"subStatus"`
+	UserMessage string `json:"userMessage"`
+	// Error and ErrorDescription are set instead by the auth endpoints
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+// tidalError decodes the error body of a Tidal response
+func tidalError(status int, body []byte) error {
+	var e tidalAPIError
+	if json.Unmarshal(body, &e) != nil {
+		return nil
+	}
+	switch {
+	case e.UserMessage != "":
+		return fmt.Errorf("tidal: %s (HTTP %d, sub-status %d)", e.UserMessage, status, e.SubStatus)
+	case e.Error != "":
+		return fmt.Errorf("tidal: %s: %s (HTTP %d)", e.Error, e.ErrorDescription, status)
+	}
+	return nil
+}
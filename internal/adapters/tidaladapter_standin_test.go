@@ -0,0 +1,68 @@
+package adapters_test
+
+import (
+	"context"
+	"reflect"
+	"slices"
+	"testing"
+
+	"golang.org/x/oauth2"
+
+	"soundporter/internal/adapters"
+	"soundporter/internal/playlist"
+	"soundporter/internal/standin"
+)
+
+// startTidal starts a Tidal stand-in for the conformance suite
+func startTidal(t *testing.T, catalog []playlist.Track, playlists []playlist.Playlist) (*standin.Tidal, standinRun) {
+	t.Helper()
+	s := standin.NewTidal(catalog, playlists)
+	t.Cleanup(s.Close)
+	withToken := func(token *oauth2.Token) adapters.ApiAdapter {
+		a, err := adapters.NewTidalAdapter("client-id", "client-secret", append(s.Options(), adapters.WithToken(token))...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return a
+	}
+	return s, standinRun{
+		adapter: withToken(s.OAuth.Token()),
+		// Expired tokens are refreshed through the token endpoint, and the session lookup
+		// Authenticate makes rejects a revoked token
+		accepted:    map[string]adapters.ApiAdapter{"an expired token": withToken(s.OAuth.ExpiredToken())},
+		refused:     map[string]adapters.ApiAdapter{"a revoked token": withToken(&oauth2.Token{AccessToken: "revoked"})},
+		stored:      s,
+		unavailable: s.Unavailable,
+	}
+}
+
+func TestTidalStandinTracks(t *testing.T) {
+	_, run := startTidal(t, nil, nil)
+	a := authenticate(t, run.adapter)
+
+	tracks, err := a.GetPlaylistItems(context.Background(), "fake-playlist-3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, i := range []int{3, 12} {
+		want := adapters.FakeCatalog()[i]
+		want.URL = "http://www.tidal.com/track/" + want.ID
+		if !reflect.DeepEqual(tracks[i], want) {
+			t.Errorf("track %d not converted:\n got %+v\nwant %+v", i, tracks[i], want)
+		}
+	}
+}
+
+func TestTidalStandinSearchText(t *testing.T) {
+	_, run := startTidal(t, nil, nil)
+	a := authenticate(t, run.adapter)
+
+	// Without an ISRC, the fields are searched as free text
+	tracks, err := a.SearchTracksBy(context.Background(), adapters.SearchQuery{Track: "Green Build", Artist: "Continuous Integration"}, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(trackIDs(tracks), []string{"fake-08", "fake-10"}) {
+		t.Fatalf("search found %v", trackIDs(tracks))
+	}
+}
@@ -3,9 +3,10 @@ package adapters
 import (
 	"context"
 	"fmt"
+	"html"
+	"iter"
 	"log"
 	"net/http"
-	"os"
 	"soundporter/internal/playlist"
 	"soundporter/internal/utils"
 	"strings"
@@ -19,39 +20,63 @@ import (
 
 const youtubeRedirectURI = "http://localhost:8080/callback"
 
+// YouTube only has free-text search and inserts one playlist item per request
+var youtubeCapabilities = Capabilities{
+	MaxBatchSize:         1,
+	MaxSearchLimit:       50,
+	MaxDescriptionLength: 5000,
+	PrivacyLevels:        []Privacy{PrivacyPrivate, PrivacyUnlisted, PrivacyPublic},
+	CanReorderItems:      true,
+	CanRemoveItems:       true,
+}
+
+func init() {
+	Register(Registration{
+		Name:        YoutubePlatform,
+		DisplayName: "YouTube Music",
+		Credentials: []CredentialSpec{
+			{Key: "client_id", EnvVar: "YOUTUBE_CLIENT_ID", Description: "Google OAuth client ID"},
+			{Key: "client_secret", EnvVar: "YOUTUBE_CLIENT_SECRET", Description: "Google OAuth client secret"},
+		},
+		Capabilities: youtubeCapabilities,
+		New: func(creds Credentials, opts ...Option) (ApiAdapter, error) {
+			return NewYouTubeAdapter(creds["client_id"], creds["client_secret"], opts...)
+		},
+	})
+}
+
 // YouTubeAdapter adapts the YouTube API to our common adapter interface
 type YouTubeAdapter struct {
 	BaseAdapter
 	service      *youtube.Service
 	clientID     string
 	clientSecret string
+	opts         options
 	ch           chan *youtube.Service
 	state        string
 }
 
 // NewYouTubeAdapter creates a new YouTubeAdapter
-func NewYouTubeAdapter(clientID, clientSecret string) (*YouTubeAdapter, error) {
-	if clientID == "" {
-		clientID = os.Getenv("YOUTUBE_CLIENT_ID")
-	}
-	if clientSecret == "" {
-		clientSecret = os.Getenv("YOUTUBE_CLIENT_SECRET")
-	}
+func NewYouTubeAdapter(clientID, clientSecret string, opts ...Option) (*YouTubeAdapter, error) {
 	if clientID == "" || clientSecret == "" {
-		return nil, fmt.Errorf("youtube client ID and secret must be provided or set in environment variables")
+		return nil, fmt.Errorf("youtube client ID and secret must be provided")
 	}
 
 	return &YouTubeAdapter{
 		BaseAdapter:  NewBaseAdapter("YouTube"),
 		clientID:     clientID,
 		clientSecret: clientSecret,
-		ch:           make(chan *youtube.Service),
-		state:        utils.GenerateState(),
+		opts: newOptions(options{
+			authURL:  google.Endpoint.AuthURL,
+			tokenURL: google.Endpoint.TokenURL,
+		}, opts),
+		ch:    make(chan *youtube.Service),
+		state: utils.GenerateState(),
 	}, nil
 }
 
 // Authenticate handles user authentication with YouTube API
-func (a *YouTubeAdapter) Authenticate() error {
+func (a *YouTubeAdapter) Authenticate(ctx context.Context) error {
 	// OAuth2 config for YouTube API
 	config := &oauth2.Config{
 		ClientID:     a.clientID,
@@ -61,25 +86,33 @@ func (a *YouTubeAdapter) Authenticate() error {
 			youtube.YoutubeReadonlyScope,
 			youtube.YoutubeScope,
 		},
-		Endpoint: google.Endpoint,
+		Endpoint: oauth2.Endpoint{
+			AuthURL:  a.opts.authURL,
+			TokenURL: a.opts.tokenURL,
+		},
+	}
+
+	if a.opts.token != nil {
+		service, err := a.newService(config, a.opts.token)
+		if err != nil {
+			return fmt.Errorf("error creating YouTube client: %v", err)
+		}
+		a.service = service
+		a.SetAuthenticated(true)
+		return nil
 	}
 
 	// Start HTTP server for OAuth callback
-	http.HandleFunc("/callback", func(w http.ResponseWriter, r *http.Request) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/callback", func(w http.ResponseWriter, r *http.Request) {
 		a.completeAuth(w, r, config)
 	})
 
-	http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
 		log.Println("Got request for:", r.URL.String())
 	})
 
-	// Start the HTTP server
-	go func() {
-		err := http.ListenAndServe(":8080", nil)
-		if err != nil {
-			log.Fatal(err)
-		}
-	}()
+	server := startCallbackServer(mux)
 
 	// Generate the authorization URL
 	authURL := config.AuthCodeURL(a.state, oauth2.AccessTypeOffline, oauth2.ApprovalForce)
@@ -89,108 +122,156 @@ func (a *YouTubeAdapter) Authenticate() error {
 	utils.OpenBrowser(authURL)
 
 	// Wait for auth to complete
-	a.service = <-a.ch
+	service, err := awaitCallback(ctx, a.ch, server)
+	if err != nil {
+		return err
+	}
+	a.service = service
 	a.SetAuthenticated(true)
 
 	fmt.Println("YouTube authentication successful!")
 	return nil
 }
 
-// GetUserPlaylists retrieves all playlists for the authenticated user
-func (a *YouTubeAdapter) GetUserPlaylists() ([]playlist.Playlist, error) {
-	if err := a.CheckAuth(); err != nil {
-		return nil, err
+// newService creates a YouTube client that authorizes requests with the token, refreshing it when needed
+func (a *YouTubeAdapter) newService(config *oauth2.Config, token *oauth2.Token) (*youtube.Service, error) {
+	// The token source outlives any single request, so it must not use a request's context
+	ctx := a.opts.oauthContext(context.Background())
+	clientOpts := []option.ClientOption{option.WithHTTPClient(config.Client(ctx, token))}
+	if a.opts.baseURL != "" {
+		clientOpts = append(clientOpts, option.WithEndpoint(a.opts.baseURL))
 	}
+	return youtube.NewService(ctx, clientOpts...)
+}
 
-	var playlists []playlist.Playlist
-	var nextPageToken string
-
-	for {
-		call := a.service.Playlists.List([]string{"snippet", "contentDetails"}).
-			Mine(true).
-			MaxResults(50)
+// GetUserPlaylists retrieves all playlists for the authenticated user
+func (a *YouTubeAdapter) GetUserPlaylists(ctx context.Context) ([]playlist.Playlist, error) {
+	return collect(a.UserPlaylists(ctx))
+}
 
-		if nextPageToken != "" {
-			call = call.PageToken(nextPageToken)
+// UserPlaylists streams the authenticated user's playlists page by page
+func (a *YouTubeAdapter) UserPlaylists(ctx context.Context) iter.Seq2[playlist.Playlist, error] {
+	return func(yield func(playlist.Playlist, error) bool) {
+		if err := a.CheckAuth(); err != nil {
+			yield(playlist.Playlist{}, err)
+			return
 		}
 
-		response, err := call.Do()
-		if err != nil {
-			return nil, fmt.Errorf("error fetching playlists: %v", err)
-		}
+		var nextPageToken string
 
-		for _, item := range response.Items {
-			publishedTime, _ := time.Parse(time.RFC3339, item.Snippet.PublishedAt)
-			playlists = append(playlists, playlist.Playlist{
-				ID:          item.Id,
-				Name:        item.Snippet.Title,
-				Description: item.Snippet.Description,
-				TrackCount:  int(item.ContentDetails.ItemCount),
-				CreatedAt:   publishedTime,
-			})
-		}
+		for {
+			call := a.service.Playlists.List([]string{"snippet", "contentDetails"}).
+				Mine(true).
+				MaxResults(50)
+
+			if nextPageToken != "" {
+				call = call.PageToken(nextPageToken)
+			}
 
-		nextPageToken = response.NextPageToken
-		if nextPageToken == "" {
-			break
+			response, err := call.Context(ctx).Do()
+			if err != nil {
+				yield(playlist.Playlist{}, fmt.Errorf("error fetching playlists: %v", err))
+				return
+			}
+
+			for _, item := range response.Items {
+				publishedTime, _ := time.Parse(time.RFC3339, item.Snippet.PublishedAt)
+				if !yield(playlist.Playlist{
+					ID:          item.Id,
+					Name:        item.Snippet.Title,
+					Description: item.Snippet.Description,
+					TrackCount:  int(item.ContentDetails.ItemCount),
+					CreatedAt:   publishedTime,
+				}, nil) {
+					return
+				}
+			}
+
+			nextPageToken = response.NextPageToken
+			if nextPageToken == "" {
+				return
+			}
 		}
 	}
-
-	return playlists, nil
 }
 
 // GetPlaylistItems retrieves all tracks (videos) in a playlist
-func (a *YouTubeAdapter) GetPlaylistItems(playlistID string) ([]playlist.Track, error) {
-	if err := a.CheckAuth(); err != nil {
-		return nil, err
-	}
+func (a *YouTubeAdapter) GetPlaylistItems(ctx context.Context, playlistID string) ([]playlist.Track, error) {
+	return collect(a.PlaylistItems(ctx, playlistID))
+}
 
-	var tracks []playlist.Track
-	var nextPageToken string
+// PlaylistItems streams the tracks (videos) of a playlist page by page
+func (a *YouTubeAdapter) PlaylistItems(ctx context.Context, playlistID string) iter.Seq2[playlist.Track, error] {
+	return func(yield func(playlist.Track, error) bool) {
+		if err := a.CheckAuth(); err != nil {
+			yield(playlist.Track{}, err)
+			return
+		}
 
-	for {
-		call := a.service.PlaylistItems.List([]string{"snippet", "contentDetails"}).
-			PlaylistId(playlistID).
-			MaxResults(50)
+		var nextPageToken string
 
-		if nextPageToken != "" {
-			call = call.PageToken(nextPageToken)
-		}
+		for {
+			call := a.service.PlaylistItems.List([]string{"snippet", "contentDetails"}).
+				PlaylistId(playlistID).
+				MaxResults(50)
 
-		response, err := call.Do()
-		if err != nil {
-			return nil, fmt.Errorf("error fetching playlist items: %v", err)
-		}
+			if nextPageToken != "" {
+				call = call.PageToken(nextPageToken)
+			}
 
-		for _, item := range response.Items {
-			videoID := item.ContentDetails.VideoId
-			videoURL := fmt.Sprintf("https://www.youtube.com/watch?v=%s", videoID)
+			response, err := call.Context(ctx).Do()
+			if err != nil {
+				yield(playlist.Track{}, fmt.Errorf("error fetching playlist items: %v", err))
+				return
+			}
 
-			track := playlist.Track{
-				Name:      item.Snippet.Title,
-				Artists:   []string{item.Snippet.VideoOwnerChannelTitle},
-				ID:        videoID,
-				ArtistIDs: []string{item.Snippet.VideoOwnerChannelId},
-				URL:       videoURL,
+			// Playlist items don't carry durations, so look them up for the whole page at once
+			videoIDs := make([]string, len(response.Items))
+			for i, item := range response.Items {
+				videoIDs[i] = item.ContentDetails.VideoId
+			}
+			durations, err := a.videoDurations(ctx, videoIDs)
+			if err != nil {
+				yield(playlist.Track{}, err)
+				return
 			}
 
-			tracks = append(tracks, track)
-		}
+			for _, item := range response.Items {
+				videoID := item.ContentDetails.VideoId
+				videoURL := fmt.Sprintf("https://www.youtube.com/watch?v=%s", videoID)
+
+				parsed := ParseVideoTitle(item.Snippet.Title, item.Snippet.VideoOwnerChannelTitle)
+				track := playlist.Track{
+					Name:       parsed.Title,
+					Artists:    parsed.Artists,
+					ID:         videoID,
+					ArtistIDs:  []string{item.Snippet.VideoOwnerChannelId},
+					URL:        videoURL,
+					Version:    parsed.Version,
+					DurationMs: durations[videoID],
+				}
+
+				if !yield(track, nil) {
+					return
+				}
+			}
 
-		nextPageToken = response.NextPageToken
-		if nextPageToken == "" {
-			break
+			nextPageToken = response.NextPageToken
+			if nextPageToken == "" {
+				return
+			}
 		}
 	}
-
-	return tracks, nil
 }
 
 // CreateNewPlaylist creates a new YouTube playlist
-func (a *YouTubeAdapter) CreateNewPlaylist(name string, description string) (playlist.Playlist, error) {
+func (a *YouTubeAdapter) CreateNewPlaylist(ctx context.Context, name string, description string, privacy Privacy) (playlist.Playlist, error) {
 	if err := a.CheckAuth(); err != nil {
 		return playlist.Playlist{}, err
 	}
+	if privacy == "" {
+		privacy = PrivacyPrivate
+	}
 
 	p := &youtube.Playlist{
 		Snippet: &youtube.PlaylistSnippet{
@@ -198,11 +279,11 @@ func (a *YouTubeAdapter) CreateNewPlaylist(name string, description string) (pla
 			Description: description,
 		},
 		Status: &youtube.PlaylistStatus{
-			PrivacyStatus: "private",
+			PrivacyStatus: string(privacy),
 		},
 	}
 
-	response, err := a.service.Playlists.Insert([]string{"snippet", "status"}, p).Do()
+	response, err := a.service.Playlists.Insert([]string{"snippet", "status"}, p).Context(ctx).Do()
 	if err != nil {
 		return playlist.Playlist{}, fmt.Errorf("error creating playlist: %v", err)
 	}
@@ -217,7 +298,7 @@ func (a *YouTubeAdapter) CreateNewPlaylist(name string, description string) (pla
 }
 
 // AddItemsToPlaylist adds videos to a YouTube playlist
-func (a *YouTubeAdapter) AddItemsToPlaylist(playlistID string, trackIDs []string) error {
+func (a *YouTubeAdapter) AddItemsToPlaylist(ctx context.Context, playlistID string, trackIDs []string) error {
 	if err := a.CheckAuth(); err != nil {
 		return err
 	}
@@ -247,56 +328,148 @@ func (a *YouTubeAdapter) AddItemsToPlaylist(playlistID string, trackIDs []string
 			},
 		}
 
-		_, err := a.service.PlaylistItems.Insert([]string{"snippet"}, playlistItem).Do()
+		_, err := a.service.PlaylistItems.Insert([]string{"snippet"}, playlistItem).Context(ctx).Do()
 		if err != nil {
 			return fmt.Errorf("error adding video %s to playlist: %v", videoID, err)
 		}
 
 		// YouTube API has quota limits, so add a small delay between requests
-		time.Sleep(100 * time.Millisecond)
+		select {
+		case <-time.After(100 * time.Millisecond):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
 	}
 
 	return nil
 }
 
 // SearchTracks searches for videos on YouTube
-func (a *YouTubeAdapter) SearchTracks(query string, limit int) ([]playlist.Track, error) {
+func (a *YouTubeAdapter) SearchTracks(ctx context.Context, query string, limit int) ([]playlist.Track, error) {
 	if err := a.CheckAuth(); err != nil {
 		return nil, err
 	}
 
-	if limit <= 0 || limit > 50 {
-		limit = 50 // YouTube API maximum is 50 per request
+	if limit <= 0 || limit > youtubeCapabilities.MaxSearchLimit {
+		limit = youtubeCapabilities.MaxSearchLimit
 	}
 
 	response, err := a.service.Search.List([]string{"snippet"}).
 		Q(query).
 		Type("video").
 		MaxResults(int64(limit)).
+		Context(ctx).
 		Do()
 
 	if err != nil {
 		return nil, fmt.Errorf("error searching for videos: %v", err)
 	}
 
+	videoIDs := make([]string, len(response.Items))
+	for i, item := range response.Items {
+		videoIDs[i] = item.Id.VideoId
+	}
+	durations, err := a.videoDurations(ctx, videoIDs)
+	if err != nil {
+		return nil, err
+	}
+
 	var tracks []playlist.Track
 
 	for _, item := range response.Items {
 		videoID := item.Id.VideoId
 		videoURL := fmt.Sprintf("https://www.youtube.com/watch?v=%s", videoID)
 
+		parsed := ParseVideoTitle(html.UnescapeString(item.Snippet.Title), item.Snippet.ChannelTitle)
 		tracks = append(tracks, playlist.Track{
-			Name:      item.Snippet.Title,
-			Artists:   []string{item.Snippet.ChannelTitle},
-			ID:        videoID,
-			ArtistIDs: []string{item.Snippet.ChannelId},
-			URL:       videoURL,
+			Name:       parsed.Title,
+			Artists:    parsed.Artists,
+			ID:         videoID,
+			ArtistIDs:  []string{item.Snippet.ChannelId},
+			URL:        videoURL,
+			Version:    parsed.Version,
+			DurationMs: durations[videoID],
 		})
 	}
 
 	return tracks, nil
 }
 
+// SearchTracksBy searches for videos on YouTube. YouTube has no structured search,
+// so the descriptive fields are combined into a free-text query and the ISRC is ignored.
+func (a *YouTubeAdapter) SearchTracksBy(ctx context.Context, query SearchQuery, limit int) ([]playlist.Track, error) {
+	text := query.FreeText()
+	if text == "" {
+		return nil, nil
+	}
+	return a.SearchTracks(ctx, text, limit)
+}
+
+// Capabilities describes the YouTube Data API limits
+func (a *YouTubeAdapter) Capabilities() Capabilities {
+	return youtubeCapabilities
+}
+
+// videoDurations looks up the duration in milliseconds of up to 50 videos
+func (a *YouTubeAdapter) videoDurations(ctx context.Context, videoIDs []string) (map[string]int, error) {
+	durations := make(map[string]int, len(videoIDs))
+	if len(videoIDs) == 0 {
+		return durations, nil
+	}
+
+	response, err := a.service.Videos.List([]string{"contentDetails"}).
+		Id(videoIDs...).
+		MaxResults(50).
+		Context(ctx).
+		Do()
+	if err != nil {
+		return nil, fmt.Errorf("error fetching video details: %v", err)
+	}
+
+	for _, item := range response.Items {
+		durations[item.Id] = parseISODuration(item.ContentDetails.Duration)
+	}
+	return durations, nil
+}
+
+// parseISODuration converts an ISO 8601 duration such as "PT4M13S" to milliseconds.
+// Malformed durations yield 0.
+func parseISODuration(d string) int {
+	if !strings.HasPrefix(d, "P") {
+		return 0
+	}
+
+	var total time.Duration
+	var n int
+	inTime := false
+	for _, r := range d[1:] {
+		switch {
+		case r >= '0' && r <= '9':
+			n = n*10 + int(r-'0')
+		case r == 'T':
+			inTime = true
+		case r == 'W':
+			total += time.Duration(n) * 7 * 24 * time.Hour
+			n = 0
+		case r == 'D':
+			total += time.Duration(n) * 24 * time.Hour
+			n = 0
+		case r == 'H' && inTime:
+			total += time.Duration(n) * time.Hour
+			n = 0
+		case r == 'M' && inTime:
+			total += time.Duration(n) * time.Minute
+			n = 0
+		case r == 'S' && inTime:
+			total += time.Duration(n) * time.Second
+			n = 0
+		default:
+			return 0
+		}
+	}
+	return int(total.Milliseconds())
+}
+
 // completeAuth handles the OAuth2 callback
 func (a *YouTubeAdapter) completeAuth(w http.ResponseWriter, r *http.Request, config *oauth2.Config) {
 	if r.FormValue("state") != a.state {
@@ -305,14 +478,13 @@ func (a *YouTubeAdapter) completeAuth(w http.ResponseWriter, r *http.Request, co
 	}
 
 	code := r.FormValue("code")
-	token, err := config.Exchange(r.Context(), code)
+	token, err := config.Exchange(a.opts.oauthContext(r.Context()), code)
 	if err != nil {
 		http.Error(w, "Couldn't get token", http.StatusInternalServerError)
 		log.Fatalf("Error exchanging code for token: %v", err)
 	}
 
-	client := config.Client(r.Context(), token)
-	service, err := youtube.NewService(context.Background(), option.WithHTTPClient(client))
+	service, err := a.newService(config, token)
 	if err != nil {
 		http.Error(w, "Error creating YouTube client", http.StatusInternalServerError)
 		log.Fatalf("Error creating YouTube client: %v", err)
@@ -0,0 +1,113 @@
+package adapters_test
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"golang.org/x/oauth2"
+
+	"soundporter/internal/adapters"
+	"soundporter/internal/playlist"
+	"soundporter/internal/standin"
+)
+
+// startYouTube starts a YouTube stand-in for the conformance suite
+func startYouTube(t *testing.T, catalog []playlist.Track, playlists []playlist.Playlist) (*standin.YouTube, standinRun) {
+	t.Helper()
+	y := standin.NewYouTube(catalog, playlists)
+	t.Cleanup(y.Close)
+	withToken := func(token *oauth2.Token) adapters.ApiAdapter {
+		a, err := adapters.NewYouTubeAdapter("client-id", "client-secret", append(y.Options(), adapters.WithToken(token))...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return a
+	}
+	return y, standinRun{
+		adapter: withToken(y.OAuth.Token()),
+		// Expired tokens are refreshed through the token endpoint. Authenticating with a token
+		// makes no request, so a revoked token fails the first call.
+		accepted: map[string]adapters.ApiAdapter{"an expired token": withToken(y.OAuth.ExpiredToken())},
+		refused:  map[string]adapters.ApiAdapter{"a revoked token": withToken(&oauth2.Token{AccessToken: "revoked"})},
+		stored:   y,
+	}
+}
+
+func TestYouTubeStandinTracks(t *testing.T) {
+	_, run := startYouTube(t, nil, nil)
+	a := authenticate(t, run.adapter)
+	catalog := adapters.FakeCatalog()
+
+	tracks, err := a.GetPlaylistItems(context.Background(), "fake-playlist-3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Titles are parsed back into artist and title, and durations come from a separate lookup
+	// for each page
+	for _, i := range []int{0, 9} {
+		want, got := catalog[i], tracks[i]
+		if got.Name != want.Name || !slices.Equal(got.Artists, want.Artists) || got.DurationMs != want.DurationMs {
+			t.Errorf("track %d converted to %+v, want %+v", i, got, want)
+		}
+		if got.URL != "https://www.youtube.com/watch?v="+want.ID || got.ArtistIDs[0] != "UC"+want.ArtistIDs[0] {
+			t.Errorf("track %d has URL %s and channel %v", i, got.URL, got.ArtistIDs)
+		}
+	}
+	// The artists before the dash are kept as one, as the comma may be part of a name
+	if got := tracks[12]; !slices.Equal(got.Artists, []string{"Data Race, Fencepost"}) {
+		t.Fatalf("title artists converted to %q", got.Artists)
+	}
+}
+
+func TestYouTubeStandinEscapedTitles(t *testing.T) {
+	catalog := append(adapters.FakeCatalog(), playlist.Track{
+		ID:         "amp-01",
+		Name:       "Rock & Roll",
+		Artists:    []string{"Ampersand"},
+		ArtistIDs:  []string{"ampersand"},
+		DurationMs: 180000,
+	})
+	_, run := startYouTube(t, catalog, nil)
+	a := authenticate(t, run.adapter)
+
+	// Search results are HTML-escaped
+	tracks, err := a.SearchTracks(context.Background(), "Rock Roll", 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tracks) != 1 || tracks[0].Name != "Rock & Roll" {
+		t.Fatalf("escaped title not decoded: %+v", tracks)
+	}
+}
+
+func TestYouTubeStandinInsert(t *testing.T) {
+	ctx := context.Background()
+	y, run := startYouTube(t, nil, nil)
+	a := authenticate(t, run.adapter)
+
+	p, err := a.CreateNewPlaylist(ctx, "Copy", "", adapters.PrivacyUnlisted)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if privacy := y.PlaylistPrivacy(p.ID); privacy != "unlisted" {
+		t.Fatalf("playlist created as %q", privacy)
+	}
+
+	// Links are reduced to their video IDs
+	ids := []string{"fake-01", "https://www.youtube.com/watch?v=fake-02&t=42", "https://youtu.be/fake-03?si=x"}
+	if err := a.AddItemsToPlaylist(ctx, p.ID, ids); err != nil {
+		t.Fatal(err)
+	}
+	if created, _ := y.Playlist(p.ID); !slices.Equal(trackIDs(created.Tracks), []string{"fake-01", "fake-02", "fake-03"}) {
+		t.Fatalf("unexpected playlist %v", trackIDs(created.Tracks))
+	}
+
+	// Insertion stops at the first video the API rejects
+	if err := a.AddItemsToPlaylist(ctx, p.ID, []string{"fake-04", "missing", "fake-05"}); err == nil {
+		t.Fatal("expected an unknown video to fail")
+	}
+	if created, _ := y.Playlist(p.ID); !slices.Equal(trackIDs(created.Tracks), []string{"fake-01", "fake-02", "fake-03", "fake-04"}) {
+		t.Fatalf("unexpected playlist after a failed insert %v", trackIDs(created.Tracks))
+	}
+}
@@ -0,0 +1,158 @@
+package adapters
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// VideoTitle holds the metadata parsed from a YouTube video title and channel name
+type VideoTitle struct {
+	Artists []string
+	Title   string
+	Version string
+}
+
+var (
+	// bracketedRe matches (...) and [...] segments of a title
+	bracketedRe = regexp.MustCompile(`\s*[\(\[]([^\)\]]*)[\)\]]`)
+	// featRe matches a featured artists clause outside brackets, e.g. "Song feat. X"
+	featRe = regexp.MustCompile(`(?i)\s+(?:feat\.?|ft\.?|featuring)\s+(.+)$`)
+	// featPrefixRe matches a featured artists clause inside brackets, e.g. "(feat. X)"
+	featPrefixRe = regexp.MustCompile(`(?i)^(?:feat\.?|ft\.?|featuring)\s+(.+)$`)
+	// noiseRe matches words that never belong to the song title itself
+	noiseRe = regexp.MustCompile(`(?i)\b(official|music video|video|audio|lyrics?|lyric video|visuali[sz]er|hd|hq|4k|remaster(ed)?|\d{4} remaster(ed)?|mv|m/v|explicit|clean)\b`)
+	// versionRe matches words that describe a different recording of the song
+	versionRe = regexp.MustCompile(`(?i)\b(live|remix|acoustic|edit|version|mix|demo|instrumental|unplugged|cover|session|extended|radio|rework|bootleg)\b`)
+	// separators between artist and title in "Artist - Title"
+	titleSeparators = []string{" - ", " – ", " — ", " ~ "}
+)
+
+// ParseVideoTitle splits a music video title such as "Artist - Title (feat. X) [Official Video]"
+// into artists, title and version, falling back to the channel name for the artist.
+// Noise like "Official Music Video", "Lyrics", "HD" and "Remastered" is dropped.
+//
+// The artist part is kept whole, as the commas and ampersands between artists are also part of
+// names like "Tyler, The Creator" or "Simon & Garfunkel". Only featured artists are split off,
+// each clause becoming one more artist.
+func ParseVideoTitle(title, channel string) VideoTitle {
+	var parsed VideoTitle
+	var featured []string
+
+	// Anything after a pipe is usually channel branding or a noise suffix
+	if i := strings.Index(title, " | "); i > 0 {
+		title = title[:i]
+	}
+
+	// Pull bracketed segments out of the title, keeping featured artists and version info
+	title = bracketedRe.ReplaceAllStringFunc(title, func(segment string) string {
+		inner := strings.TrimSpace(bracketedRe.FindStringSubmatch(segment)[1])
+		if m := featPrefixRe.FindStringSubmatch(inner); m != nil {
+			featured = append(featured, cleanTitle(m[1]))
+			return ""
+		}
+		if versionRe.MatchString(inner) {
+			parsed.Version = joinVersion(parsed.Version, stripNoise(inner))
+		}
+		return ""
+	})
+
+	// Drop trailing segments such as "- Lyrics" that contain nothing but noise
+	for _, sep := range titleSeparators {
+		if i := strings.LastIndex(title, sep); i > 0 && stripNoise(title[i+len(sep):]) == "" {
+			title = title[:i]
+		}
+	}
+
+	// Split "Artist - Title", accepting "Title - Artist" when the channel says so
+	artistPart := ""
+	for _, sep := range titleSeparators {
+		if left, right, ok := strings.Cut(title, sep); ok {
+			artistPart, title = left, right
+			if channelName := NormalizeChannelName(channel); strings.EqualFold(cleanTitle(right), channelName) &&
+				!strings.EqualFold(cleanTitle(left), channelName) {
+				artistPart, title = right, left
+			}
+			break
+		}
+	}
+
+	// A trailing unbracketed "- Live at ..." style suffix is a version
+	for _, sep := range titleSeparators {
+		if left, right, ok := strings.Cut(title, sep); ok {
+			if versionRe.MatchString(right) {
+				parsed.Version = joinVersion(parsed.Version, stripNoise(right))
+				title = left
+			}
+		}
+	}
+
+	if m := featRe.FindStringSubmatch(title); m != nil {
+		featured = append(featured, cleanTitle(m[1]))
+		title = strings.TrimSpace(title[:len(title)-len(m[0])])
+	}
+	if m := featRe.FindStringSubmatch(artistPart); m != nil {
+		featured = append(featured, cleanTitle(m[1]))
+		artistPart = strings.TrimSpace(artistPart[:len(artistPart)-len(m[0])])
+	}
+
+	if artistPart != "" {
+		parsed.Artists = []string{cleanTitle(artistPart)}
+	} else if name := NormalizeChannelName(channel); name != "" {
+		parsed.Artists = []string{name}
+	}
+	parsed.Artists = append(parsed.Artists, featured...)
+	parsed.Title = cleanTitle(title)
+	return parsed
+}
+
+// NormalizeChannelName turns channel names like "Artist - Topic", "ArtistVEVO" or
+// "ArtistOfficial" into the artist's name
+func NormalizeChannelName(channel string) string {
+	name := strings.TrimSpace(channel)
+	name = strings.TrimSuffix(name, " - Topic")
+	for _, suffix := range []string{"VEVO", "Vevo", "Official", "official"} {
+		if strings.HasSuffix(name, suffix) && len(name) > len(suffix) {
+			name = strings.TrimSpace(strings.TrimSuffix(name, suffix))
+			// "TaylorSwiftVEVO" has no spaces left to tell the words apart
+			if !strings.Contains(name, " ") {
+				name = splitCamelCase(name)
+			}
+			break
+		}
+	}
+	return name
+}
+
+// splitCamelCase inserts spaces before upper case letters that follow lower case ones
+func splitCamelCase(s string) string {
+	var b strings.Builder
+	runes := []rune(s)
+	for i, r := range runes {
+		if i > 0 && unicode.IsUpper(r) && unicode.IsLower(runes[i-1]) {
+			b.WriteRune(' ')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// cleanTitle removes leftover noise words, quotes and separators from a title
+func cleanTitle(title string) string {
+	title = strings.TrimSpace(title)
+	title = strings.Trim(title, `"'“”‘’`)
+	title = strings.Trim(title, " -–—|~,")
+	return strings.Join(strings.Fields(title), " ")
+}
+
+// stripNoise removes noise words from s, returning what is left
+func stripNoise(s string) string {
+	return cleanTitle(noiseRe.ReplaceAllString(s, ""))
+}
+
+func joinVersion(existing, version string) string {
+	if existing == "" {
+		return version
+	}
+	return existing + ", " + version
+}
@@ -0,0 +1,62 @@
+package adapters_test
+
+import (
+	"slices"
+	"testing"
+
+	"soundporter/internal/adapters"
+)
+
+func TestParseVideoTitle(t *testing.T) {
+	tests := []struct {
+		title, channel string
+		want           adapters.VideoTitle
+	}{
+		{"Daft Punk - One More Time (Official Video)", "Daft Punk", adapters.VideoTitle{Artists: []string{"Daft Punk"}, Title: "One More Time"}},
+		// Commas, ampersands and "and" are part of names as often as they separate them
+		{"Tyler, The Creator - EARFQUAKE", "Tyler, The Creator", adapters.VideoTitle{Artists: []string{"Tyler, The Creator"}, Title: "EARFQUAKE"}},
+		{"Simon & Garfunkel - The Boxer (Audio)", "SimonGarfunkelVEVO", adapters.VideoTitle{Artists: []string{"Simon & Garfunkel"}, Title: "The Boxer"}},
+		{"Earth, Wind & Fire - September", "", adapters.VideoTitle{Artists: []string{"Earth, Wind & Fire"}, Title: "September"}},
+		{"Mumford & Sons - Little Lion Man [HD]", "", adapters.VideoTitle{Artists: []string{"Mumford & Sons"}, Title: "Little Lion Man"}},
+		// Featured artists are split off, each clause kept whole
+		{"Calvin Harris - Feels (feat. Pharrell Williams, Katy Perry & Big Sean)", "", adapters.VideoTitle{
+			Artists: []string{"Calvin Harris", "Pharrell Williams, Katy Perry & Big Sean"}, Title: "Feels"}},
+		{"Mark Ronson ft. Bruno Mars - Uptown Funk", "", adapters.VideoTitle{Artists: []string{"Mark Ronson", "Bruno Mars"}, Title: "Uptown Funk"}},
+		{"Kendrick Lamar - LOVE. featuring Zacari", "", adapters.VideoTitle{Artists: []string{"Kendrick Lamar", "Zacari"}, Title: "LOVE."}},
+		// Versions are kept, noise is dropped
+		{"Radiohead - Creep (Live at Glastonbury 1997) [Remastered]", "", adapters.VideoTitle{Artists: []string{"Radiohead"}, Title: "Creep", Version: "Live at Glastonbury 1997"}},
+		{"Nirvana - Lithium - Acoustic Version", "", adapters.VideoTitle{Artists: []string{"Nirvana"}, Title: "Lithium", Version: "Acoustic Version"}},
+		{"Adele - Hello - Lyrics | Best Songs", "", adapters.VideoTitle{Artists: []string{"Adele"}, Title: "Hello"}},
+		// The channel stands in for a missing artist, and tells "Title - Artist" apart
+		{"Bad Guy", "Billie Eilish - Topic", adapters.VideoTitle{Artists: []string{"Billie Eilish"}, Title: "Bad Guy"}},
+		{"Shake It Off - Taylor Swift", "TaylorSwiftVEVO", adapters.VideoTitle{Artists: []string{"Taylor Swift"}, Title: "Shake It Off"}},
+		{"", "", adapters.VideoTitle{}},
+	}
+	for _, tt := range tests {
+		got := adapters.ParseVideoTitle(tt.title, tt.channel)
+		if !slices.Equal(got.Artists, tt.want.Artists) || got.Title != tt.want.Title || got.Version != tt.want.Version {
+			t.Errorf("ParseVideoTitle(%q, %q) = %+v, want %+v", tt.title, tt.channel, got, tt.want)
+		}
+	}
+}
+
+func TestNormalizeChannelName(t *testing.T) {
+	tests := []struct {
+		channel, want string
+	}{
+		{"Billie Eilish - Topic", "Billie Eilish"},
+		{"TaylorSwiftVEVO", "Taylor Swift"},
+		{"Arctic Monkeys VEVO", "Arctic Monkeys"},
+		{"ColdplayVevo", "Coldplay"},
+		{"Imagine Dragons Official", "Imagine Dragons"},
+		{"  Daft Punk  ", "Daft Punk"},
+		{"VEVO", "VEVO"},
+		{"Tyler, The Creator", "Tyler, The Creator"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := adapters.NormalizeChannelName(tt.channel); got != tt.want {
+			t.Errorf("NormalizeChannelName(%q) = %q, want %q", tt.channel, got, tt.want)
+		}
+	}
+}
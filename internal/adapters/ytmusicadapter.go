@@ -0,0 +1,733 @@
+package adapters
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"iter"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"soundporter/internal/playlist"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	ytmusicBaseURL = "https://music.youtube.com/youtubei/v1/"
+	// ytmusicOrigin is the origin the web client sends, which the SAPISIDHASH is computed for
+	ytmusicOrigin = "https://music.youtube.com"
+	// ytmusicUserAgent is sent when the headers file has no User-Agent of its own
+	ytmusicUserAgent = "Mozilla/5.0 (X11; Linux x86_64; rv:128.0) Gecko/20100101 Firefox/128.0"
+	// ytmusicSongsParams is the search filter for songs, leaving out videos, albums and playlists
+	ytmusicSongsParams = "EgWKAQIIAWoMEA4QChADEAQQCRAF"
+	// ytmusicVideoTypeUGC marks user uploaded videos, whose titles aren't clean song titles
+	ytmusicVideoTypeUGC = "MUSIC_VIDEO_TYPE_UGC"
+)
+
+var (
+	// ytmusicDurationRe matches durations like "3:45" or "1:02:03"
+	ytmusicDurationRe = regexp.MustCompile(`^(?:\d+:)?\d{1,2}:\d{2}$`)
+	// ytmusicCountRe matches the track count in a playlist's subtitle, e.g. "1,234 songs"
+	ytmusicCountRe = regexp.MustCompile(`^([\d,.]+) (?:songs?|tracks?|episodes?)$`)
+	// ytmusicForwardHeaders are the headers of the user's browser sent along with every request
+	ytmusicForwardHeaders = []string{"Cookie", "User-Agent", "Accept-Language", "X-Goog-Authuser", "X-Goog-Visitor-Id"}
+)
+
+// YouTube Music only has free-text search, but unlike the Data API it adds many songs per request
+// and isn't metered by quota
+var ytmusicCapabilities = Capabilities{
+	MaxBatchSize:         100,
+	MaxSearchLimit:       20,
+	MaxDescriptionLength: 5000,
+	PrivacyLevels:        []Privacy{PrivacyPrivate, PrivacyUnlisted, PrivacyPublic},
+}
+
+func init() {
+	Register(Registration{
+		Name:        YTMusicPlatform,
+		DisplayName: "YouTube Music (web client)",
+		Credentials: []CredentialSpec{
+			{Key: "headers", EnvVar: "YTMUSIC_HEADERS", Description: "File with the request headers or the cookie of a logged-in music.youtube.com tab"},
+		},
+		Capabilities: ytmusicCapabilities,
+		New: func(creds Credentials, opts ...Option) (ApiAdapter, error) {
+			return NewYTMusicAdapter(creds["headers"], opts...)
+		},
+	})
+}
+
+// YTMusicAdapter adapts the internal API of the YouTube Music web client, InnerTube, to our
+// common adapter interface. It acts as the user's browser, authenticated by its cookie, which
+// avoids the quota of the YouTube Data API. Searches find songs rather than videos, and tracks
+// carry their song, artist and album metadata.
+type YTMusicAdapter struct {
+	BaseAdapter
+	headers http.Header
+	sapisid string
+	api     *httpAPI
+}
+
+// NewYTMusicAdapter creates an adapter authenticating with the headers in headersFile, copied
+// from a request of a logged-in music.youtube.com tab. The file may also hold just the cookie.
+func NewYTMusicAdapter(headersFile string, opts ...Option) (*YTMusicAdapter, error) {
+	if headersFile == "" {
+		return nil, fmt.Errorf("youtube music headers file must be provided")
+	}
+	data, err := os.ReadFile(headersFile)
+	if err != nil {
+		return nil, fmt.Errorf("error reading YouTube Music headers: %v", err)
+	}
+	headers, err := parseYTMusicHeaders(data)
+	if err != nil {
+		return nil, err
+	}
+	sapisid := ytmusicSAPISID(headers.Get("Cookie"))
+	if sapisid == "" {
+		return nil, fmt.Errorf("the YouTube Music cookie has no SAPISID, copy it from a tab where you are logged in")
+	}
+
+	a := &YTMusicAdapter{
+		BaseAdapter: NewBaseAdapter("YouTube Music"),
+		headers:     headers,
+		sapisid:     sapisid,
+	}
+	a.api = newHTTPAPI(newOptions(options{baseURL: ytmusicBaseURL}, opts))
+	a.api.authorize = a.authorize
+	a.api.decodeError = ytmusicError
+	return a, nil
+}
+
+// parseYTMusicHeaders reads headers in the "Name: value" lines browsers copy, or as a JSON
+// object, or else takes the whole file as the cookie. Only the headers identifying the user's
+// session and browser are kept.
+func parseYTMusicHeaders(data []byte) (http.Header, error) {
+	parsed := make(http.Header)
+	text := strings.TrimSpace(string(data))
+	switch {
+	case strings.HasPrefix(text, "{"):
+		var obj map[string]string
+		if err := json.Unmarshal([]byte(text), &obj); err != nil {
+			return nil, fmt.Errorf("error parsing YouTube Music headers: %v", err)
+		}
+		for name, value := range obj {
+			parsed.Set(name, value)
+		}
+	default:
+		for _, line := range strings.Split(text, "\n") {
+			// HTTP/2 pseudo-headers like ":authority" and the request line are skipped
+			name, value, ok := strings.Cut(strings.TrimSpace(line), ":")
+			if !ok || name == "" || strings.ContainsAny(name, " \t") {
+				continue
+			}
+			parsed.Set(name, strings.TrimSpace(value))
+		}
+		if len(parsed) == 0 && !strings.Contains(text, "\n") {
+			parsed.Set("Cookie", text)
+		}
+	}
+
+	headers := make(http.Header)
+	for _, name := range ytmusicForwardHeaders {
+		if value := parsed.Get(name); value != "" {
+			headers.Set(name, value)
+		}
+	}
+	if headers.Get("Cookie") == "" {
+		return nil, fmt.Errorf("the YouTube Music headers have no cookie")
+	}
+	if headers.Get("User-Agent") == "" {
+		headers.Set("User-Agent", ytmusicUserAgent)
+	}
+	if headers.Get("X-Goog-Authuser") == "" {
+		headers.Set("X-Goog-Authuser", "0")
+	}
+	return headers, nil
+}
+
+// ytmusicSAPISID returns the SAPISID of a cookie, which the Authorization header is derived from
+func ytmusicSAPISID(cookie string) string {
+	var sapisid string
+	for _, part := range strings.Split(cookie, ";") {
+		name, value, _ := strings.Cut(strings.TrimSpace(part), "=")
+		switch name {
+		case "__Secure-3PAPISID":
+			return value
+		case "SAPISID":
+			sapisid = value
+		}
+	}
+	return sapisid
+}
+
+// Authenticate checks that the cookie belongs to a logged-in account and looks up its name
+func (a *YTMusicAdapter) Authenticate(ctx context.Context) error {
+	var resp json.RawMessage
+	if err := a.call(ctx, "account/account_menu", nil, map[string]any{}, &resp); err != nil {
+		return fmt.Errorf("authentication failed: %v", err)
+	}
+	var account struct {
+		AccountName ytmusicText `json:"accountName"`
+	}
+	headers := ytmusicFind(resp, "activeAccountHeaderRenderer")
+	if len(headers) == 0 || json.Unmarshal(headers[0], &account) != nil {
+		return fmt.Errorf("authentication failed: YouTube Music doesn't recognise the cookie, copy the headers from a logged-in tab again")
+	}
+
+	fmt.Println("You are logged in as:", account.AccountName.String())
+	a.SetAuthenticated(true)
+	return nil
+}
+
+// authorize adds the browser's headers and an Authorization header hashing the SAPISID, the
+// time and the origin, as the web client does
+func (a *YTMusicAdapter) authorize(req *http.Request) error {
+	for name, values := range a.headers {
+		req.Header[name] = values
+	}
+	now := strconv.FormatInt(time.Now().Unix(), 10)
+	sum := sha1.Sum([]byte(now + " " + a.sapisid + " " + ytmusicOrigin))
+	req.Header.Set("Authorization", "SAPISIDHASH "+now+"_"+hex.EncodeToString(sum[:]))
+	req.Header.Set("Origin", ytmusicOrigin)
+	req.Header.Set("X-Origin", ytmusicOrigin)
+	return nil
+}
+
+// call posts body to an InnerTube endpoint, adding the context of the web client
+func (a *YTMusicAdapter) call(ctx context.Context, endpoint string, query url.Values, body map[string]any, out any) error {
+	body["context"] = map[string]any{
+		"client": map[string]string{
+			"clientName": "WEB_REMIX",
+			// The web client's version is dated, and InnerTube accepts today's
+			"clientVersion": "1." + time.Now().UTC().Format("20060102") + ".01.00",
+			// English labels, so that subtitles like "12 songs" can be read
+			"hl": "en",
+		},
+		"user": map[string]any{},
+	}
+	if query == nil {
+		query = url.Values{}
+	}
+	query.Set("prettyPrint", "false")
+	return a.api.do(ctx, http.MethodPost, endpoint, query, body, out)
+}
+
+// browse requests a page, or the continuation of one if token is set, and returns the first
+// of the containers named by keys that it holds
+func (a *YTMusicAdapter) browse(ctx context.Context, browseID string, token ytmusicContinuation, keys ...string) (json.RawMessage, error) {
+	body := map[string]any{}
+	var query url.Values
+	switch {
+	case token.body:
+		body["continuation"] = token.token
+	case token.token != "":
+		query = url.Values{"ctoken": {token.token}, "continuation": {token.token}, "type": {"next"}}
+	default:
+		body["browseId"] = browseID
+	}
+
+	var resp json.RawMessage
+	if err := a.call(ctx, "browse", query, body, &resp); err != nil {
+		return nil, err
+	}
+	for _, key := range keys {
+		if found := ytmusicFind(resp, key); len(found) > 0 {
+			return found[0], nil
+		}
+	}
+	return nil, nil
+}
+
+// GetUserPlaylists retrieves all playlists for the authenticated user
+func (a *YTMusicAdapter) GetUserPlaylists(ctx context.Context) ([]playlist.Playlist, error) {
+	return collect(a.UserPlaylists(ctx))
+}
+
+// UserPlaylists streams the playlists in the user's library, including Liked Music and the
+// playlists the user saved
+func (a *YTMusicAdapter) UserPlaylists(ctx context.Context) iter.Seq2[playlist.Playlist, error] {
+	return func(yield func(playlist.Playlist, error) bool) {
+		if err := a.CheckAuth(); err != nil {
+			yield(playlist.Playlist{}, err)
+			return
+		}
+
+		var token ytmusicContinuation
+		for {
+			grid, err := a.browse(ctx, "FEmusic_liked_playlists", token, "gridRenderer", "gridContinuation", "appendContinuationItemsAction")
+			if err != nil {
+				yield(playlist.Playlist{}, fmt.Errorf("error getting playlists: %v", err))
+				return
+			}
+			for _, raw := range ytmusicFind(grid, "musicTwoRowItemRenderer") {
+				var item ytmusicTwoRowItem
+				if err := json.Unmarshal(raw, &item); err != nil {
+					yield(playlist.Playlist{}, fmt.Errorf("error reading playlist: %v", err))
+					return
+				}
+				// The grid starts with a button creating a playlist
+				p, ok := item.playlist()
+				if !ok {
+					continue
+				}
+				if !yield(p, nil) {
+					return
+				}
+			}
+
+			if token = ytmusicNextContinuation(grid); token.token == "" {
+				return
+			}
+		}
+	}
+}
+
+// GetPlaylistItems retrieves all tracks in a playlist
+func (a *YTMusicAdapter) GetPlaylistItems(ctx context.Context, playlistID string) ([]playlist.Track, error) {
+	return collect(a.PlaylistItems(ctx, playlistID))
+}
+
+// PlaylistItems streams the tracks of a playlist, continuation by continuation
+func (a *YTMusicAdapter) PlaylistItems(ctx context.Context, playlistID string) iter.Seq2[playlist.Track, error] {
+	return func(yield func(playlist.Track, error) bool) {
+		if err := a.CheckAuth(); err != nil {
+			yield(playlist.Track{}, err)
+			return
+		}
+
+		var token ytmusicContinuation
+		for {
+			// Playlist pages also suggest songs to add, so only the playlist's own shelf is read
+			shelf, err := a.browse(ctx, "VL"+strings.TrimPrefix(playlistID, "VL"), token,
+				"musicPlaylistShelfRenderer", "musicPlaylistShelfContinuation", "appendContinuationItemsAction")
+			if err != nil {
+				yield(playlist.Track{}, fmt.Errorf("error getting playlist items: %v", err))
+				return
+			}
+			for _, raw := range ytmusicFind(shelf, "musicResponsiveListItemRenderer") {
+				var item ytmusicListItem
+				if err := json.Unmarshal(raw, &item); err != nil {
+					yield(playlist.Track{}, fmt.Errorf("error reading playlist item: %v", err))
+					return
+				}
+				// Deleted videos are listed without an ID
+				track, ok := item.track()
+				if !ok {
+					continue
+				}
+				if !yield(track, nil) {
+					return
+				}
+			}
+
+			if token = ytmusicNextContinuation(shelf); token.token == "" {
+				return
+			}
+		}
+	}
+}
+
+// CreateNewPlaylist creates an empty playlist in the user's library
+func (a *YTMusicAdapter) CreateNewPlaylist(ctx context.Context, name string, description string, privacy Privacy) (playlist.Playlist, error) {
+	if err := a.CheckAuth(); err != nil {
+		return playlist.Playlist{}, err
+	}
+	if privacy == "" {
+		privacy = PrivacyPrivate
+	}
+
+	body := map[string]any{
+		"title":         name,
+		"description":   description,
+		"privacyStatus": strings.ToUpper(string(privacy)),
+	}
+	var resp struct {
+		PlaylistID string `json:"playlistId"`
+	}
+	if err := a.call(ctx, "playlist/create", nil, body, &resp); err != nil {
+		return playlist.Playlist{}, fmt.Errorf("error creating playlist: %v", err)
+	}
+	if resp.PlaylistID == "" {
+		return playlist.Playlist{}, fmt.Errorf("error creating playlist: YouTube Music returned no playlist")
+	}
+	return playlist.Playlist{
+		ID:          resp.PlaylistID,
+		Name:        name,
+		Description: description,
+		CreatedAt:   time.Now(),
+	}, nil
+}
+
+// AddItemsToPlaylist appends songs to a playlist in one request. Songs already in the
+// playlist are skipped, as the web client offers to.
+func (a *YTMusicAdapter) AddItemsToPlaylist(ctx context.Context, playlistID string, trackIDs []string) error {
+	if err := a.CheckAuth(); err != nil {
+		return err
+	}
+
+	actions := make([]map[string]string, len(trackIDs))
+	for i, id := range trackIDs {
+		actions[i] = map[string]string{
+			"action":       "ACTION_ADD_VIDEO",
+			"addedVideoId": id,
+			"dedupeOption": "DEDUPE_OPTION_SKIP",
+		}
+	}
+	body := map[string]any{
+		"playlistId": strings.TrimPrefix(playlistID, "VL"),
+		"actions":    actions,
+	}
+	var resp struct {
+		Status string `json:"status"`
+	}
+	if err := a.call(ctx, "browse/edit_playlist", nil, body, &resp); err != nil {
+		return fmt.Errorf("error adding tracks to playlist: %v", err)
+	}
+	if resp.Status != "STATUS_SUCCEEDED" {
+		return fmt.Errorf("error adding tracks to playlist: YouTube Music answered %s", resp.Status)
+	}
+	return nil
+}
+
+// SearchTracks searches for songs, leaving out videos
+func (a *YTMusicAdapter) SearchTracks(ctx context.Context, query string, limit int) ([]playlist.Track, error) {
+	if err := a.CheckAuth(); err != nil {
+		return nil, err
+	}
+	if limit <= 0 || limit > ytmusicCapabilities.MaxSearchLimit {
+		limit = ytmusicCapabilities.MaxSearchLimit
+	}
+
+	var resp json.RawMessage
+	body := map[string]any{"query": query, "params": ytmusicSongsParams}
+	if err := a.call(ctx, "search", nil, body, &resp); err != nil {
+		return nil, fmt.Errorf("error searching tracks: %v", err)
+	}
+
+	var tracks []playlist.Track
+	for _, shelf := range ytmusicFind(resp, "musicShelfRenderer") {
+		for _, raw := range ytmusicFind(shelf, "musicResponsiveListItemRenderer") {
+			var item ytmusicListItem
+			if err := json.Unmarshal(raw, &item); err != nil {
+				return nil, fmt.Errorf("error reading search result: %v", err)
+			}
+			// Songs that can't be played are greyed out
+			track, ok := item.track()
+			if !ok || item.DisplayPolicy == "MUSIC_ITEM_RENDERER_DISPLAY_POLICY_GREY_OUT" {
+				continue
+			}
+			tracks = append(tracks, track)
+			if len(tracks) == limit {
+				return tracks, nil
+			}
+		}
+	}
+	return tracks, nil
+}
+
+// SearchTracksBy searches for songs. YouTube Music has no structured search, so the descriptive
+// fields are combined into a free-text query and the ISRC is ignored.
+func (a *YTMusicAdapter) SearchTracksBy(ctx context.Context, query SearchQuery, limit int) ([]playlist.Track, error) {
+	text := query.FreeText()
+	if text == "" {
+		return nil, nil
+	}
+	return a.SearchTracks(ctx, text, limit)
+}
+
+// Capabilities describes the limits of the YouTube Music web client
+func (a *YTMusicAdapter) Capabilities() Capabilities {
+	return ytmusicCapabilities
+}
+
+// ytmusicText is the formatted text of InnerTube, a list of runs that may link somewhere
+type ytmusicText struct {
+	Runs []ytmusicRun `json:"runs"`
+}
+
+type ytmusicRun struct {
+	Text               string           `json:"text"`
+	NavigationEndpoint *ytmusicEndpoint `json:"navigationEndpoint"`
+}
+
+// String joins the text of the runs
+func (t ytmusicText) String() string {
+	var b strings.Builder
+	for _, run := range t.Runs {
+		b.WriteString(run.Text)
+	}
+	return b.String()
+}
+
+// ytmusicEndpoint is where a link leads: a page, or a song to play
+type ytmusicEndpoint struct {
+	BrowseEndpoint *struct {
+		BrowseID string `json:"browseId"`
+		Configs  struct {
+			Music struct {
+				PageType string `json:"pageType"`
+			} `json:"browseEndpointContextMusicConfig"`
+		} `json:"browseEndpointContextSupportedConfigs"`
+	} `json:"browseEndpoint"`
+	WatchEndpoint *struct {
+		VideoID string `json:"videoId"`
+		Configs struct {
+			Music struct {
+				VideoType string `json:"musicVideoType"`
+			} `json:"watchEndpointMusicConfig"`
+		} `json:"watchEndpointMusicSupportedConfigs"`
+	} `json:"watchEndpoint"`
+}
+
+// pageType returns the type of page a link leads to, e.g. MUSIC_PAGE_TYPE_ARTIST
+func (e *ytmusicEndpoint) pageType() string {
+	if e == nil || e.BrowseEndpoint == nil {
+		return ""
+	}
+	return e.BrowseEndpoint.Configs.Music.PageType
+}
+
+// ytmusicTwoRowItem is an item of a grid, such as a playlist in the library
+type ytmusicTwoRowItem struct {
+	Title              ytmusicText     `json:"title"`
+	Subtitle           ytmusicText     `json:"subtitle"`
+	NavigationEndpoint ytmusicEndpoint `json:"navigationEndpoint"`
+}
+
+// playlist converts a grid item into our playlist model, if it links to a playlist
+func (i ytmusicTwoRowItem) playlist() (playlist.Playlist, bool) {
+	browse := i.NavigationEndpoint.BrowseEndpoint
+	if browse == nil || !strings.HasPrefix(browse.BrowseID, "VL") {
+		return playlist.Playlist{}, false
+	}
+	p := playlist.Playlist{
+		ID:   strings.TrimPrefix(browse.BrowseID, "VL"),
+		Name: i.Title.String(),
+	}
+	for _, run := range i.Subtitle.Runs {
+		if m := ytmusicCountRe.FindStringSubmatch(strings.TrimSpace(run.Text)); m != nil {
+			p.TrackCount, _ = strconv.Atoi(strings.NewReplacer(",", "", ".", "").Replace(m[1]))
+		}
+	}
+	return p, true
+}
+
+// ytmusicListItem is a row of a list, such as a song in a playlist or a search result. The
+// first column holds the title, the others link to the artists and the album.
+type ytmusicListItem struct {
+	FlexColumns []struct {
+		Column struct {
+			Text ytmusicText `json:"text"`
+		} `json:"musicResponsiveListItemFlexColumnRenderer"`
+	} `json:"flexColumns"`
+	FixedColumns []struct {
+		Column struct {
+			Text ytmusicText `json:"text"`
+		} `json:"musicResponsiveListItemFixedColumnRenderer"`
+	} `json:"fixedColumns"`
+	PlaylistItemData struct {
+		VideoID string `json:"videoId"`
+	} `json:"playlistItemData"`
+	Overlay struct {
+		Overlay struct {
+			Content struct {
+				Button struct {
+					Endpoint ytmusicEndpoint `json:"playNavigationEndpoint"`
+				} `json:"musicPlayButtonRenderer"`
+			} `json:"content"`
+		} `json:"musicItemThumbnailOverlayRenderer"`
+	} `json:"overlay"`
+	Badges []struct {
+		Badge struct {
+			Icon struct {
+				IconType string `json:"iconType"`
+			} `json:"icon"`
+		} `json:"musicInlineBadgeRenderer"`
+	} `json:"badges"`
+	DisplayPolicy string `json:"musicItemRendererDisplayPolicy"`
+}
+
+// track converts a list row into our track model. Rows without a video ID, such as deleted
+// videos, can't be referred to and are skipped.
+func (i ytmusicListItem) track() (playlist.Track, bool) {
+	if len(i.FlexColumns) == 0 {
+		return playlist.Track{}, false
+	}
+	title := i.FlexColumns[0].Column.Text
+
+	videoID, videoType := i.PlaylistItemData.VideoID, ""
+	for _, e := range []*ytmusicEndpoint{&i.Overlay.Overlay.Content.Button.Endpoint, ytmusicRunEndpoint(title)} {
+		if e != nil && e.WatchEndpoint != nil {
+			if videoID == "" {
+				videoID = e.WatchEndpoint.VideoID
+			}
+			if videoType == "" {
+				videoType = e.WatchEndpoint.Configs.Music.VideoType
+			}
+		}
+	}
+	if videoID == "" {
+		return playlist.Track{}, false
+	}
+
+	track := playlist.Track{
+		Name: title.String(),
+		ID:   videoID,
+		URL:  "https://music.youtube.com/watch?v=" + videoID,
+	}
+	var columns []ytmusicText
+	for _, c := range i.FlexColumns[1:] {
+		columns = append(columns, c.Column.Text)
+	}
+	for _, c := range i.FixedColumns {
+		columns = append(columns, c.Column.Text)
+	}
+	var texts []string
+	for _, column := range columns {
+		for _, run := range column.Runs {
+			text := strings.TrimSpace(run.Text)
+			switch page := run.NavigationEndpoint.pageType(); {
+			case page == "MUSIC_PAGE_TYPE_ARTIST" || page == "MUSIC_PAGE_TYPE_USER_CHANNEL":
+				track.Artists = append(track.Artists, text)
+				track.ArtistIDs = append(track.ArtistIDs, run.NavigationEndpoint.BrowseEndpoint.BrowseID)
+			case page == "MUSIC_PAGE_TYPE_ALBUM":
+				track.Album = text
+				track.AlbumID = run.NavigationEndpoint.BrowseEndpoint.BrowseID
+			case ytmusicDurationRe.MatchString(text):
+				track.DurationMs = ytmusicDuration(text)
+			case strings.Trim(text, "•&, ") != "":
+				texts = append(texts, text)
+			}
+		}
+	}
+	// Artists without a page, like "Various Artists", are text rather than links
+	if len(track.Artists) == 0 {
+		for _, text := range texts {
+			if text != "Song" && text != "Video" {
+				track.Artists = []string{text}
+				break
+			}
+		}
+	}
+	for _, b := range i.Badges {
+		if b.Badge.Icon.IconType == "MUSIC_EXPLICIT_BADGE" {
+			track.Explicit = true
+		}
+	}
+
+	// Uploaded videos are titled however the uploader liked, under the uploader's name
+	if videoType == ytmusicVideoTypeUGC {
+		channel := ""
+		if len(track.Artists) > 0 {
+			channel = track.Artists[0]
+		}
+		parsed := ParseVideoTitle(track.Name, channel)
+		track.Name, track.Artists, track.Version = parsed.Title, parsed.Artists, parsed.Version
+	}
+	return track, true
+}
+
+// ytmusicRunEndpoint returns the link of the first run of a text, if any
+func ytmusicRunEndpoint(t ytmusicText) *ytmusicEndpoint {
+	if len(t.Runs) == 0 {
+		return nil
+	}
+	return t.Runs[0].NavigationEndpoint
+}
+
+// ytmusicDuration converts a duration like "3:45" to milliseconds
+func ytmusicDuration(text string) int {
+	var seconds int
+	for _, part := range strings.Split(text, ":") {
+		n, _ := strconv.Atoi(part)
+		seconds = seconds*60 + n
+	}
+	return seconds * 1000
+}
+
+// ytmusicContinuation is the token of the next part of a list. Newer lists want it in the
+// request body, older ones as parameters.
+type ytmusicContinuation struct {
+	token string
+	body  bool
+}
+
+// ytmusicNextContinuation returns the continuation of a list, if there is more of it
+func ytmusicNextContinuation(list json.RawMessage) ytmusicContinuation {
+	for _, raw := range ytmusicFind(list, "continuationCommand") {
+		var c struct {
+			Token string `json:"token"`
+		}
+		if json.Unmarshal(raw, &c) == nil && c.Token != "" {
+			return ytmusicContinuation{token: c.Token, body: true}
+		}
+	}
+	for _, raw := range ytmusicFind(list, "nextContinuationData") {
+		var c struct {
+			Continuation string `json:"continuation"`
+		}
+		if json.Unmarshal(raw, &c) == nil && c.Continuation != "" {
+			return ytmusicContinuation{token: c.Continuation}
+		}
+	}
+	return ytmusicContinuation{}
+}
+
+// ytmusicFind returns the values of every key named key in a JSON document, in document order.
+// InnerTube nests its renderers differently from page to page and moves them between layouts,
+// so they are looked up wherever they are rather than by path.
+func ytmusicFind(data json.RawMessage, key string) []json.RawMessage {
+	var found []json.RawMessage
+	var walk func(json.RawMessage)
+	walk = func(value json.RawMessage) {
+		value = bytes.TrimSpace(value)
+		if len(value) == 0 || (value[0] != '{' && value[0] != '[') {
+			return
+		}
+		dec := json.NewDecoder(bytes.NewReader(value))
+		open, err := dec.Token()
+		if err != nil {
+			return
+		}
+		for dec.More() {
+			name := ""
+			if open == json.Delim('{') {
+				tok, err := dec.Token()
+				if err != nil {
+					return
+				}
+				name, _ = tok.(string)
+			}
+			var v json.RawMessage
+			if err := dec.Decode(&v); err != nil {
+				return
+			}
+			if name == key {
+				found = append(found, v)
+				continue
+			}
+			walk(v)
+		}
+	}
+	walk(data)
+	return found
+}
+
+// ytmusicError decodes the error body of an InnerTube response
+func ytmusicError(status int, body []byte) error {
+	var resp struct {
+		Error struct {
+			Message string `json:"message"`
+			Status  string `json:"status"`
+		} `json:"error"`
+	}
+	if json.Unmarshal(body, &resp) != nil || resp.Error.Message == "" {
+		return nil
+	}
+	return fmt.Errorf("%s: %s (HTTP %d)", resp.Error.Status, resp.Error.Message, status)
+}
@@ -0,0 +1,147 @@
+package adapters_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"soundporter/internal/adapters"
+	"soundporter/internal/playlist"
+	"soundporter/internal/standin"
+)
+
+// writeHeaders writes headers copied from a browser tab to a file for the adapter to read
+func writeHeaders(t *testing.T, headers string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "headers.txt")
+	if err := os.WriteFile(path, []byte(headers), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// startYTMusic starts a YouTube Music stand-in for the conformance suite
+func startYTMusic(t *testing.T, catalog []playlist.Track, playlists []playlist.Playlist) (*standin.YTMusic, standinRun) {
+	t.Helper()
+	y := standin.NewYTMusic(catalog, playlists)
+	t.Cleanup(y.Close)
+	withHeaders := func(headers string) adapters.ApiAdapter {
+		a, err := adapters.NewYTMusicAdapter(writeHeaders(t, headers), y.Options()...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return a
+	}
+	return y, standinRun{
+		adapter: withHeaders(y.Headers()),
+		// The cookie alone is enough, as a file holding a single line, and a stale one is
+		// answered as signed out
+		accepted: map[string]adapters.ApiAdapter{"the cookie alone": withHeaders(standin.YTMusicCookie)},
+		refused:  map[string]adapters.ApiAdapter{"a stale cookie": withHeaders(strings.ReplaceAll(y.Headers(), standin.YTMusicSAPISID, "expired-sapisid"))},
+		stored:   y,
+	}
+}
+
+func TestYTMusicStandinSignedOut(t *testing.T) {
+	y, _ := startYTMusic(t, nil, nil)
+
+	// A cookie of a signed out tab has no SAPISID
+	if _, err := adapters.NewYTMusicAdapter(writeHeaders(t, "VISITOR_INFO1_LIVE=standin; YSC=signed-out"), y.Options()...); err == nil {
+		t.Fatal("expected a cookie without a SAPISID to fail")
+	}
+}
+
+func TestYTMusicStandinTracks(t *testing.T) {
+	ctx := context.Background()
+	_, run := startYTMusic(t, nil, nil)
+	a := authenticate(t, run.adapter)
+	catalog := adapters.FakeCatalog()
+
+	tracks, err := a.GetPlaylistItems(ctx, "fake-playlist-3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, i := range []int{3, 9} {
+		want, got := catalog[i], tracks[i]
+		if got.Name != want.Name || got.Album != want.Album || got.DurationMs != want.DurationMs/1000*1000 ||
+			got.Explicit != want.Explicit || got.AlbumID != "MPREb_"+want.AlbumID {
+			t.Errorf("track %d converted to %+v, want %+v", i, got, want)
+		}
+		if !slices.Equal(got.Artists, want.Artists) || len(got.ArtistIDs) != len(want.ArtistIDs) {
+			t.Errorf("track %d has artists %v %v, want %v %v", i, got.Artists, got.ArtistIDs, want.Artists, want.ArtistIDs)
+		}
+	}
+	// Artists with pages are linked one by one
+	if got := tracks[12]; !slices.Equal(got.Artists, []string{"Data Race", "Fencepost"}) || len(got.ArtistIDs) != 2 {
+		t.Fatalf("linked artists converted to %q %v", got.Artists, got.ArtistIDs)
+	}
+
+	// Searches are filtered to songs, so fan uploads are left out
+	found, err := a.SearchTracksBy(ctx, adapters.SearchQuery{Track: "Green Build", Artist: "Continuous Integration"}, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(trackIDs(found), []string{"fake-08", "fake-10"}) {
+		t.Fatalf("search found %v", trackIDs(found))
+	}
+	if got, want := found[0], catalog[7]; got.Album != want.Album || got.DurationMs != want.DurationMs/1000*1000 {
+		t.Fatalf("search result converted to %+v", got)
+	}
+}
+
+func TestYTMusicStandinUnlinkedArtists(t *testing.T) {
+	ctx := context.Background()
+	catalog := adapters.FakeCatalog()
+	for i := range catalog {
+		catalog[i].ArtistIDs = nil
+	}
+	_, run := startYTMusic(t, catalog, []playlist.Playlist{{ID: "PLcredits", Name: "Credits", Tracks: catalog[11:13]}})
+	a := authenticate(t, run.adapter)
+
+	// Artists without pages are one run of text, read as one artist
+	tracks, err := a.GetPlaylistItems(ctx, "PLcredits")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tracks) != 2 || !slices.Equal(tracks[0].Artists, catalog[11].Artists) ||
+		!slices.Equal(tracks[1].Artists, []string{"Data Race & Fencepost"}) || tracks[1].ArtistIDs != nil {
+		t.Fatalf("unlinked artists converted to %+v", tracks)
+	}
+
+	found, err := a.SearchTracks(ctx, "Off By One", 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(found) != 1 || !slices.Equal(found[0].Artists, []string{"Data Race & Fencepost"}) || found[0].Album != "Happens Before" {
+		t.Fatalf("unlinked search result converted to %+v", found)
+	}
+}
+
+func TestYTMusicStandinDuplicates(t *testing.T) {
+	ctx := context.Background()
+	y, run := startYTMusic(t, nil, nil)
+	a := authenticate(t, run.adapter)
+
+	created, err := a.CreateNewPlaylist(ctx, "Copy", "", adapters.PrivacyUnlisted)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if privacy := y.Privacy(created.ID); privacy != "UNLISTED" {
+		t.Fatalf("playlist created as %q", privacy)
+	}
+
+	// Songs already in the playlist are skipped
+	ids := []string{"fake-01", "fake-02"}
+	if err := a.AddItemsToPlaylist(ctx, created.ID, ids); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.AddItemsToPlaylist(ctx, created.ID, ids); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := y.Playlist(created.ID); !slices.Equal(trackIDs(got.Tracks), ids) {
+		t.Fatalf("unexpected tracks after adding them again: %v", trackIDs(got.Tracks))
+	}
+}
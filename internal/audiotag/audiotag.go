@@ -0,0 +1,143 @@
+// Package audiotag reads the tags and duration of audio files: ID3 tags of MP3 files, Vorbis
+// comments of FLAC, Ogg Vorbis and Opus files, and the iTunes metadata of MP4 files. Only the
+// parts of a file holding metadata are read, so scanning a large library stays fast.
+package audiotag
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrUnsupported is returned for files that aren't in one of the supported formats
+var ErrUnsupported = errors.New("unsupported audio format")
+
+// Tags is the metadata of an audio file. Fields the file doesn't carry are left empty.
+type Tags struct {
+	Title       string
+	Artists     []string
+	Album       string
+	AlbumArtist string
+	ISRC        string
+	// Date is the release date, e.g. "1981" or "1981-12-15"
+	Date        string
+	TrackNumber int
+	DiscNumber  int
+	Explicit    bool
+	Duration    time.Duration
+}
+
+// ReadFile reads the tags of the audio file at path
+func ReadFile(path string) (Tags, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return Tags{}, err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return Tags{}, err
+	}
+	return Read(f, info.Size())
+}
+
+// Read reads the tags of an audio file of the given size. The format is detected from the
+// file's contents rather than its name.
+func Read(r io.ReaderAt, size int64) (Tags, error) {
+	head := make([]byte, 12)
+	if _, err := r.ReadAt(head, 0); err != nil {
+		return Tags{}, ErrUnsupported
+	}
+
+	var (
+		t   Tags
+		err error
+	)
+	switch {
+	case bytes.HasPrefix(head, []byte("ID3")):
+		// FLAC files sometimes carry an ID3 tag before their own header
+		offset, sizeErr := id3Size(r)
+		if sizeErr != nil {
+			return Tags{}, sizeErr
+		}
+		magic := make([]byte, 4)
+		if _, readErr := r.ReadAt(magic, offset); readErr == nil && string(magic) == "fLaC" {
+			t, err = readFLAC(io.NewSectionReader(r, offset, size-offset))
+		} else {
+			t, err = readMP3(r, size)
+		}
+	case bytes.HasPrefix(head, []byte("fLaC")):
+		t, err = readFLAC(io.NewSectionReader(r, 0, size))
+	case bytes.HasPrefix(head, []byte("OggS")):
+		t, err = readOgg(r, size)
+	case string(head[4:8]) == "ftyp":
+		t, err = readMP4(r, size)
+	case isMPEGFrame(head):
+		t, err = readMP3(r, size)
+	default:
+		return Tags{}, ErrUnsupported
+	}
+	if err != nil {
+		return Tags{}, fmt.Errorf("malformed tags: %v", err)
+	}
+	return t, nil
+}
+
+// setNumber parses a number like "3" or "3/12" into n, keeping n if there is none
+func setNumber(n *int, value string) {
+	value, _, _ = strings.Cut(strings.TrimSpace(value), "/")
+	if i, err := strconv.Atoi(value); err == nil && i > 0 {
+		*n = i
+	}
+}
+
+// normalizeDate shortens a timestamp like "1981-12-15T00:00:00Z" to its date
+func normalizeDate(date string) string {
+	date = strings.TrimSpace(date)
+	if len(date) > 10 && date[4] == '-' {
+		return date[:10]
+	}
+	return date
+}
+
+// appendUnique appends the non-empty values that aren't in list yet
+func appendUnique(list []string, values ...string) []string {
+	for _, v := range values {
+		v = strings.TrimSpace(v)
+		if v == "" {
+			continue
+		}
+		found := false
+		for _, existing := range list {
+			if existing == v {
+				found = true
+				break
+			}
+		}
+		if !found {
+			list = append(list, v)
+		}
+	}
+	return list
+}
+
+// readAt reads n bytes at offset, failing if the file is shorter
+func readAt(r io.ReaderAt, offset int64, n int) ([]byte, error) {
+	if n < 0 {
+		return nil, errors.New("negative length")
+	}
+	buf := make([]byte, n)
+	if _, err := r.ReadAt(buf, offset); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	return buf, nil
+}
@@ -0,0 +1,134 @@
+package audiotag
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// fixture is a small audio file built in memory and the tags it should read as
+type fixture struct {
+	name string
+	data []byte
+	want Tags
+}
+
+// join concatenates parts of a fixture
+func join(parts ...[]byte) []byte {
+	var data []byte
+	for _, p := range parts {
+		data = append(data, p...)
+	}
+	return data
+}
+
+// checkFixtures reads each fixture and compares its tags
+func checkFixtures(t *testing.T, fixtures []fixture) {
+	t.Helper()
+	for _, f := range fixtures {
+		got, err := Read(bytes.NewReader(f.data), int64(len(f.data)))
+		if err != nil {
+			t.Errorf("%s: %v", f.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, f.want) {
+			t.Errorf("%s:\n got %+v\nwant %+v", f.name, got, f.want)
+		}
+	}
+}
+
+func TestReadUnsupported(t *testing.T) {
+	for _, data := range [][]byte{
+		nil,
+		[]byte("short"),
+		[]byte("RIFF\x24\x00\x00\x00WAVEfmt "),
+		[]byte("not an audio file at all"),
+	} {
+		if _, err := Read(bytes.NewReader(data), int64(len(data))); !errors.Is(err, ErrUnsupported) {
+			t.Errorf("Read(%q) = %v, want ErrUnsupported", data, err)
+		}
+	}
+}
+
+func TestReadMalformed(t *testing.T) {
+	comment := vorbisComment("vendor", "TITLE=Counted")
+	binary.LittleEndian.PutUint32(comment[10:], 5)
+	mvhd := make([]byte, 20)
+	binary.BigEndian.PutUint32(mvhd[12:], 10)
+	binary.BigEndian.PutUint32(mvhd[16:], 20)
+	ilst := mp4Atom("ilst", mp4Item("\xa9nam", "Cut Short"))
+	binary.BigEndian.PutUint32(ilst[8:], 1000)
+
+	tests := []struct {
+		name    string
+		data    []byte
+		want    Tags
+		wantErr bool
+	}{
+		{"unknown ID3 version", id3Tag(5, 0, id3Frame(4, "TIT2", 0, id3Text(3, "Future"))), Tags{}, true},
+		{"ID3 extended header past the tag", id3Tag(3, 0x40, []byte{0, 0, 0, 100, 0, 0}), Tags{}, true},
+		{
+			"ID3 frame past the tag",
+			id3Tag(4, 0, id3Frame(4, "TIT2", 0, id3Text(3, "Kept")), []byte("TPE1\x00\x00\x00\x64\x00\x00lost")),
+			Tags{Title: "Kept"},
+			false,
+		},
+		{"Vorbis comment count past the fields", join([]byte("fLaC"), flacBlock(flacVorbisComment, true, comment)), Tags{}, true},
+		{"FLAC block past the end", join([]byte("fLaC"), flacBlock(flacStreamInfo, true, streamInfo(44100, 44100))[:20]), Tags{}, true},
+		{"Ogg page out of sync", join(oggPage(1, 0, []byte("OpusHead\x01\x02\x00\x00\x44\xac\x00\x00\x00\x00\x00")), []byte("garbage after the first page")), Tags{}, true},
+		{"Ogg stream of another codec", oggPage(1, 0, []byte("\x80theora video")), Tags{}, true},
+		{"MP4 box smaller than its header", join(mp4Atom("ftyp", []byte("M4A ")), []byte{0, 0, 0, 4, 'f', 'r', 'e', 'e'}), Tags{}, true},
+		{"MP4 without a movie box", join(mp4Atom("ftyp", []byte("M4A ")), mp4Atom("mdat", make([]byte, 16))), Tags{}, true},
+		{
+			"MP4 metadata past its box",
+			join(mp4Atom("ftyp", []byte("M4A ")), mp4Atom("moov", mp4Atom("mvhd", mvhd), mp4Atom("udta", mp4Atom("meta", make([]byte, 4), ilst)))),
+			Tags{Duration: 2 * time.Second},
+			false,
+		},
+	}
+	for _, tt := range tests {
+		got, err := Read(bytes.NewReader(tt.data), int64(len(tt.data)))
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: error %v, want error %t", tt.name, err, tt.wantErr)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s:\n got %+v\nwant %+v", tt.name, got, tt.want)
+		}
+	}
+}
+
+// TestReadTruncatedAndCorrupt reads every prefix of each fixture, and each fixture with every
+// byte overwritten in turn, to prove that broken files give errors or partial tags and never
+// a panic
+func TestReadTruncatedAndCorrupt(t *testing.T) {
+	var fixtures []fixture
+	fixtures = append(fixtures, id3Fixtures()...)
+	fixtures = append(fixtures, vorbisFixtures()...)
+	fixtures = append(fixtures, mp4Fixtures()...)
+
+	for _, f := range fixtures {
+		read := func(data []byte, what string) {
+			defer func() {
+				if r := recover(); r != nil {
+					t.Fatalf("%s %s: panic: %v", f.name, what, r)
+				}
+			}()
+			Read(bytes.NewReader(data), int64(len(data)))
+		}
+
+		for n := range len(f.data) {
+			read(f.data[:n], "truncated")
+		}
+		for i := range f.data {
+			for _, b := range []byte{0x00, 0xFF} {
+				corrupt := bytes.Clone(f.data)
+				corrupt[i] = b
+				read(corrupt, "corrupted")
+			}
+		}
+	}
+}
@@ -0,0 +1,316 @@
+package audiotag
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf16"
+)
+
+// id3v22Frames maps the three letter frame IDs of ID3v2.2 to their later names
+var id3v22Frames = map[string]string{
+	"TT2": "TIT2",
+	"TP1": "TPE1",
+	"TP2": "TPE2",
+	"TAL": "TALB",
+	"TRK": "TRCK",
+	"TPA": "TPOS",
+	"TYE": "TYER",
+	"TLE": "TLEN",
+	"TRC": "TSRC",
+	"TXX": "TXXX",
+}
+
+// id3Size returns the size of the ID3v2 tag at the start of the file, header and footer included
+func id3Size(r io.ReaderAt) (int64, error) {
+	header, err := readAt(r, 0, 10)
+	if err != nil {
+		return 0, err
+	}
+	size := int64(syncsafe(header[6:10])) + 10
+	if header[3] == 4 && header[5]&0x10 != 0 {
+		size += 10
+	}
+	return size, nil
+}
+
+// readMP3 reads the ID3v2 tag of an MP3 file, falling back to ID3v1, and works out the duration
+// from the tag or the first MPEG frame
+func readMP3(r io.ReaderAt, size int64) (Tags, error) {
+	var t Tags
+	var audioStart int64
+	if head, err := readAt(r, 0, 3); err == nil && string(head) == "ID3" {
+		tagSize, err := id3Size(r)
+		if err != nil {
+			return Tags{}, err
+		}
+		tag, err := readAt(r, 0, int(min(tagSize, size)))
+		if err != nil {
+			return Tags{}, err
+		}
+		if err := parseID3v2(tag, &t); err != nil {
+			return Tags{}, err
+		}
+		audioStart = tagSize
+	}
+
+	audioEnd := size
+	if v1, err := readAt(r, size-128, 128); err == nil && string(v1[:3]) == "TAG" {
+		if t.Title == "" {
+			parseID3v1(v1, &t)
+		}
+		audioEnd -= 128
+	}
+
+	if t.Duration == 0 {
+		t.Duration = mpegDuration(r, audioStart, audioEnd)
+	}
+	return t, nil
+}
+
+// parseID3v2 reads the frames of an ID3v2.2, 2.3 or 2.4 tag
+func parseID3v2(tag []byte, t *Tags) error {
+	version, flags := tag[3], tag[5]
+	if version < 2 || version > 4 {
+		return errors.New("unknown ID3v2 version " + strconv.Itoa(int(version)))
+	}
+	body := tag[10:]
+	// Before 2.4 unsynchronisation applies to the whole tag, in 2.4 to single frames
+	if flags&0x80 != 0 && version < 4 {
+		body = unsynchronise(body)
+	}
+	if flags&0x40 != 0 && version > 2 && len(body) >= 4 {
+		extended := int(binary.BigEndian.Uint32(body))
+		if version == 4 {
+			extended = syncsafe(body[:4])
+		} else {
+			extended += 4
+		}
+		if extended > len(body) {
+			return errors.New("extended header exceeds the tag")
+		}
+		body = body[extended:]
+	}
+
+	var year, day, length string
+	idLen, headerLen := 4, 10
+	if version == 2 {
+		idLen, headerLen = 3, 6
+	}
+	for len(body) >= headerLen && body[0] != 0 {
+		id := string(body[:idLen])
+		var size int
+		switch version {
+		case 2:
+			size = int(body[3])<<16 | int(body[4])<<8 | int(body[5])
+		case 3:
+			size = int(binary.BigEndian.Uint32(body[4:8]))
+		case 4:
+			size = syncsafe(body[4:8])
+		}
+		if size > len(body)-headerLen {
+			break
+		}
+		data := body[headerLen : headerLen+size]
+		var frameFlags byte
+		if version > 2 {
+			frameFlags = body[9]
+		}
+		body = body[headerLen+size:]
+
+		if version == 2 {
+			id = id3v22Frames[id]
+		}
+		data, ok := frameData(version, frameFlags, data)
+		if !ok || !strings.HasPrefix(id, "T") {
+			continue
+		}
+
+		values := decodeText(data)
+		value := strings.Join(values, "/")
+		switch id {
+		case "TIT2":
+			t.Title = value
+		case "TPE1":
+			t.Artists = appendUnique(nil, values...)
+		case "TALB":
+			t.Album = value
+		case "TPE2":
+			t.AlbumArtist = value
+		case "TSRC":
+			t.ISRC = value
+		case "TRCK":
+			setNumber(&t.TrackNumber, value)
+		case "TPOS":
+			setNumber(&t.DiscNumber, value)
+		case "TDRC", "TDOR":
+			if t.Date == "" || id == "TDRC" {
+				t.Date = normalizeDate(value)
+			}
+		case "TYER":
+			year = value
+		case "TDAT":
+			day = value
+		case "TLEN":
+			length = value
+		case "TXXX":
+			// User defined frames hold a description followed by the values
+			if len(values) < 2 {
+				continue
+			}
+			switch strings.ToUpper(values[0]) {
+			case "ARTISTS":
+				t.Artists = appendUnique(nil, values[1:]...)
+			case "ISRC":
+				if t.ISRC == "" {
+					t.ISRC = values[1]
+				}
+			case "ITUNESADVISORY":
+				t.Explicit = values[1] == "1"
+			}
+		}
+	}
+
+	// ID3v2.3 splits the date into a year and a DDMM day
+	if t.Date == "" && len(year) == 4 {
+		t.Date = year
+		if len(day) == 4 {
+			t.Date += "-" + day[2:] + "-" + day[:2]
+		}
+	}
+	if ms, err := strconv.Atoi(strings.TrimSpace(length)); err == nil && ms > 0 {
+		t.Duration = time.Duration(ms) * time.Millisecond
+	}
+	return nil
+}
+
+// frameData undoes the per-frame encodings of ID3v2.3 and 2.4. Compressed and encrypted frames
+// are skipped.
+func frameData(version, flags byte, data []byte) ([]byte, bool) {
+	switch version {
+	case 3:
+		if flags&0xC0 != 0 {
+			return nil, false
+		}
+		if flags&0x20 != 0 && len(data) > 0 {
+			data = data[1:]
+		}
+	case 4:
+		if flags&0x0C != 0 {
+			return nil, false
+		}
+		if flags&0x40 != 0 && len(data) > 0 {
+			data = data[1:]
+		}
+		if flags&0x01 != 0 && len(data) >= 4 {
+			data = data[4:]
+		}
+		if flags&0x02 != 0 {
+			data = unsynchronise(data)
+		}
+	}
+	return data, true
+}
+
+// decodeText decodes a text frame into its values, which ID3v2.4 separates with null characters
+func decodeText(data []byte) []string {
+	if len(data) == 0 {
+		return nil
+	}
+	var text string
+	switch data[0] {
+	case 0:
+		text = latin1(data[1:])
+	case 1:
+		text = decodeUTF16(data[1:], nil)
+	case 2:
+		text = decodeUTF16(data[1:], binary.BigEndian)
+	default:
+		text = string(data[1:])
+	}
+
+	var values []string
+	for _, v := range strings.Split(strings.TrimRight(text, "\x00"), "\x00") {
+		values = append(values, strings.TrimSpace(v))
+	}
+	return values
+}
+
+// decodeUTF16 decodes UTF-16 text. Without a byte order each string starts with a byte order
+// mark, which defaults to little endian.
+func decodeUTF16(data []byte, order binary.ByteOrder) string {
+	var units []uint16
+	current := order
+	for i := 0; i+1 < len(data); i += 2 {
+		if current == nil {
+			switch {
+			case data[i] == 0xFE && data[i+1] == 0xFF:
+				current = binary.BigEndian
+				continue
+			case data[i] == 0xFF && data[i+1] == 0xFE:
+				current = binary.LittleEndian
+				continue
+			default:
+				current = binary.LittleEndian
+			}
+		}
+		u := current.Uint16(data[i:])
+		units = append(units, u)
+		if u == 0 && order == nil {
+			// The next value starts with its own byte order mark
+			current = nil
+		}
+	}
+	return string(utf16.Decode(units))
+}
+
+func latin1(data []byte) string {
+	runes := make([]rune, len(data))
+	for i, b := range data {
+		runes[i] = rune(b)
+	}
+	return string(runes)
+}
+
+// parseID3v1 reads the fixed fields of an ID3v1 tag
+func parseID3v1(tag []byte, t *Tags) {
+	field := func(b []byte) string {
+		if i := bytes.IndexByte(b, 0); i >= 0 {
+			b = b[:i]
+		}
+		return strings.TrimSpace(latin1(b))
+	}
+	t.Title = field(tag[3:33])
+	if artist := field(tag[33:63]); artist != "" {
+		t.Artists = []string{artist}
+	}
+	t.Album = field(tag[63:93])
+	if year := field(tag[93:97]); len(year) == 4 {
+		t.Date = year
+	}
+	// ID3v1.1 keeps the track number in the last byte of the comment
+	if tag[125] == 0 && tag[126] != 0 {
+		t.TrackNumber = int(tag[126])
+	}
+}
+
+// syncsafe decodes a 28 bit integer stored in the low 7 bits of four bytes
+func syncsafe(b []byte) int {
+	return int(b[0]&0x7F)<<21 | int(b[1]&0x7F)<<14 | int(b[2]&0x7F)<<7 | int(b[3]&0x7F)
+}
+
+// unsynchronise removes the zero bytes inserted after 0xFF bytes
+func unsynchronise(data []byte) []byte {
+	out := make([]byte, 0, len(data))
+	for i := 0; i < len(data); i++ {
+		out = append(out, data[i])
+		if data[i] == 0xFF && i+1 < len(data) && data[i+1] == 0 {
+			i++
+		}
+	}
+	return out
+}
@@ -0,0 +1,163 @@
+package audiotag
+
+import (
+	"encoding/binary"
+	"strings"
+	"testing"
+	"time"
+)
+
+// syncsafeBytes encodes n in the low 7 bits of four bytes
+func syncsafeBytes(n int) []byte {
+	return []byte{byte(n >> 21 & 0x7F), byte(n >> 14 & 0x7F), byte(n >> 7 & 0x7F), byte(n & 0x7F)}
+}
+
+// id3Tag builds an ID3v2 tag around the already encoded frames in body
+func id3Tag(version, flags byte, body ...[]byte) []byte {
+	data := join(body...)
+	tag := append([]byte{'I', 'D', '3', version, 0, flags}, syncsafeBytes(len(data))...)
+	return append(tag, data...)
+}
+
+// id3Frame builds a frame with the header layout of the given ID3v2 version
+func id3Frame(version byte, id string, flags byte, data []byte) []byte {
+	frame := []byte(id)
+	switch version {
+	case 2:
+		frame = append(frame, byte(len(data)>>16), byte(len(data)>>8), byte(len(data)))
+	case 3:
+		frame = binary.BigEndian.AppendUint32(frame, uint32(len(data)))
+		frame = append(frame, 0, flags)
+	case 4:
+		frame = append(frame, syncsafeBytes(len(data))...)
+		frame = append(frame, 0, flags)
+	}
+	return append(frame, data...)
+}
+
+// id3Text encodes the values of a text frame, separated by null characters
+func id3Text(encoding byte, values ...string) []byte {
+	return append([]byte{encoding}, strings.Join(values, "\x00")...)
+}
+
+// addUnsync inserts the zero bytes unsynchronisation puts after 0xFF bytes
+func addUnsync(data []byte) []byte {
+	var out []byte
+	for _, b := range data {
+		out = append(out, b)
+		if b == 0xFF {
+			out = append(out, 0)
+		}
+	}
+	return out
+}
+
+// mpegFrames returns n bytes of MPEG-1 layer III audio at 128 kbit/s and 44.1 kHz, starting
+// with a Xing header counting frames if frames isn't 0
+func mpegFrames(n int, frames uint32) []byte {
+	audio := make([]byte, n)
+	copy(audio, []byte{0xFF, 0xFB, 0x90, 0x64})
+	if frames > 0 {
+		copy(audio[36:], "Xing")
+		binary.BigEndian.PutUint32(audio[40:], 1)
+		binary.BigEndian.PutUint32(audio[44:], frames)
+	}
+	return audio
+}
+
+// id3v1 builds an ID3v1.1 tag
+func id3v1(title, artist, album, year string, track byte) []byte {
+	tag := make([]byte, 128)
+	copy(tag, "TAG")
+	copy(tag[3:33], title)
+	copy(tag[33:63], artist)
+	copy(tag[63:93], album)
+	copy(tag[93:97], year)
+	tag[126] = track
+	return tag
+}
+
+func id3Fixtures() []fixture {
+	// A data length indicator precedes the album, and the compressed title can't be read
+	v24 := id3Tag(4, 0,
+		id3Frame(4, "TIT2", 0, id3Text(3, "Golden Master")),
+		id3Frame(4, "TPE1", 0, id3Text(3, "Data Race", "Fencepost")),
+		id3Frame(4, "TALB", 0x01, append(syncsafeBytes(16), id3Text(3, "Stubs and Spies")...)),
+		id3Frame(4, "TPE2", 0, id3Text(3, "Mock Orchestra")),
+		id3Frame(4, "TSRC", 0, id3Text(3, "XXFAK2500006")),
+		id3Frame(4, "TRCK", 0, id3Text(3, "3/12")),
+		id3Frame(4, "TPOS", 0, id3Text(3, "1/2")),
+		id3Frame(4, "TDRC", 0, id3Text(3, "2025-01-01T10:00:00")),
+		id3Frame(4, "TXXX", 0, id3Text(3, "ITUNESADVISORY", "1")),
+		id3Frame(4, "TLEN", 0, id3Text(3, "198000")),
+		id3Frame(4, "APIC", 0, []byte("\x00image/png\x00\x03\x00not a picture")),
+		id3Frame(4, "TIT2", 0x08, id3Text(3, "Compressed")),
+		make([]byte, 32),
+	)
+
+	// ID3v2.3 unsynchronises the whole tag, here after an extended header, and splits the date
+	utf16 := []byte{1, 0xFF, 0xFE}
+	for _, r := range "Björk" {
+		utf16 = binary.LittleEndian.AppendUint16(utf16, uint16(r))
+	}
+	extended := []byte{0, 0, 0, 6, 0, 0, 0, 0, 0, 0}
+	v23body := addUnsync(join(extended,
+		id3Frame(3, "TIT2", 0, []byte{0, 0xFF, 'e', 's'}),
+		id3Frame(3, "TPE1", 0, utf16),
+		id3Frame(3, "TYER", 0, id3Text(0, "2024")),
+		id3Frame(3, "TDAT", 0, id3Text(0, "1503")),
+		id3Frame(3, "TXXX", 0, id3Text(0, "ISRC", "GBAYE0000001")),
+	))
+	v23 := join(id3Tag(3, 0xC0, v23body), mpegFrames(1600, 0))
+
+	v22 := id3Tag(2, 0,
+		id3Frame(2, "TT2", 0, id3Text(0, "Bohemian Rhapsody")),
+		id3Frame(2, "TP1", 0, id3Text(0, "Queen")),
+		id3Frame(2, "TAL", 0, id3Text(0, "A Night at the Opera")),
+		id3Frame(2, "TRK", 0, id3Text(0, "11")),
+		id3Frame(2, "TYE", 0, id3Text(0, "1975")),
+	)
+
+	v1 := join(mpegFrames(417, 100), id3v1("Old Song", "Old Artist", "Old Album", "1999", 7))
+
+	return []fixture{
+		{"ID3v2.4", v24, Tags{
+			Title:       "Golden Master",
+			Artists:     []string{"Data Race", "Fencepost"},
+			Album:       "Stubs and Spies",
+			AlbumArtist: "Mock Orchestra",
+			ISRC:        "XXFAK2500006",
+			Date:        "2025-01-01",
+			TrackNumber: 3,
+			DiscNumber:  1,
+			Explicit:    true,
+			Duration:    198 * time.Second,
+		}},
+		{"ID3v2.3", v23, Tags{
+			Title:    "ÿes",
+			Artists:  []string{"Björk"},
+			ISRC:     "GBAYE0000001",
+			Date:     "2024-03-15",
+			Duration: 100 * time.Millisecond,
+		}},
+		{"ID3v2.2", v22, Tags{
+			Title:       "Bohemian Rhapsody",
+			Artists:     []string{"Queen"},
+			Album:       "A Night at the Opera",
+			Date:        "1975",
+			TrackNumber: 11,
+		}},
+		{"ID3v1", v1, Tags{
+			Title:       "Old Song",
+			Artists:     []string{"Old Artist"},
+			Album:       "Old Album",
+			Date:        "1999",
+			TrackNumber: 7,
+			Duration:    time.Duration(100*1152) * time.Second / 44100,
+		}},
+	}
+}
+
+func TestReadID3(t *testing.T) {
+	checkFixtures(t, id3Fixtures())
+}
@@ -0,0 +1,170 @@
+package audiotag
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"time"
+)
+
+// maxMoovSize limits the movie box we read, which holds the metadata and sample tables
+const maxMoovSize = 64 << 20
+
+// mp4Box is a box, also called an atom, of an MP4 file
+type mp4Box struct {
+	kind string
+	data []byte
+}
+
+// readMP4 reads the iTunes metadata and the duration from the movie box of an MP4 file,
+// which may come before or after the media data
+func readMP4(r io.ReaderAt, size int64) (Tags, error) {
+	var moov []byte
+	for offset := int64(0); offset+8 <= size; {
+		header, err := readAt(r, offset, 8)
+		if err != nil {
+			return Tags{}, err
+		}
+		boxSize, headerSize := int64(binary.BigEndian.Uint32(header)), int64(8)
+		switch boxSize {
+		case 0:
+			boxSize = size - offset
+		case 1:
+			ext, err := readAt(r, offset+8, 8)
+			if err != nil {
+				return Tags{}, err
+			}
+			boxSize, headerSize = int64(binary.BigEndian.Uint64(ext)), 16
+		}
+		if boxSize < headerSize {
+			return Tags{}, errors.New("invalid box size")
+		}
+
+		if string(header[4:8]) == "moov" {
+			if boxSize-headerSize > maxMoovSize {
+				return Tags{}, errors.New("movie box too large")
+			}
+			if moov, err = readAt(r, offset+headerSize, int(boxSize-headerSize)); err != nil {
+				return Tags{}, err
+			}
+			break
+		}
+		offset += boxSize
+	}
+	if moov == nil {
+		return Tags{}, errors.New("missing movie box")
+	}
+
+	var t Tags
+	for _, box := range mp4Boxes(moov) {
+		switch box.kind {
+		case "mvhd":
+			t.Duration = mvhdDuration(box.data)
+		case "udta":
+			for _, meta := range mp4Boxes(box.data) {
+				// The meta box is a full box, with 4 bytes of version and flags before its children
+				if meta.kind != "meta" || len(meta.data) < 4 {
+					continue
+				}
+				for _, ilst := range mp4Boxes(meta.data[4:]) {
+					if ilst.kind == "ilst" {
+						parseIlst(ilst.data, &t)
+					}
+				}
+			}
+		}
+	}
+	return t, nil
+}
+
+// mp4Boxes splits data into the boxes it contains, ignoring a truncated last box
+func mp4Boxes(data []byte) []mp4Box {
+	var boxes []mp4Box
+	for len(data) >= 8 {
+		size := int(binary.BigEndian.Uint32(data))
+		if size < 8 || size > len(data) {
+			break
+		}
+		boxes = append(boxes, mp4Box{kind: string(data[4:8]), data: data[8:size]})
+		data = data[size:]
+	}
+	return boxes
+}
+
+// mvhdDuration reads the duration from a movie header, which is in units of its time scale
+func mvhdDuration(data []byte) time.Duration {
+	var scale, duration uint64
+	switch {
+	case len(data) >= 32 && data[0] == 1:
+		scale, duration = uint64(binary.BigEndian.Uint32(data[20:])), binary.BigEndian.Uint64(data[24:])
+	case len(data) >= 20:
+		scale, duration = uint64(binary.BigEndian.Uint32(data[12:])), uint64(binary.BigEndian.Uint32(data[16:]))
+	}
+	if scale == 0 {
+		return 0
+	}
+	return time.Duration(duration) * time.Second / time.Duration(scale)
+}
+
+// parseIlst reads the items of an iTunes metadata list. Each item holds data boxes with
+// 4 bytes of type and 4 of locale before the value.
+func parseIlst(data []byte, t *Tags) {
+	var multiArtists []string
+	for _, item := range mp4Boxes(data) {
+		var name string
+		var values [][]byte
+		for _, child := range mp4Boxes(item.data) {
+			switch child.kind {
+			case "name":
+				// Freeform items name themselves, after 4 bytes of version and flags
+				if len(child.data) >= 4 {
+					name = string(child.data[4:])
+				}
+			case "data":
+				if len(child.data) >= 8 {
+					values = append(values, child.data[8:])
+				}
+			}
+		}
+		if len(values) == 0 {
+			continue
+		}
+		text := string(values[0])
+
+		switch item.kind {
+		case "\xa9nam":
+			t.Title = text
+		case "\xa9ART":
+			t.Artists = appendUnique(nil, text)
+		case "\xa9alb":
+			t.Album = text
+		case "aART":
+			t.AlbumArtist = text
+		case "\xa9day":
+			t.Date = normalizeDate(text)
+		case "trkn":
+			if len(values[0]) >= 4 {
+				t.TrackNumber = int(binary.BigEndian.Uint16(values[0][2:]))
+			}
+		case "disk":
+			if len(values[0]) >= 4 {
+				t.DiscNumber = int(binary.BigEndian.Uint16(values[0][2:]))
+			}
+		case "rtng":
+			// 1 and 4 mark explicit content, 2 clean versions
+			t.Explicit = len(values[0]) > 0 && (values[0][0] == 1 || values[0][0] == 4)
+		case "----":
+			switch name {
+			case "ISRC":
+				t.ISRC = text
+			case "ARTISTS":
+				for _, v := range values {
+					multiArtists = appendUnique(multiArtists, string(v))
+				}
+			}
+		}
+	}
+	if len(multiArtists) > 0 {
+		t.Artists = multiArtists
+	}
+}
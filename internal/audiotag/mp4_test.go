@@ -0,0 +1,112 @@
+package audiotag
+
+import (
+	"encoding/binary"
+	"testing"
+	"time"
+)
+
+// mp4Atom builds a box around its children
+func mp4Atom(kind string, children ...[]byte) []byte {
+	data := join(children...)
+	box := binary.BigEndian.AppendUint32(nil, uint32(8+len(data)))
+	return append(append(box, kind...), data...)
+}
+
+// mp4Data builds a data box, with its type and locale left empty
+func mp4Data(value []byte) []byte {
+	return mp4Atom("data", make([]byte, 8), value)
+}
+
+// mp4Item builds an iTunes metadata item holding text values
+func mp4Item(kind string, values ...string) []byte {
+	var data [][]byte
+	for _, v := range values {
+		data = append(data, mp4Data([]byte(v)))
+	}
+	return mp4Atom(kind, data...)
+}
+
+// mp4Freeform builds a freeform item, which names itself
+func mp4Freeform(name string, values ...string) []byte {
+	children := [][]byte{
+		mp4Atom("mean", make([]byte, 4), []byte("com.apple.iTunes")),
+		mp4Atom("name", make([]byte, 4), []byte(name)),
+	}
+	for _, v := range values {
+		children = append(children, mp4Data([]byte(v)))
+	}
+	return mp4Atom("----", children...)
+}
+
+// mp4Moov builds a movie box with a movie header and the metadata items
+func mp4Moov(mvhd []byte, items ...[]byte) []byte {
+	meta := mp4Atom("meta", make([]byte, 4), mp4Atom("hdlr", make([]byte, 25)), mp4Atom("ilst", items...))
+	return mp4Atom("moov", mp4Atom("mvhd", mvhd), mp4Atom("trak", make([]byte, 16)), mp4Atom("udta", meta))
+}
+
+func mp4Fixtures() []fixture {
+	mvhd := make([]byte, 100)
+	binary.BigEndian.PutUint32(mvhd[12:], 1000)
+	binary.BigEndian.PutUint32(mvhd[16:], 5000)
+	m4a := join(
+		mp4Atom("ftyp", []byte("M4A \x00\x00\x02\x00M4A isom")),
+		mp4Atom("mdat", make([]byte, 64)),
+		mp4Moov(mvhd,
+			mp4Item("\xa9nam", "Off By One"),
+			mp4Item("\xa9ART", "Data Race feat. Fencepost"),
+			mp4Item("\xa9alb", "Happens Before"),
+			mp4Item("aART", "Data Race"),
+			mp4Item("\xa9day", "2025-01-01T07:00:00Z"),
+			mp4Atom("trkn", mp4Data([]byte{0, 0, 0, 3, 0, 12, 0, 0})),
+			mp4Atom("disk", mp4Data([]byte{0, 0, 0, 1, 0, 2})),
+			mp4Atom("rtng", mp4Data([]byte{1})),
+			mp4Atom("covr", mp4Data([]byte("not a picture"))),
+			mp4Freeform("ISRC", "XXFAK2500013"),
+			mp4Freeform("ARTISTS", "Data Race", "Fencepost"),
+		),
+	)
+
+	// Version 1 movie headers have 64 bit times, and the media data has a 64 bit size
+	mvhd64 := make([]byte, 112)
+	mvhd64[0] = 1
+	binary.BigEndian.PutUint32(mvhd64[20:], 44100)
+	binary.BigEndian.PutUint64(mvhd64[24:], 7*44100)
+	mdat64 := binary.BigEndian.AppendUint32(nil, 1)
+	mdat64 = append(mdat64, "mdat"...)
+	mdat64 = binary.BigEndian.AppendUint64(mdat64, 16+32)
+	mdat64 = append(mdat64, make([]byte, 32)...)
+	mp4 := join(
+		mp4Atom("ftyp", []byte("mp42\x00\x00\x00\x00mp42isom")),
+		mdat64,
+		mp4Moov(mvhd64,
+			mp4Item("\xa9nam", "The Boxer"),
+			mp4Item("\xa9ART", "Simon & Garfunkel"),
+			mp4Atom("rtng", mp4Data([]byte{2})),
+		),
+	)
+
+	return []fixture{
+		{"M4A", m4a, Tags{
+			Title:       "Off By One",
+			Artists:     []string{"Data Race", "Fencepost"},
+			Album:       "Happens Before",
+			AlbumArtist: "Data Race",
+			ISRC:        "XXFAK2500013",
+			Date:        "2025-01-01",
+			TrackNumber: 3,
+			DiscNumber:  1,
+			Explicit:    true,
+			Duration:    5 * time.Second,
+		}},
+		{"MP4 with 64 bit sizes", mp4, Tags{
+			Title:    "The Boxer",
+			Artists:  []string{"Simon & Garfunkel"},
+			Duration: 7 * time.Second,
+		}},
+	}
+}
+
+func TestReadMP4(t *testing.T) {
+	checkFixtures(t, mp4Fixtures())
+}
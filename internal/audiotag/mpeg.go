@@ -0,0 +1,131 @@
+package audiotag
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"time"
+)
+
+// mpegBitrates are the bitrates in kbit/s by bitrate index, for MPEG-1 layers I to III and for
+// MPEG-2 and 2.5 layer I and layers II and III
+var mpegBitrates = [5][16]int{
+	{0, 32, 64, 96, 128, 160, 192, 224, 256, 288, 320, 352, 384, 416, 448},
+	{0, 32, 48, 56, 64, 80, 96, 112, 128, 160, 192, 224, 256, 320, 384},
+	{0, 32, 40, 48, 56, 64, 80, 96, 112, 128, 160, 192, 224, 256, 320},
+	{0, 32, 48, 56, 64, 80, 96, 112, 128, 144, 160, 176, 192, 224, 256},
+	{0, 8, 16, 24, 32, 40, 48, 56, 64, 80, 96, 112, 128, 144, 160},
+}
+
+// mpegSampleRates are the sample rates of MPEG-1 by sample rate index
+var mpegSampleRates = [3]int{44100, 48000, 32000}
+
+// mpegFrame is the header of an MPEG audio frame
+type mpegFrame struct {
+	// version is 1 for MPEG-1, 2 for MPEG-2 and 25 for MPEG-2.5
+	version    int
+	layer      int
+	bitrate    int
+	sampleRate int
+	mono       bool
+}
+
+// isMPEGFrame reports whether b starts with an MPEG audio frame header
+func isMPEGFrame(b []byte) bool {
+	_, ok := parseMPEGFrame(b)
+	return ok
+}
+
+func parseMPEGFrame(b []byte) (mpegFrame, bool) {
+	if len(b) < 4 || b[0] != 0xFF || b[1]&0xE0 != 0xE0 {
+		return mpegFrame{}, false
+	}
+	versionBits, layerBits := b[1]>>3&3, b[1]>>1&3
+	bitrateIndex, rateIndex := b[2]>>4, b[2]>>2&3
+	if versionBits == 1 || layerBits == 0 || bitrateIndex == 0 || bitrateIndex == 15 || rateIndex == 3 {
+		return mpegFrame{}, false
+	}
+
+	f := mpegFrame{layer: 4 - int(layerBits), mono: b[3]>>6 == 3}
+	f.sampleRate = mpegSampleRates[rateIndex]
+	switch versionBits {
+	case 3:
+		f.version = 1
+		f.bitrate = mpegBitrates[f.layer-1][bitrateIndex]
+	case 2:
+		f.version = 2
+		f.sampleRate /= 2
+	case 0:
+		f.version = 25
+		f.sampleRate /= 4
+	}
+	if f.version != 1 {
+		table := 4
+		if f.layer == 1 {
+			table = 3
+		}
+		f.bitrate = mpegBitrates[table][bitrateIndex]
+	}
+	return f, true
+}
+
+// samplesPerFrame returns the number of samples each frame decodes to
+func (f mpegFrame) samplesPerFrame() int {
+	switch {
+	case f.layer == 1:
+		return 384
+	case f.layer == 3 && f.version != 1:
+		return 576
+	default:
+		return 1152
+	}
+}
+
+// mpegDuration works out the duration of the MPEG audio between start and end. VBR files carry
+// their frame count in a Xing or VBRI header in the first frame; other files are assumed to be
+// encoded at a constant bitrate.
+func mpegDuration(r io.ReaderAt, start, end int64) time.Duration {
+	// Skip padding between the tag and the first frame
+	buf := make([]byte, 8192)
+	n, _ := r.ReadAt(buf, start)
+	buf = buf[:n]
+	var f mpegFrame
+	offset := -1
+	for i := 0; i+4 <= len(buf); i++ {
+		if frame, ok := parseMPEGFrame(buf[i:]); ok {
+			f, offset = frame, i
+			break
+		}
+	}
+	if offset < 0 {
+		return 0
+	}
+	frame := buf[offset:]
+
+	// The Xing header follows the side information, whose size depends on version and channels
+	side := 32
+	switch {
+	case f.version == 1 && f.mono, f.version != 1 && !f.mono:
+		side = 17
+	case f.version != 1 && f.mono:
+		side = 9
+	}
+	var frames uint32
+	if x := 4 + side; len(frame) >= x+12 && (bytes.Equal(frame[x:x+4], []byte("Xing")) || bytes.Equal(frame[x:x+4], []byte("Info"))) {
+		if binary.BigEndian.Uint32(frame[x+4:])&1 != 0 {
+			frames = binary.BigEndian.Uint32(frame[x+8:])
+		}
+	} else if len(frame) >= 36+18 && bytes.Equal(frame[36:40], []byte("VBRI")) {
+		frames = binary.BigEndian.Uint32(frame[36+14:])
+	}
+	if frames > 0 {
+		samples := int64(frames) * int64(f.samplesPerFrame())
+		return time.Duration(samples) * time.Second / time.Duration(f.sampleRate)
+	}
+
+	audio := end - start - int64(offset)
+	if audio <= 0 {
+		return 0
+	}
+	return time.Duration(audio*8) * time.Millisecond / time.Duration(f.bitrate)
+}
@@ -0,0 +1,256 @@
+package audiotag
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"strings"
+	"time"
+)
+
+const (
+	flacStreamInfo    = 0
+	flacVorbisComment = 4
+	// oggTailSize is how much of the end of an Ogg file is searched for the last page
+	oggTailSize = 65536
+	// maxPacketSize limits the comment packets we assemble, which may embed cover art
+	maxPacketSize = 16 << 20
+)
+
+// readFLAC reads the STREAMINFO and VORBIS_COMMENT metadata blocks of a FLAC stream
+func readFLAC(r io.ReaderAt) (Tags, error) {
+	var t Tags
+	offset := int64(4)
+	for {
+		header, err := readAt(r, offset, 4)
+		if err != nil {
+			return Tags{}, err
+		}
+		last, kind := header[0]&0x80 != 0, header[0]&0x7F
+		length := int(header[1])<<16 | int(header[2])<<8 | int(header[3])
+		offset += 4
+
+		switch kind {
+		case flacStreamInfo:
+			info, err := readAt(r, offset, length)
+			if err != nil {
+				return Tags{}, err
+			}
+			if len(info) >= 18 {
+				// 20 bits of sample rate, 3 of channels, 5 of sample size and 36 of samples
+				packed := binary.BigEndian.Uint64(info[10:18])
+				rate := packed >> 44
+				samples := packed & (1<<36 - 1)
+				if rate > 0 {
+					t.Duration = time.Duration(samples) * time.Second / time.Duration(rate)
+				}
+			}
+		case flacVorbisComment:
+			block, err := readAt(r, offset, length)
+			if err != nil {
+				return Tags{}, err
+			}
+			if err := parseVorbisComment(block, &t); err != nil {
+				return Tags{}, err
+			}
+		}
+
+		if last {
+			return t, nil
+		}
+		offset += int64(length)
+	}
+}
+
+// readOgg reads the comment header of an Ogg Vorbis or Opus stream, and works out the duration
+// from the granule position of the last page
+func readOgg(r io.ReaderAt, size int64) (Tags, error) {
+	packets := &oggPackets{r: r}
+	ident, err := packets.next()
+	if err != nil {
+		return Tags{}, err
+	}
+
+	var rate, preSkip int64
+	var prefix []byte
+	switch {
+	case len(ident) >= 16 && bytes.HasPrefix(ident, []byte("\x01vorbis")):
+		rate = int64(binary.LittleEndian.Uint32(ident[12:]))
+		prefix = []byte("\x03vorbis")
+	case len(ident) >= 12 && bytes.HasPrefix(ident, []byte("OpusHead")):
+		// Opus always runs at 48 kHz, whatever rate the input had
+		rate = 48000
+		preSkip = int64(binary.LittleEndian.Uint16(ident[10:]))
+		prefix = []byte("OpusTags")
+	default:
+		return Tags{}, ErrUnsupported
+	}
+
+	var t Tags
+	comment, err := packets.next()
+	if err != nil {
+		return Tags{}, err
+	}
+	if !bytes.HasPrefix(comment, prefix) {
+		return Tags{}, errors.New("missing comment header")
+	}
+	if err := parseVorbisComment(comment[len(prefix):], &t); err != nil {
+		return Tags{}, err
+	}
+
+	if granule := lastGranule(r, size); granule > preSkip && rate > 0 {
+		t.Duration = time.Duration(granule-preSkip) * time.Second / time.Duration(rate)
+	}
+	return t, nil
+}
+
+// oggPackets assembles the packets of the first logical stream in an Ogg file
+type oggPackets struct {
+	r       io.ReaderAt
+	offset  int64
+	serial  uint32
+	started bool
+	// pending are the segment lengths of the current page still to be read, and data where
+	// they begin
+	pending []byte
+	data    int64
+}
+
+// next returns the next packet, which may span several pages
+func (p *oggPackets) next() ([]byte, error) {
+	var packet []byte
+	for {
+		for len(p.pending) > 0 {
+			n := int(p.pending[0])
+			p.pending = p.pending[1:]
+			segment, err := readAt(p.r, p.data, n)
+			if err != nil {
+				return nil, err
+			}
+			p.data += int64(n)
+			if packet = append(packet, segment...); len(packet) > maxPacketSize {
+				return nil, errors.New("packet too large")
+			}
+			// A segment shorter than 255 bytes ends the packet
+			if n < 255 {
+				return packet, nil
+			}
+		}
+		if err := p.nextPage(); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// nextPage reads the header of the next page of the stream
+func (p *oggPackets) nextPage() error {
+	for {
+		header, err := readAt(p.r, p.offset, 27)
+		if err != nil {
+			return err
+		}
+		if string(header[:4]) != "OggS" {
+			return errors.New("lost page sync")
+		}
+		segments, err := readAt(p.r, p.offset+27, int(header[26]))
+		if err != nil {
+			return err
+		}
+		length := 0
+		for _, s := range segments {
+			length += int(s)
+		}
+		start := p.offset + 27 + int64(len(segments))
+		p.offset = start + int64(length)
+
+		serial := binary.LittleEndian.Uint32(header[14:])
+		if !p.started {
+			p.serial, p.started = serial, true
+		} else if serial != p.serial {
+			continue
+		}
+		p.pending, p.data = segments, start
+		return nil
+	}
+}
+
+// lastGranule finds the granule position of the last page in the file, or 0
+func lastGranule(r io.ReaderAt, size int64) int64 {
+	start := max(0, size-oggTailSize)
+	tail := make([]byte, size-start)
+	n, _ := r.ReadAt(tail, start)
+	tail = tail[:n]
+	i := bytes.LastIndex(tail, []byte("OggS"))
+	if i < 0 || len(tail)-i < 14 {
+		return 0
+	}
+	return int64(binary.LittleEndian.Uint64(tail[i+6:]))
+}
+
+// parseVorbisComment reads a Vorbis comment block: a vendor string and KEY=value fields, with
+// little endian lengths
+func parseVorbisComment(data []byte, t *Tags) error {
+	read := func() (string, error) {
+		if len(data) < 4 {
+			return "", io.ErrUnexpectedEOF
+		}
+		n := binary.LittleEndian.Uint32(data)
+		if uint64(n) > uint64(len(data)-4) {
+			return "", io.ErrUnexpectedEOF
+		}
+		s := string(data[4 : 4+n])
+		data = data[4+n:]
+		return s, nil
+	}
+
+	if _, err := read(); err != nil {
+		return err
+	}
+	if len(data) < 4 {
+		return io.ErrUnexpectedEOF
+	}
+	count := binary.LittleEndian.Uint32(data)
+	data = data[4:]
+
+	var artists, multiArtists []string
+	for range count {
+		field, err := read()
+		if err != nil {
+			return err
+		}
+		key, value, ok := strings.Cut(field, "=")
+		if !ok {
+			continue
+		}
+		switch strings.ToUpper(key) {
+		case "TITLE":
+			t.Title = value
+		case "ARTIST":
+			artists = appendUnique(artists, value)
+		case "ARTISTS":
+			multiArtists = appendUnique(multiArtists, value)
+		case "ALBUM":
+			t.Album = value
+		case "ALBUMARTIST", "ALBUM ARTIST":
+			t.AlbumArtist = value
+		case "ISRC":
+			t.ISRC = value
+		case "DATE":
+			t.Date = normalizeDate(value)
+		case "TRACKNUMBER":
+			setNumber(&t.TrackNumber, value)
+		case "DISCNUMBER":
+			setNumber(&t.DiscNumber, value)
+		case "ITUNESADVISORY":
+			t.Explicit = value == "1"
+		}
+	}
+
+	// ARTISTS lists the individual artists that ARTIST may credit together
+	t.Artists = artists
+	if len(multiArtists) > 0 {
+		t.Artists = multiArtists
+	}
+	return nil
+}
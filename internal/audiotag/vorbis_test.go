@@ -0,0 +1,150 @@
+package audiotag
+
+import (
+	"encoding/binary"
+	"strings"
+	"testing"
+	"time"
+)
+
+// vorbisComment builds a Vorbis comment block
+func vorbisComment(vendor string, fields ...string) []byte {
+	data := binary.LittleEndian.AppendUint32(nil, uint32(len(vendor)))
+	data = append(data, vendor...)
+	data = binary.LittleEndian.AppendUint32(data, uint32(len(fields)))
+	for _, f := range fields {
+		data = binary.LittleEndian.AppendUint32(data, uint32(len(f)))
+		data = append(data, f...)
+	}
+	return data
+}
+
+// flacBlock builds a FLAC metadata block
+func flacBlock(kind byte, last bool, data []byte) []byte {
+	if last {
+		kind |= 0x80
+	}
+	return append([]byte{kind, byte(len(data) >> 16), byte(len(data) >> 8), byte(len(data))}, data...)
+}
+
+// streamInfo builds a STREAMINFO block body for a stream of samples at rate Hz
+func streamInfo(rate, samples uint64) []byte {
+	info := make([]byte, 34)
+	binary.BigEndian.PutUint64(info[10:], rate<<44|1<<41|15<<36|samples)
+	return info
+}
+
+// oggSegments splits a packet into the segments of its lacing values. A packet filling its last
+// segment is ended by an empty one.
+func oggSegments(packet []byte) [][]byte {
+	var segments [][]byte
+	for len(packet) >= 255 {
+		segments = append(segments, packet[:255])
+		packet = packet[255:]
+	}
+	return append(segments, packet)
+}
+
+// oggPage builds a page of the logical stream serial holding the given segments. The checksum
+// is left empty, as readers of tags don't verify it.
+func oggPage(serial uint32, granule int64, segments ...[]byte) []byte {
+	page := []byte("OggS\x00\x00")
+	page = binary.LittleEndian.AppendUint64(page, uint64(granule))
+	page = binary.LittleEndian.AppendUint32(page, serial)
+	page = append(page, make([]byte, 8)...)
+	page = append(page, byte(len(segments)))
+	for _, s := range segments {
+		page = append(page, byte(len(s)))
+	}
+	return join(page, join(segments...))
+}
+
+func vorbisFixtures() []fixture {
+	// Fields are matched without regard to case, and ARTISTS wins over the combined ARTIST
+	flac := join([]byte("fLaC"),
+		flacBlock(flacStreamInfo, false, streamInfo(44100, 441000)),
+		flacBlock(1, false, make([]byte, 64)),
+		flacBlock(flacVorbisComment, true, vorbisComment("reference libFLAC 1.4.3",
+			"TITLE=Off By One",
+			"artist=Data Race feat. Fencepost",
+			"ARTISTS=Data Race",
+			"ARTISTS=Fencepost",
+			"Album=Happens Before",
+			"ALBUM ARTIST=Data Race",
+			"ISRC=XXFAK2500013",
+			"DATE=2025-01-01T00:00:00Z",
+			"TRACKNUMBER=3/12",
+			"DISCNUMBER=1",
+			"ITUNESADVISORY=1",
+			"NOEQUALSSIGN",
+			"COMMENT=ignored",
+		)),
+	)
+
+	// Some taggers put an ID3 tag before the FLAC stream, which is skipped
+	id3flac := join(id3Tag(4, 0, id3Frame(4, "TIT2", 0, id3Text(3, "Ignored"))),
+		[]byte("fLaC"),
+		flacBlock(flacStreamInfo, false, streamInfo(48000, 96000)),
+		flacBlock(flacVorbisComment, true, vorbisComment("", "TITLE=The Boxer", "ARTIST=Simon & Garfunkel")),
+	)
+
+	opusHead := []byte("OpusHead\x01\x02")
+	opusHead = binary.LittleEndian.AppendUint16(opusHead, 312)
+	opusHead = binary.LittleEndian.AppendUint32(opusHead, 44100)
+	opusHead = append(opusHead, 0, 0, 0)
+	opus := join(
+		oggPage(1, 0, opusHead),
+		oggPage(1, 0, oggSegments(join([]byte("OpusTags"), vorbisComment("libopus", "TITLE=Golden Master", "ARTIST=Mock Orchestra")))...),
+		oggPage(1, 3*48000+312, make([]byte, 100)),
+	)
+
+	// The comment packet spans two pages, between which a page of another stream is interleaved
+	vorbisID := []byte("\x01vorbis\x00\x00\x00\x00\x02")
+	vorbisID = binary.LittleEndian.AppendUint32(vorbisID, 44100)
+	vorbisID = append(vorbisID, make([]byte, 14)...)
+	comment := oggSegments(join([]byte("\x03vorbis"),
+		vorbisComment("Xiph.Org libVorbis", "TITLE=Null Pointer", "ARTIST=Segfault Sisters", "COMMENT="+strings.Repeat("x", 300)),
+		[]byte{1},
+	))
+	vorbis := join(
+		oggPage(7, 0, vorbisID),
+		oggPage(7, 0, comment[:1]...),
+		oggPage(8, 0, []byte("\x01other stream")),
+		oggPage(7, 0, comment[1:]...),
+		oggPage(7, 4*44100, make([]byte, 100)),
+	)
+
+	return []fixture{
+		{"FLAC", flac, Tags{
+			Title:       "Off By One",
+			Artists:     []string{"Data Race", "Fencepost"},
+			Album:       "Happens Before",
+			AlbumArtist: "Data Race",
+			ISRC:        "XXFAK2500013",
+			Date:        "2025-01-01",
+			TrackNumber: 3,
+			DiscNumber:  1,
+			Explicit:    true,
+			Duration:    10 * time.Second,
+		}},
+		{"FLAC after ID3", id3flac, Tags{
+			Title:    "The Boxer",
+			Artists:  []string{"Simon & Garfunkel"},
+			Duration: 2 * time.Second,
+		}},
+		{"Opus", opus, Tags{
+			Title:    "Golden Master",
+			Artists:  []string{"Mock Orchestra"},
+			Duration: 3 * time.Second,
+		}},
+		{"Ogg Vorbis", vorbis, Tags{
+			Title:    "Null Pointer",
+			Artists:  []string{"Segfault Sisters"},
+			Duration: 4 * time.Second,
+		}},
+	}
+}
+
+func TestReadVorbisComments(t *testing.T) {
+	checkFixtures(t, vorbisFixtures())
+}
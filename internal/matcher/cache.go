@@ -0,0 +1,122 @@
+package matcher
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// CacheEntry records a resolved mapping between a source track and a target track
+type CacheEntry struct {
+	SourcePlatform string    `json:"source_platform"`
+	SourceID       string    `json:"source_id"`
+	TargetPlatform string    `json:"target_platform"`
+	TargetID       string    `json:"target_id"`
+	Confidence     float64   `json:"confidence"`
+	ResolvedAt     time.Time `json:"resolved_at"`
+	Confirmed      bool      `json:"confirmed"`
+}
+
+// Cache is a local store of resolved track mappings between platforms
+type Cache struct {
+	path    string
+	mu      sync.Mutex
+	entries map[string]CacheEntry
+}
+
+// DefaultCachePath returns the location of the match cache in the user's config directory
+func DefaultCachePath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "soundporter-matches.json"
+	}
+	return filepath.Join(dir, "soundporter", "matches.json")
+}
+
+// LoadCache reads the cache stored at path. A missing file yields an empty cache.
+func LoadCache(path string) (*Cache, error) {
+	c := &Cache{
+		path:    path,
+		entries: make(map[string]CacheEntry),
+	}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return c, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading match cache: %v", err)
+	}
+
+	var entries []CacheEntry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("error parsing match cache %s: %v", path, err)
+	}
+	for _, e := range entries {
+		c.entries[cacheKey(e.SourcePlatform, e.SourceID, e.TargetPlatform)] = e
+	}
+	return c, nil
+}
+
+// Lookup returns the cached mapping for a source track on the target platform
+func (c *Cache) Lookup(sourcePlatform, sourceID, targetPlatform string) (CacheEntry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.entries[cacheKey(sourcePlatform, sourceID, targetPlatform)]
+	return e, ok
+}
+
+// Store adds or replaces a mapping. A confirmed mapping is never replaced by an unconfirmed one.
+func (c *Cache) Store(e CacheEntry) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	key := cacheKey(e.SourcePlatform, e.SourceID, e.TargetPlatform)
+	if existing, ok := c.entries[key]; ok && existing.Confirmed && !e.Confirmed {
+		return
+	}
+	if e.ResolvedAt.IsZero() {
+		e.ResolvedAt = time.Now()
+	}
+	c.entries[key] = e
+}
+
+// Len returns the number of mappings in the cache
+func (c *Cache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.entries)
+}
+
+// Save writes the cache back to its file, creating parent directories as needed
+func (c *Cache) Save() error {
+	c.mu.Lock()
+	entries := make([]CacheEntry, 0, len(c.entries))
+	for _, e := range c.entries {
+		entries = append(entries, e)
+	}
+	c.mu.Unlock()
+	sort.Slice(entries, func(i, j int) bool {
+		return cacheKey(entries[i].SourcePlatform, entries[i].SourceID, entries[i].TargetPlatform) <
+			cacheKey(entries[j].SourcePlatform, entries[j].SourceID, entries[j].TargetPlatform)
+	})
+
+	data, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error encoding match cache: %v", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(c.path), 0o755); err != nil {
+		return fmt.Errorf("error creating match cache directory: %v", err)
+	}
+	if err := os.WriteFile(c.path, data, 0o644); err != nil {
+		return fmt.Errorf("error writing match cache: %v", err)
+	}
+	return nil
+}
+
+func cacheKey(sourcePlatform, sourceID, targetPlatform string) string {
+	return sourcePlatform + "|" + sourceID + "|" + targetPlatform
+}
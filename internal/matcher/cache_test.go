@@ -0,0 +1,102 @@
+package matcher
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"soundporter/internal/adapters"
+)
+
+func TestCacheConfirmedEntrySurvivesStore(t *testing.T) {
+	c, err := LoadCache(filepath.Join(t.TempDir(), "matches.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Store(CacheEntry{SourcePlatform: "spotify", SourceID: "s1", TargetPlatform: "youtube", TargetID: "pinned", Confidence: 1, Confirmed: true})
+	c.Store(CacheEntry{SourcePlatform: "spotify", SourceID: "s1", TargetPlatform: "youtube", TargetID: "searched", Confidence: 0.9})
+
+	e, ok := c.Lookup("spotify", "s1", "youtube")
+	if !ok || e.TargetID != "pinned" || !e.Confirmed {
+		t.Fatalf("confirmed entry was replaced: %+v", e)
+	}
+
+	// A newer confirmation replaces an older one
+	c.Store(CacheEntry{SourcePlatform: "spotify", SourceID: "s1", TargetPlatform: "youtube", TargetID: "repinned", Confidence: 1, Confirmed: true})
+	if e, _ := c.Lookup("spotify", "s1", "youtube"); e.TargetID != "repinned" {
+		t.Fatalf("confirmed entry not updated: %+v", e)
+	}
+
+	if err := c.Save(); err != nil {
+		t.Fatal(err)
+	}
+	reloaded, err := LoadCache(c.path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e, _ := reloaded.Lookup("spotify", "s1", "youtube"); !e.Confirmed || e.TargetID != "repinned" {
+		t.Fatalf("confirmation lost on reload: %+v", e)
+	}
+}
+
+func TestMatchStoresOverridesAsConfirmed(t *testing.T) {
+	ctx := context.Background()
+	target := adapters.NewFakeAdapter(adapters.FakeConfig{})
+	if err := target.Authenticate(ctx); err != nil {
+		t.Fatal(err)
+	}
+	source := adapters.FakeCatalog()[0]
+
+	c, err := LoadCache(filepath.Join(t.TempDir(), "matches.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := NewMatcher(target, "fake", c)
+	m.Overrides = &Overrides{entries: []Override{{Source: source.ID, TargetID: "fake-04"}}}
+
+	match, err := m.Match(ctx, "spotify", source)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !match.Overridden || match.Track.ID != "fake-04" {
+		t.Fatalf("override not applied: %+v", match)
+	}
+	e, ok := c.Lookup("spotify", source.ID, "fake")
+	if !ok || !e.Confirmed || e.TargetID != "fake-04" {
+		t.Fatalf("override not cached as confirmed: %+v", e)
+	}
+
+	// Without the override, the search finds the studio recording, which must not replace the
+	// confirmed mapping
+	m.Overrides = nil
+	c.Store(CacheEntry{SourcePlatform: "spotify", SourceID: source.ID, TargetPlatform: "fake", TargetID: "fake-01", Confidence: 1})
+	match, err = m.Match(ctx, "spotify", source)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !match.Cached || match.Track.ID != "fake-04" {
+		t.Fatalf("confirmed mapping not used: %+v", match)
+	}
+}
+
+func TestMatchNeverImportIsNotCached(t *testing.T) {
+	ctx := context.Background()
+	target := adapters.NewFakeAdapter(adapters.FakeConfig{})
+	if err := target.Authenticate(ctx); err != nil {
+		t.Fatal(err)
+	}
+	source := adapters.FakeCatalog()[0]
+
+	c, err := LoadCache(filepath.Join(t.TempDir(), "matches.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := NewMatcher(target, "fake", c)
+	m.Overrides = &Overrides{entries: []Override{{Source: source.ID, Never: true}}}
+	if _, err := m.Match(ctx, "spotify", source); err != ErrNeverImport {
+		t.Fatalf("expected ErrNeverImport, got %v", err)
+	}
+	if c.Len() != 0 {
+		t.Fatalf("never-import override was cached")
+	}
+}
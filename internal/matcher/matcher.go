@@ -0,0 +1,179 @@
+package matcher
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"soundporter/internal/adapters"
+	"soundporter/internal/musicbrainz"
+	"soundporter/internal/playlist"
+	"strings"
+)
+
+// DefaultMinConfidence is the lowest score accepted for a search result
+const DefaultMinConfidence = 0.5
+
+// searchLimit is the number of candidates considered per search
+const searchLimit = 5
+
+var (
+	// ErrNoResults is returned when a search on the target platform finds nothing
+	ErrNoResults = errors.New("no search results")
+	// ErrLowConfidence is returned when no candidate scores above the minimum confidence
+	ErrLowConfidence = errors.New("no candidate with enough confidence")
+)
+
+// Match is the result of resolving a source track on the target platform
+type Match struct {
+	Track      playlist.Track
+	Confidence float64
+	Cached     bool
+	Overridden bool
+	// Identified is set when the source track was identified on MusicBrainz before the search
+	Identified bool
+}
+
+// Matcher resolves tracks from one platform to another, consulting the cache before searching
+type Matcher struct {
+	target         adapters.ApiAdapter
+	targetPlatform string
+	cache          *Cache
+	MinConfidence  float64
+	Overrides      *Overrides
+	// Resolver, if set, identifies source tracks on MusicBrainz before they are searched for
+	Resolver *musicbrainz.Resolver
+}
+
+// NewMatcher creates a Matcher for the given target adapter. cache may be nil.
+func NewMatcher(target adapters.ApiAdapter, targetPlatform string, cache *Cache) *Matcher {
+	return &Matcher{
+		target:         target,
+		targetPlatform: targetPlatform,
+		cache:          cache,
+		MinConfidence:  DefaultMinConfidence,
+	}
+}
+
+// Match finds the track on the target platform that corresponds to source
+func (m *Matcher) Match(ctx context.Context, sourcePlatform string, source playlist.Track) (Match, error) {
+	if m.Overrides != nil {
+		if o, ok := m.Overrides.Find(source, m.targetPlatform); ok {
+			if o.Never {
+				return Match{}, ErrNeverImport
+			}
+			// The user chose this mapping, so searches must never replace it in the cache
+			if m.cache != nil && source.ID != "" {
+				m.cache.Store(CacheEntry{
+					SourcePlatform: sourcePlatform,
+					SourceID:       source.ID,
+					TargetPlatform: m.targetPlatform,
+					TargetID:       o.TargetID,
+					Confidence:     1,
+					Confirmed:      true,
+				})
+			}
+			return Match{
+				Track:      playlist.Track{ID: o.TargetID},
+				Confidence: 1,
+				Overridden: true,
+			}, nil
+		}
+	}
+
+	if m.cache != nil && source.ID != "" {
+		if e, ok := m.cache.Lookup(sourcePlatform, source.ID, m.targetPlatform); ok {
+			return Match{
+				Track:      playlist.Track{ID: e.TargetID},
+				Confidence: e.Confidence,
+				Cached:     true,
+			}, nil
+		}
+	}
+
+	// Identifying the track is only worth its requests when the track has to be searched for.
+	// Tracks MusicBrainz can't identify are searched for as they are.
+	identified := false
+	if m.Resolver != nil {
+		resolved, err := m.Resolver.Resolve(ctx, source)
+		if ctx.Err() != nil {
+			return Match{}, ctx.Err()
+		}
+		if err == nil {
+			source, identified = resolved, true
+		}
+	}
+
+	candidates, err := m.search(ctx, source)
+	if err != nil {
+		return Match{}, fmt.Errorf("error searching for %q: %w", source.Name, err)
+	}
+	if len(candidates) == 0 {
+		return Match{}, ErrNoResults
+	}
+
+	var best Match
+	for _, c := range candidates {
+		if score := Score(source, c); score > best.Confidence {
+			best = Match{Track: c, Confidence: score, Identified: identified}
+		}
+	}
+	if best.Track.ID == "" || best.Confidence < m.MinConfidence {
+		return Match{}, ErrLowConfidence
+	}
+
+	if m.cache != nil && source.ID != "" {
+		m.cache.Store(CacheEntry{
+			SourcePlatform: sourcePlatform,
+			SourceID:       source.ID,
+			TargetPlatform: m.targetPlatform,
+			TargetID:       best.Track.ID,
+			Confidence:     best.Confidence,
+		})
+	}
+	return best, nil
+}
+
+// limit returns how many candidates to ask the target for
+func (m *Matcher) limit() int {
+	if max := m.target.Capabilities().MaxSearchLimit; max > 0 && max < searchLimit {
+		return max
+	}
+	return searchLimit
+}
+
+// search looks the track up by ISRC first when both sides support it, then by structured
+// artist and title fields, and only falls back to a free-text search when those find nothing
+func (m *Matcher) search(ctx context.Context, source playlist.Track) ([]playlist.Track, error) {
+	if source.ISRC != "" && m.target.Capabilities().SupportsISRC() {
+		candidates, err := m.target.SearchTracksBy(ctx, adapters.SearchQuery{ISRC: source.ISRC}, m.limit())
+		if err != nil || len(candidates) > 0 {
+			return candidates, err
+		}
+	}
+
+	if len(source.Artists) > 0 &&
+		adapters.SupportsSearchField(m.target, adapters.SearchFieldTrack) &&
+		adapters.SupportsSearchField(m.target, adapters.SearchFieldArtist) {
+		candidates, err := m.target.SearchTracksBy(ctx, adapters.SearchQuery{
+			Track:  source.Name,
+			Artist: source.Artists[0],
+		}, m.limit())
+		if err != nil || len(candidates) > 0 {
+			return candidates, err
+		}
+	}
+
+	query := searchQuery(source)
+	if strings.TrimSpace(query) == "" {
+		return nil, nil
+	}
+	return m.target.SearchTracks(ctx, query, m.limit())
+}
+
+// searchQuery builds a free-text query from the track's artists and title
+func searchQuery(t playlist.Track) string {
+	if len(t.Artists) == 0 {
+		return t.Name
+	}
+	return strings.Join(t.Artists, " ") + " " + t.Name
+}
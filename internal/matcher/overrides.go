@@ -0,0 +1,150 @@
+package matcher
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"soundporter/internal/playlist"
+	"strings"
+)
+
+// NeverImport is the target value that marks a source track as never to be imported
+const NeverImport = "never"
+
+// ErrNeverImport is returned for tracks the overrides file excludes from imports
+var ErrNeverImport = errors.New("track is marked as never import")
+
+// Override pins a source track to a specific target track, or excludes it
+type Override struct {
+	// Source is a track ID or an "artist - title" string
+	Source string
+	// TargetPlatform limits the override to one platform; empty applies to all
+	TargetPlatform string
+	TargetID       string
+	Never          bool
+}
+
+// Overrides is a user-editable set of track mappings applied before any search
+type Overrides struct {
+	entries []Override
+}
+
+// DefaultOverridesPath returns the location of the overrides file in the user's config directory
+func DefaultOverridesPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "soundporter-overrides.csv"
+	}
+	return filepath.Join(dir, "soundporter", "overrides.csv")
+}
+
+// LoadOverrides reads an overrides CSV file with the columns source, target_platform and target.
+// The target column holds the target track ID, or "never" to skip the track. A missing file yields no overrides.
+func LoadOverrides(path string) (*Overrides, error) {
+	o := &Overrides{}
+
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return o, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error opening overrides file: %v", err)
+	}
+	defer file.Close()
+
+	reader := csv.NewReader(file)
+	reader.FieldsPerRecord = -1
+	reader.Comment = '#'
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("error reading overrides file: %v", err)
+	}
+	if len(records) == 0 {
+		return o, nil
+	}
+
+	sourceIdx, platformIdx, targetIdx := -1, -1, -1
+	for i, colName := range records[0] {
+		switch strings.ToLower(strings.TrimSpace(colName)) {
+		case "source":
+			sourceIdx = i
+		case "target_platform":
+			platformIdx = i
+		case "target":
+			targetIdx = i
+		}
+	}
+	if sourceIdx == -1 || targetIdx == -1 {
+		return nil, fmt.Errorf("overrides file %s must have source and target columns", path)
+	}
+
+	for line, record := range records[1:] {
+		if len(record) <= sourceIdx || len(record) <= targetIdx {
+			return nil, fmt.Errorf("overrides file %s: line %d has too few columns", path, line+2)
+		}
+		target := strings.TrimSpace(record[targetIdx])
+		override := Override{
+			Source:   strings.TrimSpace(record[sourceIdx]),
+			TargetID: target,
+			Never:    strings.EqualFold(target, NeverImport),
+		}
+		if override.Never {
+			override.TargetID = ""
+		}
+		if platformIdx != -1 && len(record) > platformIdx {
+			override.TargetPlatform = strings.ToLower(strings.TrimSpace(record[platformIdx]))
+		}
+		if override.Source == "" || (override.TargetID == "" && !override.Never) {
+			return nil, fmt.Errorf("overrides file %s: line %d needs both a source and a target", path, line+2)
+		}
+		o.entries = append(o.entries, override)
+	}
+	return o, nil
+}
+
+// Find returns the override for a track on the target platform.
+// Platform-specific overrides win over ones that apply to every platform.
+func (o *Overrides) Find(t playlist.Track, targetPlatform string) (Override, bool) {
+	var fallback Override
+	found := false
+	for _, e := range o.entries {
+		if !matchesSource(e.Source, t) {
+			continue
+		}
+		if e.TargetPlatform == targetPlatform {
+			return e, true
+		}
+		if e.TargetPlatform == "" && !found {
+			fallback = e
+			found = true
+		}
+	}
+	return fallback, found
+}
+
+// matchesSource reports whether an override source refers to the track, either by ID
+// or by an "artist - title" string compared case-insensitively
+func matchesSource(source string, t playlist.Track) bool {
+	if t.ID != "" && source == t.ID {
+		return true
+	}
+	artist, title, ok := strings.Cut(source, " - ")
+	if !ok {
+		return false
+	}
+	if !strings.EqualFold(strings.TrimSpace(title), strings.TrimSpace(t.Name)) {
+		return false
+	}
+	artist = strings.TrimSpace(artist)
+	if strings.EqualFold(artist, strings.Join(t.Artists, ", ")) {
+		return true
+	}
+	for _, a := range t.Artists {
+		if strings.EqualFold(artist, a) {
+			return true
+		}
+	}
+	return false
+}
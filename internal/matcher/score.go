@@ -0,0 +1,78 @@
+package matcher
+
+import (
+	"soundporter/internal/playlist"
+	"strings"
+	"unicode"
+)
+
+// maxDurationDiffMs is how far apart two durations can be and still be the same recording
+const maxDurationDiffMs = 10000
+
+// Score returns how confident we are that candidate is the same recording as source, from 0 to 1.
+// Titles weigh more than artists since channel and artist names vary between platforms.
+// A candidate that is a different version (live, remix, ...) of the source or whose duration
+// differs noticeably is penalised, and a shared ISRC or MusicBrainz ID is a certain match.
+func Score(source, candidate playlist.Track) float64 {
+	if source.ISRC != "" && strings.EqualFold(source.ISRC, candidate.ISRC) {
+		return 1
+	}
+	if source.MBID != "" && strings.EqualFold(source.MBID, candidate.MBID) {
+		return 1
+	}
+
+	score := similarity(tokens(source.Name), tokens(candidate.Name))
+	if len(source.Artists) > 0 && len(candidate.Artists) > 0 {
+		artist := similarity(tokens(strings.Join(source.Artists, " ")), tokens(strings.Join(candidate.Artists, " ")))
+		score = 0.6*score + 0.4*artist
+	}
+	if !strings.EqualFold(source.Version, candidate.Version) {
+		score *= 0.8
+	}
+	if source.DurationMs > 0 && candidate.DurationMs > 0 {
+		diff := source.DurationMs - candidate.DurationMs
+		if diff < 0 {
+			diff = -diff
+		}
+		if diff > maxDurationDiffMs {
+			score *= 0.8
+		}
+	}
+	return score
+}
+
+// tokens lowercases s and splits it into words, dropping punctuation
+func tokens(s string) []string {
+	return strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+}
+
+// similarity averages the Jaccard index of two token sets with the share of a's tokens found in b,
+// so that extra words on the candidate side (e.g. "Official Video") cost less than missing ones
+func similarity(a, b []string) float64 {
+	if len(a) == 0 || len(b) == 0 {
+		return 0
+	}
+	set := make(map[string]bool, len(a))
+	for _, t := range a {
+		set[t] = true
+	}
+	union := len(set)
+	shared := 0
+	seen := make(map[string]bool, len(b))
+	for _, t := range b {
+		if seen[t] {
+			continue
+		}
+		seen[t] = true
+		if set[t] {
+			shared++
+		} else {
+			union++
+		}
+	}
+	jaccard := float64(shared) / float64(union)
+	containment := float64(shared) / float64(len(set))
+	return (jaccard + containment) / 2
+}
@@ -0,0 +1,66 @@
+package matcher
+
+import (
+	"math"
+	"testing"
+
+	"soundporter/internal/playlist"
+)
+
+func TestScore(t *testing.T) {
+	source := playlist.Track{Name: "Bohemian Rhapsody", Artists: []string{"Queen"}, DurationMs: 354000, ISRC: "GBUM71029604"}
+	tests := []struct {
+		name      string
+		candidate playlist.Track
+		want      float64
+	}{
+		{"same recording", playlist.Track{Name: "Bohemian Rhapsody", Artists: []string{"Queen"}, DurationMs: 355000}, 1},
+		{"same ISRC in another case", playlist.Track{Name: "Bohemian Rhapsody (Live Aid)", ISRC: "gbum71029604"}, 1},
+		{"no artists to compare", playlist.Track{Name: "bohemian rhapsody!"}, 1},
+		// Extra words on the candidate cost less than missing ones
+		{"extra title words", playlist.Track{Name: "Bohemian Rhapsody - Remastered 2011", Artists: []string{"Queen"}}, 0.85},
+		{"missing title words", playlist.Track{Name: "Rhapsody", Artists: []string{"Queen"}}, 0.7},
+		{"another artist", playlist.Track{Name: "Bohemian Rhapsody", Artists: []string{"Panic! At The Disco"}}, 0.6},
+		// Other versions and durations are penalised
+		{"live version", playlist.Track{Name: "Bohemian Rhapsody", Artists: []string{"Queen"}, Version: "Live Aid"}, 0.8},
+		{"duration within the margin", playlist.Track{Name: "Bohemian Rhapsody", Artists: []string{"Queen"}, DurationMs: 364000}, 1},
+		{"duration past the margin", playlist.Track{Name: "Bohemian Rhapsody", Artists: []string{"Queen"}, DurationMs: 420000}, 0.8},
+		{"live version of another duration", playlist.Track{Name: "Bohemian Rhapsody", Artists: []string{"Queen"}, Version: "Live", DurationMs: 420000}, 0.64},
+		// Other songs
+		{"another song by the artist", playlist.Track{Name: "Killer Queen", Artists: []string{"Queen"}, DurationMs: 180000}, 0.32},
+		{"nothing in common", playlist.Track{Name: "Under Pressure", Artists: []string{"David Bowie"}}, 0},
+		{"empty", playlist.Track{}, 0},
+	}
+	for _, tt := range tests {
+		if got := Score(source, tt.candidate); math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("%s: scored %.3f, want %.3f", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestScoreMatches(t *testing.T) {
+	source := playlist.Track{Name: "Golden Master", Artists: []string{"Mock Orchestra"}, DurationMs: 198000}
+	tests := []struct {
+		name      string
+		candidate playlist.Track
+		match     bool
+	}{
+		{"video title", playlist.Track{Name: "Golden Master (Official Video)", Artists: []string{"Mock Orchestra VEVO"}, DurationMs: 201000}, true},
+		{"remix", playlist.Track{Name: "Golden Master", Artists: []string{"Mock Orchestra"}, Version: "Remix", DurationMs: 198000}, true},
+		{"another song", playlist.Track{Name: "Green Build", Artists: []string{"Mock Orchestra"}, DurationMs: 198000}, false},
+		{"same title by someone else", playlist.Track{Name: "Golden Master", Artists: []string{"The Placeholders"}, DurationMs: 260000}, false},
+	}
+	for _, tt := range tests {
+		if got := Score(source, tt.candidate); (got >= DefaultMinConfidence) != tt.match {
+			t.Errorf("%s: scored %.3f, want a match %v", tt.name, got, tt.match)
+		}
+	}
+
+	// The original outranks its other versions, and those their versions of another length
+	original := Score(source, playlist.Track{Name: "Golden Master", Artists: []string{"Mock Orchestra"}, DurationMs: 198000})
+	live := Score(source, playlist.Track{Name: "Golden Master", Artists: []string{"Mock Orchestra"}, Version: "Live", DurationMs: 198000})
+	longer := Score(source, playlist.Track{Name: "Golden Master", Artists: []string{"Mock Orchestra"}, Version: "Live", DurationMs: 260000})
+	if !(original > live && live > longer) {
+		t.Fatalf("expected %.3f > %.3f > %.3f", original, live, longer)
+	}
+}
@@ -0,0 +1,111 @@
+package musicbrainz
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// missTTL is how long a lookup that found nothing is remembered, since MusicBrainz grows
+const missTTL = 30 * 24 * time.Hour
+
+// CacheEntry records the recording a lookup found, or that it found none
+type CacheEntry struct {
+	Key string `json:"key"`
+	// Recording is nil when MusicBrainz had no matching recording
+	Recording  *Recording `json:"recording"`
+	ResolvedAt time.Time  `json:"resolved_at"`
+}
+
+// Cache is a local store of MusicBrainz lookups, so that tracks are only looked up once
+type Cache struct {
+	path    string
+	mu      sync.Mutex
+	entries map[string]CacheEntry
+}
+
+// DefaultCachePath returns the location of the MusicBrainz cache in the user's cache directory
+func DefaultCachePath() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return filepath.Join(os.TempDir(), "soundporter-musicbrainz.json")
+	}
+	return filepath.Join(dir, "soundporter", "musicbrainz.json")
+}
+
+// LoadCache reads the cache stored at path. A missing file yields an empty cache.
+func LoadCache(path string) (*Cache, error) {
+	c := &Cache{
+		path:    path,
+		entries: make(map[string]CacheEntry),
+	}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return c, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading MusicBrainz cache: %v", err)
+	}
+
+	var entries []CacheEntry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("error parsing MusicBrainz cache %s: %v", path, err)
+	}
+	for _, e := range entries {
+		c.entries[e.Key] = e
+	}
+	return c, nil
+}
+
+// Lookup returns the cached result of a lookup. Lookups that found nothing expire after a while.
+func (c *Cache) Lookup(key string) (CacheEntry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.entries[key]
+	if ok && e.Recording == nil && time.Since(e.ResolvedAt) > missTTL {
+		return CacheEntry{}, false
+	}
+	return e, ok
+}
+
+// Store adds or replaces the result of a lookup
+func (c *Cache) Store(key string, rec *Recording) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries[key] = CacheEntry{Key: key, Recording: rec, ResolvedAt: time.Now()}
+}
+
+// Len returns the number of lookups in the cache
+func (c *Cache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.entries)
+}
+
+// Save writes the cache back to its file, creating parent directories as needed
+func (c *Cache) Save() error {
+	c.mu.Lock()
+	entries := make([]CacheEntry, 0, len(c.entries))
+	for _, e := range c.entries {
+		entries = append(entries, e)
+	}
+	c.mu.Unlock()
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Key < entries[j].Key })
+
+	data, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error encoding MusicBrainz cache: %v", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(c.path), 0o755); err != nil {
+		return fmt.Errorf("error creating MusicBrainz cache directory: %v", err)
+	}
+	if err := os.WriteFile(c.path, data, 0o644); err != nil {
+		return fmt.Errorf("error writing MusicBrainz cache: %v", err)
+	}
+	return nil
+}
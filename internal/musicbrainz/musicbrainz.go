@@ -0,0 +1,241 @@
+// Package musicbrainz looks tracks up on MusicBrainz, the open music encyclopedia, to give them
+// canonical identifiers and metadata that are the same on every platform
+package musicbrainz
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultBaseURL is the MusicBrainz web service
+	DefaultBaseURL = "https://musicbrainz.org/ws/2/"
+	// DefaultUserAgent identifies us, as MusicBrainz requires of every client
+	DefaultUserAgent = "Soundporter/1.0 (playlist transfer tool)"
+	// DefaultInterval is the least time between requests, as MusicBrainz asks of each client
+	DefaultInterval = time.Second
+	// maxRetries is how often a request throttled with 503 is retried
+	maxRetries = 3
+)
+
+// ErrNotFound is returned when MusicBrainz has no recording for a track
+var ErrNotFound = errors.New("not found on MusicBrainz")
+
+// Recording is a MusicBrainz recording: a distinct recorded performance, whatever releases
+// it appears on
+type Recording struct {
+	ID    string `json:"id"`
+	Title string `json:"title"`
+	// Artists are the names the recording credits, in credit order, and ArtistIDs their MBIDs
+	Artists    []string  `json:"artists"`
+	ArtistIDs  []string  `json:"artist_ids"`
+	DurationMs int       `json:"duration_ms,omitempty"`
+	ISRCs      []string  `json:"isrcs,omitempty"`
+	Releases   []Release `json:"releases,omitempty"`
+	// Score is how well a search result matches the query, from 0 to 100
+	Score int `json:"-"`
+}
+
+// Release is a release a recording appears on, such as an album or single
+type Release struct {
+	ID    string `json:"id"`
+	Title string `json:"title"`
+	// Date is as precise as known, e.g. "1975", "1975-10" or "1975-10-31"
+	Date   string `json:"date,omitempty"`
+	Status string `json:"status,omitempty"`
+}
+
+// Client calls the MusicBrainz web service, keeping to its rate limit. Requests from several
+// goroutines are spaced out as well.
+type Client struct {
+	BaseURL    string
+	UserAgent  string
+	HTTPClient *http.Client
+	// Interval is the least time between two requests
+	Interval time.Duration
+
+	mu   sync.Mutex
+	next time.Time
+}
+
+// NewClient creates a client of the MusicBrainz web service with the default settings
+func NewClient() *Client {
+	return &Client{
+		BaseURL:    DefaultBaseURL,
+		UserAgent:  DefaultUserAgent,
+		HTTPClient: http.DefaultClient,
+		Interval:   DefaultInterval,
+	}
+}
+
+// mbRecording is a recording as the web service returns it
+type mbRecording struct {
+	ID     string `json:"id"`
+	Title  string `json:"title"`
+	Score  int    `json:"score"`
+	Length int    `json:"length"`
+	Credit []struct {
+		Name   string `json:"name"`
+		Artist struct {
+			ID string `json:"id"`
+		} `json:"artist"`
+	} `json:"artist-credit"`
+	ISRCs    []string  `json:"isrcs"`
+	Releases []Release `json:"releases"`
+}
+
+func (r mbRecording) recording() Recording {
+	rec := Recording{
+		ID:         r.ID,
+		Title:      r.Title,
+		DurationMs: r.Length,
+		ISRCs:      r.ISRCs,
+		Releases:   r.Releases,
+		Score:      r.Score,
+	}
+	for _, c := range r.Credit {
+		rec.Artists = append(rec.Artists, c.Name)
+		rec.ArtistIDs = append(rec.ArtistIDs, c.Artist.ID)
+	}
+	return rec
+}
+
+// Recording looks a recording up by its MBID
+func (c *Client) Recording(ctx context.Context, mbid string) (Recording, error) {
+	var r mbRecording
+	params := url.Values{"inc": {"artist-credits isrcs releases"}}
+	if err := c.get(ctx, "recording/"+url.PathEscape(mbid), params, &r); err != nil {
+		return Recording{}, err
+	}
+	return r.recording(), nil
+}
+
+// RecordingsByISRC returns the recordings with the given ISRC, usually one
+func (c *Client) RecordingsByISRC(ctx context.Context, isrc string) ([]Recording, error) {
+	var resp struct {
+		Recordings []mbRecording `json:"recordings"`
+	}
+	params := url.Values{"inc": {"artist-credits isrcs releases"}}
+	if err := c.get(ctx, "isrc/"+url.PathEscape(strings.ToUpper(isrc)), params, &resp); err != nil {
+		return nil, err
+	}
+	return recordings(resp.Recordings), nil
+}
+
+// SearchRecordings searches recordings by title and credited artist, best matches first
+func (c *Client) SearchRecordings(ctx context.Context, title, artist string, limit int) ([]Recording, error) {
+	query := `recording:"` + escapePhrase(title) + `"`
+	if artist != "" {
+		query += ` AND artist:"` + escapePhrase(artist) + `"`
+	}
+	var resp struct {
+		Recordings []mbRecording `json:"recordings"`
+	}
+	params := url.Values{"query": {query}, "limit": {strconv.Itoa(limit)}}
+	if err := c.get(ctx, "recording", params, &resp); err != nil {
+		return nil, err
+	}
+	return recordings(resp.Recordings), nil
+}
+
+func recordings(rs []mbRecording) []Recording {
+	recs := make([]Recording, len(rs))
+	for i, r := range rs {
+		recs[i] = r.recording()
+	}
+	return recs
+}
+
+// escapePhrase escapes the characters that end a quoted phrase in a Lucene query
+func escapePhrase(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s)
+}
+
+// get requests path relative to the base URL and decodes the JSON response into out. Requests
+// that MusicBrainz throttles with 503 are retried after backing off, and a 404 is ErrNotFound.
+func (c *Client) get(ctx context.Context, path string, query url.Values, out any) error {
+	query.Set("fmt", "json")
+	u := strings.TrimSuffix(c.BaseURL, "/") + "/" + path + "?" + query.Encode()
+
+	for attempt := 0; ; attempt++ {
+		if err := c.wait(ctx); err != nil {
+			return err
+		}
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Accept", "application/json")
+		req.Header.Set("User-Agent", c.UserAgent)
+
+		resp, err := c.HTTPClient.Do(req)
+		if err != nil {
+			return err
+		}
+		data, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case resp.StatusCode == http.StatusServiceUnavailable && attempt < maxRetries:
+			c.backOff(resp.Header.Get("Retry-After"), attempt)
+			continue
+		case resp.StatusCode == http.StatusNotFound:
+			return ErrNotFound
+		case resp.StatusCode < 200 || resp.StatusCode >= 300:
+			var apiErr struct {
+				Error string `json:"error"`
+			}
+			if json.Unmarshal(data, &apiErr) == nil && apiErr.Error != "" {
+				return fmt.Errorf("musicbrainz: %s (HTTP %d)", apiErr.Error, resp.StatusCode)
+			}
+			return fmt.Errorf("musicbrainz: HTTP %d: %s", resp.StatusCode, strings.TrimSpace(string(data)))
+		}
+		return json.Unmarshal(data, out)
+	}
+}
+
+// wait blocks until the client may send its next request, and reserves the slot after it
+func (c *Client) wait(ctx context.Context) error {
+	c.mu.Lock()
+	at := c.next
+	if now := time.Now(); at.Before(now) {
+		at = now
+	}
+	c.next = at.Add(c.Interval)
+	c.mu.Unlock()
+
+	timer := time.NewTimer(time.Until(at))
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// backOff delays the next request after a throttled one, by the Retry-After seconds if the
+// server sent them, or else by a doubling number of intervals, at least the default one
+func (c *Client) backOff(retryAfter string, attempt int) {
+	delay := max(c.Interval, DefaultInterval) << attempt
+	if seconds, err := strconv.Atoi(retryAfter); err == nil {
+		delay = time.Duration(seconds) * time.Second
+	}
+	c.mu.Lock()
+	if until := time.Now().Add(delay); c.next.Before(until) {
+		c.next = until
+	}
+	c.mu.Unlock()
+}
@@ -0,0 +1,75 @@
+package musicbrainz_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"soundporter/internal/musicbrainz"
+	"soundporter/internal/standin"
+)
+
+func TestClientKeepsToTheRateLimit(t *testing.T) {
+	// MusicBrainz allows each client one request a second
+	if c := musicbrainz.NewClient(); c.Interval != time.Second {
+		t.Fatalf("default interval is %v", c.Interval)
+	}
+
+	const interval = 100 * time.Millisecond
+	mb := standin.NewMusicBrainz(nil, interval)
+	defer mb.Close()
+	c := mb.APIClient()
+	ctx := context.Background()
+
+	// Requests from several goroutines are spaced out as well
+	isrcs := []string{"XXFAK2500001", "XXFAK2500002", "XXFAK2500003", "XXFAK2500004", "XXFAK2500005", "XXFAK2500006"}
+	start := time.Now()
+	var wg sync.WaitGroup
+	for _, isrc := range isrcs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			recs, err := c.RecordingsByISRC(ctx, isrc)
+			if err != nil || len(recs) != 1 {
+				t.Errorf("%s: found %v, %v", isrc, recs, err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if elapsed, least := time.Since(start), time.Duration(len(isrcs)-1)*interval; elapsed < least {
+		t.Errorf("%d requests took %v, less than %v", len(isrcs), elapsed, least)
+	}
+	if served, throttled := mb.Requests(); served != len(isrcs) || throttled != 0 {
+		t.Fatalf("served %d requests, %d throttled", served, throttled)
+	}
+}
+
+func TestClientRetriesThrottledRequests(t *testing.T) {
+	mb := standin.NewMusicBrainz(nil, 200*time.Millisecond)
+	defer mb.Close()
+	ctx := context.Background()
+
+	// A client ignoring the interval is throttled, and backs off for at least the default
+	// interval before retrying
+	c := mb.APIClient()
+	c.Interval = 0
+	if _, err := c.Recording(ctx, standin.MusicBrainzID("fake-01")); err != nil {
+		t.Fatal(err)
+	}
+	start := time.Now()
+	rec, err := c.Recording(ctx, standin.MusicBrainzID("fake-02"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rec.ID != standin.MusicBrainzID("fake-02") {
+		t.Fatalf("retry returned %+v", rec)
+	}
+	if elapsed := time.Since(start); elapsed < musicbrainz.DefaultInterval {
+		t.Errorf("retried after %v", elapsed)
+	}
+	if served, throttled := mb.Requests(); served != 3 || throttled != 1 {
+		t.Fatalf("served %d requests, %d throttled", served, throttled)
+	}
+}
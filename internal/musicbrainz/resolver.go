@@ -0,0 +1,193 @@
+package musicbrainz
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"soundporter/internal/playlist"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	// searchLimit is the number of recordings considered per search
+	searchLimit = 5
+	// minSearchScore is the lowest search score accepted, out of 100
+	minSearchScore = 90
+	// maxDurationDiffMs is how far apart two durations can be and still be the same recording
+	maxDurationDiffMs = 10000
+)
+
+// Resolver identifies tracks on MusicBrainz and enriches them with the recording's canonical
+// metadata. Lookups go through the cache, so each track is only looked up once.
+type Resolver struct {
+	client *Client
+	cache  *Cache
+	warn   sync.Once
+}
+
+// NewResolver creates a Resolver looking tracks up with client. cache may be nil.
+func NewResolver(client *Client, cache *Cache) *Resolver {
+	return &Resolver{client: client, cache: cache}
+}
+
+// Resolve looks the track up by its MBID, its ISRC, or else its artist, title and duration,
+// and returns it with the recording's MBID, credited artists and release information. Missing
+// titles, ISRCs and durations are filled in too. A track MusicBrainz doesn't know is returned
+// unchanged with ErrNotFound.
+func (r *Resolver) Resolve(ctx context.Context, t playlist.Track) (playlist.Track, error) {
+	rec, err := r.find(ctx, t)
+	if err != nil {
+		if ctx.Err() == nil {
+			r.warn.Do(func() {
+				fmt.Println("Warning: MusicBrainz lookups are failing, so tracks are matched without them:", err)
+			})
+		}
+		return t, err
+	}
+	if rec == nil {
+		return t, ErrNotFound
+	}
+	return enrich(t, *rec), nil
+}
+
+// Save writes the cache back to its file, if there is one
+func (r *Resolver) Save() error {
+	if r.cache == nil {
+		return nil
+	}
+	return r.cache.Save()
+}
+
+// find returns the recording of a track, or nil if there is none
+func (r *Resolver) find(ctx context.Context, t playlist.Track) (*Recording, error) {
+	if t.MBID != "" {
+		return r.cached("mbid:"+t.MBID, func() (*Recording, error) {
+			rec, err := r.client.Recording(ctx, t.MBID)
+			if err != nil {
+				return nil, err
+			}
+			return &rec, nil
+		})
+	}
+
+	if t.ISRC != "" {
+		rec, err := r.cached("isrc:"+strings.ToUpper(t.ISRC), func() (*Recording, error) {
+			recs, err := r.client.RecordingsByISRC(ctx, t.ISRC)
+			if err != nil {
+				return nil, err
+			}
+			return best(t, recs, 0), nil
+		})
+		// Not every ISRC is on MusicBrainz, so fall back to a search
+		if err != nil || rec != nil {
+			return rec, err
+		}
+	}
+
+	if t.Name == "" || len(t.Artists) == 0 {
+		return nil, nil
+	}
+	key := "search:" + strings.ToLower(t.Artists[0]) + "|" + strings.ToLower(t.Name) + "|" + strconv.Itoa(t.DurationMs/1000)
+	return r.cached(key, func() (*Recording, error) {
+		recs, err := r.client.SearchRecordings(ctx, t.Name, t.Artists[0], searchLimit)
+		if err != nil {
+			return nil, err
+		}
+		return best(t, recs, minSearchScore), nil
+	})
+}
+
+// cached returns the cached result of a lookup, or else fetches and caches it. ErrNotFound is
+// cached as a lookup that found nothing.
+func (r *Resolver) cached(key string, fetch func() (*Recording, error)) (*Recording, error) {
+	if r.cache != nil {
+		if e, ok := r.cache.Lookup(key); ok {
+			return e.Recording, nil
+		}
+	}
+	rec, err := fetch()
+	if errors.Is(err, ErrNotFound) {
+		rec, err = nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if r.cache != nil {
+		r.cache.Store(key, rec)
+	}
+	return rec, nil
+}
+
+// best returns the first recording scoring at least minScore whose duration is close to the
+// track's, or nil
+func best(t playlist.Track, recs []Recording, minScore int) *Recording {
+	for _, rec := range recs {
+		if rec.Score < minScore {
+			continue
+		}
+		if t.DurationMs > 0 && rec.DurationMs > 0 && abs(t.DurationMs-rec.DurationMs) > maxDurationDiffMs {
+			continue
+		}
+		return &rec
+	}
+	return nil
+}
+
+// enrich adds the canonical metadata of a recording to a track. The credited artists replace
+// the track's, which platforms spell in their own ways.
+func enrich(t playlist.Track, rec Recording) playlist.Track {
+	t.MBID = rec.ID
+	if t.Name == "" {
+		t.Name = rec.Title
+	}
+	if len(rec.Artists) > 0 {
+		t.Artists = rec.Artists
+	}
+	if t.ISRC == "" && len(rec.ISRCs) > 0 {
+		t.ISRC = rec.ISRCs[0]
+	}
+	if t.DurationMs == 0 {
+		t.DurationMs = rec.DurationMs
+	}
+	if release, ok := rec.release(t.Album); ok {
+		if t.Album == "" {
+			t.Album = release.Title
+		}
+		if t.ReleaseDate == "" {
+			t.ReleaseDate = release.Date
+		}
+	}
+	return t
+}
+
+// release returns the release titled album, or else the earliest official release, which is
+// usually the original album or single
+func (r Recording) release(album string) (Release, bool) {
+	if len(r.Releases) == 0 {
+		return Release{}, false
+	}
+	for _, rel := range r.Releases {
+		if album != "" && strings.EqualFold(rel.Title, album) {
+			return rel, true
+		}
+	}
+	earliest := -1
+	for i, rel := range r.Releases {
+		if rel.Status != "" && rel.Status != "Official" {
+			continue
+		}
+		if earliest < 0 || (rel.Date != "" && (r.Releases[earliest].Date == "" || rel.Date < r.Releases[earliest].Date)) {
+			earliest = i
+		}
+	}
+	return r.Releases[max(earliest, 0)], true
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
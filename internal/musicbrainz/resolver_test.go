@@ -0,0 +1,145 @@
+package musicbrainz_test
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"soundporter/internal/matcher"
+	"soundporter/internal/musicbrainz"
+	"soundporter/internal/playlist"
+	"soundporter/internal/standin"
+)
+
+// newResolver starts a MusicBrainz stand-in and returns a resolver looking tracks up on it,
+// with a cache kept at path
+func newResolver(t *testing.T, path string) (*standin.MusicBrainz, *musicbrainz.Resolver) {
+	t.Helper()
+	mb := standin.NewMusicBrainz(nil, 10*time.Millisecond)
+	t.Cleanup(mb.Close)
+	cache, err := musicbrainz.LoadCache(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return mb, musicbrainz.NewResolver(mb.APIClient(), cache)
+}
+
+func TestResolveEnrichesTracks(t *testing.T) {
+	ctx := context.Background()
+	_, r := newResolver(t, filepath.Join(t.TempDir(), "musicbrainz.json"))
+	mbid := standin.MusicBrainzID("fake-06")
+
+	tests := []struct {
+		name  string
+		track playlist.Track
+	}{
+		{"by MBID", playlist.Track{MBID: mbid}},
+		{"by ISRC", playlist.Track{Name: "Golden Master", ISRC: "xxfak2500006"}},
+		{"by ISRC missing from MusicBrainz", playlist.Track{Name: "Golden Master", Artists: []string{"Mock Orchestra"}, ISRC: "ZZZZZ0000000"}},
+		{"by search", playlist.Track{Name: "Golden Master", Artists: []string{"Mock Orchestra"}, DurationMs: 200000}},
+	}
+	for _, tt := range tests {
+		got, err := r.Resolve(ctx, tt.track)
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if got.MBID != mbid || got.Name != "Golden Master" || !slices.Equal(got.Artists, []string{"Mock Orchestra"}) ||
+			got.Album != "Stubs and Spies" || got.ReleaseDate != "2025-01-01" {
+			t.Errorf("%s: resolved to %+v", tt.name, got)
+		}
+		if tt.track.ISRC == "" && got.ISRC != "XXFAK2500006" {
+			t.Errorf("%s: ISRC not filled in: %+v", tt.name, got)
+		}
+	}
+
+	// A search result whose duration is too far off is a different recording
+	far := playlist.Track{Name: "Golden Master", Artists: []string{"Mock Orchestra"}, DurationMs: 240000}
+	if got, err := r.Resolve(ctx, far); !errors.Is(err, musicbrainz.ErrNotFound) || got.MBID != "" {
+		t.Fatalf("resolved a track of another duration to %+v, %v", got, err)
+	}
+}
+
+func TestResolveCachesLookups(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "musicbrainz.json")
+	mb, r := newResolver(t, path)
+
+	// Searches are cached by artist, title and duration in seconds, whatever their case
+	found := []playlist.Track{
+		{Name: "Golden Master", Artists: []string{"Mock Orchestra"}, DurationMs: 198000},
+		{Name: "GOLDEN MASTER", Artists: []string{"mock orchestra"}, DurationMs: 198400},
+		{Name: "Null Pointer", ISRC: "XXFAK2500014"},
+		{Name: "Null Pointer", ISRC: "xxfak2500014"},
+	}
+	missing := playlist.Track{Name: "Unreleased", Artists: []string{"Nobody"}}
+	resolve := func(r *musicbrainz.Resolver) {
+		t.Helper()
+		for _, track := range found {
+			if got, err := r.Resolve(ctx, track); err != nil || got.MBID == "" {
+				t.Fatalf("%s resolved to %+v, %v", track.Name, got, err)
+			}
+		}
+		// Lookups that found nothing are cached too
+		for range 2 {
+			if _, err := r.Resolve(ctx, missing); !errors.Is(err, musicbrainz.ErrNotFound) {
+				t.Fatalf("missing track resolved with %v", err)
+			}
+		}
+	}
+
+	resolve(r)
+	if served, _ := mb.Requests(); served != 3 {
+		t.Fatalf("expected 3 lookups, served %d", served)
+	}
+
+	// The saved cache answers every lookup of a new resolver
+	if err := r.Save(); err != nil {
+		t.Fatal(err)
+	}
+	mb, r = newResolver(t, path)
+	resolve(r)
+	if served, _ := mb.Requests(); served != 0 {
+		t.Fatalf("expected the cache to answer, served %d", served)
+	}
+
+	// Without a cache every resolve is a lookup
+	r = musicbrainz.NewResolver(mb.APIClient(), nil)
+	resolve(r)
+	if served, _ := mb.Requests(); served != 6 {
+		t.Fatalf("expected 6 lookups without a cache, served %d", served)
+	}
+}
+
+func TestResolvedMBIDsMatchWithCertainty(t *testing.T) {
+	ctx := context.Background()
+	_, r := newResolver(t, filepath.Join(t.TempDir(), "musicbrainz.json"))
+
+	// A source identified by its MBID, as ListenBrainz tracks are, and a candidate labelled as
+	// another version, which scores below a certain match until MusicBrainz identifies it
+	source := playlist.Track{Name: "Golden Master", Artists: []string{"Mock Orchestra"}, MBID: standin.MusicBrainzID("fake-06")}
+	candidate := playlist.Track{Name: "Golden Master", Artists: []string{"Mock Orchestra"}, Version: "Remastered", DurationMs: 198000}
+	if score := matcher.Score(source, candidate); score >= 1 {
+		t.Fatalf("unresolved candidate scored %v", score)
+	}
+
+	resolved, err := r.Resolve(ctx, candidate)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if score := matcher.Score(source, resolved); score != 1 {
+		t.Fatalf("candidate with the same MBID scored %v", score)
+	}
+
+	// A different recording keeps its score
+	other, err := r.Resolve(ctx, playlist.Track{Name: "Null Pointer", Artists: []string{"Segfault Sisters"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if other.MBID == source.MBID || matcher.Score(source, other) >= 1 {
+		t.Fatalf("other recording resolved to %+v", other)
+	}
+}
@@ -4,13 +4,24 @@ import "time"
 
 // Track represents a single music track with essential metadata
 type Track struct {
-	Name      string   `csv:"name"`
-	Artists   []string `csv:"artists"`
-	Album     string   `csv:"album"`
-	ID        string   `csv:"id"`
-	ArtistIDs []string `csv:"artist_ids"`
-	AlbumID   string   `csv:"album_id"`
-	URL       string   `csv:"url"`
+	Name        string   `csv:"name"`
+	Artists     []string `csv:"artists"`
+	Album       string   `csv:"album"`
+	ID          string   `csv:"id"`
+	ArtistIDs   []string `csv:"artist_ids"`
+	AlbumID     string   `csv:"album_id"`
+	URL         string   `csv:"url"`
+	Version     string   `csv:"version"`
+	ISRC        string   `csv:"isrc"`
+	DurationMs  int      `csv:"duration_ms"`
+	Explicit    bool     `csv:"explicit"`
+	Popularity  int      `csv:"popularity"`
+	DiscNumber  int      `csv:"disc_number"`
+	TrackNumber int      `csv:"track_number"`
+	// ReleaseDate is the album release date as reported by the platform, e.g. "1981", "1981-12" or "1981-12-15"
+	ReleaseDate string `csv:"release_date"`
+	// MBID is the MusicBrainz recording ID, when the platform or a MusicBrainz lookup provides it
+	MBID string `csv:"mbid"`
 }
 
 // Playlist represents a collection of tracks
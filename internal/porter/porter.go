@@ -1,11 +1,16 @@
 package porter
 
 import (
+	"context"
 	"encoding/csv"
 	"fmt"
+	"io"
+	"iter"
 	"os"
+	"reflect"
 	"soundporter/internal/adapters"
 	"soundporter/internal/playlist"
+	"soundporter/internal/utils"
 	"strings"
 	"time"
 )
@@ -13,28 +18,45 @@ import (
 // Porter implements the core.Porter interface
 // using an adapter to interact with a specific music platform
 type Porter struct {
-	adapter adapters.ApiAdapter
+	platform string
+	adapter  adapters.ApiAdapter
 }
 
 // NewPorter creates a new playlist service using the specified adapter
-func NewPorter(adapter adapters.ApiAdapter) *Porter {
+func NewPorter(platform string, adapter adapters.ApiAdapter) *Porter {
 	return &Porter{
-		adapter: adapter,
+		platform: platform,
+		adapter:  adapter,
 	}
 }
 
-// NewPorterWithPlatform creates a new Porter instance with the specified platform type
-func NewPorterWithCredentials(platform string, clientID, clientSecret string) (*Porter, error) {
-	adapter, err := adapters.NewApiAdapter(platform, clientID, clientSecret)
+// NewPorterWithCredentials creates a new Porter for a registered platform.
+// Credentials that aren't given are read from the platform's environment variables.
+func NewPorterWithCredentials(platform string, creds adapters.Credentials) (*Porter, error) {
+	adapter, err := adapters.NewApiAdapter(platform, creds)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create adapter for platform %s: %v", platform, err)
 	}
-	return NewPorter(adapter), nil
+	return NewPorter(platform, adapter), nil
+}
+
+// Close releases what the adapter holds open, such as a plugin process. Adapters that hold
+// nothing open don't implement io.Closer, and closing them does nothing.
+func (s *Porter) Close() error {
+	if c, ok := s.adapter.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
+
+// Platform returns the name of the platform the porter talks to
+func (s *Porter) Platform() string {
+	return s.platform
 }
 
 // Authenticate delegates authentication to the adapter
-func (s *Porter) Authenticate() error {
-	return s.adapter.Authenticate()
+func (s *Porter) Authenticate(ctx context.Context) error {
+	return s.adapter.Authenticate(ctx)
 }
 
 // IsAuthenticated checks if the service is authenticated
@@ -43,83 +65,80 @@ func (s *Porter) IsAuthenticated() bool {
 }
 
 // GetPlaylists retrieves all playlists via the adapter
-func (s *Porter) GetPlaylists() ([]playlist.Playlist, error) {
-	return s.adapter.GetUserPlaylists()
+func (s *Porter) GetPlaylists(ctx context.Context) ([]playlist.Playlist, error) {
+	return s.adapter.GetUserPlaylists(ctx)
 }
 
 // GetPlaylistTracks retrieves all tracks in a playlist
-func (s *Porter) GetPlaylistTracks(playlistID string) ([]playlist.Track, error) {
-	return s.adapter.GetPlaylistItems(playlistID)
+func (s *Porter) GetPlaylistTracks(ctx context.Context, playlistID string) ([]playlist.Track, error) {
+	return s.adapter.GetPlaylistItems(ctx, playlistID)
 }
 
-// CreatePlaylist creates a new playlist
-func (s *Porter) CreatePlaylist(name, description string) (playlist.Playlist, error) {
+// Capabilities returns the limits and features of the adapter's platform
+func (s *Porter) Capabilities() adapters.Capabilities {
+	return s.adapter.Capabilities()
+}
+
+// CreatePlaylist creates a new playlist, trimming the description to what the platform accepts.
+// An empty privacy uses the platform's default.
+func (s *Porter) CreatePlaylist(ctx context.Context, name, description string, privacy adapters.Privacy) (playlist.Playlist, error) {
+	caps := s.adapter.Capabilities()
+	if privacy == "" && len(caps.PrivacyLevels) > 0 {
+		privacy = caps.PrivacyLevels[0]
+	}
+	if !caps.SupportsPrivacy(privacy) {
+		return playlist.Playlist{}, fmt.Errorf("%s playlists are not supported on %s", privacy, s.platform)
+	}
 	if description == "" {
 		description = fmt.Sprintf("Playlist created via Soundporter on %s", time.Now().Format("2006-01-02"))
 	}
-	return s.adapter.CreateNewPlaylist(name, description)
+	if max := caps.MaxDescriptionLength; max > 0 && len([]rune(description)) > max {
+		description = string([]rune(description)[:max])
+	}
+	return s.adapter.CreateNewPlaylist(ctx, name, description, privacy)
 }
 
 // AddTracksToPlaylist adds tracks to an existing playlist
-func (s *Porter) AddTracksToPlaylist(playlistID string, trackIDs []string) error {
-	return s.adapter.AddItemsToPlaylist(playlistID, trackIDs)
+func (s *Porter) AddTracksToPlaylist(ctx context.Context, playlistID string, trackIDs []string) error {
+	return s.adapter.AddItemsToPlaylist(ctx, playlistID, trackIDs)
 }
 
-// ExportPlaylistToCSV exports a playlist to a CSV file
-func (s *Porter) ExportPlaylistToCSV(playlistID, filepath string) error {
-	// Get tracks from playlist
-	tracks, err := s.adapter.GetPlaylistItems(playlistID)
-	if err != nil {
-		return fmt.Errorf("failed to get playlist tracks: %v", err)
-	}
-
-	// Ensure filepath has .csv extension
-	if !strings.HasSuffix(filepath, ".csv") {
-		filepath += ".csv"
-	}
+// PlaylistTracks streams the tracks in a playlist
+func (s *Porter) PlaylistTracks(ctx context.Context, playlistID string) iter.Seq2[playlist.Track, error] {
+	return s.adapter.PlaylistItems(ctx, playlistID)
+}
 
-	// Create CSV file
-	file, err := os.Create(filepath)
+// ExportPlaylist streams a playlist to a CSV file with one column per playlist.Track field.
+// progress, if not nil, is called with the number of tracks written after each track.
+func (s *Porter) ExportPlaylist(ctx context.Context, playlistID, filepath string, progress func(written int)) (int, error) {
+	headers := utils.StructToCsvHeader(reflect.TypeOf(playlist.Track{}))
+	w, err := utils.NewCsvWriter[playlist.Track](filepath, headers)
 	if err != nil {
-		return fmt.Errorf("error creating CSV file: %v", err)
+		return 0, fmt.Errorf("error creating CSV file: %v", err)
 	}
-	defer file.Close()
 
-	// Write tracks to CSV
-	writer := csv.NewWriter(file)
-	defer writer.Flush()
-
-	// Write header
-	header := []string{"Track Name", "Artist Name", "Album Name", "Track ID", "Artist ID", "Album ID", "Track URL"}
-	if err := writer.Write(header); err != nil {
-		return fmt.Errorf("error writing CSV header: %v", err)
-	}
-
-	// Write track data
-	for _, track := range tracks {
-		artistNames := strings.Join(track.Artists, ", ")
-		artistIDs := strings.Join(track.ArtistIDs, ", ")
-
-		row := []string{
-			track.Name,
-			artistNames,
-			track.Album,
-			track.ID,
-			artistIDs,
-			track.AlbumID,
-			track.URL,
+	written := 0
+	for track, err := range s.adapter.PlaylistItems(ctx, playlistID) {
+		if err != nil {
+			w.Close()
+			return written, fmt.Errorf("failed to get tracks for playlist %s: %v", playlistID, err)
 		}
-
-		if err := writer.Write(row); err != nil {
-			return fmt.Errorf("error writing track to CSV: %v", err)
+		if err := w.Write(track); err != nil {
+			w.Close()
+			return written, fmt.Errorf("error writing track to CSV: %v", err)
+		}
+		written++
+		if progress != nil {
+			progress(written)
 		}
 	}
 
-	return nil
+	return written, w.Close()
 }
 
-// ImportPlaylistFromCSV imports a playlist from a CSV file
-func (s *Porter) ImportPlaylistFromCSV(filepath string, playlistName string) error {
+// ImportPlaylistFromCSV imports a playlist from a CSV file.
+// Rows with a track ID are added as-is; rows with only a name and artists, an ISRC or a MusicBrainz ID are matched on the target platform.
+func (s *Porter) ImportPlaylistFromCSV(ctx context.Context, filepath string, playlistName string, opts TransferOptions) error {
 	// Open and read CSV file
 	file, err := os.Open(filepath)
 	if err != nil {
@@ -138,59 +157,92 @@ func (s *Porter) ImportPlaylistFromCSV(filepath string, playlistName string) err
 		return fmt.Errorf("CSV file is empty or contains only header")
 	}
 
-	// Find the track ID column index
+	// Find the track ID, name and artist column indexes. Both the csv tags of playlist.Track,
+	// which ExportPlaylist writes, and the headers of older exports are recognised.
 	header := records[0]
-	trackIDIndex := -1
+	trackIDIndex, nameIndex, artistIndex, isrcIndex, mbidIndex := -1, -1, -1, -1, -1
 	for i, colName := range header {
-		if strings.Contains(strings.ToLower(colName), "track id") {
+		colName = strings.ToLower(strings.TrimSpace(colName))
+		switch {
+		case colName == "id" || strings.Contains(colName, "track id"):
 			trackIDIndex = i
-			break
+		case colName == "name" || strings.Contains(colName, "track name"):
+			nameIndex = i
+		case colName == "artists" || strings.Contains(colName, "artist name"):
+			artistIndex = i
+		case colName == "isrc":
+			isrcIndex = i
+		case colName == "mbid":
+			mbidIndex = i
 		}
 	}
 
-	if trackIDIndex == -1 {
-		return fmt.Errorf("track ID column not found in CSV")
+	if trackIDIndex == -1 && nameIndex == -1 {
+		return fmt.Errorf("track ID or track name column not found in CSV")
+	}
+
+	var tracks []playlist.Track
+	for _, record := range records[1:] {
+		var track playlist.Track
+		if trackIDIndex != -1 && len(record) > trackIDIndex {
+			track.ID = record[trackIDIndex]
+		}
+		if nameIndex != -1 && len(record) > nameIndex {
+			track.Name = record[nameIndex]
+		}
+		if artistIndex != -1 && len(record) > artistIndex && record[artistIndex] != "" {
+			track.Artists = splitArtists(record[artistIndex])
+		}
+		if isrcIndex != -1 && len(record) > isrcIndex {
+			track.ISRC = record[isrcIndex]
+		}
+		if mbidIndex != -1 && len(record) > mbidIndex {
+			track.MBID = record[mbidIndex]
+		}
+		tracks = append(tracks, track)
 	}
 
 	// Create a new playlist
 	description := fmt.Sprintf("Playlist imported via Soundporter on %s", time.Now().Format("2006-01-02"))
-	playlist, err := s.adapter.CreateNewPlaylist(playlistName, description)
+	p, err := s.CreatePlaylist(ctx, playlistName, description, opts.Privacy)
 	if err != nil {
 		return fmt.Errorf("error creating playlist: %v", err)
 	}
 
-	// Extract track IDs
-	var trackIDs []string
-	var skippedTracks int
-
-	for _, record := range records[1:] {
-		if len(record) <= trackIDIndex || record[trackIDIndex] == "" {
-			skippedTracks++
+	m := s.newMatcher(opts)
+	var sum summary
+	var resolved []resolvedTrack
+	for _, track := range tracks {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if track.ID != "" {
+			resolved = append(resolved, resolvedTrack{source: track, targetID: track.ID})
 			continue
 		}
-
-		trackIDs = append(trackIDs, record[trackIDIndex])
-
-		// Add tracks in batches of 100 (Spotify's limit)
-		if len(trackIDs) >= 100 {
-			if err := s.adapter.AddItemsToPlaylist(playlist.ID, trackIDs); err != nil {
-				return fmt.Errorf("error adding tracks to playlist: %v", err)
-			}
-			trackIDs = nil
+		if track.Name == "" && track.ISRC == "" && track.MBID == "" {
+			sum.skipped++
+			continue
 		}
-	}
-
-	// Add remaining tracks
-	if len(trackIDs) > 0 {
-		if err := s.adapter.AddItemsToPlaylist(playlist.ID, trackIDs); err != nil {
-			return fmt.Errorf("error adding remaining tracks to playlist: %v", err)
+		if r, ok := s.resolveTrack(ctx, m, csvSource, track, p.ID, opts, &sum); ok {
+			resolved = append(resolved, r)
 		}
 	}
+	if err := s.addResolvedTracks(ctx, csvSource, p.ID, resolved, opts, &sum); err != nil {
+		return err
+	}
 
-	fmt.Printf("Successfully imported playlist '%s' with %d tracks\n", playlistName, len(records)-1-skippedTracks)
-	if skippedTracks > 0 {
-		fmt.Printf("Skipped %d tracks due to missing or invalid track IDs\n", skippedTracks)
+	if err := opts.save(); err != nil {
+		return err
 	}
 
+	fmt.Printf("Successfully imported playlist '%s' with %d tracks\n", playlistName, sum.added)
+	sum.print(s.platform)
 	return nil
 }
+
+// splitArtists splits an artist column written by WriteToCsvFile. Artists are joined with ";",
+// since names like "Earth, Wind & Fire" contain commas.
+func splitArtists(value string) []string {
+	return strings.Split(value, ";")
+}
@@ -0,0 +1,140 @@
+package porter
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"testing"
+
+	"soundporter/internal/adapters"
+	"soundporter/internal/playlist"
+	"soundporter/internal/report"
+	"soundporter/internal/utils"
+)
+
+// catalogWithMBIDs returns the fake catalog with MusicBrainz IDs on its first tracks
+func catalogWithMBIDs() []playlist.Track {
+	catalog := adapters.FakeCatalog()
+	catalog[1].MBID = "6f9e4b3c-0a55-4b8e-9d0e-000000000002"
+	catalog[5].MBID = "6f9e4b3c-0a55-4b8e-9d0e-000000000006"
+	return catalog
+}
+
+func TestExportImportRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "focus.csv")
+	source, _ := newFakePorter(t, adapters.FakeConfig{Catalog: catalogWithMBIDs(), PageSize: 3})
+
+	var progress []int
+	written, err := source.ExportPlaylist(ctx, "fake-playlist-2", path, func(n int) { progress = append(progress, n) })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if written != 4 || !slices.Equal(progress, []int{1, 2, 3, 4}) {
+		t.Fatalf("expected 4 tracks written, got %d with progress %v", written, progress)
+	}
+
+	want, err := source.GetPlaylistTracks(ctx, "fake-playlist-2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	exported, err := utils.ReadCsvFile[playlist.Track](path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(exported, want) {
+		t.Fatalf("export doesn't round trip:\n got %+v\nwant %+v", exported, want)
+	}
+	if exported[0].MBID == "" || exported[1].MBID == "" {
+		t.Fatalf("mbid column lost: %+v", exported)
+	}
+
+	// Rows with a track ID are added as they are
+	target, _ := newFakePorter(t, adapters.FakeConfig{Catalog: catalogWithMBIDs()})
+	if err := target.ImportPlaylistFromCSV(ctx, path, "Focus", TransferOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	wantIDs := []string{"fake-02", "fake-06", "fake-12", "fake-15"}
+	if ids := playlistTrackIDs(t, target, lastPlaylistID(t, target)); !slices.Equal(ids, wantIDs) {
+		t.Fatalf("expected %v, got %v", wantIDs, ids)
+	}
+}
+
+func TestImportMatchesRowsWithoutIDs(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "import.csv")
+	csv := "Track Name,Artist Name(s),ISRC,mbid\n" +
+		"Golden Master,Mock Orchestra,,\n" +
+		",,XXFAK2500012,\n" +
+		"Unreleased Demo,Nobody,,\n" +
+		",,,6f9e4b3c-0a55-4b8e-9d0e-000000000099\n" +
+		",,,\n"
+	if err := os.WriteFile(path, []byte(csv), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	target, _ := newFakePorter(t, adapters.FakeConfig{})
+	rep := &report.Report{}
+	if err := target.ImportPlaylistFromCSV(ctx, path, "Imported", TransferOptions{Report: rep}); err != nil {
+		t.Fatal(err)
+	}
+
+	if ids := playlistTrackIDs(t, target, lastPlaylistID(t, target)); !slices.Equal(ids, []string{"fake-06", "fake-12"}) {
+		t.Fatalf("unexpected playlist %v", ids)
+	}
+
+	// The unmatched rows keep their MusicBrainz ID in the report, and the empty row is skipped
+	if len(rep.Entries) != 2 {
+		t.Fatalf("expected two unmatched rows, got %+v", rep.Entries)
+	}
+	if e := rep.Entries[1]; e.MBID != "6f9e4b3c-0a55-4b8e-9d0e-000000000099" || e.Reason != report.ReasonNoResults || e.SourcePlatform != csvSource {
+		t.Fatalf("unexpected report entry %+v", e)
+	}
+}
+
+func TestImportReportsRegionUnavailableTracks(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "import.csv")
+	csv := "name,artists\nStack Overflow,Segfault Sisters\nNull Pointer,Segfault Sisters\n"
+	if err := os.WriteFile(path, []byte(csv), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	target, _ := newFakePorter(t, adapters.FakeConfig{UnavailableTrackIDs: []string{"fake-15"}})
+	rep := &report.Report{}
+	if err := target.ImportPlaylistFromCSV(ctx, path, "Imported", TransferOptions{Report: rep}); err != nil {
+		t.Fatal(err)
+	}
+
+	if ids := playlistTrackIDs(t, target, lastPlaylistID(t, target)); !slices.Equal(ids, []string{"fake-14"}) {
+		t.Fatalf("unexpected playlist %v", ids)
+	}
+	if len(rep.Entries) != 1 || rep.Entries[0].Name != "Stack Overflow" || rep.Entries[0].Reason != report.ReasonRegionUnavailable {
+		t.Fatalf("expected Stack Overflow to be region unavailable, got %+v", rep.Entries)
+	}
+}
+
+// closingAdapter records whether it was closed, like an adapter running a plugin process
+type closingAdapter struct {
+	*adapters.FakeAdapter
+	closed bool
+}
+
+func (a *closingAdapter) Close() error {
+	a.closed = true
+	return nil
+}
+
+func TestCloseClosesAdaptersThatHoldResources(t *testing.T) {
+	a := &closingAdapter{FakeAdapter: adapters.NewFakeAdapter(adapters.FakeConfig{})}
+	if err := NewPorter("plugin", a).Close(); err != nil || !a.closed {
+		t.Fatalf("adapter not closed: %v", err)
+	}
+
+	// Adapters without a Close method have nothing to release
+	if err := NewPorter(string(adapters.FakePlatform), adapters.NewFakeAdapter(adapters.FakeConfig{})).Close(); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,255 @@
+package porter
+
+import (
+	"context"
+	"fmt"
+	"soundporter/internal/adapters"
+	"soundporter/internal/matcher"
+	"soundporter/internal/musicbrainz"
+	"soundporter/internal/playlist"
+	"soundporter/internal/report"
+	"time"
+)
+
+// csvSource is the source platform recorded for tracks imported from a CSV file
+const csvSource = "csv"
+
+// TransferOptions configures how tracks are matched during an import or transfer
+type TransferOptions struct {
+	// Cache remembers resolved tracks between runs; it may be nil
+	Cache *matcher.Cache
+	// Overrides pins or excludes specific tracks before any search; it may be nil
+	Overrides *matcher.Overrides
+	// Resolver identifies tracks on MusicBrainz before they are searched for; it may be nil
+	Resolver *musicbrainz.Resolver
+	// Report collects tracks that could not be matched or added; it may be nil
+	Report *report.Report
+	// MinConfidence overrides matcher.DefaultMinConfidence when set
+	MinConfidence float64
+	// Privacy of the created playlist; empty uses the platform's default
+	Privacy adapters.Privacy
+}
+
+// save writes the match cache and the MusicBrainz cache back to their files
+func (opts TransferOptions) save() error {
+	if opts.Cache != nil {
+		if err := opts.Cache.Save(); err != nil {
+			return err
+		}
+	}
+	if opts.Resolver != nil {
+		if err := opts.Resolver.Save(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// resolvedTrack pairs a source track with the target track ID it resolved to
+type resolvedTrack struct {
+	source   playlist.Track
+	targetID string
+}
+
+// summary counts the outcome of an import or transfer
+type summary struct {
+	added      int
+	cached     int
+	excluded   int
+	failed     int
+	skipped    int
+	identified int
+}
+
+func (sum summary) print(platform string) {
+	if sum.cached > 0 {
+		fmt.Printf("Resolved %d tracks from the match cache\n", sum.cached)
+	}
+	if sum.identified > 0 {
+		fmt.Printf("Identified %d tracks on MusicBrainz before searching\n", sum.identified)
+	}
+	if sum.excluded > 0 {
+		fmt.Printf("Skipped %d tracks marked as never import\n", sum.excluded)
+	}
+	if sum.skipped > 0 {
+		fmt.Printf("Skipped %d tracks due to missing track IDs and names\n", sum.skipped)
+	}
+	if sum.failed > 0 {
+		fmt.Printf("Could not match or add %d tracks on %s\n", sum.failed, platform)
+	}
+}
+
+// TransferPlaylist copies a playlist from the source porter into a new playlist on this porter's platform.
+// Each track is resolved through the overrides and the match cache before falling back to a search on the target.
+func (s *Porter) TransferPlaylist(ctx context.Context, source *Porter, playlistID, playlistName string, opts TransferOptions) error {
+	tracks, err := source.GetPlaylistTracks(ctx, playlistID)
+	if err != nil {
+		return fmt.Errorf("failed to get playlist tracks: %v", err)
+	}
+
+	description := fmt.Sprintf("Playlist transferred from %s via Soundporter on %s", source.Platform(), time.Now().Format("2006-01-02"))
+	p, err := s.CreatePlaylist(ctx, playlistName, description, opts.Privacy)
+	if err != nil {
+		return fmt.Errorf("error creating playlist: %v", err)
+	}
+
+	if err := s.transferTracks(ctx, source.Platform(), tracks, p.ID, opts); err != nil {
+		return err
+	}
+	fmt.Printf("Transferred playlist '%s'\n", playlistName)
+	return nil
+}
+
+// RetryUnmatched tries again to match and add the tracks of a previous report to an existing playlist.
+// Tracks that still fail are recorded in opts.Report.
+func (s *Porter) RetryUnmatched(ctx context.Context, entries []report.Entry, playlistID string, opts TransferOptions) error {
+	// Group by source platform so cache lookups use the right key
+	bySource := make(map[string][]playlist.Track)
+	var order []string
+	for _, e := range entries {
+		if _, ok := bySource[e.SourcePlatform]; !ok {
+			order = append(order, e.SourcePlatform)
+		}
+		bySource[e.SourcePlatform] = append(bySource[e.SourcePlatform], e.Track())
+	}
+
+	for _, sourcePlatform := range order {
+		if err := s.transferTracks(ctx, sourcePlatform, bySource[sourcePlatform], playlistID, opts); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// transferTracks resolves tracks from the source platform and adds them to an existing playlist
+func (s *Porter) transferTracks(ctx context.Context, sourcePlatform string, tracks []playlist.Track, playlistID string, opts TransferOptions) error {
+	m := s.newMatcher(opts)
+	var sum summary
+	var resolved []resolvedTrack
+	for _, track := range tracks {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if r, ok := s.resolveTrack(ctx, m, sourcePlatform, track, playlistID, opts, &sum); ok {
+			resolved = append(resolved, r)
+		}
+	}
+	if err := s.addResolvedTracks(ctx, sourcePlatform, playlistID, resolved, opts, &sum); err != nil {
+		return err
+	}
+
+	if err := opts.save(); err != nil {
+		return err
+	}
+
+	fmt.Printf("Added %d of %d tracks\n", sum.added, len(tracks))
+	sum.print(s.platform)
+	return nil
+}
+
+func (s *Porter) newMatcher(opts TransferOptions) *matcher.Matcher {
+	m := matcher.NewMatcher(s.adapter, s.platform, opts.Cache)
+	m.Overrides = opts.Overrides
+	m.Resolver = opts.Resolver
+	if opts.MinConfidence > 0 {
+		m.MinConfidence = opts.MinConfidence
+	}
+	return m
+}
+
+// resolveTrack matches a single track, recording failures in the report
+func (s *Porter) resolveTrack(ctx context.Context, m *matcher.Matcher, sourcePlatform string, track playlist.Track, playlistID string, opts TransferOptions, sum *summary) (resolvedTrack, bool) {
+	match, err := m.Match(ctx, sourcePlatform, track)
+	if err == matcher.ErrNeverImport {
+		sum.excluded++
+		return resolvedTrack{}, false
+	}
+	if err != nil {
+		sum.failed++
+		if opts.Report != nil {
+			opts.Report.Add(sourcePlatform, track, s.platform, playlistID, err)
+		}
+		return resolvedTrack{}, false
+	}
+	if match.Cached {
+		sum.cached++
+	}
+	if match.Identified {
+		sum.identified++
+	}
+	return resolvedTrack{source: track, targetID: match.Track.ID}, true
+}
+
+// addResolvedTracks adds tracks to the playlist in batches of the platform's maximum size.
+// When a batch fails, the tracks that didn't make it in are retried one at a time, so that only
+// the rejected ones are recorded in the report. Cancelling ctx stops before the next batch.
+func (s *Porter) addResolvedTracks(ctx context.Context, sourcePlatform, playlistID string, resolved []resolvedTrack, opts TransferOptions, sum *summary) error {
+	batchSize := max(s.adapter.Capabilities().MaxBatchSize, 1)
+	for start := 0; start < len(resolved); start += batchSize {
+		batch := resolved[start:min(start+batchSize, len(resolved))]
+		trackIDs := make([]string, len(batch))
+		for i, r := range batch {
+			trackIDs[i] = r.targetID
+		}
+
+		err := s.adapter.AddItemsToPlaylist(ctx, playlistID, trackIDs)
+		if err == nil {
+			sum.added += len(batch)
+			continue
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if len(batch) == 1 {
+			s.reportFailed(sourcePlatform, playlistID, batch[0], err, opts, sum)
+			continue
+		}
+
+		inserted := s.insertedPrefix(ctx, playlistID, trackIDs)
+		sum.added += inserted
+		for _, r := range batch[inserted:] {
+			if err := s.adapter.AddItemsToPlaylist(ctx, playlistID, []string{r.targetID}); err != nil {
+				if ctx.Err() != nil {
+					return ctx.Err()
+				}
+				s.reportFailed(sourcePlatform, playlistID, r, err, opts, sum)
+				continue
+			}
+			sum.added++
+		}
+	}
+	return nil
+}
+
+// reportFailed counts a track that could not be added and records it in the report
+func (s *Porter) reportFailed(sourcePlatform, playlistID string, r resolvedTrack, err error, opts TransferOptions, sum *summary) {
+	sum.failed++
+	if opts.Report != nil {
+		opts.Report.Add(sourcePlatform, r.source, s.platform, playlistID, err)
+	}
+}
+
+// insertedPrefix returns how many tracks at the start of a failed batch were added anyway, by
+// comparing them with the end of the playlist. Platforms add a batch in order and stop at the
+// first track they reject, if they add any of it at all. If the playlist can't be read, none
+// are assumed to have been added.
+func (s *Porter) insertedPrefix(ctx context.Context, playlistID string, trackIDs []string) int {
+	items, err := s.adapter.GetPlaylistItems(ctx, playlistID)
+	if err != nil {
+		return 0
+	}
+	for n := min(len(trackIDs), len(items)); n > 0; n-- {
+		tail := items[len(items)-n:]
+		matches := true
+		for i, id := range trackIDs[:n] {
+			if tail[i].ID != id {
+				matches = false
+				break
+			}
+		}
+		if matches {
+			return n
+		}
+	}
+	return 0
+}
@@ -0,0 +1,221 @@
+package porter
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"soundporter/internal/adapters"
+	"soundporter/internal/matcher"
+	"soundporter/internal/report"
+)
+
+// newFakePorter returns an authenticated porter for a fake adapter seeded from config
+func newFakePorter(t *testing.T, config adapters.FakeConfig) (*Porter, *adapters.FakeAdapter) {
+	t.Helper()
+	a := adapters.NewFakeAdapter(config)
+	p := NewPorter(string(adapters.FakePlatform), a)
+	if err := p.Authenticate(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	return p, a
+}
+
+// playlistTrackIDs returns the IDs of the tracks in a playlist
+func playlistTrackIDs(t *testing.T, p *Porter, playlistID string) []string {
+	t.Helper()
+	tracks, err := p.GetPlaylistTracks(context.Background(), playlistID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ids := make([]string, len(tracks))
+	for i, track := range tracks {
+		ids[i] = track.ID
+	}
+	return ids
+}
+
+func TestTransferReportsOnlyRejectedTracksOfFailedBatch(t *testing.T) {
+	ctx := context.Background()
+	source, _ := newFakePorter(t, adapters.FakeConfig{})
+	target, _ := newFakePorter(t, adapters.FakeConfig{FailTrackIDs: []string{"fake-05"}})
+	rep := &report.Report{}
+
+	if err := target.TransferPlaylist(ctx, source, "fake-playlist-3", "Copy", TransferOptions{Report: rep}); err != nil {
+		t.Fatal(err)
+	}
+
+	playlists, err := target.GetPlaylists(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	copied := playlists[len(playlists)-1]
+	ids := playlistTrackIDs(t, target, copied.ID)
+	if len(ids) != 19 || slices.Contains(ids, "fake-05") {
+		t.Fatalf("expected every track but fake-05 once, got %v", ids)
+	}
+	for _, id := range []string{"fake-01", "fake-04", "fake-06", "fake-20"} {
+		if n := count(ids, id); n != 1 {
+			t.Errorf("%s added %d times", id, n)
+		}
+	}
+
+	if len(rep.Entries) != 1 || rep.Entries[0].SourceID != "fake-05" || rep.Entries[0].Reason != report.ReasonAPIError {
+		t.Fatalf("expected only fake-05 in the report, got %+v", rep.Entries)
+	}
+}
+
+// lastPlaylistID returns the ID of the most recently created playlist
+func lastPlaylistID(t *testing.T, p *Porter) string {
+	t.Helper()
+	playlists, err := p.GetPlaylists(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return playlists[len(playlists)-1].ID
+}
+
+func TestTransferAppliesOverridesCacheAndReport(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	overridesPath := filepath.Join(dir, "overrides.csv")
+	overridesCSV := "source,target_platform,target\n" +
+		"fake-01,fake,fake-04\n" +
+		"Mock Orchestra - Midnight Fixture,,never\n"
+	if err := os.WriteFile(overridesPath, []byte(overridesCSV), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	overrides, err := matcher.LoadOverrides(overridesPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cachePath := filepath.Join(dir, "matches.json")
+	cache, err := matcher.LoadCache(cachePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Road Trip holds fake-01, fake-05, fake-08, fake-11, fake-14 and fake-17
+	source, _ := newFakePorter(t, adapters.FakeConfig{})
+	target, _ := newFakePorter(t, adapters.FakeConfig{UnavailableTrackIDs: []string{"fake-08"}})
+	rep := &report.Report{}
+	opts := TransferOptions{Cache: cache, Overrides: overrides, Report: rep}
+	if err := target.TransferPlaylist(ctx, source, "fake-playlist-1", "Copy", opts); err != nil {
+		t.Fatal(err)
+	}
+
+	copied := lastPlaylistID(t, target)
+	want := []string{"fake-04", "fake-11", "fake-14", "fake-17"}
+	if ids := playlistTrackIDs(t, target, copied); !slices.Equal(ids, want) {
+		t.Fatalf("expected %v, got %v", want, ids)
+	}
+	if len(rep.Entries) != 1 {
+		t.Fatalf("expected one report entry, got %+v", rep.Entries)
+	}
+	e := rep.Entries[0]
+	if e.SourceID != "fake-08" || e.Reason != report.ReasonRegionUnavailable || e.TargetPlatform != "fake" || e.TargetPlaylistID != copied {
+		t.Fatalf("unexpected report entry %+v", e)
+	}
+
+	// The cache was saved, so a second run resolves the same tracks without searching, even on a
+	// target where searches find nothing available
+	cache, err = matcher.LoadCache(cachePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cache.Len() != 4 {
+		t.Fatalf("expected 4 cached matches, got %d", cache.Len())
+	}
+	var unavailable []string
+	for _, track := range adapters.FakeCatalog() {
+		unavailable = append(unavailable, track.ID)
+	}
+	target, _ = newFakePorter(t, adapters.FakeConfig{UnavailableTrackIDs: unavailable})
+	rep = &report.Report{}
+	if err := target.TransferPlaylist(ctx, source, "fake-playlist-1", "Copy", TransferOptions{Cache: cache, Report: rep}); err != nil {
+		t.Fatal(err)
+	}
+	if ids := playlistTrackIDs(t, target, lastPlaylistID(t, target)); !slices.Equal(ids, want) {
+		t.Fatalf("expected %v from the cache, got %v", want, ids)
+	}
+	var reported []string
+	for _, e := range rep.Entries {
+		reported = append(reported, e.SourceID)
+	}
+	if !slices.Equal(reported, []string{"fake-05", "fake-08"}) {
+		t.Fatalf("expected fake-05 and fake-08 in the report, got %v", reported)
+	}
+}
+
+func TestTransferReportsRateLimitedSearches(t *testing.T) {
+	ctx := context.Background()
+	source, _ := newFakePorter(t, adapters.FakeConfig{})
+	// Creating the playlist is the first call and each search one more, so the searches for the
+	// 3rd, 7th, 11th, 15th and 19th tracks are rate limited. The batch insert and a single page
+	// read of the result are the 22nd and 23rd calls.
+	target, _ := newFakePorter(t, adapters.FakeConfig{RateLimitEvery: 4, PageSize: 100})
+	rep := &report.Report{}
+
+	if err := target.TransferPlaylist(ctx, source, "fake-playlist-3", "Copy", TransferOptions{Report: rep}); err != nil {
+		t.Fatal(err)
+	}
+
+	limited := []string{"fake-03", "fake-07", "fake-11", "fake-15", "fake-19"}
+	var reported []string
+	for _, e := range rep.Entries {
+		if e.Reason != report.ReasonAPIError {
+			t.Errorf("%s reported as %s", e.SourceID, e.Reason)
+		}
+		reported = append(reported, e.SourceID)
+	}
+	if !slices.Equal(reported, limited) {
+		t.Fatalf("expected %v in the report, got %v", limited, reported)
+	}
+
+	ids := playlistTrackIDs(t, target, "fake-playlist-new-1")
+	if len(ids) != 15 {
+		t.Fatalf("expected 15 tracks, got %v", ids)
+	}
+	for _, id := range limited {
+		if slices.Contains(ids, id) {
+			t.Errorf("rate limited %s was added", id)
+		}
+	}
+}
+
+func TestRetryUnmatchedAddsTracksThatNowMatch(t *testing.T) {
+	ctx := context.Background()
+	target, _ := newFakePorter(t, adapters.FakeConfig{FailTrackIDs: []string{"fake-03"}})
+	p, err := target.CreatePlaylist(ctx, "Retry", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	previous := &report.Report{}
+	for _, track := range adapters.FakeCatalog()[:4] {
+		previous.Add("fake", track, "fake", p.ID, matcher.ErrNoResults)
+	}
+	rep := &report.Report{}
+	if err := target.RetryUnmatched(ctx, previous.Entries, p.ID, TransferOptions{Report: rep}); err != nil {
+		t.Fatal(err)
+	}
+
+	if ids := playlistTrackIDs(t, target, p.ID); !slices.Equal(ids, []string{"fake-01", "fake-02", "fake-04"}) {
+		t.Fatalf("unexpected playlist %v", ids)
+	}
+	if len(rep.Entries) != 1 || rep.Entries[0].SourceID != "fake-03" {
+		t.Fatalf("expected only fake-03 to remain unmatched, got %+v", rep.Entries)
+	}
+}
+
+func count(ids []string, id string) int {
+	n := 0
+	for _, v := range ids {
+		if v == id {
+			n++
+		}
+	}
+	return n
+}
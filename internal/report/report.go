@@ -0,0 +1,117 @@
+package report
+
+import (
+	"errors"
+	"reflect"
+	"soundporter/internal/adapters"
+	"soundporter/internal/matcher"
+	"soundporter/internal/playlist"
+	"soundporter/internal/utils"
+	"sync"
+)
+
+// Reason explains why a track could not be matched or added
+type Reason string
+
+const (
+	ReasonNoResults         Reason = "no_results"
+	ReasonLowConfidence     Reason = "low_confidence"
+	ReasonAPIError          Reason = "api_error"
+	ReasonRegionUnavailable Reason = "region_unavailable"
+)
+
+// Entry is a single track that did not make it into the target playlist
+type Entry struct {
+	Reason           Reason   `csv:"reason"`
+	Detail           string   `csv:"detail"`
+	SourcePlatform   string   `csv:"source_platform"`
+	SourceID         string   `csv:"source_id"`
+	Name             string   `csv:"name"`
+	Artists          []string `csv:"artists"`
+	Album            string   `csv:"album"`
+	ISRC             string   `csv:"isrc"`
+	DurationMs       int      `csv:"duration_ms"`
+	Version          string   `csv:"version"`
+	MBID             string   `csv:"mbid"`
+	TargetPlatform   string   `csv:"target_platform"`
+	TargetPlaylistID string   `csv:"target_playlist_id"`
+}
+
+// Track returns the source track the entry refers to
+func (e Entry) Track() playlist.Track {
+	return playlist.Track{
+		ID:         e.SourceID,
+		Name:       e.Name,
+		Artists:    e.Artists,
+		Album:      e.Album,
+		ISRC:       e.ISRC,
+		DurationMs: e.DurationMs,
+		Version:    e.Version,
+		MBID:       e.MBID,
+	}
+}
+
+// Report collects the tracks that could not be matched or added during an import or transfer
+type Report struct {
+	mu      sync.Mutex
+	Entries []Entry
+}
+
+// Load reads a report previously written with Save
+func Load(path string) (*Report, error) {
+	entries, err := utils.ReadCsvFile[Entry](path)
+	if err != nil {
+		return nil, err
+	}
+	return &Report{Entries: entries}, nil
+}
+
+// Add records a failed track together with the error that caused it
+func (r *Report) Add(sourcePlatform string, t playlist.Track, targetPlatform, targetPlaylistID string, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.Entries = append(r.Entries, Entry{
+		Reason:           ReasonFor(err),
+		Detail:           err.Error(),
+		SourcePlatform:   sourcePlatform,
+		SourceID:         t.ID,
+		Name:             t.Name,
+		Artists:          t.Artists,
+		Album:            t.Album,
+		ISRC:             t.ISRC,
+		DurationMs:       t.DurationMs,
+		Version:          t.Version,
+		MBID:             t.MBID,
+		TargetPlatform:   targetPlatform,
+		TargetPlaylistID: targetPlaylistID,
+	})
+}
+
+// Len returns the number of entries in the report
+func (r *Report) Len() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.Entries)
+}
+
+// Save writes the report to a CSV file
+func (r *Report) Save(path string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	headers := utils.StructToCsvHeader(reflect.TypeOf(Entry{}))
+	return utils.WriteToCsvFile(path, headers, r.Entries)
+}
+
+// ReasonFor classifies an error returned while matching or adding a track
+func ReasonFor(err error) Reason {
+	switch {
+	case errors.Is(err, matcher.ErrNoResults):
+		return ReasonNoResults
+	case errors.Is(err, matcher.ErrLowConfidence):
+		return ReasonLowConfidence
+	case errors.Is(err, adapters.ErrRegionUnavailable):
+		return ReasonRegionUnavailable
+	default:
+		return ReasonAPIError
+	}
+}
@@ -0,0 +1,50 @@
+package report
+
+import (
+	"errors"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"soundporter/internal/matcher"
+	"soundporter/internal/playlist"
+)
+
+func TestReportRoundTripKeepsMatchingFields(t *testing.T) {
+	track := playlist.Track{
+		ID:         "src-1",
+		Name:       "Lorem Ipsum",
+		Artists:    []string{"The Placeholders", "Guest"},
+		Album:      "Live at the Test Suite",
+		ISRC:       "XXFAK2500004",
+		DurationMs: 261000,
+		Version:    "Live",
+		MBID:       "0f1e2d3c-4b5a-6978-8796-a5b4c3d2e1f0",
+	}
+	r := &Report{}
+	r.Add("spotify", track, "youtube", "PL1", matcher.ErrNoResults)
+	r.Add("spotify", playlist.Track{ID: "src-2", Name: "Other"}, "deezer", "42", errors.New("boom"))
+
+	path := filepath.Join(t.TempDir(), "unmatched.csv")
+	if err := r.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(loaded.Entries) != 2 {
+		t.Fatalf("expected 2 entries, got %d", len(loaded.Entries))
+	}
+
+	e := loaded.Entries[0]
+	if e.Reason != ReasonNoResults || e.TargetPlatform != "youtube" || e.TargetPlaylistID != "PL1" {
+		t.Errorf("unexpected entry %+v", e)
+	}
+	if got := e.Track(); !reflect.DeepEqual(got, track) {
+		t.Errorf("track changed in the round trip:\n got %+v\nwant %+v", got, track)
+	}
+	if loaded.Entries[1].Reason != ReasonAPIError || loaded.Entries[1].Detail != "boom" {
+		t.Errorf("unexpected entry %+v", loaded.Entries[1])
+	}
+}
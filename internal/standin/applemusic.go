@@ -0,0 +1,383 @@
+package standin
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"time"
+
+	"soundporter/internal/adapters"
+	"soundporter/internal/playlist"
+)
+
+const (
+	// AppleTeamID and AppleKeyID identify the developer key the Apple Music stand-in accepts
+	AppleTeamID = "STANDINTEAM"
+	AppleKeyID  = "STANDINKEY"
+	// AppleUserToken is the music user token the Apple Music stand-in accepts
+	AppleUserToken = "standin-music-user-token"
+	// appleStorefront is the storefront of the stand-in user
+	appleStorefront = "us"
+)
+
+// AppleMusic is a stand-in for the Apple Music API. It checks the ES256 signature of developer
+// tokens against its own key pair. Catalog songs use the track IDs as catalog IDs and library
+// songs prefix them with "i.".
+type AppleMusic struct {
+	*httptest.Server
+	*library
+
+	key *ecdsa.PrivateKey
+	// Unavailable lists catalog IDs that have no play parameters in the stand-in's storefront
+	Unavailable map[string]bool
+}
+
+// NewAppleMusic starts an Apple Music stand-in seeded with the given catalog and playlists.
+// Nil arguments fall back to the fake adapter's seed data.
+func NewAppleMusic(catalog []playlist.Track, playlists []playlist.Playlist) *AppleMusic {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		panic(fmt.Sprintf("standin: generating key: %v", err))
+	}
+
+	a := &AppleMusic{
+		library:     newLibrary(catalog, playlists),
+		key:         key,
+		Unavailable: make(map[string]bool),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /v1/me/storefront", a.authed(a.storefront))
+	mux.HandleFunc("GET /v1/me/library/playlists", a.authed(a.libraryPlaylists))
+	mux.HandleFunc("POST /v1/me/library/playlists", a.authed(a.createPlaylist))
+	mux.HandleFunc("GET /v1/me/library/playlists/{id}/tracks", a.authed(a.playlistTracks))
+	mux.HandleFunc("POST /v1/me/library/playlists/{id}/tracks", a.authed(a.addTracks))
+	mux.HandleFunc("GET /v1/catalog/{storefront}/search", a.authed(a.searchCatalog))
+	mux.HandleFunc("GET /v1/catalog/{storefront}/songs", a.authed(a.songsByISRC))
+	a.Server = httptest.NewServer(mux)
+	return a
+}
+
+// APIURL returns the API base URL to pass to the adapter
+func (a *AppleMusic) APIURL() string {
+	return a.URL + "/v1/"
+}
+
+// PrivateKeyPEM returns the developer key in the PEM encoded PKCS #8 format of .p8 files
+func (a *AppleMusic) PrivateKeyPEM() []byte {
+	der, err := x509.MarshalPKCS8PrivateKey(a.key)
+	if err != nil {
+		panic(fmt.Sprintf("standin: encoding key: %v", err))
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+}
+
+// PrivateKey returns the developer key
+func (a *AppleMusic) PrivateKey() *ecdsa.PrivateKey {
+	return a.key
+}
+
+// Options returns adapter options pointing the Apple Music adapter at this stand-in, signed in as its user
+func (a *AppleMusic) Options() []adapters.Option {
+	return []adapters.Option{
+		adapters.WithHTTPClient(a.Client()),
+		adapters.WithBaseURL(a.APIURL()),
+	}
+}
+
+// authed rejects requests without a valid developer token and music user token
+func (a *AppleMusic) authed(h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || a.verifyDeveloperToken(token) != nil {
+			appleError(w, http.StatusUnauthorized, "Authentication Failed", "invalid developer token")
+			return
+		}
+		if r.Header.Get("Music-User-Token") != AppleUserToken {
+			appleError(w, http.StatusForbidden, "Forbidden", "invalid music user token")
+			return
+		}
+		if sf := r.PathValue("storefront"); sf != "" && sf != appleStorefront {
+			appleError(w, http.StatusNotFound, "Resource Not Found", "unknown storefront "+sf)
+			return
+		}
+		h(w, r)
+	}
+}
+
+// verifyDeveloperToken checks the header, claims and ES256 signature of a developer token
+func (a *AppleMusic) verifyDeveloperToken(token string) error {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return fmt.Errorf("malformed token")
+	}
+
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	var claims struct {
+		Iss string `json:"iss"`
+		Iat int64  `json:"iat"`
+		Exp int64  `json:"exp"`
+	}
+	if err := decodeJWTPart(parts[0], &header); err != nil {
+		return err
+	}
+	if err := decodeJWTPart(parts[1], &claims); err != nil {
+		return err
+	}
+	if header.Alg != "ES256" || header.Kid != AppleKeyID || claims.Iss != AppleTeamID {
+		return fmt.Errorf("unexpected header or issuer")
+	}
+	if now := time.Now().Unix(); claims.Exp <= now || claims.Iat > now+60 {
+		return fmt.Errorf("token expired or not yet valid")
+	}
+
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil || len(sig) != 64 {
+		return fmt.Errorf("malformed signature")
+	}
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	r, s := new(big.Int).SetBytes(sig[:32]), new(big.Int).SetBytes(sig[32:])
+	if !ecdsa.Verify(&a.key.PublicKey, digest[:], r, s) {
+		return fmt.Errorf("bad signature")
+	}
+	return nil
+}
+
+func decodeJWTPart(part string, v any) error {
+	data, err := base64.RawURLEncoding.DecodeString(part)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+func (a *AppleMusic) storefront(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]any{
+		"data": []map[string]any{{"id": appleStorefront, "type": "storefronts"}},
+	})
+}
+
+func (a *AppleMusic) libraryPlaylists(w http.ResponseWriter, r *http.Request) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	offset, limit := pageParams(r, 25, 100)
+	start, end := pageBounds(offset, limit, len(a.playlists))
+	data := []map[string]any{}
+	for _, p := range a.playlists[start:end] {
+		data = append(data, applePlaylistJSON(p))
+	}
+	writeJSON(w, http.StatusOK, appleList(data, r, end, limit, len(a.playlists)))
+}
+
+func (a *AppleMusic) createPlaylist(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Attributes struct {
+			Name        string `json:"name"`
+			Description string `json:"description"`
+		} `json:"attributes"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil || body.Attributes.Name == "" {
+		appleError(w, http.StatusBadRequest, "Invalid Attribute", "name is required")
+		return
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	p := a.addPlaylist("p.standin", body.Attributes.Name, body.Attributes.Description)
+	writeJSON(w, http.StatusCreated, map[string]any{"data": []map[string]any{applePlaylistJSON(p)}})
+}
+
+func (a *AppleMusic) playlistTracks(w http.ResponseWriter, r *http.Request) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	p := a.findPlaylist(r.PathValue("id"))
+	if p == nil {
+		appleError(w, http.StatusNotFound, "Resource Not Found", "playlist not found")
+		return
+	}
+
+	offset, limit := pageParams(r, 100, 100)
+	start, end := pageBounds(offset, limit, len(p.Tracks))
+	includeCatalog := strings.Contains(r.FormValue("include"), "catalog")
+	data := []map[string]any{}
+	for _, t := range p.Tracks[start:end] {
+		// Library songs carry less metadata than their catalog counterparts
+		song := map[string]any{
+			"id":   "i." + t.ID,
+			"type": "library-songs",
+			"attributes": map[string]any{
+				"name":             t.Name,
+				"artistName":       strings.Join(t.Artists, " & "),
+				"albumName":        t.Album,
+				"durationInMillis": t.DurationMs,
+				"trackNumber":      t.TrackNumber,
+				"discNumber":       t.DiscNumber,
+				"playParams":       map[string]any{"id": "i." + t.ID, "kind": "song", "isLibrary": true, "catalogId": t.ID},
+			},
+		}
+		if includeCatalog {
+			song["relationships"] = map[string]any{
+				"catalog": map[string]any{"data": []map[string]any{a.songJSON(t)}},
+			}
+		}
+		data = append(data, song)
+	}
+	writeJSON(w, http.StatusOK, appleList(data, r, end, limit, len(p.Tracks)))
+}
+
+func (a *AppleMusic) addTracks(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Data []struct {
+			ID   string `json:"id"`
+			Type string `json:"type"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		appleError(w, http.StatusBadRequest, "Invalid Request Body", err.Error())
+		return
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	p := a.findPlaylist(r.PathValue("id"))
+	if p == nil {
+		appleError(w, http.StatusNotFound, "Resource Not Found", "playlist not found")
+		return
+	}
+
+	var tracks []playlist.Track
+	for _, ref := range body.Data {
+		id := ref.ID
+		if ref.Type == "library-songs" {
+			id = strings.TrimPrefix(id, "i.")
+		} else if ref.Type != "songs" {
+			appleError(w, http.StatusBadRequest, "Invalid Relationship", "unsupported type "+ref.Type)
+			return
+		}
+		t, ok := a.findTrack(id)
+		if !ok {
+			appleError(w, http.StatusNotFound, "Resource Not Found", "song "+ref.ID+" not found")
+			return
+		}
+		tracks = append(tracks, t)
+	}
+	p.Tracks = append(p.Tracks, tracks...)
+	p.TrackCount = len(p.Tracks)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (a *AppleMusic) searchCatalog(w http.ResponseWriter, r *http.Request) {
+	if r.FormValue("types") != "songs" {
+		appleError(w, http.StatusBadRequest, "Invalid Parameter Value", "only songs are supported")
+		return
+	}
+	_, limit := pageParams(r, 5, 25)
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	data := []map[string]any{}
+	for _, t := range a.search(adapters.SearchQuery{Track: r.FormValue("term")}) {
+		if len(data) == limit {
+			break
+		}
+		data = append(data, a.songJSON(t))
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"results": map[string]any{"songs": map[string]any{"data": data}},
+	})
+}
+
+func (a *AppleMusic) songsByISRC(w http.ResponseWriter, r *http.Request) {
+	isrc := r.FormValue("filter[isrc]")
+	if isrc == "" {
+		appleError(w, http.StatusBadRequest, "Missing Parameter", "filter[isrc] is required")
+		return
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	data := []map[string]any{}
+	for _, t := range a.search(adapters.SearchQuery{ISRC: isrc}) {
+		data = append(data, a.songJSON(t))
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"data": data})
+}
+
+// songJSON renders a catalog song resource. a.mu must be held.
+func (a *AppleMusic) songJSON(t playlist.Track) map[string]any {
+	attrs := map[string]any{
+		"name":             t.Name,
+		"artistName":       strings.Join(t.Artists, " & "),
+		"albumName":        t.Album,
+		"isrc":             t.ISRC,
+		"durationInMillis": t.DurationMs,
+		"trackNumber":      t.TrackNumber,
+		"discNumber":       t.DiscNumber,
+		"releaseDate":      t.ReleaseDate,
+		"url":              fmt.Sprintf("https://music.apple.com/%s/song/%s", appleStorefront, t.ID),
+	}
+	if t.Explicit {
+		attrs["contentRating"] = "explicit"
+	}
+	if !a.Unavailable[t.ID] {
+		attrs["playParams"] = map[string]string{"id": t.ID, "kind": "song"}
+	}
+	return map[string]any{"id": t.ID, "type": "songs", "attributes": attrs}
+}
+
+// applePlaylistJSON renders a library playlist resource
+func applePlaylistJSON(p playlist.Playlist) map[string]any {
+	return map[string]any{
+		"id":   p.ID,
+		"type": "library-playlists",
+		"attributes": map[string]any{
+			"name":        p.Name,
+			"description": map[string]string{"standard": p.Description},
+			"canEdit":     true,
+			"dateAdded":   p.CreatedAt.UTC().Format(time.RFC3339),
+		},
+	}
+}
+
+// appleList wraps resources in a paginated response. When more follow end, the next link repeats
+// the request's query with the offset of the next page.
+func appleList(data []map[string]any, r *http.Request, end, limit, total int) map[string]any {
+	resp := map[string]any{"data": data, "meta": map[string]int{"total": total}}
+	if end < total {
+		q := r.URL.Query()
+		q.Set("offset", strconv.Itoa(end))
+		q.Set("limit", strconv.Itoa(limit))
+		resp["next"] = r.URL.Path + "?" + q.Encode()
+	}
+	return resp
+}
+
+// appleError writes an error in the Apple Music API's error format
+func appleError(w http.ResponseWriter, status int, title, detail string) {
+	writeJSON(w, status, map[string]any{
+		"errors": []map[string]string{{
+			"status": strconv.Itoa(status),
+			"title":  title,
+			"detail": detail,
+		}},
+	})
+}
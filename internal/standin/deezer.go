@@ -0,0 +1,305 @@
+package standin
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"time"
+
+	"soundporter/internal/adapters"
+	"soundporter/internal/playlist"
+)
+
+// Deezer is a stand-in for the Deezer API and its OAuth endpoints. Like the real API it reports
+// errors as an error object in a 200 response and takes parameters in the query string.
+type Deezer struct {
+	*httptest.Server
+	OAuth *OAuth
+	*library
+
+	// Unavailable lists track IDs that are reported as not readable in the user's country
+	Unavailable map[string]bool
+	// QuotaEvery makes every Nth API request fail with a quota error; 0 disables it
+	QuotaEvery int
+	requests   int
+}
+
+// NewDeezer starts a Deezer stand-in seeded with the given catalog and playlists.
+// Nil arguments fall back to the fake adapter's seed data.
+func NewDeezer(catalog []playlist.Track, playlists []playlist.Playlist) *Deezer {
+	d := &Deezer{
+		OAuth:       newOAuth(),
+		library:     newLibrary(catalog, playlists),
+		Unavailable: make(map[string]bool),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /oauth/auth.php", d.OAuth.authorize)
+	mux.HandleFunc("GET /oauth/access_token.php", d.accessToken)
+	mux.HandleFunc("GET /user/me", d.authed(d.me))
+	mux.HandleFunc("GET /user/me/playlists", d.authed(d.userPlaylists))
+	mux.HandleFunc("POST /user/me/playlists", d.authed(d.createPlaylist))
+	mux.HandleFunc("POST /playlist/{id}", d.authed(d.updatePlaylist))
+	mux.HandleFunc("GET /playlist/{id}/tracks", d.authed(d.playlistTracks))
+	mux.HandleFunc("POST /playlist/{id}/tracks", d.authed(d.addTracks))
+	mux.HandleFunc("GET /track/{id}", d.authed(d.track))
+	mux.HandleFunc("GET /search/track", d.authed(d.searchTracks))
+	d.Server = httptest.NewServer(mux)
+	return d
+}
+
+// APIURL returns the API base URL to pass to the adapter
+func (d *Deezer) APIURL() string {
+	return d.URL + "/"
+}
+
+// Options returns adapter options pointing the Deezer adapter at this stand-in.
+// The adapter still runs the browser authorization flow unless a token is added with adapters.WithToken,
+// and retries requests over the quota after a millisecond rather than Deezer's quota window.
+func (d *Deezer) Options() []adapters.Option {
+	return []adapters.Option{
+		adapters.WithHTTPClient(d.Client()),
+		adapters.WithBaseURL(d.APIURL()),
+		adapters.WithOAuthEndpoints(d.URL+"/oauth/auth.php", d.URL+"/oauth/access_token.php"),
+		adapters.WithRateLimitWait(time.Millisecond),
+	}
+}
+
+// accessToken implements Deezer's token endpoint, which takes the code in the query string
+func (d *Deezer) accessToken(w http.ResponseWriter, r *http.Request) {
+	access, _, ok := d.OAuth.redeem(r.FormValue("code"))
+	if !ok || r.FormValue("app_id") == "" || r.FormValue("secret") == "" {
+		// The real endpoint answers a bad code with a plain text body
+		http.Error(w, "wrong code", http.StatusOK)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"access_token": access, "expires": 0})
+}
+
+// authed rejects requests without a valid access_token parameter and injects quota errors
+func (d *Deezer) authed(h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !d.OAuth.valid(r.FormValue("access_token")) {
+			deezerError(w, "OAuthException", "Invalid OAuth access token.", 300)
+			return
+		}
+
+		d.mu.Lock()
+		d.requests++
+		quota := d.QuotaEvery > 0 && d.requests%d.QuotaEvery == 0
+		d.mu.Unlock()
+		if quota {
+			deezerError(w, "Exception", "Quota limit exceeded", 4)
+			return
+		}
+		h(w, r)
+	}
+}
+
+func (d *Deezer) me(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]any{"id": 1000, "name": "standin-user", "country": "US"})
+}
+
+func (d *Deezer) userPlaylists(w http.ResponseWriter, r *http.Request) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	data := []map[string]any{}
+	start, end := deezerPage(r, len(d.playlists))
+	for _, p := range d.playlists[start:end] {
+		data = append(data, map[string]any{
+			"id":            p.ID,
+			"title":         p.Name,
+			"description":   p.Description,
+			"nb_tracks":     len(p.Tracks),
+			"creation_date": p.CreatedAt.UTC().Format("2006-01-02 15:04:05"),
+			"type":          "playlist",
+		})
+	}
+	writeJSON(w, http.StatusOK, deezerList(r, data, end, len(d.playlists)))
+}
+
+func (d *Deezer) createPlaylist(w http.ResponseWriter, r *http.Request) {
+	title := r.FormValue("title")
+	if title == "" {
+		deezerError(w, "ParameterException", "Wrong parameter: title", 500)
+		return
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	p := d.addPlaylist("", title, "")
+	writeJSON(w, http.StatusOK, map[string]any{"id": p.ID})
+}
+
+func (d *Deezer) updatePlaylist(w http.ResponseWriter, r *http.Request) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	p := d.findPlaylist(r.PathValue("id"))
+	if p == nil {
+		deezerError(w, "DataException", "no data", 800)
+		return
+	}
+	if r.Form.Has("title") {
+		p.Name = r.FormValue("title")
+	}
+	if r.Form.Has("description") {
+		p.Description = r.FormValue("description")
+	}
+	writeJSON(w, http.StatusOK, true)
+}
+
+func (d *Deezer) playlistTracks(w http.ResponseWriter, r *http.Request) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	p := d.findPlaylist(r.PathValue("id"))
+	if p == nil {
+		deezerError(w, "DataException", "no data", 800)
+		return
+	}
+
+	data := []map[string]any{}
+	start, end := deezerPage(r, len(p.Tracks))
+	for _, t := range p.Tracks[start:end] {
+		// Listings leave out the ISRC and contributors, which only track details have
+		data = append(data, d.trackJSON(t, false))
+	}
+	writeJSON(w, http.StatusOK, deezerList(r, data, end, len(p.Tracks)))
+}
+
+func (d *Deezer) addTracks(w http.ResponseWriter, r *http.Request) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	p := d.findPlaylist(r.PathValue("id"))
+	if p == nil {
+		deezerError(w, "DataException", "no data", 800)
+		return
+	}
+
+	var tracks []playlist.Track
+	for _, id := range strings.Split(r.FormValue("songs"), ",") {
+		t, ok := d.findTrack(id)
+		if !ok {
+			deezerError(w, "DataException", "no data", 800)
+			return
+		}
+		tracks = append(tracks, t)
+	}
+	p.Tracks = append(p.Tracks, tracks...)
+	p.TrackCount = len(p.Tracks)
+	writeJSON(w, http.StatusOK, true)
+}
+
+func (d *Deezer) track(w http.ResponseWriter, r *http.Request) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	id := r.PathValue("id")
+	var t playlist.Track
+	var ok bool
+	if isrc, isISRC := strings.CutPrefix(id, "isrc:"); isISRC {
+		if matches := d.search(adapters.SearchQuery{ISRC: isrc}); len(matches) > 0 {
+			t, ok = matches[0], true
+		}
+	} else {
+		t, ok = d.findTrack(id)
+	}
+	if !ok {
+		deezerError(w, "DataException", "no data", 800)
+		return
+	}
+	writeJSON(w, http.StatusOK, d.trackJSON(t, true))
+}
+
+func (d *Deezer) searchTracks(w http.ResponseWriter, r *http.Request) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	matches := d.search(adapters.ParseSearchQuery(r.FormValue("q")))
+	data := []map[string]any{}
+	start, end := deezerPage(r, len(matches))
+	for _, t := range matches[start:end] {
+		data = append(data, d.trackJSON(t, false))
+	}
+	writeJSON(w, http.StatusOK, deezerList(r, data, end, len(matches)))
+}
+
+// trackJSON renders a track; details include the ISRC and contributors. d.mu must be held.
+func (d *Deezer) trackJSON(t playlist.Track, details bool) map[string]any {
+	artist := map[string]any{}
+	if len(t.Artists) > 0 {
+		artist["name"] = t.Artists[0]
+	}
+	if len(t.ArtistIDs) > 0 {
+		artist["id"] = t.ArtistIDs[0]
+	}
+
+	title := t.Name
+	if t.Version != "" {
+		title += " (" + t.Version + ")"
+	}
+	track := map[string]any{
+		"id":              t.ID,
+		"readable":        !d.Unavailable[t.ID],
+		"title":           title,
+		"title_short":     t.Name,
+		"title_version":   t.Version,
+		"link":            "https://www.deezer.com/track/" + t.ID,
+		"duration":        t.DurationMs / 1000,
+		"rank":            t.Popularity * 10000,
+		"explicit_lyrics": t.Explicit,
+		"artist":          artist,
+		"album":           map[string]any{"id": t.AlbumID, "title": t.Album, "release_date": t.ReleaseDate},
+		"type":            "track",
+	}
+	if details {
+		contributors := []map[string]any{}
+		for i, name := range t.Artists {
+			c := map[string]any{"name": name, "role": "Main"}
+			if i < len(t.ArtistIDs) {
+				c["id"] = t.ArtistIDs[i]
+			}
+			contributors = append(contributors, c)
+		}
+		track["isrc"] = t.ISRC
+		track["contributors"] = contributors
+		track["track_position"] = t.TrackNumber
+		track["disk_number"] = t.DiscNumber
+		track["release_date"] = t.ReleaseDate
+	}
+	return track
+}
+
+// deezerPage reads the index and limit parameters
+func deezerPage(r *http.Request, n int) (start, end int) {
+	index, _ := strconv.Atoi(r.FormValue("index"))
+	limit, err := strconv.Atoi(r.FormValue("limit"))
+	if err != nil || limit <= 0 {
+		limit = 25
+	}
+	return pageBounds(index, min(limit, 100), n)
+}
+
+// deezerList wraps items in a list response with an absolute next link when more follow end
+func deezerList(r *http.Request, data []map[string]any, end, total int) map[string]any {
+	resp := map[string]any{"data": data, "total": total}
+	if end < total {
+		q := r.URL.Query()
+		q.Del("access_token")
+		q.Set("index", strconv.Itoa(end))
+		resp["next"] = fmt.Sprintf("http://%s%s?%s", r.Host, r.URL.Path, q.Encode())
+	}
+	return resp
+}
+
+// deezerError writes an error the way Deezer does, in the body of a 200 response
+func deezerError(w http.ResponseWriter, kind, message string, code int) {
+	writeJSON(w, http.StatusOK, map[string]any{
+		"error": map[string]any{"type": kind, "message": message, "code": code},
+	})
+}
@@ -0,0 +1,304 @@
+package standin
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+
+	"soundporter/internal/adapters"
+	"soundporter/internal/playlist"
+)
+
+const (
+	// JellyfinUser and JellyfinPassword are the credentials of the Jellyfin stand-in's user
+	JellyfinUser     = "standin"
+	JellyfinPassword = "standin-password"
+	// JellyfinAPIKey is the API key the Jellyfin stand-in accepts
+	JellyfinAPIKey = "standin-api-key"
+	// jellyfinUserID is the ID of the stand-in user
+	jellyfinUserID = "0f8e4c1b2a3d4e5f8a9b0c1d2e3f4a5b"
+)
+
+// mediaBrowserTokenRe finds the token in a MediaBrowser authorization header
+var mediaBrowserTokenRe = regexp.MustCompile(`Token="([^"]*)"`)
+
+// Jellyfin is a stand-in for a Jellyfin server, or an Emby server if Emby is set. Like the
+// real servers it matches SearchTerm against item names, and Artists and Albums exactly.
+type Jellyfin struct {
+	*httptest.Server
+	*library
+
+	// Emby makes the server read the credentials from the X-Emby-Authorization header, like
+	// Emby, instead of the Authorization header
+	Emby bool
+
+	sessionsMu sync.Mutex
+	sessions   map[string]bool
+}
+
+// NewJellyfin starts a Jellyfin stand-in seeded with the given catalog and playlists.
+// Nil arguments fall back to the fake adapter's seed data.
+func NewJellyfin(catalog []playlist.Track, playlists []playlist.Playlist) *Jellyfin {
+	j := &Jellyfin{
+		library:  newLibrary(catalog, playlists),
+		sessions: make(map[string]bool),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /Users/AuthenticateByName", j.authenticateByName)
+	mux.HandleFunc("GET /Users", j.authed(j.users))
+	mux.HandleFunc("GET /Users/{user}/Items", j.authed(j.items))
+	mux.HandleFunc("POST /Playlists", j.authed(j.createPlaylist))
+	mux.HandleFunc("GET /Playlists/{id}/Items", j.authed(j.playlistItems))
+	mux.HandleFunc("POST /Playlists/{id}/Items", j.authed(j.addItems))
+	j.Server = httptest.NewServer(mux)
+	return j
+}
+
+// Options returns adapter options for the Jellyfin adapter. The server URL, j.URL, is passed
+// as a credential like for a real server.
+func (j *Jellyfin) Options() []adapters.Option {
+	return []adapters.Option{adapters.WithHTTPClient(j.Client())}
+}
+
+// authorization returns the MediaBrowser authorization header the server reads
+func (j *Jellyfin) authorization(r *http.Request) string {
+	if j.Emby {
+		return r.Header.Get("X-Emby-Authorization")
+	}
+	return r.Header.Get("Authorization")
+}
+
+// authenticateByName starts a session for the user. Like Jellyfin it requires the client to
+// describe itself.
+func (j *Jellyfin) authenticateByName(w http.ResponseWriter, r *http.Request) {
+	auth := j.authorization(r)
+	for _, field := range []string{"Client", "Device", "DeviceId", "Version"} {
+		if !strings.Contains(auth, field+`="`) {
+			http.Error(w, "Authorization header is missing "+field, http.StatusBadRequest)
+			return
+		}
+	}
+
+	var body struct {
+		Username string `json:"Username"`
+		Pw       string `json:"Pw"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil || body.Username != JellyfinUser || body.Pw != JellyfinPassword {
+		jellyfinError(w, http.StatusUnauthorized, "Invalid username or password entered.")
+		return
+	}
+
+	j.sessionsMu.Lock()
+	token := fmt.Sprintf("session-%d", len(j.sessions)+1)
+	j.sessions[token] = true
+	j.sessionsMu.Unlock()
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"AccessToken": token,
+		"ServerId":    "standin-server",
+		"User":        jellyfinUserJSON(),
+	})
+}
+
+// authed rejects requests without the API key or a session token
+func (j *Jellyfin) authed(h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var token string
+		if m := mediaBrowserTokenRe.FindStringSubmatch(j.authorization(r)); m != nil {
+			token = m[1]
+		}
+
+		j.sessionsMu.Lock()
+		ok := token == JellyfinAPIKey || j.sessions[token]
+		j.sessionsMu.Unlock()
+		if !ok {
+			// Both servers answer unauthorized requests without a body
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		h(w, r)
+	}
+}
+
+func (j *Jellyfin) users(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, []map[string]any{
+		{"Id": "1a2b3c4d5e6f7a8b9c0d1e2f3a4b5c6d", "Name": "admin"},
+		jellyfinUserJSON(),
+	})
+}
+
+// items queries the user's library for playlists or audio items
+func (j *Jellyfin) items(w http.ResponseWriter, r *http.Request) {
+	if r.PathValue("user") != jellyfinUserID {
+		jellyfinError(w, http.StatusNotFound, "User not found")
+		return
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	var items []map[string]any
+	switch r.FormValue("IncludeItemTypes") {
+	case "Playlist":
+		for _, p := range j.playlists {
+			items = append(items, jellyfinPlaylistJSON(p))
+		}
+	case "Audio":
+		term := strings.ToLower(r.FormValue("SearchTerm"))
+		for _, t := range j.catalog {
+			if term != "" && !strings.Contains(strings.ToLower(t.Name), term) {
+				continue
+			}
+			if !jellyfinAnyEqual(r.FormValue("Artists"), t.Artists) || !jellyfinAnyEqual(r.FormValue("Albums"), []string{t.Album}) {
+				continue
+			}
+			items = append(items, jellyfinAudioJSON(t))
+		}
+	default:
+		jellyfinError(w, http.StatusBadRequest, "The stand-in only serves playlists and audio items")
+		return
+	}
+	writeJSON(w, http.StatusOK, jellyfinPage(r, items))
+}
+
+// jellyfinAnyEqual reports whether one of the pipe separated names in filter equals one of
+// values, ignoring case. An empty filter matches everything.
+func jellyfinAnyEqual(filter string, values []string) bool {
+	if filter == "" {
+		return true
+	}
+	for _, name := range strings.Split(filter, "|") {
+		for _, v := range values {
+			if strings.EqualFold(name, v) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (j *Jellyfin) createPlaylist(w http.ResponseWriter, r *http.Request) {
+	name := r.FormValue("Name")
+	if name == "" || r.FormValue("UserId") != jellyfinUserID {
+		jellyfinError(w, http.StatusBadRequest, "Name and UserId are required")
+		return
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	p := j.addPlaylist("standin", name, "")
+	writeJSON(w, http.StatusOK, map[string]any{"Id": p.ID})
+}
+
+func (j *Jellyfin) playlistItems(w http.ResponseWriter, r *http.Request) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	p := j.findPlaylist(r.PathValue("id"))
+	if p == nil {
+		jellyfinError(w, http.StatusNotFound, "Playlist not found")
+		return
+	}
+
+	items := []map[string]any{}
+	for i, t := range p.Tracks {
+		item := jellyfinAudioJSON(t)
+		item["PlaylistItemId"] = strconv.Itoa(i + 1)
+		items = append(items, item)
+	}
+	writeJSON(w, http.StatusOK, jellyfinPage(r, items))
+}
+
+// addItems appends items to a playlist. Like Jellyfin, IDs that aren't audio items are skipped.
+func (j *Jellyfin) addItems(w http.ResponseWriter, r *http.Request) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	p := j.findPlaylist(r.PathValue("id"))
+	if p == nil {
+		jellyfinError(w, http.StatusNotFound, "Playlist not found")
+		return
+	}
+	for _, id := range strings.Split(r.FormValue("Ids"), ",") {
+		if t, ok := j.findTrack(id); ok {
+			p.Tracks = append(p.Tracks, t)
+		}
+	}
+	p.TrackCount = len(p.Tracks)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func jellyfinUserJSON() map[string]any {
+	return map[string]any{"Id": jellyfinUserID, "Name": JellyfinUser}
+}
+
+func jellyfinPlaylistJSON(p playlist.Playlist) map[string]any {
+	return map[string]any{
+		"Id":          p.ID,
+		"Name":        p.Name,
+		"Overview":    p.Description,
+		"Type":        "Playlist",
+		"MediaType":   "Audio",
+		"ChildCount":  len(p.Tracks),
+		"DateCreated": p.CreatedAt.UTC().Format("2006-01-02T15:04:05.0000000Z"),
+	}
+}
+
+func jellyfinAudioJSON(t playlist.Track) map[string]any {
+	artists := []map[string]any{}
+	for i, name := range t.Artists {
+		a := map[string]any{"Name": name}
+		if i < len(t.ArtistIDs) {
+			a["Id"] = t.ArtistIDs[i]
+		}
+		artists = append(artists, a)
+	}
+
+	item := map[string]any{
+		"Id":                t.ID,
+		"Name":              t.Name,
+		"Type":              "Audio",
+		"MediaType":         "Audio",
+		"Album":             t.Album,
+		"AlbumId":           t.AlbumID,
+		"Artists":           t.Artists,
+		"ArtistItems":       artists,
+		"RunTimeTicks":      int64(t.DurationMs) * 10000,
+		"IndexNumber":       t.TrackNumber,
+		"ParentIndexNumber": t.DiscNumber,
+	}
+	if len(t.Artists) > 0 {
+		item["AlbumArtist"] = t.Artists[0]
+	}
+	if year, _, _, ok := splitDate(t.ReleaseDate); ok {
+		item["ProductionYear"] = year
+		item["PremiereDate"] = t.ReleaseDate + "T00:00:00.0000000Z"
+	}
+	return item
+}
+
+// jellyfinPage slices items by the StartIndex and Limit parameters into a query result
+func jellyfinPage(r *http.Request, items []map[string]any) map[string]any {
+	start, _ := strconv.Atoi(r.FormValue("StartIndex"))
+	limit, err := strconv.Atoi(r.FormValue("Limit"))
+	if err != nil || limit <= 0 {
+		limit = len(items)
+	}
+	from, to := pageBounds(start, limit, len(items))
+	page := items[from:to]
+	if page == nil {
+		page = []map[string]any{}
+	}
+	return map[string]any{"Items": page, "TotalRecordCount": len(items), "StartIndex": from}
+}
+
+// jellyfinError writes an error as RFC 7807 problem details, like Jellyfin
+func jellyfinError(w http.ResponseWriter, status int, detail string) {
+	writeJSON(w, status, map[string]any{"title": http.StatusText(status), "status": status, "detail": detail})
+}
@@ -0,0 +1,560 @@
+package standin
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/oauth2"
+
+	"soundporter/internal/adapters"
+	"soundporter/internal/playlist"
+)
+
+const (
+	// LastfmUser is the user of the Last.fm stand-in
+	LastfmUser = "standin"
+	// LastfmAPIKey and LastfmSecret are the API credentials the Last.fm stand-in accepts
+	LastfmAPIKey = "standin-api-key"
+	LastfmSecret = "standin-secret"
+)
+
+// Last.fm error codes
+const (
+	lastfmInvalidParameters = 6
+	lastfmInvalidSession    = 9
+	lastfmInvalidAPIKey     = 10
+	lastfmInvalidSignature  = 13
+	lastfmUnauthorizedToken = 14
+)
+
+// Scrobble is a play of a track at a time
+type Scrobble struct {
+	Track playlist.Track
+	At    time.Time
+}
+
+// Lastfm is a stand-in for the Last.fm API and its web authentication page, which approves
+// logins immediately. Signed calls are checked against LastfmSecret.
+type Lastfm struct {
+	*httptest.Server
+	*library
+
+	// Loved are the user's loved tracks, most recently loved first
+	Loved []playlist.Track
+	// Scrobbles are the user's plays, most recent first
+	Scrobbles []Scrobble
+	// NowPlaying, if set, is listed at the top of the recent tracks without being scrobbled
+	NowPlaying *playlist.Track
+
+	authMu   sync.Mutex
+	tokens   map[string]bool
+	sessions map[string]bool
+}
+
+// NewLastfm starts a Last.fm stand-in seeded with the given catalog, which track searches look
+// through. Nil falls back to the fake adapter's seed data. The first three catalog tracks are
+// loved, and the catalog is scrobbled over the last 60 days, with earlier tracks played more
+// often, plus a few plays a year ago.
+func NewLastfm(catalog []playlist.Track) *Lastfm {
+	l := &Lastfm{
+		library:  newLibrary(catalog, []playlist.Playlist{}),
+		tokens:   make(map[string]bool),
+		sessions: make(map[string]bool),
+	}
+	l.Loved = slices.Clone(l.catalog[:min(3, len(l.catalog))])
+
+	now := time.Now()
+	for i, t := range l.catalog {
+		for play := range max(1, 5-i/2) {
+			l.Scrobbles = append(l.Scrobbles, Scrobble{Track: t, At: now.Add(-time.Duration(i*3*24+play)*time.Hour - time.Minute)})
+		}
+	}
+	for _, t := range l.catalog[:min(2, len(l.catalog))] {
+		l.Scrobbles = append(l.Scrobbles, Scrobble{Track: t, At: now.AddDate(-1, 0, 0)})
+	}
+	sort.SliceStable(l.Scrobbles, func(i, j int) bool { return l.Scrobbles[i].At.After(l.Scrobbles[j].At) })
+
+	methods := map[string]http.HandlerFunc{
+		"auth.getSession":          l.getSession,
+		"user.getInfo":             l.getInfo,
+		"user.getLovedTracks":      l.lovedTracks,
+		"user.getTopTracks":        l.topTracks,
+		"user.getWeeklyTrackChart": l.weeklyTrackChart,
+		"user.getRecentTracks":     l.recentTracks,
+		"track.search":             l.searchTracks,
+		"track.love":               l.love,
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/auth/", l.authorize)
+	mux.HandleFunc("/2.0/", func(w http.ResponseWriter, r *http.Request) {
+		if r.FormValue("format") != "json" {
+			http.Error(w, "the stand-in only speaks JSON", http.StatusBadRequest)
+			return
+		}
+		if r.FormValue("api_key") != LastfmAPIKey {
+			lastfmError(w, http.StatusForbidden, lastfmInvalidAPIKey, "Invalid API key - You must be granted a valid key by last.fm")
+			return
+		}
+		h, ok := methods[r.FormValue("method")]
+		if !ok {
+			lastfmError(w, http.StatusBadRequest, 3, "Invalid Method - No method with that name in this package")
+			return
+		}
+		h(w, r)
+	})
+	l.Server = httptest.NewServer(mux)
+	return l
+}
+
+// APIURL returns the API base URL to pass to the adapter
+func (l *Lastfm) APIURL() string {
+	return l.URL + "/2.0/"
+}
+
+// Options returns adapter options pointing the Last.fm adapter at this stand-in. The adapter
+// still runs the browser login when given the secret, unless a session is added with
+// adapters.WithToken(l.Token()).
+func (l *Lastfm) Options() []adapters.Option {
+	return []adapters.Option{
+		adapters.WithHTTPClient(l.Client()),
+		adapters.WithBaseURL(l.APIURL()),
+		adapters.WithOAuthEndpoints(l.URL+"/api/auth/", ""),
+	}
+}
+
+// Token starts a session for LastfmUser and returns its key as the access token
+func (l *Lastfm) Token() *oauth2.Token {
+	l.authMu.Lock()
+	defer l.authMu.Unlock()
+	key := fmt.Sprintf("session-%d", len(l.sessions)+1)
+	l.sessions[key] = true
+	return &oauth2.Token{AccessToken: key}
+}
+
+// authorize approves the login immediately and redirects to the callback with a token
+func (l *Lastfm) authorize(w http.ResponseWriter, r *http.Request) {
+	cb, err := url.Parse(r.FormValue("cb"))
+	if err != nil || r.FormValue("cb") == "" || r.FormValue("api_key") != LastfmAPIKey {
+		http.Error(w, "invalid api_key or cb", http.StatusBadRequest)
+		return
+	}
+
+	l.authMu.Lock()
+	token := fmt.Sprintf("token-%d", len(l.tokens)+1)
+	l.tokens[token] = true
+	l.authMu.Unlock()
+
+	q := cb.Query()
+	q.Set("token", token)
+	cb.RawQuery = q.Encode()
+	http.Redirect(w, r, cb.String(), http.StatusFound)
+}
+
+// signed checks the signature of a call, and the session key if requireSession is set
+func (l *Lastfm) signed(w http.ResponseWriter, r *http.Request, requireSession bool) bool {
+	r.ParseForm()
+	var keys []string
+	for k := range r.Form {
+		if k != "format" && k != "api_sig" && k != "callback" {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	var b strings.Builder
+	for _, k := range keys {
+		b.WriteString(k + r.Form.Get(k))
+	}
+	sum := md5.Sum([]byte(b.String() + LastfmSecret))
+	if r.Form.Get("api_sig") != hex.EncodeToString(sum[:]) {
+		lastfmError(w, http.StatusForbidden, lastfmInvalidSignature, "Invalid method signature supplied")
+		return false
+	}
+
+	if !requireSession && !r.Form.Has("sk") {
+		return true
+	}
+	l.authMu.Lock()
+	ok := l.sessions[r.Form.Get("sk")]
+	l.authMu.Unlock()
+	if !ok {
+		lastfmError(w, http.StatusForbidden, lastfmInvalidSession, "Invalid session key - Please re-authenticate")
+	}
+	return ok
+}
+
+func (l *Lastfm) getSession(w http.ResponseWriter, r *http.Request) {
+	if !l.signed(w, r, false) {
+		return
+	}
+
+	l.authMu.Lock()
+	ok := l.tokens[r.FormValue("token")]
+	delete(l.tokens, r.FormValue("token"))
+	l.authMu.Unlock()
+	if !ok {
+		lastfmError(w, http.StatusForbidden, lastfmUnauthorizedToken, "Unauthorized Token - This token has not been authorized")
+		return
+	}
+
+	token := l.Token()
+	writeJSON(w, http.StatusOK, map[string]any{"session": map[string]any{
+		"name":       LastfmUser,
+		"key":        token.AccessToken,
+		"subscriber": 0,
+	}})
+}
+
+// getInfo describes the user given, or the user of the session in a signed call
+func (l *Lastfm) getInfo(w http.ResponseWriter, r *http.Request) {
+	if r.FormValue("user") == "" && !l.signed(w, r, true) {
+		return
+	}
+	if !l.knownUser(w, r) {
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	writeJSON(w, http.StatusOK, map[string]any{"user": map[string]any{
+		"name":      LastfmUser,
+		"realname":  "",
+		"url":       "https://www.last.fm/user/" + LastfmUser,
+		"playcount": strconv.Itoa(len(l.Scrobbles)),
+	}})
+}
+
+// knownUser fails the call if it names a user other than LastfmUser
+func (l *Lastfm) knownUser(w http.ResponseWriter, r *http.Request) bool {
+	if user := r.FormValue("user"); user != "" && !strings.EqualFold(user, LastfmUser) {
+		lastfmError(w, http.StatusNotFound, lastfmInvalidParameters, "User not found")
+		return false
+	}
+	return true
+}
+
+func (l *Lastfm) lovedTracks(w http.ResponseWriter, r *http.Request) {
+	if !l.knownUser(w, r) {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	page, start, end := lastfmPage(r, len(l.Loved))
+	var tracks []map[string]any
+	for _, t := range l.Loved[start:end] {
+		tracks = append(tracks, lastfmTrackJSON(t, false))
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"lovedtracks": map[string]any{
+		"track": lastfmList(tracks),
+		"@attr": page,
+	}})
+}
+
+// topTracks ranks the tracks scrobbled in one of Last.fm's periods by play count
+func (l *Lastfm) topTracks(w http.ResponseWriter, r *http.Request) {
+	if !l.knownUser(w, r) {
+		return
+	}
+	periods := map[string]time.Duration{
+		"7day":    7 * 24 * time.Hour,
+		"1month":  30 * 24 * time.Hour,
+		"3month":  90 * 24 * time.Hour,
+		"6month":  180 * 24 * time.Hour,
+		"12month": 365 * 24 * time.Hour,
+		"overall": 0,
+	}
+	period := r.FormValue("period")
+	if period == "" {
+		period = "overall"
+	}
+	span, ok := periods[period]
+	if !ok {
+		lastfmError(w, http.StatusBadRequest, lastfmInvalidParameters, "Invalid period")
+		return
+	}
+	from := time.Time{}
+	if span > 0 {
+		from = time.Now().Add(-span)
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	ranked := l.rank(from, time.Now())
+	page, start, end := lastfmPage(r, len(ranked))
+	var tracks []map[string]any
+	for _, rt := range ranked[start:end] {
+		track := lastfmTrackJSON(rt.track, true)
+		track["playcount"] = strconv.Itoa(rt.plays)
+		tracks = append(tracks, track)
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"toptracks": map[string]any{
+		"track": lastfmList(tracks),
+		"@attr": page,
+	}})
+}
+
+// weeklyTrackChart ranks every track scrobbled in the range by play count. Like Last.fm the
+// chart isn't paged.
+func (l *Lastfm) weeklyTrackChart(w http.ResponseWriter, r *http.Request) {
+	if !l.knownUser(w, r) {
+		return
+	}
+	from, to, ok := lastfmRange(w, r)
+	if !ok {
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	var tracks []map[string]any
+	for i, rt := range l.rank(from, to) {
+		tracks = append(tracks, map[string]any{
+			"name":      rt.track.Name,
+			"artist":    map[string]any{"#text": strings.Join(rt.track.Artists, ", "), "mbid": ""},
+			"url":       lastfmURL(rt.track),
+			"playcount": strconv.Itoa(rt.plays),
+			"@attr":     map[string]any{"rank": strconv.Itoa(i + 1)},
+		})
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"weeklytrackchart": map[string]any{
+		"track": lastfmList(tracks),
+		"@attr": map[string]any{"user": LastfmUser, "from": r.FormValue("from"), "to": r.FormValue("to")},
+	}})
+}
+
+// rankedTrack is a track with its number of plays
+type rankedTrack struct {
+	track playlist.Track
+	plays int
+}
+
+// rank counts the plays of each track between from and to, most played first. l.mu must be held.
+func (l *Lastfm) rank(from, to time.Time) []rankedTrack {
+	var ranked []rankedTrack
+	index := make(map[string]int)
+	for _, s := range l.Scrobbles {
+		if s.At.Before(from) || s.At.After(to) {
+			continue
+		}
+		i, ok := index[s.Track.ID]
+		if !ok {
+			i = len(ranked)
+			index[s.Track.ID] = i
+			ranked = append(ranked, rankedTrack{track: s.Track})
+		}
+		ranked[i].plays++
+	}
+	sort.SliceStable(ranked, func(i, j int) bool { return ranked[i].plays > ranked[j].plays })
+	return ranked
+}
+
+// recentTracks lists the scrobbles in the range, most recent first, after the track playing now
+func (l *Lastfm) recentTracks(w http.ResponseWriter, r *http.Request) {
+	if !l.knownUser(w, r) {
+		return
+	}
+	from, to, ok := lastfmRange(w, r)
+	if !ok {
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	var plays []Scrobble
+	for _, s := range l.Scrobbles {
+		if !s.At.Before(from) && !s.At.After(to) {
+			plays = append(plays, s)
+		}
+	}
+
+	page, start, end := lastfmPage(r, len(plays))
+	var tracks []map[string]any
+	if l.NowPlaying != nil && start == 0 {
+		track := lastfmRecentJSON(*l.NowPlaying)
+		track["@attr"] = map[string]any{"nowplaying": "true"}
+		tracks = append(tracks, track)
+	}
+	for _, s := range plays[start:end] {
+		track := lastfmRecentJSON(s.Track)
+		track["date"] = map[string]any{"uts": strconv.FormatInt(s.At.Unix(), 10), "#text": s.At.UTC().Format("02 Jan 2006, 15:04")}
+		tracks = append(tracks, track)
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"recenttracks": map[string]any{
+		"track": lastfmList(tracks),
+		"@attr": page,
+	}})
+}
+
+// searchTracks matches the track and artist parameters against the catalog
+func (l *Lastfm) searchTracks(w http.ResponseWriter, r *http.Request) {
+	if r.FormValue("track") == "" {
+		lastfmError(w, http.StatusBadRequest, lastfmInvalidParameters, "You must supply a track name")
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	matches := l.search(adapters.SearchQuery{Track: r.FormValue("track"), Artist: r.FormValue("artist")})
+	limit, err := strconv.Atoi(r.FormValue("limit"))
+	if err != nil || limit <= 0 {
+		limit = 30
+	}
+	var tracks []map[string]any
+	for _, t := range matches[:min(limit, len(matches))] {
+		tracks = append(tracks, map[string]any{
+			"name":      t.Name,
+			"artist":    strings.Join(t.Artists, ", "),
+			"url":       lastfmURL(t),
+			"listeners": strconv.Itoa(t.Popularity * 1000),
+			"mbid":      "",
+		})
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"results": map[string]any{
+		"opensearch:totalResults": strconv.Itoa(len(matches)),
+		"trackmatches":            map[string]any{"track": lastfmList(tracks)},
+	}})
+}
+
+// love loves a track. Like Last.fm any artist and title are accepted, in the catalog or not.
+func (l *Lastfm) love(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		lastfmError(w, http.StatusMethodNotAllowed, lastfmInvalidParameters, "This method must be called with POST")
+		return
+	}
+	if !l.signed(w, r, true) {
+		return
+	}
+	artist, name := r.FormValue("artist"), r.FormValue("track")
+	if artist == "" || name == "" {
+		lastfmError(w, http.StatusBadRequest, lastfmInvalidParameters, "Invalid parameters - artist and track are required")
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, t := range l.Loved {
+		if strings.EqualFold(t.Name, name) && strings.EqualFold(strings.Join(t.Artists, ", "), artist) {
+			writeJSON(w, http.StatusOK, map[string]any{})
+			return
+		}
+	}
+	track := playlist.Track{Name: name, Artists: []string{artist}}
+	for _, t := range l.catalog {
+		if strings.EqualFold(t.Name, name) && strings.EqualFold(strings.Join(t.Artists, ", "), artist) {
+			track = t
+			break
+		}
+	}
+	l.Loved = append([]playlist.Track{track}, l.Loved...)
+	writeJSON(w, http.StatusOK, map[string]any{})
+}
+
+// LovedTracks returns a copy of the loved tracks
+func (l *Lastfm) LovedTracks() []playlist.Track {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return slices.Clone(l.Loved)
+}
+
+func lastfmURL(t playlist.Track) string {
+	return "https://www.last.fm/music/" + url.QueryEscape(strings.Join(t.Artists, ", ")) + "/_/" + url.QueryEscape(t.Name)
+}
+
+// lastfmTrackJSON renders a track as loved and top tracks list it, with an artist object
+func lastfmTrackJSON(t playlist.Track, withDuration bool) map[string]any {
+	track := map[string]any{
+		"name": t.Name,
+		"url":  lastfmURL(t),
+		"mbid": "",
+		"artist": map[string]any{
+			"name": strings.Join(t.Artists, ", "),
+			"mbid": "",
+			"url":  "https://www.last.fm/music/" + url.QueryEscape(strings.Join(t.Artists, ", ")),
+		},
+	}
+	if withDuration {
+		track["duration"] = strconv.Itoa(t.DurationMs / 1000)
+	}
+	return track
+}
+
+// lastfmRecentJSON renders a track as recent tracks list it, with #text names
+func lastfmRecentJSON(t playlist.Track) map[string]any {
+	return map[string]any{
+		"name":       t.Name,
+		"url":        lastfmURL(t),
+		"mbid":       "",
+		"streamable": "0",
+		"artist":     map[string]any{"#text": strings.Join(t.Artists, ", "), "mbid": ""},
+		"album":      map[string]any{"#text": t.Album, "mbid": ""},
+	}
+}
+
+// lastfmList renders a list the way Last.fm does: a single item as the item itself
+func lastfmList(items []map[string]any) any {
+	if len(items) == 1 {
+		return items[0]
+	}
+	if items == nil {
+		return []map[string]any{}
+	}
+	return items
+}
+
+// lastfmPage reads the page and limit parameters, returning the @attr of the page and its bounds
+func lastfmPage(r *http.Request, n int) (attr map[string]any, start, end int) {
+	page, err := strconv.Atoi(r.FormValue("page"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+	limit, err := strconv.Atoi(r.FormValue("limit"))
+	if err != nil || limit <= 0 {
+		limit = 50
+	}
+	limit = min(limit, 1000)
+	start, end = pageBounds((page-1)*limit, limit, n)
+	attr = map[string]any{
+		"user":       LastfmUser,
+		"page":       strconv.Itoa(page),
+		"perPage":    strconv.Itoa(limit),
+		"totalPages": strconv.Itoa(max(1, (n+limit-1)/limit)),
+		"total":      strconv.Itoa(n),
+	}
+	return attr, start, end
+}
+
+// lastfmRange reads the from and to parameters, which are Unix timestamps
+func lastfmRange(w http.ResponseWriter, r *http.Request) (from, to time.Time, ok bool) {
+	from, to = time.Time{}, time.Now()
+	for _, p := range []struct {
+		name string
+		t    *time.Time
+	}{{"from", &from}, {"to", &to}} {
+		value := r.FormValue(p.name)
+		if value == "" {
+			continue
+		}
+		seconds, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			lastfmError(w, http.StatusBadRequest, lastfmInvalidParameters, "Invalid "+p.name+" timestamp")
+			return time.Time{}, time.Time{}, false
+		}
+		*p.t = time.Unix(seconds, 0)
+	}
+	return from, to, true
+}
+
+// lastfmError writes an error in Last.fm's format
+func lastfmError(w http.ResponseWriter, status, code int, message string) {
+	writeJSON(w, status, map[string]any{"error": code, "message": message})
+}
@@ -0,0 +1,94 @@
+package standin
+
+import (
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"soundporter/internal/adapters"
+	"soundporter/internal/playlist"
+)
+
+// library is the catalog and playlists a stand-in serves. Handlers lock mu around every access.
+type library struct {
+	mu        sync.Mutex
+	catalog   []playlist.Track
+	playlists []playlist.Playlist
+	nextID    int
+}
+
+// newLibrary seeds a library, falling back to the fake adapter's seed data for nil arguments
+func newLibrary(catalog []playlist.Track, playlists []playlist.Playlist) *library {
+	if catalog == nil {
+		catalog = adapters.FakeCatalog()
+	}
+	if playlists == nil {
+		playlists = adapters.FakePlaylists(catalog)
+	}
+	// Copy the seed data so that mutations don't leak back into the caller's slices
+	copied := make([]playlist.Playlist, len(playlists))
+	for i, p := range playlists {
+		p.Tracks = slices.Clone(p.Tracks)
+		copied[i] = p
+	}
+	return &library{catalog: slices.Clone(catalog), playlists: copied}
+}
+
+// Playlist returns a copy of a playlist's current state
+func (l *library) Playlist(id string) (playlist.Playlist, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	p := l.findPlaylist(id)
+	if p == nil {
+		return playlist.Playlist{}, false
+	}
+	cp := *p
+	cp.Tracks = slices.Clone(p.Tracks)
+	cp.TrackCount = len(cp.Tracks)
+	return cp, true
+}
+
+// findPlaylist returns the playlist with the given ID. l.mu must be held.
+func (l *library) findPlaylist(id string) *playlist.Playlist {
+	for i := range l.playlists {
+		if l.playlists[i].ID == id {
+			return &l.playlists[i]
+		}
+	}
+	return nil
+}
+
+// findTrack returns the catalog track with the given ID. l.mu must be held.
+func (l *library) findTrack(id string) (playlist.Track, bool) {
+	for _, t := range l.catalog {
+		if t.ID == id {
+			return t, true
+		}
+	}
+	return playlist.Track{}, false
+}
+
+// addPlaylist adds an empty playlist with an ID made from prefix. l.mu must be held.
+func (l *library) addPlaylist(prefix, name, description string) playlist.Playlist {
+	l.nextID++
+	p := playlist.Playlist{
+		ID:          fmt.Sprintf("%s%d", prefix, l.nextID),
+		Name:        name,
+		Description: description,
+		CreatedAt:   time.Now(),
+	}
+	l.playlists = append(l.playlists, p)
+	return p
+}
+
+// search returns the catalog tracks matching q. l.mu must be held.
+func (l *library) search(q adapters.SearchQuery) []playlist.Track {
+	var matches []playlist.Track
+	for _, t := range l.catalog {
+		if adapters.MatchesSearchQuery(t, q) {
+			matches = append(matches, t)
+		}
+	}
+	return matches
+}
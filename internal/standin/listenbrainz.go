@@ -0,0 +1,415 @@
+package standin
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"soundporter/internal/adapters"
+	"soundporter/internal/playlist"
+)
+
+const (
+	// ListenBrainzUser is the user of the ListenBrainz stand-in
+	ListenBrainzUser = "standin"
+	// ListenBrainzToken is the user token the ListenBrainz stand-in accepts
+	ListenBrainzToken = "standin-user-token"
+
+	listenbrainzPlaylistURL  = "https://listenbrainz.org/playlist/"
+	listenbrainzRecordingURL = "https://musicbrainz.org/recording/"
+	listenbrainzMaxAdd       = 100
+)
+
+// ListenBrainz is a stand-in for the ListenBrainz API. Besides the given playlists it holds the
+// Weekly Jams and Weekly Exploration playlists generated for ListenBrainzUser. Catalog tracks
+// have the same MBIDs as on the MusicBrainz stand-in, and metadata lookups return the first
+// catalog match.
+type ListenBrainz struct {
+	*httptest.Server
+	*library
+
+	// generated are the IDs of the playlists generated for the user, which the user can't change
+	generated map[string]bool
+	// public are the IDs of the public playlists
+	public map[string]bool
+
+	// Plain leaves out the artist lists of playlist tracks and lookups, which then only credit
+	// the artists in one string, as tracks added by older clients do
+	Plain bool
+}
+
+// NewListenBrainz starts a ListenBrainz stand-in seeded with the given catalog and playlists,
+// which are public. Nil arguments fall back to the fake adapter's seed data.
+func NewListenBrainz(catalog []playlist.Track, playlists []playlist.Playlist) *ListenBrainz {
+	l := &ListenBrainz{
+		library:   newLibrary(catalog, playlists),
+		generated: make(map[string]bool),
+		public:    make(map[string]bool),
+	}
+	for _, p := range l.playlists {
+		l.public[p.ID] = true
+	}
+
+	sinceMonday := (int(time.Now().Weekday()) + 6) % 7
+	week := time.Now().AddDate(0, 0, -sinceMonday).Format("2006-01-02 Mon")
+	for i, kind := range []string{"Jams", "Exploration"} {
+		p := l.addPlaylist("lb-generated-", "Weekly "+kind+" for "+ListenBrainzUser+", week of "+week,
+			`<p>This playlist was generated for you by <a href="https://listenbrainz.org/user/troi-bot/">troi-bot</a>. It is updated every Monday &amp; kept for two weeks.</p>`)
+		l.findPlaylist(p.ID).Tracks = slices.Clone(l.catalog[min(i*5, len(l.catalog)):min(i*5+5, len(l.catalog))])
+		l.generated[p.ID] = true
+		l.public[p.ID] = true
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /1/validate-token", l.validateToken)
+	mux.HandleFunc("GET /1/user/{user}/playlists", l.userPlaylists(false))
+	mux.HandleFunc("GET /1/user/{user}/playlists/createdfor", l.userPlaylists(true))
+	mux.HandleFunc("GET /1/playlist/{id}", l.playlist)
+	mux.HandleFunc("POST /1/playlist/create", l.authed(l.createPlaylist))
+	mux.HandleFunc("POST /1/playlist/{id}/item/add", l.authed(l.addItems))
+	mux.HandleFunc("GET /1/metadata/lookup/", l.lookup)
+	l.Server = httptest.NewServer(mux)
+	return l
+}
+
+// APIURL returns the API base URL to pass to the adapter
+func (l *ListenBrainz) APIURL() string {
+	return l.URL + "/1/"
+}
+
+// Options returns adapter options pointing the ListenBrainz adapter at this stand-in
+func (l *ListenBrainz) Options() []adapters.Option {
+	return []adapters.Option{
+		adapters.WithHTTPClient(l.Client()),
+		adapters.WithBaseURL(l.APIURL()),
+	}
+}
+
+// token returns the user token of a request, or "" if there is none
+func (l *ListenBrainz) token(r *http.Request) string {
+	token, _ := strings.CutPrefix(r.Header.Get("Authorization"), "Token ")
+	return token
+}
+
+// validateToken reports whether the token is valid. Like ListenBrainz it answers invalid
+// tokens with a successful status.
+func (l *ListenBrainz) validateToken(w http.ResponseWriter, r *http.Request) {
+	switch l.token(r) {
+	case "":
+		listenbrainzError(w, http.StatusBadRequest, "You need to provide an Authorization header.")
+	case ListenBrainzToken:
+		writeJSON(w, http.StatusOK, map[string]any{"code": 200, "message": "Token valid.", "valid": true, "user_name": ListenBrainzUser})
+	default:
+		writeJSON(w, http.StatusOK, map[string]any{"code": 200, "message": "Token invalid.", "valid": false})
+	}
+}
+
+// authed rejects requests without the user token
+func (l *ListenBrainz) authed(h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if l.token(r) != ListenBrainzToken {
+			listenbrainzError(w, http.StatusUnauthorized, "Invalid authorization token.")
+			return
+		}
+		h(w, r)
+	}
+}
+
+// userPlaylists lists the user's own or generated playlists without their tracks. Private
+// playlists are only listed to the user.
+func (l *ListenBrainz) userPlaylists(generated bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.PathValue("user") != ListenBrainzUser {
+			listenbrainzError(w, http.StatusNotFound, "Cannot find user: "+r.PathValue("user"))
+			return
+		}
+		owner := l.token(r) == ListenBrainzToken
+
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		var listed []playlist.Playlist
+		for _, p := range l.playlists {
+			if l.generated[p.ID] == generated && (owner || l.public[p.ID]) {
+				listed = append(listed, p)
+			}
+		}
+
+		offset, _ := strconv.Atoi(r.FormValue("offset"))
+		count, err := strconv.Atoi(r.FormValue("count"))
+		if err != nil || count <= 0 || count > 100 {
+			count = 25
+		}
+		start, end := pageBounds(offset, count, len(listed))
+		items := []map[string]any{}
+		for _, p := range listed[start:end] {
+			items = append(items, map[string]any{"playlist": l.jspfPlaylist(p, false)})
+		}
+		writeJSON(w, http.StatusOK, map[string]any{
+			"playlists":      items,
+			"playlist_count": len(listed),
+			"count":          len(items),
+			"offset":         offset,
+		})
+	}
+}
+
+// playlist returns a playlist with its tracks
+func (l *ListenBrainz) playlist(w http.ResponseWriter, r *http.Request) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	p := l.findPlaylist(r.PathValue("id"))
+	if p == nil || (!l.public[p.ID] && l.token(r) != ListenBrainzToken) {
+		listenbrainzError(w, http.StatusNotFound, "Cannot find playlist: "+r.PathValue("id"))
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"playlist": l.jspfPlaylist(*p, true)})
+}
+
+func (l *ListenBrainz) createPlaylist(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Playlist struct {
+			Title      string `json:"title"`
+			Annotation string `json:"annotation"`
+			Extension  map[string]struct {
+				Public *bool `json:"public"`
+			} `json:"extension"`
+		} `json:"playlist"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		listenbrainzError(w, http.StatusBadRequest, "Invalid JSPF: "+err.Error())
+		return
+	}
+	ext, ok := body.Playlist.Extension["https://musicbrainz.org/doc/jspf#playlist"]
+	if body.Playlist.Title == "" || !ok || ext.Public == nil {
+		listenbrainzError(w, http.StatusBadRequest, "JSPF playlist must contain a title and the public field of its extension.")
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	p := l.addPlaylist("lb-playlist-", body.Playlist.Title, body.Playlist.Annotation)
+	l.public[p.ID] = *ext.Public
+	writeJSON(w, http.StatusOK, map[string]any{"playlist_mbid": p.ID, "status": "ok"})
+}
+
+// addItems appends recordings to a playlist. Like ListenBrainz it accepts any recording,
+// whether the catalog knows it or not.
+func (l *ListenBrainz) addItems(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Playlist struct {
+			Track []struct {
+				Identifier json.RawMessage `json:"identifier"`
+			} `json:"track"`
+		} `json:"playlist"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		listenbrainzError(w, http.StatusBadRequest, "Invalid JSPF: "+err.Error())
+		return
+	}
+	if len(body.Playlist.Track) > listenbrainzMaxAdd {
+		listenbrainzError(w, http.StatusBadRequest, "Too many recordings, at most 100 can be added at a time.")
+		return
+	}
+
+	var ids []string
+	for _, t := range body.Playlist.Track {
+		var id string
+		if json.Unmarshal(t.Identifier, &id) != nil {
+			var list []string
+			if json.Unmarshal(t.Identifier, &list) == nil && len(list) > 0 {
+				id = list[0]
+			}
+		}
+		mbid, ok := strings.CutPrefix(id, listenbrainzRecordingURL)
+		if !ok || mbid == "" {
+			listenbrainzError(w, http.StatusBadRequest, "JSPF playlist track identifiers must be MusicBrainz recording URLs.")
+			return
+		}
+		ids = append(ids, mbid)
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	p := l.findPlaylist(r.PathValue("id"))
+	if p == nil {
+		listenbrainzError(w, http.StatusNotFound, "Cannot find playlist: "+r.PathValue("id"))
+		return
+	}
+	if l.generated[p.ID] {
+		listenbrainzError(w, http.StatusForbidden, "You are not allowed to add recordings to this playlist.")
+		return
+	}
+	for _, id := range ids {
+		t := playlist.Track{ID: id, MBID: id}
+		for _, c := range l.catalog {
+			if MusicBrainzID(c.ID) == id {
+				t = c
+				break
+			}
+		}
+		p.Tracks = append(p.Tracks, t)
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"status": "ok"})
+}
+
+// lookup returns the catalog track best matching an artist and recording name, or an empty
+// object
+func (l *ListenBrainz) lookup(w http.ResponseWriter, r *http.Request) {
+	if r.FormValue("artist_name") == "" || r.FormValue("recording_name") == "" {
+		listenbrainzError(w, http.StatusBadRequest, "artist_name and recording_name are required.")
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	matches := l.search(adapters.SearchQuery{
+		Artist: r.FormValue("artist_name"),
+		Track:  r.FormValue("recording_name"),
+		Album:  r.FormValue("release_name"),
+	})
+	if len(matches) == 0 {
+		writeJSON(w, http.StatusOK, map[string]any{})
+		return
+	}
+
+	t := matches[0]
+	match := map[string]any{
+		"artist_credit_name": artistCredit(t.Artists),
+		"artist_mbids":       musicbrainzIDs(t.ArtistIDs),
+		"recording_mbid":     recordingMBID(t),
+		"recording_name":     t.Name,
+		"release_mbid":       MusicBrainzID(t.AlbumID),
+		"release_name":       t.Album,
+	}
+	if r.FormValue("metadata") == "true" {
+		var artists []map[string]any
+		for i, name := range t.Artists {
+			artist := map[string]any{"name": name, "join_phrase": listenbrainzJoinPhrase(i, len(t.Artists))}
+			if i < len(t.ArtistIDs) {
+				artist["artist_mbid"] = MusicBrainzID(t.ArtistIDs[i])
+			}
+			artists = append(artists, artist)
+		}
+		year, _, _, _ := splitDate(t.ReleaseDate)
+		metadata := map[string]any{
+			"recording": map[string]any{"name": t.Name, "length": t.DurationMs},
+			"release":   map[string]any{"name": t.Album, "mbid": MusicBrainzID(t.AlbumID), "year": year},
+			"artist":    map[string]any{"name": match["artist_credit_name"], "artists": artists},
+		}
+		if l.Plain {
+			delete(metadata, "artist")
+		}
+		match["metadata"] = metadata
+	}
+	writeJSON(w, http.StatusOK, match)
+}
+
+// jspfPlaylist renders a playlist as JSPF, with or without its tracks. l.mu must be held.
+func (l *ListenBrainz) jspfPlaylist(p playlist.Playlist, withTracks bool) map[string]any {
+	extension := map[string]any{"public": l.public[p.ID], "creator": ListenBrainzUser}
+	creator := ListenBrainzUser
+	if l.generated[p.ID] {
+		creator = "troi-bot"
+		extension["creator"] = creator
+		extension["created_for"] = ListenBrainzUser
+	}
+
+	tracks := []map[string]any{}
+	if withTracks {
+		for _, t := range p.Tracks {
+			tracks = append(tracks, l.jspfTrack(t))
+		}
+	}
+	return map[string]any{
+		"title":      p.Name,
+		"identifier": listenbrainzPlaylistURL + p.ID,
+		"creator":    creator,
+		"annotation": p.Description,
+		"date":       p.CreatedAt.UTC().Format(time.RFC3339),
+		"extension":  map[string]any{"https://musicbrainz.org/doc/jspf#playlist": extension},
+		"track":      tracks,
+	}
+}
+
+// jspfTrack renders a track as JSPF, the artists credited with join phrases and, unless l.Plain
+// is set, listed
+func (l *ListenBrainz) jspfTrack(t playlist.Track) map[string]any {
+	var artists []map[string]any
+	var artistIDs []string
+	for i, name := range t.Artists {
+		join := listenbrainzJoinPhrase(i, len(t.Artists))
+		artist := map[string]any{"artist_credit_name": name, "join_phrase": join}
+		if i < len(t.ArtistIDs) {
+			artist["artist_mbid"] = MusicBrainzID(t.ArtistIDs[i])
+			artistIDs = append(artistIDs, "https://musicbrainz.org/artist/"+MusicBrainzID(t.ArtistIDs[i]))
+		}
+		artists = append(artists, artist)
+	}
+
+	ext := map[string]any{
+		"added_by":            ListenBrainzUser,
+		"artist_identifiers":  artistIDs,
+		"additional_metadata": map[string]any{"artists": artists},
+	}
+	if l.Plain {
+		delete(ext, "additional_metadata")
+	}
+	if t.AlbumID != "" {
+		ext["release_identifier"] = "https://musicbrainz.org/release/" + MusicBrainzID(t.AlbumID)
+	}
+	return map[string]any{
+		"identifier": []string{listenbrainzRecordingURL + recordingMBID(t)},
+		"title":      t.Name,
+		"creator":    artistCredit(t.Artists),
+		"album":      t.Album,
+		"duration":   t.DurationMs,
+		"extension":  map[string]any{"https://musicbrainz.org/doc/jspf#track": ext},
+	}
+}
+
+// recordingMBID returns the MBID of a catalog track, or of a recording added by MBID
+func recordingMBID(t playlist.Track) string {
+	if t.MBID != "" {
+		return t.MBID
+	}
+	return MusicBrainzID(t.ID)
+}
+
+// musicbrainzIDs returns the MBIDs of catalog items
+func musicbrainzIDs(ids []string) []string {
+	mbids := make([]string, len(ids))
+	for i, id := range ids {
+		mbids[i] = MusicBrainzID(id)
+	}
+	return mbids
+}
+
+// artistCredit joins artists the way MusicBrainz credits them, e.g. "A, B & C"
+func artistCredit(artists []string) string {
+	var credit strings.Builder
+	for i, name := range artists {
+		credit.WriteString(name + listenbrainzJoinPhrase(i, len(artists)))
+	}
+	return credit.String()
+}
+
+// listenbrainzJoinPhrase returns the phrase following the i-th of n credited artists
+func listenbrainzJoinPhrase(i, n int) string {
+	switch {
+	case i == n-1:
+		return ""
+	case i == n-2:
+		return " & "
+	default:
+		return ", "
+	}
+}
+
+// listenbrainzError writes an error in ListenBrainz's format
+func listenbrainzError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]any{"code": status, "error": message})
+}
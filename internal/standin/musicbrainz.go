@@ -0,0 +1,213 @@
+package standin
+
+import (
+	"crypto/md5"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"soundporter/internal/adapters"
+	"soundporter/internal/musicbrainz"
+	"soundporter/internal/playlist"
+)
+
+// musicbrainzFieldRe finds the field:"phrase" terms of a Lucene query
+var musicbrainzFieldRe = regexp.MustCompile(`(\w+):"((?:[^"\\]|\\.)*)"`)
+
+// MusicBrainz is a stand-in for the MusicBrainz web service. Each catalog track is a recording
+// whose MBID is MusicBrainzID of the track ID. Like MusicBrainz it rejects requests without a
+// User-Agent, and answers requests coming faster than Interval with 503.
+type MusicBrainz struct {
+	*httptest.Server
+	*library
+
+	rateMu    sync.Mutex
+	interval  time.Duration
+	last      time.Time
+	requests  int
+	throttled int
+}
+
+// NewMusicBrainz starts a MusicBrainz stand-in seeded with the given catalog. Nil falls back to
+// the fake adapter's seed data. Requests must be at least interval apart.
+func NewMusicBrainz(catalog []playlist.Track, interval time.Duration) *MusicBrainz {
+	m := &MusicBrainz{
+		library:  newLibrary(catalog, []playlist.Playlist{}),
+		interval: interval,
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /ws/2/recording/{id}", m.limited(m.recording))
+	mux.HandleFunc("GET /ws/2/isrc/{isrc}", m.limited(m.isrc))
+	mux.HandleFunc("GET /ws/2/recording", m.limited(m.searchRecordings))
+	m.Server = httptest.NewServer(mux)
+	return m
+}
+
+// APIClient returns a MusicBrainz client pointed at this stand-in, keeping to its interval
+func (m *MusicBrainz) APIClient() *musicbrainz.Client {
+	c := musicbrainz.NewClient()
+	c.BaseURL = m.URL + "/ws/2/"
+	c.HTTPClient = m.Server.Client()
+	c.Interval = m.interval
+	return c
+}
+
+// Requests returns the number of requests served and how many of them were throttled
+func (m *MusicBrainz) Requests() (served, throttled int) {
+	m.rateMu.Lock()
+	defer m.rateMu.Unlock()
+	return m.requests, m.throttled
+}
+
+// MusicBrainzID returns the MBID the stand-ins give the catalog item with the given ID
+func MusicBrainzID(id string) string {
+	sum := md5.Sum([]byte(id))
+	return fmt.Sprintf("%x-%x-%x-%x-%x", sum[0:4], sum[4:6], sum[6:8], sum[8:10], sum[10:16])
+}
+
+// limited checks the User-Agent and the rate of requests, and that JSON is asked for
+func (m *MusicBrainz) limited(h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if ua := r.UserAgent(); ua == "" || strings.HasPrefix(ua, "Go-http-client") {
+			musicbrainzError(w, http.StatusForbidden, "Please identify your application with a meaningful User-Agent.")
+			return
+		}
+
+		m.rateMu.Lock()
+		now := time.Now()
+		// Allow for timer slack on the client's side
+		tooSoon := !m.last.IsZero() && now.Sub(m.last) < m.interval*9/10
+		m.requests++
+		if tooSoon {
+			m.throttled++
+		} else {
+			m.last = now
+		}
+		m.rateMu.Unlock()
+		if tooSoon {
+			musicbrainzError(w, http.StatusServiceUnavailable, "Your requests are exceeding the allowable rate limit.")
+			return
+		}
+
+		if r.FormValue("fmt") != "json" {
+			http.Error(w, "the stand-in only speaks JSON", http.StatusNotAcceptable)
+			return
+		}
+		h(w, r)
+	}
+}
+
+func (m *MusicBrainz) recording(w http.ResponseWriter, r *http.Request) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, t := range m.catalog {
+		if MusicBrainzID(t.ID) == r.PathValue("id") {
+			writeJSON(w, http.StatusOK, musicbrainzRecordingJSON(t, 0))
+			return
+		}
+	}
+	musicbrainzError(w, http.StatusNotFound, "Not Found")
+}
+
+func (m *MusicBrainz) isrc(w http.ResponseWriter, r *http.Request) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var recordings []map[string]any
+	for _, t := range m.catalog {
+		if t.ISRC != "" && strings.EqualFold(t.ISRC, r.PathValue("isrc")) {
+			recordings = append(recordings, musicbrainzRecordingJSON(t, 0))
+		}
+	}
+	if recordings == nil {
+		musicbrainzError(w, http.StatusNotFound, "Not Found")
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"isrc": r.PathValue("isrc"), "recordings": recordings})
+}
+
+// searchRecordings matches the recording and artist terms of the query against the catalog. Matches
+// score 100, or 95 when the title only contains the phrase, best first.
+func (m *MusicBrainz) searchRecordings(w http.ResponseWriter, r *http.Request) {
+	var q adapters.SearchQuery
+	for _, term := range musicbrainzFieldRe.FindAllStringSubmatch(r.FormValue("query"), -1) {
+		phrase := strings.NewReplacer(`\"`, `"`, `\\`, `\`).Replace(term[2])
+		switch term[1] {
+		case "recording":
+			q.Track = phrase
+		case "artist":
+			q.Artist = phrase
+		}
+	}
+	if q.Track == "" && q.Artist == "" {
+		musicbrainzError(w, http.StatusBadRequest, "Invalid query.")
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var exact, partial []map[string]any
+	for _, t := range m.search(q) {
+		if strings.EqualFold(t.Name, q.Track) {
+			exact = append(exact, musicbrainzRecordingJSON(t, 100))
+		} else {
+			partial = append(partial, musicbrainzRecordingJSON(t, 95))
+		}
+	}
+	recordings := append(exact, partial...)
+	offset, limit := pageParams(r, 25, 100)
+	start, end := pageBounds(offset, limit, len(recordings))
+	writeJSON(w, http.StatusOK, map[string]any{
+		"created":    time.Now().UTC().Format(time.RFC3339),
+		"count":      len(recordings),
+		"offset":     offset,
+		"recordings": append([]map[string]any{}, recordings[start:end]...),
+	})
+}
+
+// musicbrainzRecordingJSON renders a catalog track as a recording on its album, with a search
+// score if score is set
+func musicbrainzRecordingJSON(t playlist.Track, score int) map[string]any {
+	var credit []map[string]any
+	for i, name := range t.Artists {
+		id := name
+		if i < len(t.ArtistIDs) {
+			id = t.ArtistIDs[i]
+		}
+		credit = append(credit, map[string]any{
+			"name":       name,
+			"joinphrase": listenbrainzJoinPhrase(i, len(t.Artists)),
+			"artist":     map[string]any{"id": MusicBrainzID(id), "name": name},
+		})
+	}
+	recording := map[string]any{
+		"id":             MusicBrainzID(t.ID),
+		"title":          t.Name,
+		"length":         t.DurationMs,
+		"disambiguation": strings.ToLower(t.Version),
+		"artist-credit":  credit,
+		"isrcs":          []string{},
+		"releases": []map[string]any{{
+			"id":     MusicBrainzID(t.AlbumID),
+			"title":  t.Album,
+			"date":   t.ReleaseDate,
+			"status": "Official",
+		}},
+	}
+	if t.ISRC != "" {
+		recording["isrcs"] = []string{t.ISRC}
+	}
+	if score > 0 {
+		recording["score"] = score
+	}
+	return recording
+}
+
+// musicbrainzError writes an error in the format of the MusicBrainz web service
+func musicbrainzError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]any{"error": message, "help": "For usage, please see: https://musicbrainz.org/development/mmd"})
+}
@@ -0,0 +1,216 @@
+// Package standin provides local stand-ins for the music platform APIs the adapters talk to.
+// Each stand-in is an httptest server seeded with playlists and a searchable catalog, so the
+// real adapter code paths can be exercised without network access or accounts.
+package standin
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+// OAuth is a stand-in OAuth 2.0 authorization server. It approves every authorization request
+// and issues opaque bearer tokens that the stand-in APIs check. Codes requested with a PKCE
+// challenge can only be redeemed with the matching verifier.
+type OAuth struct {
+	mu   sync.Mutex
+	next int
+	// codes maps issued authorization codes to their S256 PKCE challenge, if any
+	codes         map[string]string
+	devices       map[string]*deviceGrant
+	accessTokens  map[string]bool
+	refreshTokens map[string]bool
+	// ExpiresIn is the lifetime in seconds of issued access tokens
+	ExpiresIn int
+}
+
+func newOAuth() *OAuth {
+	return &OAuth{
+		codes:         make(map[string]string),
+		devices:       make(map[string]*deviceGrant),
+		accessTokens:  make(map[string]bool),
+		refreshTokens: make(map[string]bool),
+		ExpiresIn:     3600,
+	}
+}
+
+// register mounts the authorization and token endpoints on mux
+func (o *OAuth) register(mux *http.ServeMux, authorizePath, tokenPath string) {
+	mux.HandleFunc("GET "+authorizePath, o.authorize)
+	mux.HandleFunc("POST "+tokenPath, o.token)
+}
+
+// Token issues a valid access and refresh token pair without going through the authorization flow
+func (o *OAuth) Token() *oauth2.Token {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	access, refresh := o.issue()
+	return &oauth2.Token{
+		AccessToken:  access,
+		TokenType:    "Bearer",
+		RefreshToken: refresh,
+		Expiry:       time.Now().Add(time.Duration(o.ExpiresIn) * time.Second),
+	}
+}
+
+// ExpiredToken issues a token whose access token has already expired, so the client has to
+// use the refresh token before its first request
+func (o *OAuth) ExpiredToken() *oauth2.Token {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	access, refresh := o.issue()
+	delete(o.accessTokens, access)
+	return &oauth2.Token{
+		AccessToken:  access,
+		TokenType:    "Bearer",
+		RefreshToken: refresh,
+		Expiry:       time.Now().Add(-time.Minute),
+	}
+}
+
+// ExpireAccessTokens invalidates every issued access token, as if they had been revoked
+func (o *OAuth) ExpireAccessTokens() {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.accessTokens = make(map[string]bool)
+}
+
+// authorized reports whether the request carries a valid bearer token
+func (o *OAuth) authorized(r *http.Request) bool {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return ok && o.valid(token)
+}
+
+// valid reports whether an access token was issued and hasn't been expired
+func (o *OAuth) valid(token string) bool {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.accessTokens[token]
+}
+
+// redeem exchanges a one-time authorization code for a new token pair
+func (o *OAuth) redeem(code string) (access, refresh string, ok bool) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if _, ok := o.codes[code]; !ok {
+		return "", "", false
+	}
+	delete(o.codes, code)
+	access, refresh = o.issue()
+	return access, refresh, true
+}
+
+// deviceGrant is a pending device authorization
+type deviceGrant struct {
+	userCode string
+	// polled is set by the first token request, which is answered with authorization_pending
+	polled bool
+}
+
+// startDevice begins a device authorization. Like authorization requests it's approved without
+// user interaction, but the client has to poll once before getting a token.
+func (o *OAuth) startDevice() (deviceCode, userCode string) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.next++
+	deviceCode = fmt.Sprintf("device-%d", o.next)
+	userCode = fmt.Sprintf("USER%d", o.next)
+	o.devices[deviceCode] = &deviceGrant{userCode: userCode}
+	return deviceCode, userCode
+}
+
+// issue creates a new token pair. o.mu must be held.
+func (o *OAuth) issue() (access, refresh string) {
+	o.next++
+	access = fmt.Sprintf("access-%d", o.next)
+	refresh = fmt.Sprintf("refresh-%d", o.next)
+	o.accessTokens[access] = true
+	o.refreshTokens[refresh] = true
+	return access, refresh
+}
+
+// authorize approves the request immediately and redirects back with a code
+func (o *OAuth) authorize(w http.ResponseWriter, r *http.Request) {
+	redirect, err := url.Parse(r.FormValue("redirect_uri"))
+	if err != nil || redirect.String() == "" {
+		http.Error(w, "missing redirect_uri", http.StatusBadRequest)
+		return
+	}
+
+	o.mu.Lock()
+	o.next++
+	code := fmt.Sprintf("code-%d", o.next)
+	o.codes[code] = r.FormValue("code_challenge")
+	o.mu.Unlock()
+
+	q := redirect.Query()
+	q.Set("code", code)
+	q.Set("state", r.FormValue("state"))
+	redirect.RawQuery = q.Encode()
+	http.Redirect(w, r, redirect.String(), http.StatusFound)
+}
+
+// token exchanges an authorization code or refresh token for a new access token
+func (o *OAuth) token(w http.ResponseWriter, r *http.Request) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	switch r.FormValue("grant_type") {
+	case "authorization_code":
+		code := r.FormValue("code")
+		challenge, ok := o.codes[code]
+		if !ok || (challenge != "" && challenge != oauth2.S256ChallengeFromVerifier(r.FormValue("code_verifier"))) {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant"})
+			return
+		}
+		delete(o.codes, code)
+	case "urn:ietf:params:oauth:grant-type:device_code":
+		code := r.FormValue("device_code")
+		device := o.devices[code]
+		if device == nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "expired_token"})
+			return
+		}
+		if !device.polled {
+			device.polled = true
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "authorization_pending"})
+			return
+		}
+		delete(o.devices, code)
+	case "refresh_token":
+		if !o.refreshTokens[r.FormValue("refresh_token")] {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant"})
+			return
+		}
+	default:
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "unsupported_grant_type"})
+		return
+	}
+
+	access, refresh := o.issue()
+	writeJSON(w, http.StatusOK, map[string]any{
+		"access_token":  access,
+		"token_type":    "Bearer",
+		"expires_in":    o.ExpiresIn,
+		"refresh_token": refresh,
+	})
+}
+
+// writeJSON writes v as a JSON response with the given status
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// pageBounds returns the slice bounds for a page of n items
+func pageBounds(offset, limit, n int) (int, int) {
+	start := min(max(offset, 0), n)
+	return start, min(start+limit, n)
+}
@@ -0,0 +1,313 @@
+package standin
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+
+	"soundporter/internal/adapters"
+	"soundporter/internal/playlist"
+)
+
+const (
+	// PlexToken is the X-Plex-Token the Plex stand-in accepts
+	PlexToken = "standin-plex-token"
+	// plexMachineID identifies the stand-in server in item URIs
+	plexMachineID = "5f2a9c0e7b1d4e3fa6c8b0d2e4f6a8c0"
+	// plexMusicSection is the key of the stand-in's music library
+	plexMusicSection = "1"
+)
+
+// Plex is a stand-in for a Plex Media Server with one music library holding the catalog.
+// Like Plex, its track filters match titles that contain the given text.
+type Plex struct {
+	*httptest.Server
+	*library
+}
+
+// NewPlex starts a Plex stand-in seeded with the given catalog and playlists.
+// Nil arguments fall back to the fake adapter's seed data.
+func NewPlex(catalog []playlist.Track, playlists []playlist.Playlist) *Plex {
+	p := &Plex{library: newLibrary(catalog, playlists)}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /{$}", p.authed(p.root))
+	mux.HandleFunc("GET /library/sections", p.authed(p.sections))
+	mux.HandleFunc("GET /library/sections/{key}/all", p.authed(p.sectionItems))
+	mux.HandleFunc("GET /playlists", p.authed(p.playlistsList))
+	mux.HandleFunc("POST /playlists", p.authed(p.createPlaylist))
+	mux.HandleFunc("PUT /playlists/{id}", p.authed(p.updatePlaylist))
+	mux.HandleFunc("GET /playlists/{id}/items", p.authed(p.playlistItems))
+	mux.HandleFunc("PUT /playlists/{id}/items", p.authed(p.addItems))
+	p.Server = httptest.NewServer(mux)
+	return p
+}
+
+// Options returns adapter options for the Plex adapter. The server URL, p.URL, is passed as a
+// credential like for a real server.
+func (p *Plex) Options() []adapters.Option {
+	return []adapters.Option{adapters.WithHTTPClient(p.Client())}
+}
+
+// authed rejects requests without the token, which Plex accepts as a header or a parameter
+func (p *Plex) authed(h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token := r.Header.Get("X-Plex-Token")
+		if token == "" {
+			token = r.FormValue("X-Plex-Token")
+		}
+		if token != PlexToken {
+			// Plex answers unauthorized requests with an HTML page
+			w.Header().Set("Content-Type", "text/html")
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte("<html><head><title>Unauthorized</title></head><body><h1>401 Unauthorized</h1></body></html>"))
+			return
+		}
+		h(w, r)
+	}
+}
+
+func (p *Plex) root(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]any{"MediaContainer": map[string]any{
+		"friendlyName":      "standin",
+		"machineIdentifier": plexMachineID,
+		"version":           "1.41.0.0000",
+	}})
+}
+
+func (p *Plex) sections(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]any{"MediaContainer": map[string]any{
+		"size": 2,
+		"Directory": []map[string]any{
+			{"key": plexMusicSection, "type": "artist", "title": "Music"},
+			{"key": "2", "type": "movie", "title": "Movies"},
+		},
+	}})
+}
+
+// sectionItems lists the tracks of the music library matching the title filters
+func (p *Plex) sectionItems(w http.ResponseWriter, r *http.Request) {
+	if r.PathValue("key") != plexMusicSection {
+		writeJSON(w, http.StatusOK, plexContainer(r, nil))
+		return
+	}
+	if r.FormValue("type") != "10" {
+		plexError(w, http.StatusBadRequest, "The stand-in only lists tracks")
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var items []map[string]any
+	for _, t := range p.catalog {
+		if !plexContains(r.FormValue("title"), t.Name) ||
+			!plexContains(r.FormValue("grandparentTitle"), strings.Join(t.Artists, ", ")) ||
+			!plexContains(r.FormValue("parentTitle"), t.Album) {
+			continue
+		}
+		items = append(items, plexTrackJSON(t))
+	}
+	writeJSON(w, http.StatusOK, plexContainer(r, items))
+}
+
+// plexContains reports whether value contains filter, ignoring case. An empty filter matches
+// everything.
+func plexContains(filter, value string) bool {
+	return strings.Contains(strings.ToLower(value), strings.ToLower(filter))
+}
+
+func (p *Plex) playlistsList(w http.ResponseWriter, r *http.Request) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var items []map[string]any
+	if kind := r.FormValue("playlistType"); kind == "" || kind == "audio" {
+		for _, pl := range p.playlists {
+			items = append(items, plexPlaylistJSON(pl))
+		}
+	}
+	writeJSON(w, http.StatusOK, plexContainer(r, items))
+}
+
+// createPlaylist creates a playlist holding the items of the uri parameter
+func (p *Plex) createPlaylist(w http.ResponseWriter, r *http.Request) {
+	title := r.FormValue("title")
+	if title == "" || r.FormValue("type") != "audio" || r.FormValue("smart") == "1" {
+		plexError(w, http.StatusBadRequest, "title and type=audio are required")
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	tracks, ok := p.uriTracks(w, r.FormValue("uri"))
+	if !ok {
+		return
+	}
+	created := p.addPlaylist("", title, "")
+	pl := p.findPlaylist(created.ID)
+	pl.Tracks = tracks
+	pl.TrackCount = len(tracks)
+	writeJSON(w, http.StatusOK, map[string]any{"MediaContainer": map[string]any{
+		"size":     1,
+		"Metadata": []map[string]any{plexPlaylistJSON(*pl)},
+	}})
+}
+
+func (p *Plex) updatePlaylist(w http.ResponseWriter, r *http.Request) {
+	r.ParseForm()
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	pl := p.findPlaylist(r.PathValue("id"))
+	if pl == nil {
+		plexError(w, http.StatusNotFound, "Playlist not found")
+		return
+	}
+	if r.Form.Has("title") {
+		pl.Name = r.FormValue("title")
+	}
+	if r.Form.Has("summary") {
+		pl.Description = r.FormValue("summary")
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+func (p *Plex) playlistItems(w http.ResponseWriter, r *http.Request) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	pl := p.findPlaylist(r.PathValue("id"))
+	if pl == nil {
+		plexError(w, http.StatusNotFound, "Playlist not found")
+		return
+	}
+
+	var items []map[string]any
+	for i, t := range pl.Tracks {
+		item := plexTrackJSON(t)
+		item["playlistItemID"] = i + 1
+		items = append(items, item)
+	}
+	writeJSON(w, http.StatusOK, plexContainer(r, items))
+}
+
+func (p *Plex) addItems(w http.ResponseWriter, r *http.Request) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	pl := p.findPlaylist(r.PathValue("id"))
+	if pl == nil {
+		plexError(w, http.StatusNotFound, "Playlist not found")
+		return
+	}
+	tracks, ok := p.uriTracks(w, r.FormValue("uri"))
+	if !ok {
+		return
+	}
+	pl.Tracks = append(pl.Tracks, tracks...)
+	pl.TrackCount = len(pl.Tracks)
+	writeJSON(w, http.StatusOK, map[string]any{"MediaContainer": map[string]any{
+		"leafCountAdded": len(tracks),
+		"Metadata":       []map[string]any{plexPlaylistJSON(*pl)},
+	}})
+}
+
+// uriTracks looks up the items of a server://<machine>/com.plexapp.plugins.library URI, which
+// lists rating keys after /library/metadata/. p.mu must be held.
+func (p *Plex) uriTracks(w http.ResponseWriter, uri string) ([]playlist.Track, bool) {
+	rest, ok := strings.CutPrefix(uri, "server://"+plexMachineID+"/com.plexapp.plugins.library")
+	if !ok {
+		plexError(w, http.StatusBadRequest, "Invalid uri: "+uri)
+		return nil, false
+	}
+	keys, ok := strings.CutPrefix(rest, "/library/metadata/")
+	if !ok {
+		if rest != "" {
+			plexError(w, http.StatusBadRequest, "Invalid uri: "+uri)
+			return nil, false
+		}
+		return nil, true
+	}
+
+	var tracks []playlist.Track
+	for _, key := range strings.Split(keys, ",") {
+		t, ok := p.findTrack(key)
+		if !ok {
+			plexError(w, http.StatusBadRequest, "No item with rating key "+key)
+			return nil, false
+		}
+		tracks = append(tracks, t)
+	}
+	return tracks, true
+}
+
+func plexPlaylistJSON(p playlist.Playlist) map[string]any {
+	duration := 0
+	for _, t := range p.Tracks {
+		duration += t.DurationMs
+	}
+	return map[string]any{
+		"ratingKey":    p.ID,
+		"key":          "/playlists/" + p.ID + "/items",
+		"type":         "playlist",
+		"title":        p.Name,
+		"summary":      p.Description,
+		"smart":        false,
+		"playlistType": "audio",
+		"leafCount":    len(p.Tracks),
+		"duration":     duration,
+		"addedAt":      p.CreatedAt.Unix(),
+	}
+}
+
+// plexTrackJSON renders a track with its album as parent and first artist as grandparent.
+// Tracks with several artists name them all in originalTitle, like compilations.
+func plexTrackJSON(t playlist.Track) map[string]any {
+	item := map[string]any{
+		"ratingKey":       t.ID,
+		"key":             "/library/metadata/" + t.ID,
+		"type":            "track",
+		"title":           t.Name,
+		"parentTitle":     t.Album,
+		"parentRatingKey": t.AlbumID,
+		"duration":        t.DurationMs,
+		"index":           t.TrackNumber,
+		"parentIndex":     t.DiscNumber,
+	}
+	if len(t.Artists) > 0 {
+		item["grandparentTitle"] = t.Artists[0]
+	}
+	if len(t.ArtistIDs) > 0 {
+		item["grandparentRatingKey"] = t.ArtistIDs[0]
+	}
+	if len(t.Artists) > 1 {
+		item["originalTitle"] = strings.Join(t.Artists, ", ")
+	}
+	if year, _, _, ok := splitDate(t.ReleaseDate); ok {
+		item["parentYear"] = year
+	}
+	return item
+}
+
+// plexContainer slices items by the X-Plex-Container parameters into a media container
+func plexContainer(r *http.Request, items []map[string]any) map[string]any {
+	start, _ := strconv.Atoi(r.FormValue("X-Plex-Container-Start"))
+	size, err := strconv.Atoi(r.FormValue("X-Plex-Container-Size"))
+	if err != nil || size <= 0 {
+		size = len(items)
+	}
+	from, to := pageBounds(start, size, len(items))
+	container := map[string]any{"size": to - from, "totalSize": len(items), "offset": from}
+	if to > from {
+		container["Metadata"] = items[from:to]
+	}
+	return map[string]any{"MediaContainer": container}
+}
+
+// plexError writes an error in the JSON format of newer Plex servers
+func plexError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]any{"errors": []map[string]any{{"code": 1000, "message": message, "status": status}}})
+}
@@ -0,0 +1,336 @@
+package standin
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+
+	"soundporter/internal/adapters"
+	"soundporter/internal/playlist"
+)
+
+// SoundCloud is a stand-in for the SoundCloud API and its OAuth 2.1 endpoints. Track titles are
+// rendered the way uploads name them, "Artist - Title (Version)", next to publisher metadata.
+type SoundCloud struct {
+	*httptest.Server
+	OAuth *OAuth
+	*library
+
+	// Likes are the IDs of the user's liked tracks, most recent first
+	Likes []string
+	// Unavailable lists track IDs that are blocked in the stand-in user's country
+	Unavailable map[string]bool
+}
+
+// NewSoundCloud starts a SoundCloud stand-in seeded with the given catalog and playlists.
+// Nil arguments fall back to the fake adapter's seed data. The first two catalog tracks are liked.
+func NewSoundCloud(catalog []playlist.Track, playlists []playlist.Playlist) *SoundCloud {
+	s := &SoundCloud{
+		OAuth:       newOAuth(),
+		library:     newLibrary(catalog, playlists),
+		Unavailable: make(map[string]bool),
+	}
+	for _, t := range s.catalog[:min(2, len(s.catalog))] {
+		s.Likes = append(s.Likes, t.ID)
+	}
+
+	mux := http.NewServeMux()
+	s.OAuth.register(mux, "/authorize", "/oauth/token")
+	mux.HandleFunc("GET /me", s.authed(s.me))
+	mux.HandleFunc("GET /me/playlists", s.authed(s.userPlaylists))
+	mux.HandleFunc("GET /me/likes/tracks", s.authed(s.likedTracks))
+	mux.HandleFunc("POST /likes/tracks/{id}", s.authed(s.like))
+	mux.HandleFunc("POST /playlists", s.authed(s.createPlaylist))
+	mux.HandleFunc("PUT /playlists/{id}", s.authed(s.updatePlaylist))
+	mux.HandleFunc("GET /playlists/{id}/tracks", s.authed(s.playlistTracks))
+	mux.HandleFunc("GET /tracks", s.authed(s.searchTracks))
+	s.Server = httptest.NewServer(mux)
+	return s
+}
+
+// APIURL returns the API base URL to pass to the adapter
+func (s *SoundCloud) APIURL() string {
+	return s.URL + "/"
+}
+
+// Options returns adapter options pointing the SoundCloud adapter at this stand-in.
+// The adapter still runs the browser authorization flow unless a token is added with adapters.WithToken.
+func (s *SoundCloud) Options() []adapters.Option {
+	return []adapters.Option{
+		adapters.WithHTTPClient(s.Client()),
+		adapters.WithBaseURL(s.APIURL()),
+		adapters.WithOAuthEndpoints(s.URL+"/authorize", s.URL+"/oauth/token"),
+	}
+}
+
+// authed rejects requests without a valid bearer token
+func (s *SoundCloud) authed(h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !s.OAuth.authorized(r) {
+			soundCloudError(w, http.StatusUnauthorized, "401 - Unauthorized")
+			return
+		}
+		h(w, r)
+	}
+}
+
+func (s *SoundCloud) me(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]any{"id": 1000, "username": "standin-user", "kind": "user"})
+}
+
+func (s *SoundCloud) userPlaylists(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	collection := []map[string]any{}
+	start, end := soundCloudPage(r, len(s.playlists))
+	for _, p := range s.playlists[start:end] {
+		collection = append(collection, soundCloudPlaylistJSON(p))
+	}
+	writeJSON(w, http.StatusOK, soundCloudList(r, collection, end, len(s.playlists)))
+}
+
+func (s *SoundCloud) likedTracks(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	collection := []map[string]any{}
+	start, end := soundCloudPage(r, len(s.Likes))
+	for _, id := range s.Likes[start:end] {
+		if t, ok := s.findTrack(id); ok {
+			collection = append(collection, s.trackJSON(t))
+		}
+	}
+	writeJSON(w, http.StatusOK, soundCloudList(r, collection, end, len(s.Likes)))
+}
+
+func (s *SoundCloud) like(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	id := r.PathValue("id")
+	if _, ok := s.findTrack(id); !ok {
+		soundCloudError(w, http.StatusNotFound, "404 - Not Found")
+		return
+	}
+	for _, liked := range s.Likes {
+		if liked == id {
+			writeJSON(w, http.StatusOK, map[string]string{"status": "200 - OK"})
+			return
+		}
+	}
+	s.Likes = append([]string{id}, s.Likes...)
+	writeJSON(w, http.StatusCreated, map[string]string{"status": "201 - Created"})
+}
+
+// soundCloudPlaylistBody is the body of playlist create and update requests
+type soundCloudPlaylistBody struct {
+	Playlist struct {
+		Title       *string `json:"title"`
+		Description *string `json:"description"`
+		Sharing     string  `json:"sharing"`
+		Tracks      *[]struct {
+			// ID may be a number or a string
+			ID json.RawMessage `json:"id"`
+		} `json:"tracks"`
+	} `json:"playlist"`
+}
+
+func (s *SoundCloud) createPlaylist(w http.ResponseWriter, r *http.Request) {
+	var body soundCloudPlaylistBody
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil || body.Playlist.Title == nil || *body.Playlist.Title == "" {
+		soundCloudError(w, http.StatusUnprocessableEntity, "Title can't be blank")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	description := ""
+	if body.Playlist.Description != nil {
+		description = *body.Playlist.Description
+	}
+	created := s.addPlaylist("", *body.Playlist.Title, description)
+	p := s.findPlaylist(created.ID)
+	if !s.setTracks(w, p, body) {
+		return
+	}
+	writeJSON(w, http.StatusCreated, soundCloudPlaylistJSON(*p))
+}
+
+// updatePlaylist replaces the playlist's fields, including the whole track list if given
+func (s *SoundCloud) updatePlaylist(w http.ResponseWriter, r *http.Request) {
+	var body soundCloudPlaylistBody
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		soundCloudError(w, http.StatusBadRequest, "400 - Bad Request")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	p := s.findPlaylist(r.PathValue("id"))
+	if p == nil {
+		soundCloudError(w, http.StatusNotFound, "404 - Not Found")
+		return
+	}
+	if body.Playlist.Title != nil {
+		p.Name = *body.Playlist.Title
+	}
+	if body.Playlist.Description != nil {
+		p.Description = *body.Playlist.Description
+	}
+	if !s.setTracks(w, p, body) {
+		return
+	}
+	writeJSON(w, http.StatusOK, soundCloudPlaylistJSON(*p))
+}
+
+// setTracks replaces the tracks of p with the ones in body, if any. Like SoundCloud it refuses
+// more than 500 tracks. s.mu must be held.
+func (s *SoundCloud) setTracks(w http.ResponseWriter, p *playlist.Playlist, body soundCloudPlaylistBody) bool {
+	if body.Playlist.Tracks == nil {
+		return true
+	}
+	if len(*body.Playlist.Tracks) > 500 {
+		soundCloudError(w, http.StatusUnprocessableEntity, "Playlists can have at most 500 tracks")
+		return false
+	}
+
+	var tracks []playlist.Track
+	for _, ref := range *body.Playlist.Tracks {
+		id := strings.Trim(string(ref.ID), `"`)
+		t, ok := s.findTrack(id)
+		if !ok {
+			soundCloudError(w, http.StatusUnprocessableEntity, "Track "+id+" doesn't exist")
+			return false
+		}
+		tracks = append(tracks, t)
+	}
+	p.Tracks = tracks
+	p.TrackCount = len(tracks)
+	return true
+}
+
+func (s *SoundCloud) playlistTracks(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	p := s.findPlaylist(r.PathValue("id"))
+	if p == nil {
+		soundCloudError(w, http.StatusNotFound, "404 - Not Found")
+		return
+	}
+
+	collection := []map[string]any{}
+	start, end := soundCloudPage(r, len(p.Tracks))
+	for _, t := range p.Tracks[start:end] {
+		collection = append(collection, s.trackJSON(t))
+	}
+	writeJSON(w, http.StatusOK, soundCloudList(r, collection, end, len(p.Tracks)))
+}
+
+func (s *SoundCloud) searchTracks(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	matches := s.search(adapters.ParseSearchQuery(r.FormValue("q")))
+	collection := []map[string]any{}
+	start, end := soundCloudPage(r, len(matches))
+	for _, t := range matches[start:end] {
+		collection = append(collection, s.trackJSON(t))
+	}
+	writeJSON(w, http.StatusOK, soundCloudList(r, collection, end, len(matches)))
+}
+
+// trackJSON renders a track as an upload by its first artist. s.mu must be held.
+func (s *SoundCloud) trackJSON(t playlist.Track) map[string]any {
+	title := t.Name
+	if len(t.Artists) > 0 {
+		title = strings.Join(t.Artists, ", ") + " - " + title
+	}
+	if t.Version != "" {
+		title += " (" + t.Version + ")"
+	}
+
+	user := map[string]any{"id": "", "username": ""}
+	if len(t.Artists) > 0 {
+		user["username"] = t.Artists[0]
+	}
+	if len(t.ArtistIDs) > 0 {
+		user["id"] = t.ArtistIDs[0]
+	}
+
+	access := "playable"
+	if s.Unavailable[t.ID] {
+		access = "blocked"
+	}
+	track := map[string]any{
+		"kind":           "track",
+		"id":             t.ID,
+		"title":          title,
+		"permalink_url":  "https://soundcloud.com/standin/" + t.ID,
+		"duration":       t.DurationMs,
+		"playback_count": t.Popularity * 10000,
+		"access":         access,
+		"user":           user,
+		"publisher_metadata": map[string]any{
+			"artist":      strings.Join(t.Artists, ", "),
+			"album_title": t.Album,
+			"isrc":        t.ISRC,
+			"explicit":    t.Explicit,
+		},
+	}
+	if year, month, day, ok := splitDate(t.ReleaseDate); ok {
+		track["release_year"], track["release_month"], track["release_day"] = year, month, day
+	}
+	return track
+}
+
+// splitDate splits a YYYY-MM-DD date
+func splitDate(date string) (year, month, day int, ok bool) {
+	_, err := fmt.Sscanf(date, "%4d-%2d-%2d", &year, &month, &day)
+	return year, month, day, err == nil
+}
+
+func soundCloudPlaylistJSON(p playlist.Playlist) map[string]any {
+	return map[string]any{
+		"kind":        "playlist",
+		"id":          p.ID,
+		"title":       p.Name,
+		"description": p.Description,
+		"track_count": len(p.Tracks),
+		"created_at":  p.CreatedAt.UTC().Format("2006/01/02 15:04:05 -0700"),
+		"sharing":     "private",
+	}
+}
+
+// soundCloudPage reads the offset and limit parameters
+func soundCloudPage(r *http.Request, n int) (start, end int) {
+	offset, _ := strconv.Atoi(r.FormValue("offset"))
+	limit, err := strconv.Atoi(r.FormValue("limit"))
+	if err != nil || limit <= 0 {
+		limit = 50
+	}
+	return pageBounds(offset, min(limit, 200), n)
+}
+
+// soundCloudList wraps a collection in a linked partitioning response with an absolute next
+// link when more items follow end
+func soundCloudList(r *http.Request, collection []map[string]any, end, total int) map[string]any {
+	resp := map[string]any{"collection": collection, "next_href": nil}
+	if end < total {
+		q := r.URL.Query()
+		q.Set("offset", strconv.Itoa(end))
+		resp["next_href"] = fmt.Sprintf("http://%s%s?%s", r.Host, r.URL.Path, q.Encode())
+	}
+	return resp
+}
+
+// soundCloudError writes an error in SoundCloud's format
+func soundCloudError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]any{"code": status, "message": message, "errors": []any{}})
+}
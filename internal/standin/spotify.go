@@ -0,0 +1,283 @@
+package standin
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+
+	"soundporter/internal/adapters"
+	"soundporter/internal/playlist"
+)
+
+// spotifyUserID is the ID of the single user the Spotify stand-in knows about
+const spotifyUserID = "standin-user"
+
+// Spotify is a stand-in for the Spotify Web API and accounts service. It serves the endpoints
+// the Spotify adapter uses from an in-memory catalog and set of playlists.
+type Spotify struct {
+	*httptest.Server
+	OAuth *OAuth
+	*library
+
+	// Unavailable lists track IDs that are reported as not playable in the user's market
+	Unavailable map[string]bool
+}
+
+// NewSpotify starts a Spotify stand-in seeded with the given catalog and playlists.
+// Nil arguments fall back to the fake adapter's seed data.
+func NewSpotify(catalog []playlist.Track, playlists []playlist.Playlist) *Spotify {
+	s := &Spotify{
+		OAuth:       newOAuth(),
+		library:     newLibrary(catalog, playlists),
+		Unavailable: make(map[string]bool),
+	}
+
+	mux := http.NewServeMux()
+	s.OAuth.register(mux, "/authorize", "/api/token")
+	mux.HandleFunc("GET /v1/me", s.authed(s.me))
+	mux.HandleFunc("GET /v1/me/playlists", s.authed(s.userPlaylists))
+	mux.HandleFunc("POST /v1/users/{user}/playlists", s.authed(s.createPlaylist))
+	mux.HandleFunc("GET /v1/playlists/{id}/tracks", s.authed(s.playlistTracks))
+	mux.HandleFunc("POST /v1/playlists/{id}/tracks", s.authed(s.addTracks))
+	mux.HandleFunc("GET /v1/search", s.authed(s.searchTracks))
+	s.Server = httptest.NewServer(mux)
+	return s
+}
+
+// APIURL returns the Web API base URL to pass to the adapter
+func (s *Spotify) APIURL() string {
+	return s.URL + "/v1/"
+}
+
+// AuthURL returns the authorization endpoint
+func (s *Spotify) AuthURL() string {
+	return s.URL + "/authorize"
+}
+
+// TokenURL returns the token endpoint
+func (s *Spotify) TokenURL() string {
+	return s.URL + "/api/token"
+}
+
+// Options returns adapter options pointing the Spotify adapter at this stand-in.
+// The adapter still runs the browser authorization flow unless a token is added with adapters.WithToken.
+func (s *Spotify) Options() []adapters.Option {
+	return []adapters.Option{
+		adapters.WithHTTPClient(s.Client()),
+		adapters.WithBaseURL(s.APIURL()),
+		adapters.WithOAuthEndpoints(s.AuthURL(), s.TokenURL()),
+	}
+}
+
+// authed rejects requests without a valid bearer token
+func (s *Spotify) authed(h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !s.OAuth.authorized(r) {
+			spotifyError(w, http.StatusUnauthorized, "The access token expired")
+			return
+		}
+		h(w, r)
+	}
+}
+
+func (s *Spotify) me(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]any{
+		"id":           spotifyUserID,
+		"display_name": "Stand-in User",
+		"country":      "US",
+	})
+}
+
+func (s *Spotify) userPlaylists(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	offset, limit := pageParams(r, 20, 50)
+	start, end := pageBounds(offset, limit, len(s.playlists))
+	items := []map[string]any{}
+	for _, p := range s.playlists[start:end] {
+		items = append(items, spotifyPlaylistJSON(p))
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"items":  items,
+		"limit":  limit,
+		"offset": offset,
+		"total":  len(s.playlists),
+	})
+}
+
+func (s *Spotify) createPlaylist(w http.ResponseWriter, r *http.Request) {
+	if r.PathValue("user") != spotifyUserID {
+		spotifyError(w, http.StatusForbidden, "You cannot create a playlist for another user")
+		return
+	}
+
+	var body struct {
+		Name        string `json:"name"`
+		Description string `json:"description"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil || body.Name == "" {
+		spotifyError(w, http.StatusBadRequest, "Missing required field: name")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	p := s.addPlaylist("standin-playlist-", body.Name, body.Description)
+	writeJSON(w, http.StatusCreated, spotifyPlaylistJSON(p))
+}
+
+func (s *Spotify) playlistTracks(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	p := s.findPlaylist(r.PathValue("id"))
+	if p == nil {
+		spotifyError(w, http.StatusNotFound, "Resource not found")
+		return
+	}
+
+	offset, limit := pageParams(r, 100, 100)
+	start, end := pageBounds(offset, limit, len(p.Tracks))
+	items := []map[string]any{}
+	for _, t := range p.Tracks[start:end] {
+		items = append(items, map[string]any{
+			"added_at": "2025-01-01T00:00:00Z",
+			"is_local": false,
+			"track":    s.trackJSON(t),
+		})
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"items":  items,
+		"limit":  limit,
+		"offset": offset,
+		"total":  len(p.Tracks),
+	})
+}
+
+func (s *Spotify) addTracks(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		URIs []string `json:"uris"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		spotifyError(w, http.StatusBadRequest, "Error parsing JSON")
+		return
+	}
+	if len(body.URIs) > 100 {
+		spotifyError(w, http.StatusBadRequest, "Too many ids requested")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	p := s.findPlaylist(r.PathValue("id"))
+	if p == nil {
+		spotifyError(w, http.StatusNotFound, "Resource not found")
+		return
+	}
+
+	var tracks []playlist.Track
+	for _, uri := range body.URIs {
+		id, ok := strings.CutPrefix(uri, "spotify:track:")
+		t, found := s.findTrack(id)
+		if !ok || !found {
+			spotifyError(w, http.StatusBadRequest, "Invalid track uri: "+uri)
+			return
+		}
+		tracks = append(tracks, t)
+	}
+	p.Tracks = append(p.Tracks, tracks...)
+	p.TrackCount = len(p.Tracks)
+	writeJSON(w, http.StatusCreated, map[string]string{"snapshot_id": strconv.Itoa(len(p.Tracks))})
+}
+
+func (s *Spotify) searchTracks(w http.ResponseWriter, r *http.Request) {
+	if r.FormValue("type") != "track" {
+		spotifyError(w, http.StatusBadRequest, "Only track search is supported")
+		return
+	}
+
+	query := adapters.ParseSearchQuery(r.FormValue("q"))
+	offset, limit := pageParams(r, 20, 50)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	matches := s.search(query)
+	start, end := pageBounds(offset, limit, len(matches))
+	items := []map[string]any{}
+	for _, t := range matches[start:end] {
+		items = append(items, s.trackJSON(t))
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"tracks": map[string]any{
+			"items":  items,
+			"limit":  limit,
+			"offset": offset,
+			"total":  len(matches),
+		},
+	})
+}
+
+// trackJSON renders a track the way the Web API does. s.mu must be held.
+func (s *Spotify) trackJSON(t playlist.Track) map[string]any {
+	artists := []map[string]any{}
+	for i, name := range t.Artists {
+		artist := map[string]any{"name": name, "type": "artist"}
+		if i < len(t.ArtistIDs) {
+			artist["id"] = t.ArtistIDs[i]
+		}
+		artists = append(artists, artist)
+	}
+
+	return map[string]any{
+		"type":    "track",
+		"id":      t.ID,
+		"uri":     "spotify:track:" + t.ID,
+		"name":    t.Name,
+		"artists": artists,
+		"album": map[string]any{
+			"id":           t.AlbumID,
+			"name":         t.Album,
+			"release_date": t.ReleaseDate,
+		},
+		"duration_ms":  t.DurationMs,
+		"explicit":     t.Explicit,
+		"popularity":   t.Popularity,
+		"disc_number":  t.DiscNumber,
+		"track_number": t.TrackNumber,
+		"external_ids": map[string]string{"isrc": t.ISRC},
+		"is_playable":  !s.Unavailable[t.ID],
+	}
+}
+
+// spotifyPlaylistJSON renders a simplified playlist object
+func spotifyPlaylistJSON(p playlist.Playlist) map[string]any {
+	return map[string]any{
+		"id":          p.ID,
+		"name":        p.Name,
+		"description": p.Description,
+		"owner":       map[string]string{"id": spotifyUserID},
+		"tracks":      map[string]int{"total": len(p.Tracks)},
+	}
+}
+
+// spotifyError writes an error in the Web API's error format
+func spotifyError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]any{
+		"error": map[string]any{"status": status, "message": message},
+	})
+}
+
+// pageParams reads the limit and offset query parameters, applying a default and maximum limit
+func pageParams(r *http.Request, defaultLimit, maxLimit int) (offset, limit int) {
+	offset, _ = strconv.Atoi(r.FormValue("offset"))
+	limit, err := strconv.Atoi(r.FormValue("limit"))
+	if err != nil || limit <= 0 {
+		limit = defaultLimit
+	}
+	return offset, min(limit, maxLimit)
+}
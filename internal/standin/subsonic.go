@@ -0,0 +1,310 @@
+package standin
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+
+	"soundporter/internal/adapters"
+	"soundporter/internal/playlist"
+)
+
+const (
+	// SubsonicUser and SubsonicPassword are the credentials the Subsonic stand-in accepts
+	SubsonicUser     = "standin"
+	SubsonicPassword = "standin-password"
+)
+
+// Subsonic is a stand-in for a Subsonic server with the OpenSubsonic extensions, such as
+// Navidrome. Methods are served under /rest/, with or without the .view suffix.
+type Subsonic struct {
+	*httptest.Server
+	*library
+
+	// LDAP makes the server reject token authentication like servers backed by LDAP do
+	LDAP bool
+	// LegacyAPI makes createPlaylist return an empty response, as servers older than 1.14 do
+	LegacyAPI bool
+	// Plain makes songs leave out the OpenSubsonic fields, as servers without the extensions do
+	Plain bool
+}
+
+// NewSubsonic starts a Subsonic stand-in seeded with the given catalog and playlists.
+// Nil arguments fall back to the fake adapter's seed data.
+func NewSubsonic(catalog []playlist.Track, playlists []playlist.Playlist) *Subsonic {
+	s := &Subsonic{library: newLibrary(catalog, playlists)}
+
+	methods := map[string]http.HandlerFunc{
+		"ping":           s.ping,
+		"getPlaylists":   s.getPlaylists,
+		"getPlaylist":    s.getPlaylist,
+		"createPlaylist": s.createPlaylist,
+		"updatePlaylist": s.updatePlaylist,
+		"search3":        s.search3,
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/rest/{method}", func(w http.ResponseWriter, r *http.Request) {
+		h, ok := methods[strings.TrimSuffix(r.PathValue("method"), ".view")]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		s.authed(h)(w, r)
+	})
+	s.Server = httptest.NewServer(mux)
+	return s
+}
+
+// Options returns adapter options for the Subsonic adapter. The server URL, s.URL, is passed
+// as a credential like for a real server.
+func (s *Subsonic) Options() []adapters.Option {
+	return []adapters.Option{adapters.WithHTTPClient(s.Client())}
+}
+
+// authed checks the credentials, either a salted token or the plain or hex encoded password
+func (s *Subsonic) authed(h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.FormValue("v") == "" || r.FormValue("c") == "" {
+			subsonicFailed(w, 10, "Required parameter is missing.")
+			return
+		}
+
+		var ok bool
+		if token := r.FormValue("t"); token != "" {
+			if s.LDAP {
+				subsonicFailed(w, 41, "Token authentication not supported for LDAP users.")
+				return
+			}
+			sum := md5.Sum([]byte(SubsonicPassword + r.FormValue("s")))
+			ok = r.FormValue("s") != "" && token == hex.EncodeToString(sum[:])
+		} else {
+			password := r.FormValue("p")
+			if encoded, isHex := strings.CutPrefix(password, "enc:"); isHex {
+				decoded, _ := hex.DecodeString(encoded)
+				password = string(decoded)
+			}
+			ok = password == SubsonicPassword
+		}
+		if !ok || r.FormValue("u") != SubsonicUser {
+			subsonicFailed(w, 40, "Wrong username or password.")
+			return
+		}
+		h(w, r)
+	}
+}
+
+func (s *Subsonic) ping(w http.ResponseWriter, r *http.Request) {
+	subsonicOK(w, nil)
+}
+
+func (s *Subsonic) getPlaylists(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	lists := []map[string]any{}
+	for _, p := range s.playlists {
+		lists = append(lists, subsonicPlaylistJSON(p))
+	}
+	subsonicOK(w, map[string]any{"playlists": map[string]any{"playlist": lists}})
+}
+
+func (s *Subsonic) getPlaylist(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	p := s.findPlaylist(r.FormValue("id"))
+	if p == nil {
+		subsonicFailed(w, 70, "Playlist not found")
+		return
+	}
+	subsonicOK(w, map[string]any{"playlist": s.playlistWithEntries(*p)})
+}
+
+// createPlaylist creates a playlist with the given songs
+func (s *Subsonic) createPlaylist(w http.ResponseWriter, r *http.Request) {
+	name := r.FormValue("name")
+	if name == "" {
+		subsonicFailed(w, 10, "Required parameter is missing: name")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tracks, ok := s.songs(w, r.Form["songId"])
+	if !ok {
+		return
+	}
+	created := s.addPlaylist("standin-", name, "")
+	p := s.findPlaylist(created.ID)
+	p.Tracks = tracks
+	p.TrackCount = len(tracks)
+
+	if s.LegacyAPI {
+		subsonicOK(w, nil)
+		return
+	}
+	subsonicOK(w, map[string]any{"playlist": s.playlistWithEntries(*p)})
+}
+
+func (s *Subsonic) updatePlaylist(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	p := s.findPlaylist(r.FormValue("playlistId"))
+	if p == nil {
+		subsonicFailed(w, 70, "Playlist not found")
+		return
+	}
+	tracks, ok := s.songs(w, r.Form["songIdToAdd"])
+	if !ok {
+		return
+	}
+
+	if r.Form.Has("name") {
+		p.Name = r.FormValue("name")
+	}
+	if r.Form.Has("comment") {
+		p.Description = r.FormValue("comment")
+	}
+	p.Tracks = append(p.Tracks, tracks...)
+	p.TrackCount = len(p.Tracks)
+	subsonicOK(w, nil)
+}
+
+// songs looks up song IDs, failing the request if one doesn't exist. s.mu must be held.
+func (s *Subsonic) songs(w http.ResponseWriter, ids []string) ([]playlist.Track, bool) {
+	var tracks []playlist.Track
+	for _, id := range ids {
+		t, ok := s.findTrack(id)
+		if !ok {
+			subsonicFailed(w, 70, "Song not found: "+id)
+			return nil, false
+		}
+		tracks = append(tracks, t)
+	}
+	return tracks, true
+}
+
+// search3 matches the query's words against the title, artist and album of each song
+func (s *Subsonic) search3(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	matches := s.search(adapters.SearchQuery{Track: r.FormValue("query")})
+	offset, _ := strconv.Atoi(r.FormValue("songOffset"))
+	count, err := strconv.Atoi(r.FormValue("songCount"))
+	if err != nil {
+		count = 20
+	}
+
+	songs := []map[string]any{}
+	start, end := pageBounds(offset, count, len(matches))
+	for _, t := range matches[start:end] {
+		songs = append(songs, s.songJSON(t))
+	}
+	subsonicOK(w, map[string]any{"searchResult3": map[string]any{"song": songs}})
+}
+
+func subsonicPlaylistJSON(p playlist.Playlist) map[string]any {
+	duration := 0
+	for _, t := range p.Tracks {
+		duration += t.DurationMs / 1000
+	}
+	return map[string]any{
+		"id":        p.ID,
+		"name":      p.Name,
+		"comment":   p.Description,
+		"owner":     SubsonicUser,
+		"public":    false,
+		"songCount": len(p.Tracks),
+		"duration":  duration,
+		"created":   p.CreatedAt.UTC().Format("2006-01-02T15:04:05.000Z"),
+		"changed":   p.CreatedAt.UTC().Format("2006-01-02T15:04:05.000Z"),
+	}
+}
+
+func (s *Subsonic) playlistWithEntries(p playlist.Playlist) map[string]any {
+	entries := []map[string]any{}
+	for _, t := range p.Tracks {
+		entries = append(entries, s.songJSON(t))
+	}
+	resp := subsonicPlaylistJSON(p)
+	resp["entry"] = entries
+	return resp
+}
+
+// songJSON renders a track as a song, with the OpenSubsonic fields unless s.Plain is set
+func (s *Subsonic) songJSON(t playlist.Track) map[string]any {
+	artists := []map[string]any{}
+	for i, name := range t.Artists {
+		a := map[string]any{"name": name}
+		if i < len(t.ArtistIDs) {
+			a["id"] = t.ArtistIDs[i]
+		}
+		artists = append(artists, a)
+	}
+
+	song := map[string]any{
+		"id":             t.ID,
+		"isDir":          false,
+		"title":          t.Name,
+		"album":          t.Album,
+		"artist":         strings.Join(t.Artists, " & "),
+		"track":          t.TrackNumber,
+		"discNumber":     t.DiscNumber,
+		"duration":       t.DurationMs / 1000,
+		"albumId":        t.AlbumID,
+		"type":           "music",
+		"mediaType":      "song",
+		"suffix":         "flac",
+		"artists":        artists,
+		"isrc":           []string{},
+		"explicitStatus": "",
+	}
+	if len(t.ArtistIDs) > 0 {
+		song["artistId"] = t.ArtistIDs[0]
+	}
+	if t.ISRC != "" {
+		song["isrc"] = []string{t.ISRC}
+	}
+	if t.Explicit {
+		song["explicitStatus"] = "explicit"
+	}
+	if year, _, _, ok := splitDate(t.ReleaseDate); ok {
+		song["year"] = year
+	}
+	if s.Plain {
+		delete(song, "artists")
+		delete(song, "isrc")
+		delete(song, "explicitStatus")
+	}
+	return song
+}
+
+// subsonicOK writes a successful response with the given fields
+func subsonicOK(w http.ResponseWriter, fields map[string]any) {
+	resp := map[string]any{"status": "ok"}
+	for k, v := range fields {
+		resp[k] = v
+	}
+	subsonicWrite(w, resp)
+}
+
+// subsonicFailed writes a failed response, which Subsonic servers send with a 200 status
+func subsonicFailed(w http.ResponseWriter, code int, message string) {
+	subsonicWrite(w, map[string]any{
+		"status": "failed",
+		"error":  map[string]any{"code": code, "message": message},
+	})
+}
+
+func subsonicWrite(w http.ResponseWriter, resp map[string]any) {
+	resp["version"] = "1.16.1"
+	resp["type"] = "standin"
+	resp["openSubsonic"] = true
+	writeJSON(w, http.StatusOK, map[string]any{"subsonic-response": resp})
+}
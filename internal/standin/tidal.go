@@ -0,0 +1,328 @@
+package standin
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+
+	"soundporter/internal/adapters"
+	"soundporter/internal/playlist"
+)
+
+const (
+	// tidalUserID and tidalCountryCode describe the stand-in user's session
+	tidalUserID      = 4242
+	tidalCountryCode = "US"
+)
+
+// Tidal is a stand-in for the Tidal API and its device flow login. Device logins are approved
+// on the first poll for the token, without visiting the verification link.
+type Tidal struct {
+	*httptest.Server
+	OAuth *OAuth
+	*library
+
+	// Unavailable lists track IDs that can't be streamed in the stand-in user's country
+	Unavailable map[string]bool
+}
+
+// NewTidal starts a Tidal stand-in seeded with the given catalog and playlists.
+// Nil arguments fall back to the fake adapter's seed data.
+func NewTidal(catalog []playlist.Track, playlists []playlist.Playlist) *Tidal {
+	t := &Tidal{
+		OAuth:       newOAuth(),
+		library:     newLibrary(catalog, playlists),
+		Unavailable: make(map[string]bool),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /v1/oauth2/device_authorization", t.deviceAuthorization)
+	mux.HandleFunc("POST /v1/oauth2/token", t.OAuth.token)
+	mux.HandleFunc("GET /link/{code}", t.link)
+	mux.HandleFunc("GET /v1/sessions", t.authed(t.session))
+	mux.HandleFunc("GET /v1/users/{user}/playlists", t.authed(t.userPlaylists))
+	mux.HandleFunc("POST /v1/users/{user}/playlists", t.authed(t.createPlaylist))
+	mux.HandleFunc("GET /v1/playlists/{id}", t.authed(t.playlist))
+	mux.HandleFunc("GET /v1/playlists/{id}/tracks", t.authed(t.playlistTracks))
+	mux.HandleFunc("POST /v1/playlists/{id}/items", t.authed(t.addItems))
+	mux.HandleFunc("GET /v1/search/tracks", t.authed(t.searchTracks))
+	mux.HandleFunc("GET /v1/tracks", t.authed(t.tracksByISRC))
+	t.Server = httptest.NewServer(mux)
+	return t
+}
+
+// APIURL returns the API base URL to pass to the adapter
+func (t *Tidal) APIURL() string {
+	return t.URL + "/v1/"
+}
+
+// Options returns adapter options pointing the Tidal adapter at this stand-in.
+// The adapter still runs the device flow unless a token is added with adapters.WithToken.
+func (t *Tidal) Options() []adapters.Option {
+	return []adapters.Option{
+		adapters.WithHTTPClient(t.Client()),
+		adapters.WithBaseURL(t.APIURL()),
+		adapters.WithOAuthEndpoints(t.URL+"/v1/oauth2/device_authorization", t.URL+"/v1/oauth2/token"),
+	}
+}
+
+// deviceAuthorization starts a device login. Like Tidal it answers in camelCase and leaves the
+// scheme off the verification links.
+func (t *Tidal) deviceAuthorization(w http.ResponseWriter, r *http.Request) {
+	if r.FormValue("client_id") == "" {
+		tidalError(w, http.StatusBadRequest, 1002, "Missing client_id")
+		return
+	}
+
+	deviceCode, userCode := t.OAuth.startDevice()
+	writeJSON(w, http.StatusOK, map[string]any{
+		"deviceCode":              deviceCode,
+		"userCode":                userCode,
+		"verificationUri":         r.Host + "/link",
+		"verificationUriComplete": r.Host + "/link/" + userCode,
+		"expiresIn":               300,
+		"interval":                1,
+	})
+}
+
+// link is the page users would confirm the login on
+func (t *Tidal) link(w http.ResponseWriter, r *http.Request) {
+	fmt.Fprintf(w, "Device %s is connected to the Tidal stand-in.", r.PathValue("code"))
+}
+
+// authed rejects requests without a valid bearer token. Apart from the session, requests
+// also need the user's country code, like Tidal's catalog endpoints.
+func (t *Tidal) authed(h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !t.OAuth.authorized(r) {
+			tidalError(w, http.StatusUnauthorized, 11002, "Invalid access token")
+			return
+		}
+		if r.URL.Path != "/v1/sessions" && r.FormValue("countryCode") == "" {
+			tidalError(w, http.StatusBadRequest, 1002, "Missing countryCode parameter")
+			return
+		}
+		h(w, r)
+	}
+}
+
+func (t *Tidal) session(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]any{
+		"sessionId":   "standin-session",
+		"userId":      tidalUserID,
+		"countryCode": tidalCountryCode,
+	})
+}
+
+// ownUser rejects requests for other users' playlists
+func ownUser(w http.ResponseWriter, r *http.Request) bool {
+	if r.PathValue("user") != strconv.Itoa(tidalUserID) {
+		tidalError(w, http.StatusForbidden, 4005, "Not allowed to access this user")
+		return false
+	}
+	return true
+}
+
+func (t *Tidal) userPlaylists(w http.ResponseWriter, r *http.Request) {
+	if !ownUser(w, r) {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	items := []map[string]any{}
+	start, end := tidalPage(r, len(t.playlists))
+	for _, p := range t.playlists[start:end] {
+		items = append(items, tidalPlaylistJSON(p))
+	}
+	writeJSON(w, http.StatusOK, tidalList(start, items, len(t.playlists)))
+}
+
+func (t *Tidal) createPlaylist(w http.ResponseWriter, r *http.Request) {
+	if !ownUser(w, r) {
+		return
+	}
+	title := r.FormValue("title")
+	if title == "" {
+		tidalError(w, http.StatusBadRequest, 1002, "Missing title")
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	p := t.addPlaylist("standin-uuid-", title, r.FormValue("description"))
+	w.Header().Set("ETag", tidalETag(p))
+	writeJSON(w, http.StatusCreated, tidalPlaylistJSON(p))
+}
+
+func (t *Tidal) playlist(w http.ResponseWriter, r *http.Request) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	p := t.findPlaylist(r.PathValue("id"))
+	if p == nil {
+		tidalError(w, http.StatusNotFound, 2001, "Playlist not found")
+		return
+	}
+	w.Header().Set("ETag", tidalETag(*p))
+	writeJSON(w, http.StatusOK, tidalPlaylistJSON(*p))
+}
+
+func (t *Tidal) playlistTracks(w http.ResponseWriter, r *http.Request) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	p := t.findPlaylist(r.PathValue("id"))
+	if p == nil {
+		tidalError(w, http.StatusNotFound, 2001, "Playlist not found")
+		return
+	}
+
+	items := []map[string]any{}
+	start, end := tidalPage(r, len(p.Tracks))
+	for _, track := range p.Tracks[start:end] {
+		items = append(items, t.trackJSON(track))
+	}
+	writeJSON(w, http.StatusOK, tidalList(start, items, len(p.Tracks)))
+}
+
+// addItems appends tracks to a playlist. Like Tidal it refuses changes made against a stale
+// ETag, and fails the whole request when a track doesn't exist.
+func (t *Tidal) addItems(w http.ResponseWriter, r *http.Request) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	p := t.findPlaylist(r.PathValue("id"))
+	if p == nil {
+		tidalError(w, http.StatusNotFound, 2001, "Playlist not found")
+		return
+	}
+	if r.Header.Get("If-None-Match") != tidalETag(*p) {
+		tidalError(w, http.StatusPreconditionFailed, 7001, "The playlist has been modified")
+		return
+	}
+
+	var tracks []playlist.Track
+	for _, id := range strings.Split(r.FormValue("trackIds"), ",") {
+		track, ok := t.findTrack(id)
+		if !ok {
+			tidalError(w, http.StatusNotFound, 2001, "Track "+id+" not found")
+			return
+		}
+		tracks = append(tracks, track)
+	}
+	p.Tracks = append(p.Tracks, tracks...)
+	p.TrackCount = len(p.Tracks)
+
+	w.Header().Set("ETag", tidalETag(*p))
+	writeJSON(w, http.StatusOK, map[string]any{"lastUpdated": 0, "addedItemIds": strings.Split(r.FormValue("trackIds"), ",")})
+}
+
+func (t *Tidal) searchTracks(w http.ResponseWriter, r *http.Request) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	matches := t.search(adapters.ParseSearchQuery(r.FormValue("query")))
+	items := []map[string]any{}
+	start, end := tidalPage(r, len(matches))
+	for _, track := range matches[start:end] {
+		items = append(items, t.trackJSON(track))
+	}
+	writeJSON(w, http.StatusOK, tidalList(start, items, len(matches)))
+}
+
+func (t *Tidal) tracksByISRC(w http.ResponseWriter, r *http.Request) {
+	isrc := r.FormValue("isrc")
+	if isrc == "" {
+		tidalError(w, http.StatusBadRequest, 1002, "Missing isrc parameter")
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	items := []map[string]any{}
+	matches := t.search(adapters.SearchQuery{ISRC: isrc})
+	for _, track := range matches {
+		items = append(items, t.trackJSON(track))
+	}
+	writeJSON(w, http.StatusOK, tidalList(0, items, len(matches)))
+}
+
+// trackJSON renders a track. t.mu must be held.
+func (t *Tidal) trackJSON(track playlist.Track) map[string]any {
+	artists := []map[string]any{}
+	for i, name := range track.Artists {
+		a := map[string]any{"name": name, "type": "MAIN"}
+		if i < len(track.ArtistIDs) {
+			a["id"] = track.ArtistIDs[i]
+		}
+		artists = append(artists, a)
+	}
+
+	available := !t.Unavailable[track.ID]
+	var version any
+	if track.Version != "" {
+		version = track.Version
+	}
+	return map[string]any{
+		"id":             track.ID,
+		"title":          track.Name,
+		"version":        version,
+		"duration":       track.DurationMs / 1000,
+		"explicit":       track.Explicit,
+		"isrc":           track.ISRC,
+		"popularity":     track.Popularity,
+		"trackNumber":    track.TrackNumber,
+		"volumeNumber":   track.DiscNumber,
+		"url":            "http://www.tidal.com/track/" + track.ID,
+		"streamReady":    available,
+		"allowStreaming": available,
+		"artists":        artists,
+		"album":          map[string]any{"id": track.AlbumID, "title": track.Album, "releaseDate": track.ReleaseDate},
+	}
+}
+
+func tidalPlaylistJSON(p playlist.Playlist) map[string]any {
+	return map[string]any{
+		"uuid":           p.ID,
+		"title":          p.Name,
+		"description":    p.Description,
+		"numberOfTracks": len(p.Tracks),
+		"created":        p.CreatedAt.UTC().Format("2006-01-02T15:04:05.000-0700"),
+		"type":           "USER",
+		"publicPlaylist": false,
+	}
+}
+
+// tidalETag identifies the current state of a playlist
+func tidalETag(p playlist.Playlist) string {
+	return fmt.Sprintf("%q", fmt.Sprintf("%s-%d", p.ID, len(p.Tracks)))
+}
+
+// tidalPage reads the offset and limit parameters
+func tidalPage(r *http.Request, n int) (start, end int) {
+	offset, _ := strconv.Atoi(r.FormValue("offset"))
+	limit, err := strconv.Atoi(r.FormValue("limit"))
+	if err != nil || limit <= 0 {
+		limit = 10
+	}
+	return pageBounds(offset, min(limit, 100), n)
+}
+
+func tidalList(offset int, items []map[string]any, total int) map[string]any {
+	return map[string]any{
+		"limit":              len(items),
+		"offset":             offset,
+		"totalNumberOfItems": total,
+		"items":              items,
+	}
+}
+
+// tidalError writes an error in Tidal's format
+func tidalError(w http.ResponseWriter, status, subStatus int, message string) {
+	writeJSON(w, status, map[string]any{"status": status, "subStatus": subStatus, "userMessage": message})
+}
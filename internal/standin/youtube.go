@@ -0,0 +1,348 @@
+package standin
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"time"
+
+	"soundporter/internal/adapters"
+	"soundporter/internal/playlist"
+)
+
+// youtubeChannelID is the ID of the channel owning the stand-in user's playlists
+const youtubeUserChannelID = "UCstandin"
+
+// YouTube is a stand-in for the YouTube Data API v3 and Google's OAuth endpoints. Catalog tracks
+// are served as music videos titled "Artist - Title" and uploaded by the artist's Topic channel.
+type YouTube struct {
+	*httptest.Server
+	OAuth *OAuth
+	*library
+
+	privacy map[string]string
+}
+
+// NewYouTube starts a YouTube stand-in seeded with the given catalog and playlists.
+// Nil arguments fall back to the fake adapter's seed data.
+func NewYouTube(catalog []playlist.Track, playlists []playlist.Playlist) *YouTube {
+	y := &YouTube{
+		OAuth:   newOAuth(),
+		library: newLibrary(catalog, playlists),
+		privacy: make(map[string]string),
+	}
+
+	mux := http.NewServeMux()
+	y.OAuth.register(mux, "/o/oauth2/auth", "/token")
+	mux.HandleFunc("GET /youtube/v3/playlists", y.authed(y.listPlaylists))
+	mux.HandleFunc("POST /youtube/v3/playlists", y.authed(y.insertPlaylist))
+	mux.HandleFunc("GET /youtube/v3/playlistItems", y.authed(y.listPlaylistItems))
+	mux.HandleFunc("POST /youtube/v3/playlistItems", y.authed(y.insertPlaylistItem))
+	mux.HandleFunc("GET /youtube/v3/search", y.authed(y.searchVideos))
+	mux.HandleFunc("GET /youtube/v3/videos", y.authed(y.listVideos))
+	y.Server = httptest.NewServer(mux)
+	return y
+}
+
+// APIURL returns the API root to pass to the adapter
+func (y *YouTube) APIURL() string {
+	return y.URL + "/"
+}
+
+// AuthURL returns the authorization endpoint
+func (y *YouTube) AuthURL() string {
+	return y.URL + "/o/oauth2/auth"
+}
+
+// TokenURL returns the token endpoint
+func (y *YouTube) TokenURL() string {
+	return y.URL + "/token"
+}
+
+// Options returns adapter options pointing the YouTube adapter at this stand-in.
+// The adapter still runs the browser authorization flow unless a token is added with adapters.WithToken.
+func (y *YouTube) Options() []adapters.Option {
+	return []adapters.Option{
+		adapters.WithHTTPClient(y.Client()),
+		adapters.WithBaseURL(y.APIURL()),
+		adapters.WithOAuthEndpoints(y.AuthURL(), y.TokenURL()),
+	}
+}
+
+// PlaylistPrivacy returns the privacy status a playlist was created with
+func (y *YouTube) PlaylistPrivacy(id string) string {
+	y.mu.Lock()
+	defer y.mu.Unlock()
+	return y.privacy[id]
+}
+
+// authed rejects requests without a valid bearer token
+func (y *YouTube) authed(h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !y.OAuth.authorized(r) {
+			youtubeError(w, http.StatusUnauthorized, "authError", "Invalid Credentials")
+			return
+		}
+		h(w, r)
+	}
+}
+
+func (y *YouTube) listPlaylists(w http.ResponseWriter, r *http.Request) {
+	if r.FormValue("mine") != "true" {
+		youtubeError(w, http.StatusBadRequest, "missingRequiredParameter", "Only mine=true is supported")
+		return
+	}
+
+	y.mu.Lock()
+	defer y.mu.Unlock()
+
+	offset, limit := youtubePageParams(r)
+	start, end := pageBounds(offset, limit, len(y.playlists))
+	items := []map[string]any{}
+	for _, p := range y.playlists[start:end] {
+		items = append(items, y.playlistJSON(p))
+	}
+	writeJSON(w, http.StatusOK, youtubeList("youtube#playlistListResponse", items, end, len(y.playlists)))
+}
+
+func (y *YouTube) insertPlaylist(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Snippet struct {
+			Title       string `json:"title"`
+			Description string `json:"description"`
+		} `json:"snippet"`
+		Status struct {
+			PrivacyStatus string `json:"privacyStatus"`
+		} `json:"status"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil || body.Snippet.Title == "" {
+		youtubeError(w, http.StatusBadRequest, "playlistTitleRequired", "The playlist title is required")
+		return
+	}
+
+	y.mu.Lock()
+	defer y.mu.Unlock()
+	p := y.addPlaylist("PLstandin", body.Snippet.Title, body.Snippet.Description)
+	y.privacy[p.ID] = body.Status.PrivacyStatus
+	writeJSON(w, http.StatusOK, y.playlistJSON(p))
+}
+
+func (y *YouTube) listPlaylistItems(w http.ResponseWriter, r *http.Request) {
+	y.mu.Lock()
+	defer y.mu.Unlock()
+
+	p := y.findPlaylist(r.FormValue("playlistId"))
+	if p == nil {
+		youtubeError(w, http.StatusNotFound, "playlistNotFound", "The playlist identified with the request's playlistId parameter cannot be found.")
+		return
+	}
+
+	offset, limit := youtubePageParams(r)
+	start, end := pageBounds(offset, limit, len(p.Tracks))
+	items := []map[string]any{}
+	for i, t := range p.Tracks[start:end] {
+		items = append(items, map[string]any{
+			"kind": "youtube#playlistItem",
+			"id":   fmt.Sprintf("%s-%d", p.ID, start+i),
+			"snippet": map[string]any{
+				"playlistId":             p.ID,
+				"position":               start + i,
+				"title":                  youtubeVideoTitle(t),
+				"videoOwnerChannelTitle": youtubeChannelTitle(t),
+				"videoOwnerChannelId":    youtubeChannelID(t),
+				"resourceId":             map[string]string{"kind": "youtube#video", "videoId": t.ID},
+			},
+			"contentDetails": map[string]string{"videoId": t.ID},
+		})
+	}
+	writeJSON(w, http.StatusOK, youtubeList("youtube#playlistItemListResponse", items, end, len(p.Tracks)))
+}
+
+func (y *YouTube) insertPlaylistItem(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Snippet struct {
+			PlaylistID string `json:"playlistId"`
+			ResourceID struct {
+				VideoID string `json:"videoId"`
+			} `json:"resourceId"`
+		} `json:"snippet"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		youtubeError(w, http.StatusBadRequest, "parseError", "Parse Error")
+		return
+	}
+
+	y.mu.Lock()
+	defer y.mu.Unlock()
+
+	p := y.findPlaylist(body.Snippet.PlaylistID)
+	if p == nil {
+		youtubeError(w, http.StatusNotFound, "playlistNotFound", "Playlist not found.")
+		return
+	}
+	t, ok := y.findTrack(body.Snippet.ResourceID.VideoID)
+	if !ok {
+		youtubeError(w, http.StatusNotFound, "videoNotFound", "Video not found.")
+		return
+	}
+	p.Tracks = append(p.Tracks, t)
+	p.TrackCount = len(p.Tracks)
+	writeJSON(w, http.StatusOK, map[string]any{
+		"kind": "youtube#playlistItem",
+		"id":   fmt.Sprintf("%s-%d", p.ID, len(p.Tracks)-1),
+		"snippet": map[string]any{
+			"playlistId": p.ID,
+			"position":   len(p.Tracks) - 1,
+			"resourceId": map[string]string{"kind": "youtube#video", "videoId": t.ID},
+		},
+	})
+}
+
+func (y *YouTube) searchVideos(w http.ResponseWriter, r *http.Request) {
+	if r.FormValue("type") != "video" {
+		youtubeError(w, http.StatusBadRequest, "invalidSearchFilter", "Only video search is supported")
+		return
+	}
+
+	q := r.FormValue("q")
+	_, limit := youtubePageParams(r)
+
+	y.mu.Lock()
+	defer y.mu.Unlock()
+
+	items := []map[string]any{}
+	for _, t := range y.catalog {
+		if len(items) == limit {
+			break
+		}
+		if q == "" || !adapters.MatchesSearchQuery(t, adapters.SearchQuery{Track: q}) {
+			continue
+		}
+		items = append(items, map[string]any{
+			"kind": "youtube#searchResult",
+			"id":   map[string]string{"kind": "youtube#video", "videoId": t.ID},
+			"snippet": map[string]any{
+				// Search results come back HTML-escaped, unlike every other endpoint
+				"title":        html.EscapeString(youtubeVideoTitle(t)),
+				"channelTitle": youtubeChannelTitle(t),
+				"channelId":    youtubeChannelID(t),
+			},
+		})
+	}
+	writeJSON(w, http.StatusOK, youtubeList("youtube#searchListResponse", items, 0, 0))
+}
+
+func (y *YouTube) listVideos(w http.ResponseWriter, r *http.Request) {
+	r.ParseForm()
+	var ids []string
+	for _, v := range r.Form["id"] {
+		ids = append(ids, strings.Split(v, ",")...)
+	}
+	if len(ids) > 50 {
+		youtubeError(w, http.StatusBadRequest, "tooManyIds", "Too many video IDs")
+		return
+	}
+
+	y.mu.Lock()
+	defer y.mu.Unlock()
+
+	items := []map[string]any{}
+	for _, id := range ids {
+		t, ok := y.findTrack(id)
+		if !ok {
+			continue
+		}
+		items = append(items, map[string]any{
+			"kind":           "youtube#video",
+			"id":             t.ID,
+			"contentDetails": map[string]string{"duration": isoDuration(t.DurationMs)},
+		})
+	}
+	writeJSON(w, http.StatusOK, youtubeList("youtube#videoListResponse", items, 0, 0))
+}
+
+// playlistJSON renders a playlist resource. y.mu must be held.
+func (y *YouTube) playlistJSON(p playlist.Playlist) map[string]any {
+	privacy := y.privacy[p.ID]
+	if privacy == "" {
+		privacy = "private"
+	}
+	return map[string]any{
+		"kind": "youtube#playlist",
+		"id":   p.ID,
+		"snippet": map[string]any{
+			"title":       p.Name,
+			"description": p.Description,
+			"channelId":   youtubeUserChannelID,
+			"publishedAt": p.CreatedAt.UTC().Format(time.RFC3339),
+		},
+		"status":         map[string]string{"privacyStatus": privacy},
+		"contentDetails": map[string]int{"itemCount": len(p.Tracks)},
+	}
+}
+
+// youtubeVideoTitle formats a track the way official music videos are usually titled
+func youtubeVideoTitle(t playlist.Track) string {
+	return strings.Join(t.Artists, ", ") + " - " + t.Name
+}
+
+// youtubeChannelTitle returns the auto-generated Topic channel of the track's main artist
+func youtubeChannelTitle(t playlist.Track) string {
+	if len(t.Artists) == 0 {
+		return "Various Artists - Topic"
+	}
+	return t.Artists[0] + " - Topic"
+}
+
+// youtubeChannelID derives a stable channel ID for the track's main artist
+func youtubeChannelID(t playlist.Track) string {
+	if len(t.ArtistIDs) == 0 {
+		return "UCunknown"
+	}
+	return "UC" + t.ArtistIDs[0]
+}
+
+// youtubeList wraps items in a list response, adding a page token when more items follow end
+func youtubeList(kind string, items []map[string]any, end, total int) map[string]any {
+	resp := map[string]any{
+		"kind":     kind,
+		"items":    items,
+		"pageInfo": map[string]int{"totalResults": total, "resultsPerPage": len(items)},
+	}
+	if end < total {
+		resp["nextPageToken"] = strconv.Itoa(end)
+	}
+	return resp
+}
+
+// youtubePageParams reads the pageToken and maxResults query parameters.
+// Page tokens are opaque to clients; the stand-in uses the offset of the next page.
+func youtubePageParams(r *http.Request) (offset, limit int) {
+	offset, _ = strconv.Atoi(r.FormValue("pageToken"))
+	limit, err := strconv.Atoi(r.FormValue("maxResults"))
+	if err != nil || limit <= 0 {
+		limit = 5
+	}
+	return offset, min(limit, 50)
+}
+
+// isoDuration formats milliseconds as an ISO 8601 duration like PT4M13S
+func isoDuration(ms int) string {
+	d := time.Duration(ms) * time.Millisecond
+	return fmt.Sprintf("PT%dM%dS", int(d.Minutes()), int(d.Seconds())%60)
+}
+
+// youtubeError writes an error in Google's API error format
+func youtubeError(w http.ResponseWriter, status int, reason, message string) {
+	writeJSON(w, status, map[string]any{
+		"error": map[string]any{
+			"code":    status,
+			"message": message,
+			"errors":  []map[string]string{{"reason": reason, "message": message, "domain": "youtube"}},
+		},
+	})
+}
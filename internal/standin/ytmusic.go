@@ -0,0 +1,569 @@
+package standin
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"time"
+
+	"soundporter/internal/adapters"
+	"soundporter/internal/playlist"
+)
+
+const (
+	// YTMusicSAPISID is the SAPISID cookie of the stand-in's logged-in user
+	YTMusicSAPISID = "standin-sapisid"
+	// YTMusicCookie is the cookie of a browser logged in to the YouTube Music stand-in
+	YTMusicCookie = "VISITOR_INFO1_LIVE=standin; SID=standin-sid; SAPISID=" + YTMusicSAPISID + "; __Secure-3PAPISID=" + YTMusicSAPISID
+	// YTMusicAccount is the name of the stand-in's logged-in user
+	YTMusicAccount = "Stand-in"
+
+	ytmusicOrigin = "https://music.youtube.com"
+	// ytmusicSongsParams is the search filter the web client sends for songs
+	ytmusicSongsParams = "EgWKAQIIAWoMEA4QChADEAQQCRAF"
+	// ytmusicGridPage and ytmusicShelfPage are the sizes of the pages of the library and of
+	// playlists, smaller than YouTube Music's so that continuations are exercised
+	ytmusicGridPage  = 2
+	ytmusicShelfPage = 5
+)
+
+// YTMusic is a stand-in for InnerTube, the internal API of the YouTube Music web client. Like
+// YouTube Music it checks the SAPISIDHASH of every request, lists the library as a grid with
+// the old continuations and playlists in the two-column layout with the new ones. Catalog
+// tracks are songs, and unfiltered searches also find a fan upload of the first match.
+type YTMusic struct {
+	*httptest.Server
+	*library
+
+	privacy map[string]string
+}
+
+// ytmusicRequest is the body of an InnerTube request, with the fields of every endpoint
+type ytmusicRequest struct {
+	Context struct {
+		Client struct {
+			ClientName string `json:"clientName"`
+		} `json:"client"`
+	} `json:"context"`
+	BrowseID      string `json:"browseId"`
+	Continuation  string `json:"continuation"`
+	Query         string `json:"query"`
+	Params        string `json:"params"`
+	Title         string `json:"title"`
+	Description   string `json:"description"`
+	PrivacyStatus string `json:"privacyStatus"`
+	PlaylistID    string `json:"playlistId"`
+	Actions       []struct {
+		Action       string `json:"action"`
+		AddedVideoID string `json:"addedVideoId"`
+		DedupeOption string `json:"dedupeOption"`
+	} `json:"actions"`
+}
+
+// NewYTMusic starts a YouTube Music stand-in seeded with the given catalog and playlists, plus
+// the user's Liked Music. Nil arguments fall back to the fake adapter's seed data.
+func NewYTMusic(catalog []playlist.Track, playlists []playlist.Playlist) *YTMusic {
+	y := &YTMusic{
+		library: newLibrary(catalog, playlists),
+		privacy: make(map[string]string),
+	}
+	liked := playlist.Playlist{ID: "LM", Name: "Liked Music", CreatedAt: time.Now()}
+	liked.Tracks = append(liked.Tracks, y.catalog[:min(2, len(y.catalog))]...)
+	y.playlists = append([]playlist.Playlist{liked}, y.playlists...)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /youtubei/v1/account/account_menu", y.innertube(y.accountMenu))
+	mux.HandleFunc("POST /youtubei/v1/browse", y.innertube(y.browse))
+	mux.HandleFunc("POST /youtubei/v1/search", y.innertube(y.searchSongs))
+	mux.HandleFunc("POST /youtubei/v1/playlist/create", y.innertube(y.createPlaylist))
+	mux.HandleFunc("POST /youtubei/v1/browse/edit_playlist", y.innertube(y.editPlaylist))
+	y.Server = httptest.NewServer(mux)
+	return y
+}
+
+// Options returns adapter options pointing the YouTube Music adapter at this stand-in
+func (y *YTMusic) Options() []adapters.Option {
+	return []adapters.Option{
+		adapters.WithHTTPClient(y.Client()),
+		adapters.WithBaseURL(y.URL + "/youtubei/v1/"),
+	}
+}
+
+// Headers returns request headers as a browser logged in to the stand-in would send them, in
+// the form the adapter reads from its headers file
+func (y *YTMusic) Headers() string {
+	return strings.Join([]string{
+		"POST /youtubei/v1/browse?prettyPrint=false HTTP/2",
+		":authority: music.youtube.com",
+		"accept: */*",
+		"content-type: application/json",
+		"cookie: " + YTMusicCookie,
+		"user-agent: Mozilla/5.0 (standin)",
+		"x-goog-authuser: 0",
+		"x-origin: " + ytmusicOrigin,
+	}, "\n")
+}
+
+// Privacy returns the privacy status a playlist was created with
+func (y *YTMusic) Privacy(id string) string {
+	y.mu.Lock()
+	defer y.mu.Unlock()
+	return y.privacy[id]
+}
+
+// innertube decodes the request and checks that it comes from the web client of a logged-in
+// user. Requests without valid credentials are treated as signed out.
+func (y *YTMusic) innertube(h func(http.ResponseWriter, *http.Request, ytmusicRequest, bool)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req ytmusicRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			ytmusicError(w, http.StatusBadRequest, "INVALID_ARGUMENT", "Invalid JSON payload received.")
+			return
+		}
+		if req.Context.Client.ClientName != "WEB_REMIX" {
+			ytmusicError(w, http.StatusBadRequest, "INVALID_ARGUMENT", "Request contains an invalid argument.")
+			return
+		}
+		h(w, r, req, ytmusicSignedIn(r))
+	}
+}
+
+// ytmusicSignedIn checks the cookie and that the Authorization header hashes its SAPISID
+// with a recent time and the origin
+func ytmusicSignedIn(r *http.Request) bool {
+	sapisid, err := r.Cookie("__Secure-3PAPISID")
+	if err != nil {
+		if sapisid, err = r.Cookie("SAPISID"); err != nil {
+			return false
+		}
+	}
+	if sapisid.Value != YTMusicSAPISID || r.Header.Get("Origin") != ytmusicOrigin {
+		return false
+	}
+	hash, ok := strings.CutPrefix(r.Header.Get("Authorization"), "SAPISIDHASH ")
+	if !ok {
+		return false
+	}
+	ts, digest, _ := strings.Cut(hash, "_")
+	seconds, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil || time.Since(time.Unix(seconds, 0)).Abs() > 5*time.Minute {
+		return false
+	}
+	sum := sha1.Sum([]byte(ts + " " + sapisid.Value + " " + ytmusicOrigin))
+	return digest == hex.EncodeToString(sum[:])
+}
+
+// accountMenu answers with the menu of the logged-in account, or the signed-out menu
+func (y *YTMusic) accountMenu(w http.ResponseWriter, r *http.Request, req ytmusicRequest, signedIn bool) {
+	header := map[string]any{}
+	if signedIn {
+		header["activeAccountHeaderRenderer"] = map[string]any{
+			"accountName":   ytmusicText(YTMusicAccount),
+			"channelHandle": ytmusicText("@standin"),
+		}
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"actions": []map[string]any{{"openPopupAction": map[string]any{"popup": map[string]any{
+			"multiPageMenuRenderer": map[string]any{"header": header},
+		}}}},
+	})
+}
+
+// browse serves the library's playlists and the playlists themselves, and their continuations
+func (y *YTMusic) browse(w http.ResponseWriter, r *http.Request, req ytmusicRequest, signedIn bool) {
+	if !signedIn {
+		ytmusicUnauthenticated(w)
+		return
+	}
+	y.mu.Lock()
+	defer y.mu.Unlock()
+
+	// The library grid is continued the old way, with the token as parameters
+	if token := r.FormValue("ctoken"); token != "" {
+		offset, err := strconv.Atoi(strings.TrimPrefix(token, "grid:"))
+		if err != nil || r.FormValue("continuation") != token || r.FormValue("type") != "next" {
+			ytmusicError(w, http.StatusBadRequest, "INVALID_ARGUMENT", "Request contains an invalid argument.")
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{
+			"continuationContents": map[string]any{"gridContinuation": y.grid(offset)},
+		})
+		return
+	}
+
+	// Playlists are continued the new way, with the token in the body
+	if req.Continuation != "" {
+		id, offset, ok := strings.Cut(req.Continuation, "@")
+		start, err := strconv.Atoi(offset)
+		p := y.findPlaylist(id)
+		if !ok || err != nil || p == nil {
+			ytmusicError(w, http.StatusBadRequest, "INVALID_ARGUMENT", "Request contains an invalid argument.")
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{
+			"onResponseReceivedActions": []map[string]any{{"appendContinuationItemsAction": map[string]any{
+				"continuationItems": ytmusicShelfItems(p, start),
+			}}},
+		})
+		return
+	}
+
+	switch id := req.BrowseID; {
+	case id == "FEmusic_liked_playlists":
+		writeJSON(w, http.StatusOK, ytmusicTabs(map[string]any{"sectionListRenderer": map[string]any{
+			"contents": []map[string]any{{"gridRenderer": y.grid(0)}},
+		}}))
+	case strings.HasPrefix(id, "VL"):
+		p := y.findPlaylist(strings.TrimPrefix(id, "VL"))
+		if p == nil {
+			ytmusicError(w, http.StatusNotFound, "NOT_FOUND", "Requested entity was not found.")
+			return
+		}
+		y.playlistPage(w, p)
+	default:
+		ytmusicError(w, http.StatusBadRequest, "INVALID_ARGUMENT", "Request contains an invalid argument.")
+	}
+}
+
+// grid renders a page of the library's playlists, after the button creating one. y.mu must be held.
+func (y *YTMusic) grid(offset int) map[string]any {
+	var items []map[string]any
+	if offset == 0 {
+		items = append(items, map[string]any{"musicTwoRowItemRenderer": map[string]any{
+			"title": ytmusicText("New playlist"),
+			"navigationEndpoint": map[string]any{
+				"createPlaylistEndpoint": map[string]any{},
+			},
+		}})
+	}
+	start, end := pageBounds(offset, ytmusicGridPage, len(y.playlists))
+	for _, p := range y.playlists[start:end] {
+		subtitle := ytmusicSubtitle("Playlist", YTMusicAccount, ytmusicCount(len(p.Tracks)))
+		if p.ID == "LM" {
+			subtitle = ytmusicText("Auto playlist")
+		}
+		items = append(items, map[string]any{"musicTwoRowItemRenderer": map[string]any{
+			"title":    ytmusicText(p.Name),
+			"subtitle": subtitle,
+			"navigationEndpoint": map[string]any{"browseEndpoint": map[string]any{
+				"browseId": "VL" + p.ID,
+				"browseEndpointContextSupportedConfigs": map[string]any{
+					"browseEndpointContextMusicConfig": map[string]any{"pageType": "MUSIC_PAGE_TYPE_PLAYLIST"},
+				},
+			}},
+		}})
+	}
+	grid := map[string]any{"items": items}
+	if end < len(y.playlists) {
+		grid["continuations"] = []map[string]any{{"nextContinuationData": map[string]any{
+			"continuation": "grid:" + strconv.Itoa(end),
+		}}}
+	}
+	return grid
+}
+
+// playlistPage renders a playlist in the two-column layout, with a shelf of suggested songs
+// after the playlist's own. y.mu must be held.
+func (y *YTMusic) playlistPage(w http.ResponseWriter, p *playlist.Playlist) {
+	var suggestions []map[string]any
+	for _, t := range y.catalog[max(len(y.catalog)-3, 0):] {
+		suggestions = append(suggestions, ytmusicListItemJSON(t, false))
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"header": map[string]any{"musicEditablePlaylistDetailHeaderRenderer": map[string]any{
+			"header": map[string]any{"musicResponsiveHeaderRenderer": map[string]any{
+				"title":          ytmusicText(p.Name),
+				"description":    map[string]any{"musicDescriptionShelfRenderer": map[string]any{"description": ytmusicText(p.Description)}},
+				"secondSubtitle": ytmusicText(ytmusicCount(len(p.Tracks))),
+			}},
+		}},
+		"contents": map[string]any{"twoColumnBrowseResultsRenderer": map[string]any{
+			"secondaryContents": map[string]any{"sectionListRenderer": map[string]any{
+				"contents": []map[string]any{
+					{"musicPlaylistShelfRenderer": map[string]any{
+						"playlistId": p.ID,
+						"contents":   ytmusicShelfItems(p, 0),
+					}},
+					{"musicShelfRenderer": map[string]any{
+						"title":    ytmusicText("Suggestions"),
+						"contents": suggestions,
+					}},
+				},
+				"continuations": []map[string]any{{"nextContinuationData": map[string]any{"continuation": "suggestions"}}},
+			}},
+		}},
+	})
+}
+
+// ytmusicShelfItems renders a page of a playlist's tracks, ending with the item that continues
+// the list if more tracks follow
+func ytmusicShelfItems(p *playlist.Playlist, offset int) []map[string]any {
+	items := []map[string]any{}
+	start, end := pageBounds(offset, ytmusicShelfPage, len(p.Tracks))
+	for _, t := range p.Tracks[start:end] {
+		items = append(items, ytmusicListItemJSON(t, true))
+	}
+	if end < len(p.Tracks) {
+		items = append(items, map[string]any{"continuationItemRenderer": map[string]any{
+			"trigger": "CONTINUATION_TRIGGER_ON_ITEM_SHOWN",
+			"continuationEndpoint": map[string]any{"continuationCommand": map[string]any{
+				"token":   p.ID + "@" + strconv.Itoa(end),
+				"request": "CONTINUATION_REQUEST_TYPE_BROWSE",
+			}},
+		}})
+	}
+	return items
+}
+
+// searchSongs finds catalog songs matching the query. Without the songs filter the results also
+// hold a fan upload of the first match, as YouTube Music mixes videos in.
+func (y *YTMusic) searchSongs(w http.ResponseWriter, r *http.Request, req ytmusicRequest, signedIn bool) {
+	if !signedIn {
+		ytmusicUnauthenticated(w)
+		return
+	}
+	y.mu.Lock()
+	defer y.mu.Unlock()
+
+	var items []map[string]any
+	for _, t := range y.search(adapters.SearchQuery{Track: req.Query}) {
+		if len(items) == 0 && req.Params != ytmusicSongsParams {
+			items = append(items, ytmusicFanUploadJSON(t))
+		}
+		items = append(items, ytmusicListItemJSON(t, false))
+	}
+	shelves := []map[string]any{}
+	if len(items) > 0 {
+		shelves = append(shelves, map[string]any{"musicShelfRenderer": map[string]any{
+			"title":    ytmusicText("Songs"),
+			"contents": items,
+		}})
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"contents": map[string]any{"tabbedSearchResultsRenderer": map[string]any{"tabs": []map[string]any{{
+			"tabRenderer": map[string]any{"content": map[string]any{"sectionListRenderer": map[string]any{"contents": shelves}}},
+		}}}},
+	})
+}
+
+func (y *YTMusic) createPlaylist(w http.ResponseWriter, r *http.Request, req ytmusicRequest, signedIn bool) {
+	if !signedIn {
+		ytmusicUnauthenticated(w)
+		return
+	}
+	switch req.PrivacyStatus {
+	case "PRIVATE", "UNLISTED", "PUBLIC":
+	default:
+		ytmusicError(w, http.StatusBadRequest, "INVALID_ARGUMENT", "Request contains an invalid argument.")
+		return
+	}
+	if req.Title == "" {
+		ytmusicError(w, http.StatusBadRequest, "INVALID_ARGUMENT", "Request contains an invalid argument.")
+		return
+	}
+
+	y.mu.Lock()
+	defer y.mu.Unlock()
+	p := y.addPlaylist("PLstandin", req.Title, req.Description)
+	y.privacy[p.ID] = req.PrivacyStatus
+	writeJSON(w, http.StatusOK, map[string]any{"playlistId": p.ID})
+}
+
+// editPlaylist adds songs to a playlist. Like YouTube Music the whole edit fails if a song
+// doesn't exist, and songs already in the playlist are skipped when asked to.
+func (y *YTMusic) editPlaylist(w http.ResponseWriter, r *http.Request, req ytmusicRequest, signedIn bool) {
+	if !signedIn {
+		ytmusicUnauthenticated(w)
+		return
+	}
+	y.mu.Lock()
+	defer y.mu.Unlock()
+
+	p := y.findPlaylist(req.PlaylistID)
+	if p == nil || p.ID == "LM" {
+		writeJSON(w, http.StatusOK, map[string]any{"status": "STATUS_FAILED"})
+		return
+	}
+	var added []playlist.Track
+	for _, a := range req.Actions {
+		t, ok := y.findTrack(a.AddedVideoID)
+		if a.Action != "ACTION_ADD_VIDEO" || !ok {
+			writeJSON(w, http.StatusOK, map[string]any{"status": "STATUS_FAILED"})
+			return
+		}
+		if a.DedupeOption == "DEDUPE_OPTION_SKIP" && (ytmusicContains(p.Tracks, t.ID) || ytmusicContains(added, t.ID)) {
+			continue
+		}
+		added = append(added, t)
+	}
+	p.Tracks = append(p.Tracks, added...)
+
+	var results []map[string]any
+	for _, t := range added {
+		results = append(results, map[string]any{"playlistEditVideoAddedResultData": map[string]any{"videoId": t.ID}})
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"status": "STATUS_SUCCEEDED", "playlistEditResults": results})
+}
+
+func ytmusicContains(tracks []playlist.Track, id string) bool {
+	for _, t := range tracks {
+		if t.ID == id {
+			return true
+		}
+	}
+	return false
+}
+
+// ytmusicListItemJSON renders a catalog track as a song row. Playlists give the artists, the
+// album and the duration columns of their own, search results list them in one column. Tracks
+// without artist IDs have no artist pages, so their artists are credited in one unlinked run.
+func ytmusicListItemJSON(t playlist.Track, inPlaylist bool) map[string]any {
+	var artists []map[string]any
+	var credit strings.Builder
+	for i, name := range t.Artists {
+		if i > 0 {
+			sep := ", "
+			if i == len(t.Artists)-1 {
+				sep = " & "
+			}
+			artists = append(artists, map[string]any{"text": sep})
+			credit.WriteString(sep)
+		}
+		id := "UCunknown"
+		if i < len(t.ArtistIDs) {
+			id = "UC" + t.ArtistIDs[i]
+		}
+		artists = append(artists, ytmusicLink(name, id, "MUSIC_PAGE_TYPE_ARTIST"))
+		credit.WriteString(name)
+	}
+	if len(t.ArtistIDs) == 0 && len(t.Artists) > 0 {
+		artists = []map[string]any{{"text": credit.String()}}
+	}
+	album := ytmusicLink(t.Album, "MPREb_"+t.AlbumID, "MUSIC_PAGE_TYPE_ALBUM")
+	duration := map[string]any{"text": ytmusicDuration(t.DurationMs)}
+
+	columns := []map[string]any{ytmusicColumn([]map[string]any{ytmusicWatchRun(t.Name, t.ID, "MUSIC_VIDEO_TYPE_ATV")})}
+	item := map[string]any{
+		"overlay": map[string]any{"musicItemThumbnailOverlayRenderer": map[string]any{"content": map[string]any{
+			"musicPlayButtonRenderer": map[string]any{"playNavigationEndpoint": ytmusicWatchRun(t.Name, t.ID, "MUSIC_VIDEO_TYPE_ATV")["navigationEndpoint"]},
+		}}},
+	}
+	if inPlaylist {
+		columns = append(columns, ytmusicColumn(artists), ytmusicColumn([]map[string]any{album}))
+		item["fixedColumns"] = []map[string]any{{"musicResponsiveListItemFixedColumnRenderer": map[string]any{
+			"text": map[string]any{"runs": []map[string]any{duration}},
+		}}}
+		item["playlistItemData"] = map[string]any{"videoId": t.ID}
+	} else {
+		runs := append(artists, map[string]any{"text": " • "}, album, map[string]any{"text": " • "}, duration)
+		columns = append(columns, ytmusicColumn(runs))
+	}
+	item["flexColumns"] = columns
+	if t.Explicit {
+		item["badges"] = []map[string]any{{"musicInlineBadgeRenderer": map[string]any{
+			"icon": map[string]any{"iconType": "MUSIC_EXPLICIT_BADGE"},
+		}}}
+	}
+	return map[string]any{"musicResponsiveListItemRenderer": item}
+}
+
+// ytmusicFanUploadJSON renders a video a fan uploaded of the track, titled like a video
+func ytmusicFanUploadJSON(t playlist.Track) map[string]any {
+	id := "ugc-" + t.ID
+	return map[string]any{"musicResponsiveListItemRenderer": map[string]any{
+		"flexColumns": []map[string]any{
+			ytmusicColumn([]map[string]any{ytmusicWatchRun(youtubeVideoTitle(t)+" (Official Video) [HD]", id, "MUSIC_VIDEO_TYPE_UGC")}),
+			ytmusicColumn([]map[string]any{
+				{"text": "Video"}, {"text": " • "},
+				ytmusicLink("Fan Uploads", "UCfanuploads", "MUSIC_PAGE_TYPE_USER_CHANNEL"),
+				{"text": " • "}, {"text": "1.2M views"}, {"text": " • "}, {"text": ytmusicDuration(t.DurationMs)},
+			}),
+		},
+	}}
+}
+
+func ytmusicColumn(runs []map[string]any) map[string]any {
+	return map[string]any{"musicResponsiveListItemFlexColumnRenderer": map[string]any{
+		"text": map[string]any{"runs": runs},
+	}}
+}
+
+// ytmusicLink is a run linking to a page, such as an artist or album
+func ytmusicLink(text, browseID, pageType string) map[string]any {
+	return map[string]any{
+		"text": text,
+		"navigationEndpoint": map[string]any{"browseEndpoint": map[string]any{
+			"browseId": browseID,
+			"browseEndpointContextSupportedConfigs": map[string]any{
+				"browseEndpointContextMusicConfig": map[string]any{"pageType": pageType},
+			},
+		}},
+	}
+}
+
+// ytmusicWatchRun is a run playing a video
+func ytmusicWatchRun(text, videoID, videoType string) map[string]any {
+	return map[string]any{
+		"text": text,
+		"navigationEndpoint": map[string]any{"watchEndpoint": map[string]any{
+			"videoId": videoID,
+			"watchEndpointMusicSupportedConfigs": map[string]any{
+				"watchEndpointMusicConfig": map[string]any{"musicVideoType": videoType},
+			},
+		}},
+	}
+}
+
+// ytmusicText renders plain text as formatted text with a single run
+func ytmusicText(text string) map[string]any {
+	return map[string]any{"runs": []map[string]any{{"text": text}}}
+}
+
+// ytmusicSubtitle renders texts as runs separated by dots
+func ytmusicSubtitle(texts ...string) map[string]any {
+	var runs []map[string]any
+	for i, text := range texts {
+		if i > 0 {
+			runs = append(runs, map[string]any{"text": " • "})
+		}
+		runs = append(runs, map[string]any{"text": text})
+	}
+	return map[string]any{"runs": runs}
+}
+
+// ytmusicTabs wraps the content of a page in the single-column layout
+func ytmusicTabs(content map[string]any) map[string]any {
+	return map[string]any{"contents": map[string]any{"singleColumnBrowseResultsRenderer": map[string]any{
+		"tabs": []map[string]any{{"tabRenderer": map[string]any{"content": content}}},
+	}}}
+}
+
+// ytmusicCount formats a track count the way the web client labels it
+func ytmusicCount(n int) string {
+	if n == 1 {
+		return "1 song"
+	}
+	return fmt.Sprintf("%d songs", n)
+}
+
+// ytmusicDuration formats milliseconds like "3:45"
+func ytmusicDuration(ms int) string {
+	seconds := ms / 1000
+	return fmt.Sprintf("%d:%02d", seconds/60, seconds%60)
+}
+
+func ytmusicUnauthenticated(w http.ResponseWriter) {
+	ytmusicError(w, http.StatusUnauthorized, "UNAUTHENTICATED", "Request is missing required authentication credential.")
+}
+
+// ytmusicError writes an error in the format of InnerTube
+func ytmusicError(w http.ResponseWriter, status int, code, message string) {
+	writeJSON(w, status, map[string]any{
+		"error": map[string]any{"code": status, "message": message, "status": code},
+	})
+}
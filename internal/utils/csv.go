@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"os"
 	"reflect"
+	"strconv"
 	"strings"
 )
 
@@ -30,73 +31,120 @@ func StructToCsvHeader(t reflect.Type) []string {
 // WriteToCsvFile writes the given headers and data to a CSV file at the specified filePath.
 // For slices, it joins the elements using a semicolon (;) to handle multi-value fields.
 func WriteToCsvFile[T any](filePath string, headers []string, data []T) error {
-	file, err := os.Create(filePath)
+	w, err := NewCsvWriter[T](filePath, headers)
 	if err != nil {
 		return err
 	}
-	defer file.Close()
 
-	writer := csv.NewWriter(file)
-	defer writer.Flush()
+	for _, item := range data {
+		if err := w.Write(item); err != nil {
+			w.Close()
+			return err
+		}
+	}
+
+	return w.Close()
+}
+
+// CsvWriter streams structs to a CSV file one row at a time, so large data sets
+// never have to be held in memory. Rows are encoded the same way as WriteToCsvFile.
+type CsvWriter[T any] struct {
+	file    *os.File
+	writer  *csv.Writer
+	headers []string
+}
+
+// NewCsvWriter creates the file at filePath and writes the header row
+func NewCsvWriter[T any](filePath string, headers []string) (*CsvWriter[T], error) {
+	file, err := os.Create(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	w := &CsvWriter[T]{
+		file:    file,
+		writer:  csv.NewWriter(file),
+		headers: headers,
+	}
+	if err := w.writer.Write(headers); err != nil {
+		file.Close()
+		return nil, err
+	}
+	return w, nil
+}
 
-	// Write the headers
-	if err := writer.Write(headers); err != nil {
+// Write encodes a single struct as a CSV row
+func (w *CsvWriter[T]) Write(item T) error {
+	row, err := structToCsvRow(item, w.headers)
+	if err != nil {
 		return err
 	}
+	return w.writer.Write(row)
+}
 
-	// Write the data rows
-	for _, item := range data {
-		row := make([]string, len(headers))
-		v := reflect.ValueOf(item)
+// Flush writes any buffered rows to the file
+func (w *CsvWriter[T]) Flush() error {
+	w.writer.Flush()
+	return w.writer.Error()
+}
 
-		// If item is a pointer, get the value it points to
-		if v.Kind() == reflect.Ptr {
-			v = v.Elem()
-		}
+// Close flushes the remaining rows and closes the file
+func (w *CsvWriter[T]) Close() error {
+	if err := w.Flush(); err != nil {
+		w.file.Close()
+		return err
+	}
+	return w.file.Close()
+}
 
-		if v.Kind() != reflect.Struct {
-			return fmt.Errorf("data must be a slice of structs")
-		}
+// structToCsvRow converts a struct to a row ordered by headers
+func structToCsvRow(item any, headers []string) ([]string, error) {
+	row := make([]string, len(headers))
+	v := reflect.ValueOf(item)
 
-		t := v.Type()
-		for i := 0; i < t.NumField(); i++ {
-			field := t.Field(i)
-			fieldValue := v.Field(i)
+	// If item is a pointer, get the value it points to
+	if v.Kind() == reflect.Ptr {
+		v = v.Elem()
+	}
 
-			// Get the index in the headers slice
-			csvTag := field.Tag.Get("csv")
-			headerName := field.Name
-			if csvTag != "" {
-				headerName = csvTag
-			}
+	if v.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("data must be a slice of structs")
+	}
 
-			idx := indexOf(headers, headerName)
-			if idx < 0 {
-				continue // Skip fields not in the headers
-			}
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		fieldValue := v.Field(i)
 
-			// Convert field value to string based on its kind
-			var strValue string
-			if fieldValue.Kind() == reflect.Slice {
-				// Join slice elements with semicolon
-				var sliceValues []string
-				for j := 0; j < fieldValue.Len(); j++ {
-					sliceValues = append(sliceValues, fmt.Sprintf("%v", fieldValue.Index(j).Interface()))
-				}
-				strValue = strings.Join(sliceValues, ";")
-			} else {
-				strValue = fmt.Sprintf("%v", fieldValue.Interface())
-			}
+		// Get the index in the headers slice
+		csvTag := field.Tag.Get("csv")
+		headerName := field.Name
+		if csvTag != "" {
+			headerName = csvTag
+		}
 
-			row[idx] = strValue
+		idx := indexOf(headers, headerName)
+		if idx < 0 {
+			continue // Skip fields not in the headers
 		}
 
-		if err := writer.Write(row); err != nil {
-			return err
+		// Convert field value to string based on its kind
+		var strValue string
+		if fieldValue.Kind() == reflect.Slice {
+			// Join slice elements with semicolon
+			var sliceValues []string
+			for j := 0; j < fieldValue.Len(); j++ {
+				sliceValues = append(sliceValues, fmt.Sprintf("%v", fieldValue.Index(j).Interface()))
+			}
+			strValue = strings.Join(sliceValues, ";")
+		} else {
+			strValue = fmt.Sprintf("%v", fieldValue.Interface())
 		}
+
+		row[idx] = strValue
 	}
 
-	return nil
+	return row, nil
 }
 
 // indexOf returns the index of a string in a slice or -1 if not found
@@ -108,3 +156,104 @@ func indexOf(slice []string, item string) int {
 	}
 	return -1
 }
+
+// ReadCsvFile reads a CSV file into a slice of structs, matching columns to fields by their `csv` tag
+// or field name. Columns without a matching field are ignored. Slice fields are split on semicolons (;),
+// mirroring WriteToCsvFile.
+func ReadCsvFile[T any](filePath string) ([]T, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	reader := csv.NewReader(file)
+	reader.FieldsPerRecord = -1
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, nil
+	}
+
+	var zero T
+	t := reflect.TypeOf(zero)
+	if t.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("data must be a slice of structs")
+	}
+	headers := StructToCsvHeader(t)
+
+	// Map each column to the field it populates
+	columns := make([]int, len(records[0]))
+	for i, name := range records[0] {
+		columns[i] = indexOf(headers, strings.TrimSpace(name))
+	}
+
+	data := make([]T, 0, len(records)-1)
+	for line, record := range records[1:] {
+		var item T
+		v := reflect.ValueOf(&item).Elem()
+		for i, value := range record {
+			if i >= len(columns) || columns[i] < 0 {
+				continue
+			}
+			if err := setFieldFromString(v.Field(columns[i]), value); err != nil {
+				return nil, fmt.Errorf("line %d, column %s: %v", line+2, records[0][i], err)
+			}
+		}
+		data = append(data, item)
+	}
+
+	return data, nil
+}
+
+// setFieldFromString parses value into the field according to its kind
+func setFieldFromString(field reflect.Value, value string) error {
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(value)
+	case reflect.Slice:
+		if value == "" {
+			return nil
+		}
+		parts := strings.Split(value, ";")
+		slice := reflect.MakeSlice(field.Type(), len(parts), len(parts))
+		for i, part := range parts {
+			if err := setFieldFromString(slice.Index(i), part); err != nil {
+				return err
+			}
+		}
+		field.Set(slice)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if value == "" {
+			return nil
+		}
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return err
+		}
+		field.SetInt(n)
+	case reflect.Float32, reflect.Float64:
+		if value == "" {
+			return nil
+		}
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return err
+		}
+		field.SetFloat(f)
+	case reflect.Bool:
+		if value == "" {
+			return nil
+		}
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		field.SetBool(b)
+	default:
+		return fmt.Errorf("unsupported field type %s", field.Type())
+	}
+	return nil
+}